@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"crypto"
 	"embed"
 	"flag"
 	"fmt"
@@ -8,9 +10,15 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/lao-tseu-is-alive/go-cloud-learning-01-http/internal/todos"
 	"github.com/lao-tseu-is-alive/go-cloud-learning-01-http/pkg/config"
+	"github.com/lao-tseu-is-alive/go-cloud-learning-01-http/pkg/jwtauth"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
 )
 
 const (
@@ -27,10 +35,24 @@ const (
 	*/
 	defaultDBSslMode = "disable"
 	defaultDBDriver  = "postgres"
+	defaultDBPath    = "todos.db"
+	// defaultCacheControl lets clients keep the todos but forces them to revalidate them with a conditional GET
+	defaultCacheControl = "no-cache"
+	// defaultTrashRetention is the duration the deleted todos stay in the trash before being purged automatically
+	defaultTrashRetention = 30 * 24 * time.Hour
+	// trashJanitorInterval is the delay between two purges of the expired todos in the trash
+	trashJanitorInterval = time.Hour
+	// defaultReminderInterval is the delay between two scans of the reminders to send
+	defaultReminderInterval = time.Minute
+	// shutDownTimeout is the delay given to the requests in progress to complete when the server stops
+	shutDownTimeout = 10 * time.Second
+	// jwtLeeway is the clock skew tolerated between the issuer of the tokens and the server
+	jwtLeeway = 30 * time.Second
 	//webRootDir       = "cmd/todosServer/swagger-ui"
 	webRootDir = "swagger-ui"
+	// staticFilesPath is the route of the files of webRootDir
+	staticFilesPath = "/*"
 	/*
-		shutDownTimeout     = 2 * time.Second // number of second to wait before closing server
 		defaultReadTimeout  = 2 * time.Minute
 		defaultWriteTimeout = 2 * time.Minute
 		defaultWebRootDir   = "./web/dist"
@@ -52,11 +74,42 @@ var embededFiles embed.FS
 func GetNewServer(l *log.Logger, store todos.Storage) *echo.Echo {
 	e := echo.New()
 	e.HideBanner = true
-	e.Use(middleware.CORS())
+	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		// allows javascript clients to read the pagination and caching headers
+		ExposeHeaders: []string{todos.HeaderTotalCount, todos.HeaderLink, todos.HeaderETag, todos.HeaderLastModified},
+	}))
+	trustUserHeader, err := config.GetTrustUserHeaderFromEnv()
+	if err != nil {
+		log.Fatalf("💥💥 error doing config.GetTrustUserHeaderFromEnv. error: %v\n", err)
+	}
 	myTodosApi := todos.Service{
-		Log:   l,
-		Store: store,
+		Log:             l,
+		Store:           store,
+		CacheControl:    config.GetCacheControlFromEnv(defaultCacheControl),
+		TrustUserHeader: trustUserHeader,
+	}
+	// the static files of the swagger ui are public, every other request is made by a user
+	isPublic := func(c echo.Context) bool {
+		return c.Path() == staticFilesPath
+	}
+	// the scripts are authenticated with their API key instead of a token
+	jwtAuth, err := getJwtAuthConfig(func(c echo.Context) bool {
+		return isPublic(c) || c.Request().Header.Get(todos.HeaderApiKey) != ""
+	})
+	if err != nil {
+		log.Fatalf("💥💥 error doing getJwtAuthConfig. error: %v\n", err)
 	}
+	switch {
+	case jwtAuth != nil:
+		e.Use(jwtauth.Middleware(*jwtAuth))
+		// the requests without token are rejected by the middleware, so the header is never trusted
+		myTodosApi.TrustUserHeader = false
+	case trustUserHeader:
+		l.Printf("WARNING: AUTH_TRUST_USER_HEADER is true, the users are trusted from the %s header, only do this in dev", todos.HeaderUser)
+	default:
+		l.Printf("WARNING: no JWT_SECRET, JWT_PUBLIC_KEY_FILES or JWT_JWKS_FILE, only the requests with an API key are accepted, set ADMIN_API_KEY to create the first one")
+	}
+	e.Use(myTodosApi.Authenticate(isPublic))
 	webRootDirPath, err := filepath.Abs(webRootDir)
 	if err != nil {
 		log.Fatalf("Problem getting absolute path of directory: %s\nError:\n%v\n", webRootDir, err)
@@ -67,13 +120,63 @@ func GetNewServer(l *log.Logger, store todos.Storage) *echo.Echo {
 	l.Printf("Using live mode serving from %s", webRootDirPath)
 	e.Static("/", webRootDirPath)
 
-	// here the routes defined in OpenApi todos.yaml are registered
-	todos.RegisterHandlers(e, &myTodosApi)
+	// here the routes defined in OpenApi todos.yaml are registered, with the permission of todos.Policies they require
+	todos.RegisterHandlers(todos.NewAuthorizedRouter(e), &myTodosApi)
 	// add another route for maxId
-	e.GET("/todos/maxid", myTodosApi.GetMaxId)
+	e.GET("/todos/maxid", myTodosApi.GetMaxId, todos.Authorize("GetMaxId"))
 	return e
 }
 
+// getJwtAuthConfig returns the validation of the JSON Web Tokens defined by the environment variables,
+// or nil when the server does not use tokens
+func getJwtAuthConfig(skipper middleware.Skipper) (*jwtauth.Config, error) {
+	jwtEnv, err := config.GetJwtAuthFromEnv()
+	if err != nil || jwtEnv == nil {
+		return nil, err
+	}
+	jwtAuth := jwtauth.Config{
+		Secret:   []byte(jwtEnv.Secret),
+		Keys:     map[string]crypto.PublicKey{},
+		Issuer:   jwtEnv.Issuer,
+		Audience: jwtEnv.Audience,
+		Leeway:   jwtLeeway,
+		Skipper:  skipper,
+	}
+	if len(jwtEnv.PublicKeyFiles) > 0 {
+		keys, err := jwtauth.LoadPEMKeys(jwtEnv.PublicKeyFiles)
+		if err != nil {
+			return nil, err
+		}
+		for kid, key := range keys {
+			jwtAuth.Keys[kid] = key
+		}
+	}
+	if jwtEnv.JwksFile != "" {
+		keys, err := jwtauth.LoadJWKS(jwtEnv.JwksFile)
+		if err != nil {
+			return nil, err
+		}
+		for kid, key := range keys {
+			jwtAuth.Keys[kid] = key
+		}
+	}
+	if err := jwtAuth.Validate(); err != nil {
+		return nil, err
+	}
+	return &jwtAuth, nil
+}
+
+// getReminderNotifier returns the notifier of the reminders defined by the environment variables :
+// the webhook notifier when REMINDER_WEBHOOK_URL is set, the log notifier otherwise
+func getReminderNotifier(l *log.Logger) (todos.Notifier, error) {
+	webhookUrl, err := config.GetReminderWebhookUrlFromEnv()
+	if err != nil || webhookUrl == "" {
+		return todos.LogNotifier{Log: l}, err
+	}
+	l.Printf("info : the reminders are sent to the webhook %s", webhookUrl)
+	return todos.NewWebhookNotifier(webhookUrl), nil
+}
+
 func GetVersion() string {
 	return fmt.Sprintf("%s Ver: %s, Build: %s, rev: %s ", appName, VERSION, BuildStamp, GitRevision)
 }
@@ -109,14 +212,73 @@ func main() {
 			log.Fatalf("💥💥 error doing config.GetPgDbDsnUrlFromEnv. error: %v\n", err)
 		}
 	}
+	if driver == "sqlite" {
+		dbDsn = config.GetDbPathFromEnv(defaultDBPath)
+	}
+	if driver == "memory" {
+		// the memory driver is durable only when a directory is given
+		dbDsn = config.GetDbMemoryDirFromEnv("")
+	}
 
 	s, err := todos.GetStorageInstance(driver, dbDsn, l)
 	if err != nil {
 		l.Fatalf("💥💥 error getting Storage Instance for driver %s. error: %v\n", driver, err)
 	}
 	defer s.Close()
+	adminApiKey, err := config.GetAdminApiKeyFromEnv()
+	if err != nil {
+		log.Fatalf("💥💥 error doing config.GetAdminApiKeyFromEnv. error: %v\n", err)
+	}
+	if adminApiKey != "" {
+		created, err := todos.BootstrapAdminApiKey(context.Background(), s, adminApiKey)
+		if err != nil {
+			l.Fatalf("💥💥 error creating the API key of ADMIN_API_KEY. error: %v\n", err)
+		}
+		if created {
+			l.Printf("info : created the admin API key of ADMIN_API_KEY for user %s", todos.AdminLogin)
+		}
+	}
+
+	trashRetention, err := config.GetTrashRetentionFromEnv(defaultTrashRetention)
+	if err != nil {
+		log.Fatalf("💥💥 error doing config.GetTrashRetentionFromEnv. error: %v\n", err)
+	}
+	reminderInterval, err := config.GetReminderIntervalFromEnv(defaultReminderInterval)
+	if err != nil {
+		log.Fatalf("💥💥 error doing config.GetReminderIntervalFromEnv. error: %v\n", err)
+	}
+	notifier, err := getReminderNotifier(l)
+	if err != nil {
+		log.Fatalf("💥💥 error doing config.GetReminderWebhookUrlFromEnv. error: %v\n", err)
+	}
+	// the server and the background jobs stop when the process is interrupted or terminated
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var jobs sync.WaitGroup
+	jobs.Add(2)
+	go func() {
+		defer jobs.Done()
+		todos.RunTrashJanitor(ctx, s, trashRetention, trashJanitorInterval, l)
+	}()
+	go func() {
+		defer jobs.Done()
+		todos.RunReminderScheduler(ctx, s, notifier, reminderInterval, l)
+	}()
 
 	e := GetNewServer(l, s)
 	l.Printf("Will start http server ««%s»», listening on: %s \n", GetVersion(), listenAddress)
-	e.Logger.Fatal(e.Start(listenAddress))
+	go func() {
+		if err := e.Start(listenAddress); err != nil && err != http.ErrServerClosed {
+			e.Logger.Fatal(err)
+		}
+	}()
+	<-ctx.Done()
+	l.Printf("info : stopping the http server and the background jobs")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutDownTimeout)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		l.Printf("error : http server did not stop cleanly, error : %v", err)
+	}
+	// the store is closed once the background jobs are done with it
+	jobs.Wait()
 }
@@ -1,8 +1,17 @@
 package main
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"github.com/golang-jwt/jwt"
 	"github.com/labstack/echo/v4"
 	"github.com/lao-tseu-is-alive/go-cloud-learning-01-http/internal/todos"
 	"github.com/lao-tseu-is-alive/go-cloud-learning-01-http/pkg/config"
@@ -11,8 +20,11 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 const (
@@ -20,6 +32,14 @@ const (
 	DEBUG          = false
 )
 
+// TestMain trusts the users from the X-User header, the tests of the tokens and of the default configuration change it
+func TestMain(m *testing.M) {
+	if err := os.Setenv("AUTH_TRUST_USER_HEADER", "true"); err != nil {
+		log.Fatalf("Unable to set env variable AUTH_TRUST_USER_HEADER")
+	}
+	os.Exit(m.Run())
+}
+
 type idCounter struct {
 	currentMaxId int32
 }
@@ -62,6 +82,7 @@ type testScenario struct {
 	name           string
 	wantStatusCode int
 	wantBody       string
+	wantHeaders    map[string]string
 	r              *http.Request
 }
 
@@ -71,6 +92,12 @@ func getTestTable(t *testing.T, ts *httptest.Server, myId idCounter, jsonInitial
 		if err != nil {
 			t.Fatalf("### ERROR http.NewRequest %s on [%s] \n", method, url)
 		}
+		// the requests are made by the administrator, unless another user is given with withHeader
+		r.Header.Set(todos.HeaderUser, todos.AdminLogin)
+		return r
+	}
+	withHeader := func(r *http.Request, header, value string) *http.Request {
+		r.Header.Set(header, value)
 		return r
 	}
 
@@ -148,16 +175,16 @@ func getTestTable(t *testing.T, ts *httptest.Server, myId idCounter, jsonInitial
 			r:              newRequest(http.MethodDelete, getUrlForId(myId), ""),
 		},
 		{
-			name:           "13: GetMaxId after a successful DeleteTodo, should return one less",
+			name:           "13: GetMaxId after a successful DeleteTodo, should not change because the todo is in the trash",
 			wantStatusCode: http.StatusOK,
-			wantBody:       fmt.Sprintf("%d", myId.decrement()),
+			wantBody:       fmt.Sprintf("%d", myId.decrement()+1), // the next scenarios use the todo created before
 			r:              newRequest(http.MethodGet, "/todos/maxid", ""),
 		},
 		{
 			name:           "14: UpdateTodo with an id that does not exist, should return Not Found",
 			wantStatusCode: http.StatusNotFound,
 			wantBody:       "",
-			r:              newRequest(http.MethodPut, "/todos/123456789", ""),
+			r:              newRequest(http.MethodPut, "/todos/123456789", `{"completed":false,"id":123456789,"task":"`+defaultNewTask+`"}`),
 		},
 		{
 			name:           "15: UpdateTodo with completed=true, should return a Todo updated with completed=true",
@@ -195,54 +222,689 @@ func getTestTable(t *testing.T, ts *httptest.Server, myId idCounter, jsonInitial
 			wantBody:       "todo id : 99 does not exist",
 			r:              newRequest(http.MethodGet, "/todos/99", ""),
 		},
+		{
+			name:           "21: GetTodos with limit=1, should return only the first Todo and a link to the next page",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "[" + string(firstTodo) + "]",
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "", todos.HeaderLink: `offset=1>; rel="next"`},
+			r:              newRequest(http.MethodGet, "/todos?limit=1", ""),
+		},
+		{
+			name:           "22: GetTodos with offset=1 and limit=1, should return the second Todo and a link to the previous page",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"id":2,`,
+			wantHeaders:    map[string]string{todos.HeaderLink: `offset=0>; rel="prev"`},
+			r:              newRequest(http.MethodGet, "/todos?offset=1&limit=1", ""),
+		},
+		{
+			name:           "23: GetTodos with a cursor after the first Todo, should return the second Todo",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `[{"completed":false,"created_at":"2020-02-21T08:00:23.877Z","id":2,`,
+			wantHeaders:    map[string]string{todos.HeaderLink: `rel="next"`},
+			r:              newRequest(http.MethodGet, "/todos?limit=1&after="+todos.EncodeCursor(1), ""),
+		},
+		{
+			name:           "24: GetTodos with limit=0, should return Bad request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "GetTodos limit must be between 1 and",
+			r:              newRequest(http.MethodGet, "/todos?limit=0", ""),
+		},
+		{
+			name:           "25: GetTodos with an invalid cursor, should return Bad request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "GetTodos after parameter is not a valid cursor",
+			r:              newRequest(http.MethodGet, "/todos?after=not-a-cursor", ""),
+		},
+		{
+			name:           "26: GetTodos with both offset and after, should return Bad request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "GetTodos offset and after parameters cannot be used together",
+			r:              newRequest(http.MethodGet, "/todos?offset=1&after="+todos.EncodeCursor(1), ""),
+		},
+		{
+			name:           "27: GetTodos with completed=true, should return only the completed Todos",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"task":"Learn GO","version":1}`,
+			r:              newRequest(http.MethodGet, "/todos?completed=true", ""),
+		},
+		{
+			name:           "28: GetTodos with sort=task and limit=1, should return the Todo with the first task in alphabetical order",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "[" + string(firstTodo) + "]",
+			r:              newRequest(http.MethodGet, "/todos?sort=task&limit=1", ""),
+		},
+		{
+			name:           "29: GetTodos with sort=-task and limit=1, should return the Todo with the last task in alphabetical order",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"id":2,"owner_id":1,"task":"Learn OpenAPI","version":1}]`,
+			r:              newRequest(http.MethodGet, "/todos?sort=-task&limit=1", ""),
+		},
+		{
+			name:           "30: GetTodos with created_before, should return only the Todos created before this date",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"id":2,`,
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "2"},
+			r:              newRequest(http.MethodGet, "/todos?created_before=2021-01-01T00:00:00Z", ""),
+		},
+		{
+			name:           "31: GetTodos with completed_after and completed_before, should return only the Todos completed in this range",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "[" + string(firstTodo) + "]",
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "1"},
+			r:              newRequest(http.MethodGet, "/todos?completed_after=2021-10-07T00:00:00Z&completed_before=2021-10-08T00:00:00Z", ""),
+		},
+		{
+			name:           "32: GetTodos with an unknown sort field, should return Bad request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "GetTodos sort field nope is not one of",
+			r:              newRequest(http.MethodGet, "/todos?sort=nope", ""),
+		},
+		{
+			name:           "33: GetTodos with a cursor and sort=task, should return Bad request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "GetTodos after parameter can only be used with sort=id",
+			r:              newRequest(http.MethodGet, "/todos?sort=task&after="+todos.EncodeCursor(1), ""),
+		},
+		{
+			name:           "34: GetTodos with an invalid completed value, should return Bad request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "Invalid format for parameter completed",
+			r:              newRequest(http.MethodGet, "/todos?completed=maybe", ""),
+		},
+		{
+			name:           "35: SearchTodos with q=openapi, should return the matching Todo with a headline",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"headline":"Learn \u003cb\u003eOpenAPI\u003c/b\u003e"`,
+			r:              newRequest(http.MethodGet, "/todos/search?q=openapi", ""),
+		},
+		{
+			name:           "36: SearchTodos with two words, should return only the Todos containing both words",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `[{"headline":"\u003cb\u003eLearn\u003c/b\u003e \u003cb\u003eGO\u003c/b\u003e"`,
+			r:              newRequest(http.MethodGet, "/todos/search?q=learn+go", ""),
+		},
+		{
+			name:           "37: SearchTodos with a word that is not used, should return an empty list",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "[]",
+			r:              newRequest(http.MethodGet, "/todos/search?q=nowhere", ""),
+		},
+		{
+			name:           "38: SearchTodos with a blank query, should return Bad request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "SearchTodos q cannot be empty",
+			r:              newRequest(http.MethodGet, "/todos/search?q=%20", ""),
+		},
+		{
+			name:           "39: SearchTodos without query, should return Bad request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "Invalid format for parameter q",
+			r:              newRequest(http.MethodGet, "/todos/search", ""),
+		},
+		{
+			name:           "40: UpdateTodo with a task too short(<6), should return Bad request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "{\"message\":\"UpdateTodo task minLength is 5\"}",
+			r:              newRequest(http.MethodPut, "/todos/2", `{"completed":false,"id":2,"task":"Learn"}`),
+		},
+		{
+			name:           "41: DeleteTodo with an id that does not exist, should return the id in the message",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "todo id : 123456789 does not exist",
+			r:              newRequest(http.MethodDelete, "/todos/123456789", ""),
+		},
+		{
+			name:           "42: GetTodo 2, should return the ETag of the Todo",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"version":1`,
+			wantHeaders:    map[string]string{todos.HeaderETag: `"1"`},
+			r:              newRequest(http.MethodGet, "/todos/2", ""),
+		},
+		{
+			name:           "43: UpdateTodo with an If-Match header not matching the ETag, should return Precondition Failed",
+			wantStatusCode: http.StatusPreconditionFailed,
+			wantBody:       "UpdateTodo todo id : 2 was modified",
+			r: withHeader(newRequest(http.MethodPut, "/todos/2", `{"completed":false,"id":2,"task":"Learn OpenAPI"}`),
+				todos.HeaderIfMatch, `"99"`),
+		},
+		{
+			name:           "44: UpdateTodo with a weak If-Match header, should return Precondition Failed",
+			wantStatusCode: http.StatusPreconditionFailed,
+			wantBody:       "UpdateTodo todo id : 2 was modified",
+			r: withHeader(newRequest(http.MethodPut, "/todos/2", `{"completed":false,"id":2,"task":"Learn OpenAPI"}`),
+				todos.HeaderIfMatch, `W/"1"`),
+		},
+		{
+			name:           "45: UpdateTodo with an If-Match header matching the ETag, should return the Todo with a new ETag",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"version":2`,
+			wantHeaders:    map[string]string{todos.HeaderETag: `"2"`},
+			r: withHeader(newRequest(http.MethodPut, "/todos/2", `{"completed":false,"id":2,"task":"Learn OpenAPI"}`),
+				todos.HeaderIfMatch, `"1"`),
+		},
+		{
+			name:           "46: DeleteTodo with the ETag of a previous version, should return Precondition Failed",
+			wantStatusCode: http.StatusPreconditionFailed,
+			wantBody:       "DeleteTodo todo id : 2 was modified",
+			r:              withHeader(newRequest(http.MethodDelete, "/todos/2", ""), todos.HeaderIfMatch, `"1"`),
+		},
+		{
+			name:           "47: DeleteTodo with an If-Match header on an id that does not exist, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "todo id : 123456789 does not exist",
+			r:              withHeader(newRequest(http.MethodDelete, "/todos/123456789", ""), todos.HeaderIfMatch, "*"),
+		},
+		{
+			name:           "48: GetTodo 1 with an If-None-Match header matching the ETag, should return Not Modified",
+			wantStatusCode: http.StatusNotModified,
+			wantBody:       "",
+			wantHeaders:    map[string]string{todos.HeaderETag: `"1"`},
+			r:              withHeader(newRequest(http.MethodGet, "/todos/1", ""), "If-None-Match", `W/"1"`),
+		},
+		{
+			name:           "49: GetTodo 1 with an If-None-Match header not matching the ETag, should return the Todo",
+			wantStatusCode: http.StatusOK,
+			wantBody:       string(firstTodo),
+			r:              withHeader(newRequest(http.MethodGet, "/todos/1", ""), "If-None-Match", `"98", "99"`),
+		},
+		{
+			name:           "50: GetTodo 1 with an If-Modified-Since header after its last change, should return Not Modified",
+			wantStatusCode: http.StatusNotModified,
+			wantBody:       "",
+			r:              withHeader(newRequest(http.MethodGet, "/todos/1", ""), "If-Modified-Since", "Fri, 01 Jan 2100 00:00:00 GMT"),
+		},
+		{
+			name:           "51: GetTodo 1 with an If-Modified-Since header before its last change, should return the Todo and its Last-Modified date",
+			wantStatusCode: http.StatusOK,
+			wantBody:       string(firstTodo),
+			wantHeaders:    map[string]string{todos.HeaderLastModified: "Thu, 07 Oct 2021 15:02:23 GMT", echo.HeaderCacheControl: "no-cache"},
+			r:              withHeader(newRequest(http.MethodGet, "/todos/1", ""), "If-Modified-Since", "Mon, 01 Jan 2001 00:00:00 GMT"),
+		},
+		{
+			name:           "52: GetTodos with an If-None-Match header not matching the ETag, should return the Todos with a weak ETag",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"id":1,`,
+			wantHeaders:    map[string]string{todos.HeaderETag: `W/"`, echo.HeaderCacheControl: "no-cache"},
+			r:              withHeader(newRequest(http.MethodGet, "/todos", ""), "If-None-Match", `W/"0-0-0"`),
+		},
+		{
+			name:           "53: GetTodos with If-None-Match *, should return Not Modified",
+			wantStatusCode: http.StatusNotModified,
+			wantBody:       "",
+			r:              withHeader(newRequest(http.MethodGet, "/todos", ""), "If-None-Match", "*"),
+		},
+		{
+			name:           "54: PatchTodo with a json Content-Type, should return Unsupported Media Type",
+			wantStatusCode: http.StatusUnsupportedMediaType,
+			wantBody:       "PatchTodo Content-Type must be application/merge-patch+json or application/json-patch+json",
+			r:              newRequest(http.MethodPatch, "/todos/2", `{"completed":true}`),
+		},
+		{
+			name:           "55: PatchTodo with a merge patch and an If-Match header matching the ETag, should return the patched Todo",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"completed":true,`,
+			wantHeaders:    map[string]string{todos.HeaderETag: `"3"`},
+			r: withHeader(withHeader(newRequest(http.MethodPatch, "/todos/2", `{"completed":true}`),
+				echo.HeaderContentType, todos.MIMEApplicationMergePatch), todos.HeaderIfMatch, `"2"`),
+		},
+		{
+			name:           "56: PatchTodo with a merge patch removing a field, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "PatchTodo completed cannot be removed",
+			r: withHeader(newRequest(http.MethodPatch, "/todos/2", `{"completed":null}`),
+				echo.HeaderContentType, todos.MIMEApplicationMergePatch),
+		},
+		{
+			name:           "57: PatchTodo with a merge patch changing the id, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "PatchTodo id cannot be modified",
+			r: withHeader(newRequest(http.MethodPatch, "/todos/2", `{"id":5,"task":"Learn OpenAPI"}`),
+				echo.HeaderContentType, todos.MIMEApplicationMergePatch),
+		},
+		{
+			name:           "58: PatchTodo with a merge patch changing created_at, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "PatchTodo created_at cannot be modified",
+			r: withHeader(newRequest(http.MethodPatch, "/todos/2", `{"created_at":"2000-01-01T00:00:00Z"}`),
+				echo.HeaderContentType, todos.MIMEApplicationMergePatch),
+		},
+		{
+			name:           "59: PatchTodo with a merge patch giving a too short task, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "PatchTodo task minLength is 5",
+			r: withHeader(newRequest(http.MethodPatch, "/todos/2", `{"task":"Learn"}`),
+				echo.HeaderContentType, todos.MIMEApplicationMergePatch),
+		},
+		{
+			name:           "60: PatchTodo with a json patch whose test operation fails, should return Conflict",
+			wantStatusCode: http.StatusConflict,
+			wantBody:       "test operation 0 failed, /completed is not false",
+			r: withHeader(newRequest(http.MethodPatch, "/todos/2", `[{"op":"test","path":"/completed","value":false}]`),
+				echo.HeaderContentType, todos.MIMEApplicationJSONPatch),
+		},
+		{
+			name:           "61: PatchTodo with a valid json patch, should return the patched Todo",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"completed":false,"created_at":"2020-02-21T08:00:23.877Z","id":2,"owner_id":1,"task":"Learn OpenAPI 3"`,
+			wantHeaders:    map[string]string{todos.HeaderETag: `"4"`},
+			r: withHeader(newRequest(http.MethodPatch, "/todos/2",
+				`[{"op":"test","path":"/completed","value":true},{"op":"replace","path":"/task","value":"Learn OpenAPI 3"},{"op":"replace","path":"/completed","value":false}]`),
+				echo.HeaderContentType, todos.MIMEApplicationJSONPatch),
+		},
+		{
+			name:           "62: PatchTodo with a json patch on a nested path, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "PatchTodo /task/0 is not the path of a field of todo",
+			r: withHeader(newRequest(http.MethodPatch, "/todos/2", `[{"op":"replace","path":"/task/0","value":"L"}]`),
+				echo.HeaderContentType, todos.MIMEApplicationJSONPatch),
+		},
+		{
+			name:           "63: PatchTodo with a json patch removing the task, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "PatchTodo task cannot be removed",
+			r: withHeader(newRequest(http.MethodPatch, "/todos/2", `[{"op":"remove","path":"/task"}]`),
+				echo.HeaderContentType, todos.MIMEApplicationJSONPatch),
+		},
+		{
+			name:           "64: PatchTodo with a json patch adding an unknown field, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "PatchTodo owner is not a field of todo",
+			r: withHeader(newRequest(http.MethodPatch, "/todos/2", `[{"op":"add","path":"/owner","value":"me"}]`),
+				echo.HeaderContentType, todos.MIMEApplicationJSONPatch),
+		},
+		{
+			name:           "65: PatchTodo with the ETag of a previous version, should return Precondition Failed",
+			wantStatusCode: http.StatusPreconditionFailed,
+			wantBody:       "PatchTodo todo id : 2 was modified",
+			r: withHeader(withHeader(newRequest(http.MethodPatch, "/todos/2", `{"completed":true}`),
+				echo.HeaderContentType, todos.MIMEApplicationMergePatch), todos.HeaderIfMatch, `"3"`),
+		},
+		{
+			name:           "66: PatchTodo on an id that does not exist, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "todo id : 123456789 does not exist",
+			r: withHeader(newRequest(http.MethodPatch, "/todos/123456789", `{"completed":true}`),
+				echo.HeaderContentType, todos.MIMEApplicationMergePatch),
+		},
+		{
+			name:           "67: GetTodos with ids, should return only the Todos with these ids",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"id":2,"owner_id":1,"task":"Learn OpenAPI 3"`,
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "2"},
+			r:              newRequest(http.MethodGet, "/todos?ids=2,123456789,1", ""),
+		},
+		{
+			name:           "68: GetTodos with an invalid id in ids, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "Invalid format for parameter ids",
+			r:              newRequest(http.MethodGet, "/todos?ids=1,abc", ""),
+		},
+		{
+			name:           "69: BatchTodos with valid operations, should return the result of each operation",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"id":3,"owner_id":1,"task":"Learn batch updates"`,
+			r: newRequest(http.MethodPost, "/todos:batch",
+				`[{"op":"create","task":"Learn batches"},{"op":"complete","id":2,"version":4},{"op":"update","id":3,"task":"Learn batch updates"}]`),
+		},
+		{
+			name:           "70: BatchTodos with an operation on an id that does not exist, should return Not Found and apply nothing",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       `[{"error":"not applied because operation 1 failed","status":424},{"error":"todo id : 123456789 does not exist","status":404}]`,
+			r:              newRequest(http.MethodPost, "/todos:batch", `[{"op":"delete","id":3},{"op":"update","id":123456789,"completed":true}]`),
+		},
+		{
+			name:           "71: GetTodo 3 after a failed batch, should return the Todo not deleted",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"task":"Learn batch updates"`,
+			r:              newRequest(http.MethodGet, "/todos/3", ""),
+		},
+		{
+			name:           "72: BatchTodos with an invalid operation, should return Bad Request with the reason",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `{"error":"BatchTodos operation 0 task minLength is 5","status":400}`,
+			r:              newRequest(http.MethodPost, "/todos:batch", `[{"op":"create","task":"Learn"}]`),
+		},
+		{
+			name:           "73: BatchTodos with an empty array, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "BatchTodos operations must contain between 1 and 1000 operations",
+			r:              newRequest(http.MethodPost, "/todos:batch", `[]`),
+		},
+		{
+			name:           "74: BatchTodos with the version of a previous change, should return Precondition Failed",
+			wantStatusCode: http.StatusPreconditionFailed,
+			wantBody:       "BatchTodos operation 0 todo id : 2 was modified",
+			r:              newRequest(http.MethodPost, "/todos:batch", `[{"op":"delete","id":2,"version":4}]`),
+		},
+		{
+			name:           "75: GetTrash after a successful DeleteTodo, should return the Todo in the trash",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"id":4`,
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "1"},
+			r:              newRequest(http.MethodGet, "/todos/trash", ""),
+		},
+		{
+			name:           "76: GetTodos with the id of a Todo in the trash, should return an empty list",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "[]",
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "0"},
+			r:              newRequest(http.MethodGet, "/todos?ids=4", ""),
+		},
+		{
+			name:           "77: PatchTodo on a Todo in the trash, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "todo id : 4 does not exist",
+			r: withHeader(newRequest(http.MethodPatch, "/todos/4", `{"completed":true}`),
+				echo.HeaderContentType, todos.MIMEApplicationMergePatch),
+		},
+		{
+			name:           "78: RestoreTodo on a Todo not in the trash, should return Conflict",
+			wantStatusCode: http.StatusConflict,
+			wantBody:       "todo id 3 is not in the trash",
+			r:              newRequest(http.MethodPost, "/todos/3/restore", ""),
+		},
+		{
+			name:           "79: RestoreTodo with the ETag of a previous version, should return Precondition Failed",
+			wantStatusCode: http.StatusPreconditionFailed,
+			wantBody:       "RestoreTodo todo id : 4 was modified",
+			r:              withHeader(newRequest(http.MethodPost, "/todos/4/restore", ""), todos.HeaderIfMatch, `"1"`),
+		},
+		{
+			name:           "80: RestoreTodo on a Todo in the trash, should return the Todo with a new version",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"id":4`,
+			wantHeaders:    map[string]string{todos.HeaderETag: `"3"`},
+			r:              withHeader(newRequest(http.MethodPost, "/todos/4/restore", ""), todos.HeaderIfMatch, `"2"`),
+		},
+		{
+			name:           "81: GetTodo after a successful RestoreTodo, should return the Todo",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"id":4`,
+			r:              newRequest(http.MethodGet, "/todos/4", ""),
+		},
+		{
+			name:           "82: DeleteTodo with purge on a Todo not in the trash, should return No Content",
+			wantStatusCode: http.StatusNoContent,
+			wantBody:       "",
+			r:              newRequest(http.MethodDelete, "/todos/4?purge=true", ""),
+		},
+		{
+			name:           "83: RestoreTodo on a purged Todo, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "todo id : 4 does not exist",
+			r:              newRequest(http.MethodPost, "/todos/4/restore", ""),
+		},
+		{
+			name:           "84: DeleteTodo with an existing id, should move the Todo to the trash",
+			wantStatusCode: http.StatusNoContent,
+			wantBody:       "",
+			r:              newRequest(http.MethodDelete, "/todos/3", ""),
+		},
+		{
+			name:           "85: DeleteTodo with purge on a Todo in the trash, should return No Content",
+			wantStatusCode: http.StatusNoContent,
+			wantBody:       "",
+			r:              newRequest(http.MethodDelete, "/todos/3?purge=true", ""),
+		},
+		{
+			name:           "86: GetTrash after the purge of all the Todos in the trash, should return an empty list",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "[]",
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "0"},
+			r:              newRequest(http.MethodGet, "/todos/trash", ""),
+		},
+		{
+			name:           "87: GetTrash with a limit out of range, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "GetTrash limit must be between 1 and",
+			r:              newRequest(http.MethodGet, "/todos/trash?limit=0", ""),
+		},
+		{
+			name:           "88: GetTodoHistory on a purged Todo, should return its changes up to the purge",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"todo_id":4,"type":"purged"}]`,
+			r:              newRequest(http.MethodGet, "/todos/4/history", ""),
+		},
+		{
+			name:           "89: GetTodoHistory on a Todo completed and then uncompleted, should return both changes",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"owner_id":1,"todo_id":3,"type":"completed"},{"actor":"admin"`,
+			r:              newRequest(http.MethodGet, "/todos/3/history", ""),
+		},
+		{
+			name:           "90: GetTodoHistory on an id that does not exist, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "todo id : 123456789 does not exist",
+			r:              newRequest(http.MethodGet, "/todos/123456789/history", ""),
+		},
+		{
+			name:           "91: GetEvents with a limit, should return the first changes",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"todo_id":3,"type":"created"}]`,
+			r:              newRequest(http.MethodGet, "/events?limit=1", ""),
+		},
+		{
+			name:           "92: GetEvents since a future date-time, should return an empty list",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "[]",
+			r:              newRequest(http.MethodGet, "/events?since=2100-01-01T00:00:00Z", ""),
+		},
+		{
+			name:           "93: GetEvents with a limit out of range, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "GetEvents limit must be between 1 and 1000",
+			r:              newRequest(http.MethodGet, "/events?limit=0", ""),
+		},
 		{
 			name:           "99:  invalid path, should return 404 not found",
 			wantStatusCode: http.StatusNotFound,
 			wantBody:       "{\"message\":\"Not Found\"}",
 			r:              newRequest(http.MethodGet, "/nothing_available_here", `{"task":"123"}`),
 		},
-	}
-}
-
-func Test_goTodoServer_TodosMemory(t *testing.T) {
-	// Create server using the router initialized elsewhere. The router
-	// can be a net/http ServeMux a http.DefaultServeMux or
-	// any value that satisfies the net/http Handler interface.
-	l := log.New(ioutil.Discard, appName, 0)
-	InitialDB, _ := todos.GetStorageInstance("memory", "", l)
-	myServer := GetNewServer(l, InitialDB)
-	ts := httptest.NewServer(myServer)
-	defer ts.Close()
-
-	res, _ := InitialDB.List(0, 100)
-	jsonInitialData, _ := json.Marshal(res)
-	firstTodo, _ := json.Marshal(res[0])
-
-	tests := getTestTable(t, ts, idCounter{currentMaxId: todos.DefaultMaxId}, jsonInitialData, firstTodo)
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			tt.r.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-			resp, err := http.DefaultClient.Do(tt.r)
-			if DEBUG {
-				fmt.Printf("### %s : %s on %s\n", tt.name, tt.r.Method, tt.r.URL)
-			}
-			defer resp.Body.Close()
-			if err != nil {
-				fmt.Printf("### GOT ERROR : %s\n%s", err, resp.Body)
-				t.Fatal(err)
-			}
-			assert.Equal(t, tt.wantStatusCode, resp.StatusCode, "expected status code should be returned")
-			receivedJson, _ := ioutil.ReadAll(resp.Body)
-
-			if resp.StatusCode == http.StatusCreated {
-				// In case of Created we need to modify the CreatedAt value from the response for equality test to pass
-				myNewTodo := todos.Todo{
-					Completed:   false,
+		{
+			name:           "100: GetTodos without user, should return Unauthorized",
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       "the X-User header is required",
+			r:              withHeader(newRequest(http.MethodGet, "/todos", ""), todos.HeaderUser, ""),
+		},
+		{
+			name:           "101: GetTodos with a user that does not exist, should return Unauthorized",
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       "user nobody does not exist",
+			r:              withHeader(newRequest(http.MethodGet, "/todos", ""), todos.HeaderUser, "nobody"),
+		},
+		{
+			name:           "102: CreateUser by the administrator, should return the new User",
+			wantStatusCode: http.StatusCreated,
+			wantBody:       `"id":2,"is_admin":false,"login":"bob","name":"Bob","role":"editor"}`,
+			r:              newRequest(http.MethodPost, "/users", `{"login":"bob","name":"Bob"}`),
+		},
+		{
+			name:           "103: CreateUser with a login already used, should return Conflict",
+			wantStatusCode: http.StatusConflict,
+			wantBody:       "CreateUser",
+			r:              newRequest(http.MethodPost, "/users", `{"login":"bob"}`),
+		},
+		{
+			name:           "104: CreateUser with an empty login, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "CreateUser login cannot be empty",
+			r:              newRequest(http.MethodPost, "/users", `{"login":""}`),
+		},
+		{
+			name:           "105: CreateUser by a user who is not an administrator, should return Forbidden",
+			wantStatusCode: http.StatusForbidden,
+			wantBody:       `{"code":403,"message":"CreateUser requires the admin permission, the editor role does not have it","operation":"CreateUser","permission":"admin","role":"editor"}`,
+			r:              withHeader(newRequest(http.MethodPost, "/users", `{"login":"alice"}`), todos.HeaderUser, "bob"),
+		},
+		{
+			name:           "106: GetUsers by the administrator, should return all the Users",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"login":"bob"`,
+			r:              newRequest(http.MethodGet, "/users", ""),
+		},
+		{
+			name:           "107: GetTodos by a new user, should return an empty list",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "[]",
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "0"},
+			r:              withHeader(newRequest(http.MethodGet, "/todos", ""), todos.HeaderUser, "bob"),
+		},
+		{
+			name:           "108: GetTodo of another user, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "todo id : 2 does not exist",
+			r:              withHeader(newRequest(http.MethodGet, "/todos/2", ""), todos.HeaderUser, "bob"),
+		},
+		{
+			name:           "109: DeleteTodo of another user, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "todo id : 2 does not exist",
+			r:              withHeader(newRequest(http.MethodDelete, "/todos/2", ""), todos.HeaderUser, "bob"),
+		},
+		{
+			name:           "110: GetTodoHistory of another user, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "todo id : 2 does not exist",
+			r:              withHeader(newRequest(http.MethodGet, "/todos/2/history", ""), todos.HeaderUser, "bob"),
+		},
+		{
+			name:           "111: CreateTodo by a new user, should return a valid Todo",
+			wantStatusCode: http.StatusCreated,
+			r:              withHeader(newRequest(http.MethodPost, "/todos", `{"task":"`+defaultNewTask+`"}`), todos.HeaderUser, "bob"),
+		},
+		{
+			name:           "112: GetTodos by the administrator, should not return the Todos of the other users",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"owner_id":1`,
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "3"},
+			r:              newRequest(http.MethodGet, "/todos", ""),
+		},
+		{
+			name:           "113: GetTodos with all_owners by the administrator, should return the Todos of all the users",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"owner_id":2`,
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "4"},
+			r:              newRequest(http.MethodGet, "/todos?all_owners=true", ""),
+		},
+		{
+			name:           "114: GetTodos with all_owners by a user who is not an administrator, should return Forbidden",
+			wantStatusCode: http.StatusForbidden,
+			wantBody:       "GetTodos all_owners is only allowed to the administrators",
+			r:              withHeader(newRequest(http.MethodGet, "/todos?all_owners=true", ""), todos.HeaderUser, "bob"),
+		},
+		{
+			name:           "115: GetEvents by a new user, should return only the changes of its Todos",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `[{"actor":"bob"`,
+			r:              withHeader(newRequest(http.MethodGet, "/events", ""), todos.HeaderUser, "bob"),
+		},
+		{
+			name:           "116: CreateUser with the viewer role, should return the new User",
+			wantStatusCode: http.StatusCreated,
+			wantBody:       `"is_admin":false,"login":"dashboard","role":"viewer"}`,
+			r:              newRequest(http.MethodPost, "/users", `{"login":"dashboard","role":"viewer"}`),
+		},
+		{
+			name:           "117: CreateUser with the deprecated is_admin, should return a User with the admin role",
+			wantStatusCode: http.StatusCreated,
+			wantBody:       `"is_admin":true,"login":"carol","role":"admin"}`,
+			r:              newRequest(http.MethodPost, "/users", `{"login":"carol","is_admin":true}`),
+		},
+		{
+			name:           "118: CreateUser with an unknown role, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "CreateUser role owner is not one of viewer, editor, admin",
+			r:              newRequest(http.MethodPost, "/users", `{"login":"alice","role":"owner"}`),
+		},
+		{
+			name:           "119: CreateUser with is_admin and another role, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "CreateUser is_admin cannot be true with the viewer role",
+			r:              newRequest(http.MethodPost, "/users", `{"login":"alice","role":"viewer","is_admin":true}`),
+		},
+		{
+			name:           "120: GetTodos by a viewer, should return OK",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "[]",
+			r:              withHeader(newRequest(http.MethodGet, "/todos", ""), todos.HeaderUser, "dashboard"),
+		},
+		{
+			name:           "121: GetMaxId by a viewer, should return OK",
+			wantStatusCode: http.StatusOK,
+			r:              withHeader(newRequest(http.MethodGet, "/todos/maxid", ""), todos.HeaderUser, "dashboard"),
+		},
+		{
+			name:           "122: CreateTodo by a viewer, should return Forbidden with the denied permission",
+			wantStatusCode: http.StatusForbidden,
+			wantBody:       `{"code":403,"message":"CreateTodo requires the todos:write permission, the viewer role does not have it","operation":"CreateTodo","permission":"todos:write","role":"viewer"}`,
+			r:              withHeader(newRequest(http.MethodPost, "/todos", `{"task":"`+defaultNewTask+`"}`), todos.HeaderUser, "dashboard"),
+		},
+		{
+			name:           "123: UpdateTodo by a viewer, should return Forbidden",
+			wantStatusCode: http.StatusForbidden,
+			wantBody:       `"operation":"UpdateTodo","permission":"todos:write","role":"viewer"}`,
+			r:              withHeader(newRequest(http.MethodPut, "/todos/1", `{"id":1,"task":"`+defaultNewTask+`","completed":true}`), todos.HeaderUser, "dashboard"),
+		},
+		{
+			name:           "124: DeleteTodo by a viewer, should return Forbidden before looking for the todo",
+			wantStatusCode: http.StatusForbidden,
+			wantBody:       `"operation":"DeleteTodo","permission":"todos:delete","role":"viewer"}`,
+			r:              withHeader(newRequest(http.MethodDelete, "/todos/1", ""), todos.HeaderUser, "dashboard"),
+		},
+		{
+			name:           "125: BatchTodos by a viewer, should return Forbidden",
+			wantStatusCode: http.StatusForbidden,
+			wantBody:       `"operation":"BatchTodos","permission":"todos:write","role":"viewer"}`,
+			r:              withHeader(newRequest(http.MethodPost, "/todos:batch", `[{"op":"delete","id":1}]`), todos.HeaderUser, "dashboard"),
+		},
+		{
+			name:           "126: GetUsers by a viewer, should return Forbidden",
+			wantStatusCode: http.StatusForbidden,
+			wantBody:       `"operation":"GetUsers","permission":"admin","role":"viewer"}`,
+			r:              withHeader(newRequest(http.MethodGet, "/users", ""), todos.HeaderUser, "dashboard"),
+		},
+		{
+			name:           "127: GetUsers by a user created with the deprecated is_admin, should return OK",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"login":"dashboard"`,
+			r:              withHeader(newRequest(http.MethodGet, "/users", ""), todos.HeaderUser, "carol"),
+		},
+	}
+}
+
+// runTestScenarios sends in order the request of every scenario and checks the received response
+func runTestScenarios(t *testing.T, tests []testScenario) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.r.Header.Get(echo.HeaderContentType) == "" {
+				tt.r.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			}
+			resp, err := http.DefaultClient.Do(tt.r)
+			if DEBUG {
+				fmt.Printf("### %s : %s on %s\n", tt.name, tt.r.Method, tt.r.URL)
+			}
+			defer resp.Body.Close()
+			if err != nil {
+				fmt.Printf("### GOT ERROR : %s\n%s", err, resp.Body)
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.wantStatusCode, resp.StatusCode, "expected status code should be returned")
+			for header, wantValue := range tt.wantHeaders {
+				assert.NotEmptyf(t, resp.Header.Get(header), "header %s should be present", header)
+				assert.Containsf(t, resp.Header.Get(header), wantValue, "header %s should contain what was expected.", header)
+			}
+			receivedJson, _ := ioutil.ReadAll(resp.Body)
+
+			if resp.StatusCode == http.StatusCreated && tt.r.URL.Path == "/todos" {
+				// In case of Created we need to modify the CreatedAt value from the response for equality test to pass
+				myNewTodo := todos.Todo{
+					Completed:   false,
 					CompletedAt: nil,
 					CreatedAt:   nil,
 					Id:          0,
 					Task:        defaultNewTask,
+					Version:     1,
 				}
 				var createdTodo todos.Todo
 				err := json.Unmarshal(receivedJson, &createdTodo)
@@ -250,7 +912,9 @@ func Test_goTodoServer_TodosMemory(t *testing.T) {
 					fmt.Printf("FATAL ERROR doing json.Unmarshall of %#v   - Error: %s", string(receivedJson), err)
 				}
 				myNewTodo.CreatedAt = createdTodo.CreatedAt
+				myNewTodo.UpdatedAt = createdTodo.UpdatedAt
 				myNewTodo.Id = createdTodo.Id
+				myNewTodo.OwnerId = createdTodo.OwnerId
 				wantedTodoJson, _ := json.Marshal(myNewTodo)
 				if DEBUG {
 					fmt.Printf("WANTED   :%T - %#v\n", wantedTodoJson, string(wantedTodoJson))
@@ -270,6 +934,116 @@ func Test_goTodoServer_TodosMemory(t *testing.T) {
 	}
 }
 
+// forEachStorage runs the scenarios with a new memory store and then with a new sqlite store, the stores are closed after them
+func forEachStorage(t *testing.T, run func(t *testing.T, store todos.Storage)) {
+	l := log.New(ioutil.Discard, appName, 0)
+	stores := []struct {
+		driver string
+		dsn    string
+	}{
+		{driver: "memory", dsn: ""},
+		{driver: "sqlite", dsn: filepath.Join(t.TempDir(), "todos.db")},
+	}
+	for _, s := range stores {
+		driver, dsn := s.driver, s.dsn
+		t.Run(driver, func(t *testing.T) {
+			store, err := todos.GetStorageInstance(driver, dsn, l)
+			if err != nil {
+				t.Fatalf(fmt.Sprintf("error getting %s storage. error : %v ", driver, err))
+			}
+			defer store.Close()
+			run(t, store)
+		})
+	}
+}
+
+// forEachStore runs the scenarios against a test server using a new memory store and against one using a new sqlite store
+func forEachStore(t *testing.T, run func(t *testing.T, ts *httptest.Server)) {
+	forEachStorage(t, func(t *testing.T, store todos.Storage) {
+		ts := httptest.NewServer(GetNewServer(log.New(ioutil.Discard, appName, 0), store))
+		defer ts.Close()
+		run(t, ts)
+	})
+}
+
+// testClient sends the requests of the scenarios to a test server as a user trusted from the X-User header
+type testClient struct {
+	t     *testing.T
+	ts    *httptest.Server
+	login string
+}
+
+// newTestClient returns a client sending the requests to ts as the user login
+func newTestClient(t *testing.T, ts *httptest.Server, login string) testClient {
+	return testClient{t: t, ts: ts, login: login}
+}
+
+// as returns a client sending the requests to the same test server as the user login
+func (c testClient) as(login string) testClient {
+	return testClient{t: c.t, ts: c.ts, login: login}
+}
+
+// newRequest returns a request of the user to the test server, the body of a PATCH request is a merge patch
+func (c testClient) newRequest(method, url, body string) *http.Request {
+	r, err := http.NewRequest(method, c.ts.URL+url, strings.NewReader(body))
+	if err != nil {
+		c.t.Fatalf("### ERROR http.NewRequest %s on [%s] \n", method, url)
+	}
+	r.Header.Set(todos.HeaderUser, c.login)
+	if method == http.MethodPatch {
+		r.Header.Set(echo.HeaderContentType, todos.MIMEApplicationMergePatch)
+	} else {
+		r.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	return r
+}
+
+// send sends the request, checks its status and decodes its response in v when v is not nil
+func (c testClient) send(method, url, body string, wantStatusCode int, v interface{}) {
+	resp, err := http.DefaultClient.Do(c.newRequest(method, url, body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatusCode {
+		c.t.Fatalf("%s %s %s should return %d, got status %d", method, url, body, wantStatusCode, resp.StatusCode)
+	}
+	if v != nil && json.NewDecoder(resp.Body).Decode(v) != nil {
+		c.t.Fatalf("%s %s %s response could not be decoded", method, url, body)
+	}
+}
+
+// create sends the POST request and decodes the created list or todo in v, the ids are used by the scenarios
+func (c testClient) create(url, body string, v interface{}) {
+	c.send(http.MethodPost, url, body, http.StatusCreated, v)
+}
+
+// createTodo creates the todo of the body and returns it
+func (c testClient) createTodo(body string) todos.Todo {
+	var res todos.Todo
+	c.create("/todos", body, &res)
+	return res
+}
+
+func Test_goTodoServer_TodosMemory(t *testing.T) {
+	// Create server using the router initialized elsewhere. The router
+	// can be a net/http ServeMux a http.DefaultServeMux or
+	// any value that satisfies the net/http Handler interface.
+	l := log.New(ioutil.Discard, appName, 0)
+	InitialDB, _ := todos.GetStorageInstance("memory", "", l)
+	myServer := GetNewServer(l, InitialDB)
+	ts := httptest.NewServer(myServer)
+	defer ts.Close()
+
+	res, _ := InitialDB.List(context.Background(), todos.ListParams{Limit: todos.DefaultListLimit})
+	jsonInitialData, _ := json.Marshal(res)
+	firstTodo, _ := json.Marshal(res[0])
+
+	tests := getTestTable(t, ts, idCounter{currentMaxId: todos.DefaultMaxId}, jsonInitialData, firstTodo)
+
+	runTestScenarios(t, tests)
+}
+
 func Test_goTodoServer_TodosPostgres(t *testing.T) {
 	// Create server using the router initialized elsewhere. The router
 	// can be a net/http ServeMux a http.DefaultServeMux or
@@ -290,61 +1064,2033 @@ func Test_goTodoServer_TodosPostgres(t *testing.T) {
 	ts := httptest.NewServer(myServer)
 	defer ts.Close()
 
-	res, _ := InitialDB.List(0, 100)
+	res, _ := InitialDB.List(context.Background(), todos.ListParams{Limit: todos.DefaultListLimit})
 
 	jsonInitialData, _ := json.Marshal(res)
 	fmt.Printf("%#v", res)
 	firstTodo, _ := json.Marshal(res[0])
-	maxId, _ := InitialDB.GetMaxId()
+	maxId, _ := InitialDB.GetMaxId(context.Background())
 
 	tests := getTestTable(t, ts, idCounter{currentMaxId: maxId}, jsonInitialData, firstTodo)
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			tt.r.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-			resp, err := http.DefaultClient.Do(tt.r)
-			if DEBUG {
-				fmt.Printf("### %s : %s on %s\n", tt.name, tt.r.Method, tt.r.URL)
-			}
-			defer resp.Body.Close()
-			if err != nil {
-				fmt.Printf("### GOT ERROR : %s\n%s", err, resp.Body)
-				t.Fatal(err)
-			}
-			assert.Equal(t, tt.wantStatusCode, resp.StatusCode, "expected status code should be returned")
-			receivedJson, _ := ioutil.ReadAll(resp.Body)
+	runTestScenarios(t, tests)
+}
 
-			if resp.StatusCode == http.StatusCreated {
-				// In case of Created we need to modify the CreatedAt value from the response for equality test to pass
-				myNewTodo := todos.Todo{
-					Completed:   false,
-					CompletedAt: nil,
-					CreatedAt:   nil,
-					Id:          0,
-					Task:        defaultNewTask,
-				}
-				var createdTodo todos.Todo
-				err := json.Unmarshal(receivedJson, &createdTodo)
-				if err != nil {
-					fmt.Printf("FATAL ERROR doing json.Unmarshall of %#v   - Error: %s", string(receivedJson), err)
-				}
-				myNewTodo.CreatedAt = createdTodo.CreatedAt
-				myNewTodo.Id = createdTodo.Id
-				wantedTodoJson, _ := json.Marshal(myNewTodo)
-				if DEBUG {
-					fmt.Printf("WANTED   :%T - %#v\n", wantedTodoJson, string(wantedTodoJson))
-					fmt.Printf("RECEIVED :%T - %#v\n", receivedJson, string(receivedJson))
-				}
-				assert.JSONEqf(t, string(wantedTodoJson), string(receivedJson), "CreateTodo Response was not equal to expected.")
-			} else {
-				// here are all other cases (except Created above)
-				if DEBUG {
-					fmt.Printf("WANTED   :%T - %#v\n", tt.wantBody, tt.wantBody)
-					fmt.Printf("RECEIVED :%T - %#v\n", receivedJson, string(receivedJson))
-				}
-				// check that receivedJson contains the specified tt.wantBody substring . https://pkg.go.dev/github.com/stretchr/testify/assert#Contains
-				assert.Contains(t, string(receivedJson), tt.wantBody, "Response should contain what was expected.")
-			}
-		})
+func Test_goTodoServer_TodosSqlite(t *testing.T) {
+	l := log.New(ioutil.Discard, appName, 0)
+	dbPath := filepath.Join(t.TempDir(), "todos.db")
+	InitialDB, err := todos.GetStorageInstance("sqlite", dbPath, l)
+	if err != nil {
+		t.Fatalf(fmt.Sprintf("error getting sqlite storage. error : %v ", err))
 	}
+	defer InitialDB.Close()
+	// the new database is empty, so let's load the same initial data as the postgres test database
+	initialData, err := ioutil.ReadFile("../../test/data/initial_todos_data.sql")
+	if err != nil {
+		t.Fatalf(fmt.Sprintf("error reading initial data. error : %v ", err))
+	}
+	if _, err := InitialDB.(*todos.SQLite).Conn.Exec(string(initialData)); err != nil {
+		t.Fatalf(fmt.Sprintf("error loading initial data in sqlite. error : %v ", err))
+	}
+	myServer := GetNewServer(l, InitialDB)
+	ts := httptest.NewServer(myServer)
+	defer ts.Close()
+
+	res, _ := InitialDB.List(context.Background(), todos.ListParams{Limit: todos.DefaultListLimit})
+	jsonInitialData, _ := json.Marshal(res)
+	firstTodo, _ := json.Marshal(res[0])
+	maxId, _ := InitialDB.GetMaxId(context.Background())
+
+	tests := getTestTable(t, ts, idCounter{currentMaxId: maxId}, jsonInitialData, firstTodo)
+
+	runTestScenarios(t, tests)
+}
+
+func Test_goTodoServer_TodosMemoryDurable(t *testing.T) {
+	l := log.New(ioutil.Discard, appName, 0)
+	dataDir := t.TempDir()
+	newRequest := func(ts *httptest.Server, method, url string, body string) *http.Request {
+		r, err := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("### ERROR http.NewRequest %s on [%s] \n", method, url)
+		}
+		// the requests are made by the administrator, unless another user is given with withHeader
+		r.Header.Set(todos.HeaderUser, todos.AdminLogin)
+		return r
+	}
+	startServer := func() (todos.Storage, *httptest.Server) {
+		store, err := todos.GetStorageInstance("memory", dataDir, l)
+		if err != nil {
+			t.Fatalf(fmt.Sprintf("error getting durable memory storage. error : %v ", err))
+		}
+		return store, httptest.NewServer(GetNewServer(l, store))
+	}
+
+	// a new durable store starts empty, and Close writes a snapshot
+	store, ts := startServer()
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "1: GetTodos on a new durable store, should return an empty list",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "[]",
+			r:              newRequest(ts, http.MethodGet, "/todos", ""),
+		},
+		{
+			name:           "2: CreateTodo with a valid new Todo task, should return a valid Todo",
+			wantStatusCode: http.StatusCreated,
+			r:              newRequest(ts, http.MethodPost, "/todos", `{"task":"`+defaultNewTask+`"}`),
+		},
+		{
+			name:           "3: CreateTodo with another valid new Todo task, should return a valid Todo",
+			wantStatusCode: http.StatusCreated,
+			r:              newRequest(ts, http.MethodPost, "/todos", `{"task":"`+defaultNewTask+`"}`),
+		},
+		{
+			name:           "4: UpdateTodo with completed=true, should return a Todo updated with completed=true",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"completed":true`,
+			r:              newRequest(ts, http.MethodPut, "/todos/1", `{"completed":true,"id":1,"task":"`+defaultNewTask+`"}`),
+		},
+		{
+			name:           "5: DeleteTodo with an existing id, should return No Content",
+			wantStatusCode: http.StatusNoContent,
+			r:              newRequest(ts, http.MethodDelete, "/todos/2", ""),
+		},
+	})
+	ts.Close()
+	store.Close()
+
+	// after a restart the store is restored from the snapshot, the next change only goes to the write-ahead log
+	crashedStore, ts := startServer()
+	defer crashedStore.Close()
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "6: GetTodo 1 after a restart, should return the completed Todo",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"completed":true`,
+			r:              newRequest(ts, http.MethodGet, "/todos/1", ""),
+		},
+		{
+			name:           "7: GetTodo 2 after a restart, should still be deleted",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "todo id : 2 does not exist",
+			r:              newRequest(ts, http.MethodGet, "/todos/2", ""),
+		},
+		{
+			name:           "8: CreateTodo after a restart, should return a valid Todo",
+			wantStatusCode: http.StatusCreated,
+			r:              newRequest(ts, http.MethodPost, "/todos", `{"task":"`+defaultNewTask+`"}`),
+		},
+	})
+	ts.Close()
+
+	// without Close (like after a crash), the store is restored by replaying the write-ahead log
+	store, ts = startServer()
+	defer store.Close()
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "9: GetTodo 3 after a crash, should return the Todo created before the crash",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"id":3,"owner_id":1,"task":"` + defaultNewTask + `"`,
+			r:              newRequest(ts, http.MethodGet, "/todos/3", ""),
+		},
+		{
+			name:           "10: GetMaxId after a crash, should not reuse deleted ids",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "3",
+			r:              newRequest(ts, http.MethodGet, "/todos/maxid", ""),
+		},
+		{
+			name:           "11: BatchTodos creating and deleting Todos, should return the result of each operation",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"status":204}]`,
+			r: newRequest(ts, http.MethodPost, "/todos:batch",
+				`[{"op":"create","task":"`+defaultNewTask+`"},{"op":"delete","id":1}]`),
+		},
+	})
+	ts.Close()
+
+	// a batch is written as a single record of the write-ahead log, it is replayed entirely after a crash
+	batchStore, ts := startServer()
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "12: GetTodo 1 after a crash, should be deleted by the batch",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "todo id : 1 does not exist",
+			r:              newRequest(ts, http.MethodGet, "/todos/1", ""),
+		},
+		{
+			name:           "13: GetTodo 4 after a crash, should return the Todo created by the batch",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"id":4,"owner_id":1,"task":"` + defaultNewTask + `"`,
+			r:              newRequest(ts, http.MethodGet, "/todos/4", ""),
+		},
+		{
+			name:           "14: GetTrash after a crash, should return the Todos deleted before the crash",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"id":2`,
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "2"},
+			r:              newRequest(ts, http.MethodGet, "/todos/trash", ""),
+		},
+		{
+			name:           "15: GetTodoHistory after a crash, should return the changes made before and after the restart",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"todo_id":1,"type":"deleted"}]`,
+			r:              newRequest(ts, http.MethodGet, "/todos/1/history", ""),
+		},
+		{
+			name:           "16: CreateUser by the administrator, should return the new User",
+			wantStatusCode: http.StatusCreated,
+			wantBody:       `"login":"bob"`,
+			r:              newRequest(ts, http.MethodPost, "/users", `{"login":"bob"}`),
+		},
+	})
+	ts.Close()
+	batchStore.Close()
+
+	// the users are restored like the todos
+	userStore, ts := startServer()
+	defer userStore.Close()
+	defer ts.Close()
+	bobRequest := newRequest(ts, http.MethodGet, "/todos/3", "")
+	bobRequest.Header.Set(todos.HeaderUser, "bob")
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "17: GetTodo of another user after a restart, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "todo id : 3 does not exist",
+			r:              bobRequest,
+		},
+	})
+}
+
+func Test_goTodoServer_TodosJwtAuth(t *testing.T) {
+	l := log.New(ioutil.Discard, appName, 0)
+	keysDir := t.TempDir()
+	secret := []byte("a-shared-secret-of-at-least-32-bytes")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaDer, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	rsaFile := filepath.Join(keysDir, "issuer-rsa.pem")
+	if err := ioutil.WriteFile(rsaFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaDer}), 0600); err != nil {
+		t.Fatalf("unable to write the RSA public key. error : %v", err)
+	}
+	jwksFile := filepath.Join(keysDir, "jwks.json")
+	jwks := fmt.Sprintf(`{"keys":[{"kty":"EC","kid":"issuer-ec","crv":"P-256","x":"%s","y":"%s"}]}`,
+		base64.RawURLEncoding.EncodeToString(ecKey.X.Bytes()), base64.RawURLEncoding.EncodeToString(ecKey.Y.Bytes()))
+	if err := ioutil.WriteFile(jwksFile, []byte(jwks), 0600); err != nil {
+		t.Fatalf("unable to write the JWKS. error : %v", err)
+	}
+	env := map[string]string{
+		"JWT_SECRET":           string(secret),
+		"JWT_PUBLIC_KEY_FILES": rsaFile,
+		"JWT_JWKS_FILE":        jwksFile,
+		"JWT_ISSUER":           "https://auth.example.com",
+		"JWT_AUDIENCE":         "todos",
+	}
+	for key, value := range env {
+		if err := os.Setenv(key, value); err != nil {
+			t.Fatalf("Unable to set env variable %s", key)
+		}
+		defer os.Unsetenv(key)
+	}
+
+	store, _ := todos.GetStorageInstance("memory", "", l)
+	defer store.Close()
+	ts := httptest.NewServer(GetNewServer(l, store))
+	defer ts.Close()
+
+	claimsFor := func(login string) jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub": login,
+			"iss": "https://auth.example.com",
+			"aud": "todos",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+	}
+	sign := func(method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("unable to sign the token. error : %v", err)
+		}
+		return signed
+	}
+	newRequest := func(method, url, body, token string) *http.Request {
+		r, err := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("### ERROR http.NewRequest %s on [%s] \n", method, url)
+		}
+		if token != "" {
+			r.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		}
+		return r
+	}
+	adminToken := sign(jwt.SigningMethodHS256, secret, "", claimsFor(todos.AdminLogin))
+	withClaim := func(name string, value interface{}) jwt.MapClaims {
+		claims := claimsFor(todos.AdminLogin)
+		claims[name] = value
+		return claims
+	}
+	withUserHeader := newRequest(http.MethodGet, "/todos", "", "")
+	withUserHeader.Header.Set(todos.HeaderUser, todos.AdminLogin)
+
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "1: GetTodos without token, should return Unauthorized",
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       "the Authorization header with a Bearer token is required",
+			r:              newRequest(http.MethodGet, "/todos", "", ""),
+		},
+		{
+			name:           "2: GetTodos with only the X-User header, should return Unauthorized when the tokens are used",
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       "the Authorization header with a Bearer token is required",
+			r:              withUserHeader,
+		},
+		{
+			name:           "3: GetTodos with a token signed with HS256, should return the todos of the administrator",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"owner_id":1`,
+			r:              newRequest(http.MethodGet, "/todos", "", adminToken),
+		},
+		{
+			name:           "4: GetTodos with a token signed with RS256 and a key of a PEM file, should return the todos",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"task":"Learn GO"`,
+			r:              newRequest(http.MethodGet, "/todos", "", sign(jwt.SigningMethodRS256, rsaKey, "issuer-rsa", claimsFor(todos.AdminLogin))),
+		},
+		{
+			name:           "5: GetTodos with a token signed with ES256 and a key of the JWKS file, should return the todos",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"task":"Learn GO"`,
+			r:              newRequest(http.MethodGet, "/todos", "", sign(jwt.SigningMethodES256, ecKey, "issuer-ec", claimsFor(todos.AdminLogin))),
+		},
+		{
+			name:           "6: GetTodos with a token signed with another secret, should return Unauthorized",
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       "invalid token",
+			r:              newRequest(http.MethodGet, "/todos", "", sign(jwt.SigningMethodHS256, []byte("another-secret-of-at-least-32-bytes"), "", claimsFor(todos.AdminLogin))),
+		},
+		{
+			name:           "7: GetTodos with an expired token, should return Unauthorized",
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       "the token is expired",
+			r:              newRequest(http.MethodGet, "/todos", "", sign(jwt.SigningMethodHS256, secret, "", withClaim("exp", time.Now().Add(-time.Hour).Unix()))),
+		},
+		{
+			name:           "8: GetTodos with a token not valid yet, should return Unauthorized",
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       "the token is not valid yet",
+			r:              newRequest(http.MethodGet, "/todos", "", sign(jwt.SigningMethodHS256, secret, "", withClaim("nbf", time.Now().Add(time.Hour).Unix()))),
+		},
+		{
+			name:           "9: GetTodos with a token of another issuer, should return Unauthorized",
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       "the token has an invalid issuer",
+			r:              newRequest(http.MethodGet, "/todos", "", sign(jwt.SigningMethodHS256, secret, "", withClaim("iss", "https://evil.example.com"))),
+		},
+		{
+			name:           "10: GetTodos with a token for another audience, should return Unauthorized",
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       "the token has an invalid audience",
+			r:              newRequest(http.MethodGet, "/todos", "", sign(jwt.SigningMethodHS256, secret, "", withClaim("aud", "other"))),
+		},
+		{
+			name:           "11: GetTodos with a token of an unknown user, should return Unauthorized",
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       "user nobody does not exist",
+			r:              newRequest(http.MethodGet, "/todos", "", sign(jwt.SigningMethodHS256, secret, "", claimsFor("nobody"))),
+		},
+		{
+			name:           "12: CreateUser with the token of the administrator, should return the new User",
+			wantStatusCode: http.StatusCreated,
+			wantBody:       `"login":"bob"`,
+			r:              newRequest(http.MethodPost, "/users", `{"login":"bob"}`, adminToken),
+		},
+		{
+			name:           "13: GetTodos with the token of another user, should return only the todos of this user",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "[]",
+			r:              newRequest(http.MethodGet, "/todos", "", sign(jwt.SigningMethodHS256, secret, "", claimsFor("bob"))),
+		},
+		{
+			name:           "14: GetTodos without sub claim, should return Unauthorized",
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       "the token has no sub claim",
+			r:              newRequest(http.MethodGet, "/todos", "", sign(jwt.SigningMethodHS256, secret, "", withClaim("sub", ""))),
+		},
+		{
+			name:           "15: the static files of the swagger ui, should stay public",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "swagger",
+			r:              newRequest(http.MethodGet, "/index.html", "", ""),
+		},
+	})
+}
+
+func Test_goTodoServer_TodosUntrustedUserHeader(t *testing.T) {
+	l := log.New(ioutil.Discard, appName, 0)
+	store, _ := todos.GetStorageInstance("memory", "", l)
+	defer store.Close()
+	trusted := httptest.NewServer(GetNewServer(l, store))
+	defer trusted.Close()
+	// without AUTH_TRUST_USER_HEADER nor tokens, only the requests with an API key are accepted
+	if err := os.Unsetenv("AUTH_TRUST_USER_HEADER"); err != nil {
+		t.Fatalf("Unable to unset env variable AUTH_TRUST_USER_HEADER")
+	}
+	defer os.Setenv("AUTH_TRUST_USER_HEADER", "true")
+	ts := httptest.NewServer(GetNewServer(l, store))
+	defer ts.Close()
+	newRequest := func(ts *httptest.Server, method, url, body, login, apiKey string) *http.Request {
+		r, err := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("### ERROR http.NewRequest %s on [%s] \n", method, url)
+		}
+		if login != "" {
+			r.Header.Set(todos.HeaderUser, login)
+		}
+		if apiKey != "" {
+			r.Header.Set(todos.HeaderApiKey, apiKey)
+		}
+		r.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		return r
+	}
+	resp, err := http.DefaultClient.Do(newRequest(trusted, http.MethodPost, "/admin/apikeys",
+		`{"name":"writer","scopes":["todos:read","todos:write"]}`, todos.AdminLogin, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var writeKey todos.ApiKeyCreated
+	if resp.StatusCode != http.StatusCreated || json.NewDecoder(resp.Body).Decode(&writeKey) != nil {
+		t.Fatalf("CreateApiKey should return a new API key, got status %d", resp.StatusCode)
+	}
+
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "1: GetTodos with the X-User header of the administrator, should return Unauthorized",
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       "a valid bearer token or API key is required",
+			r:              newRequest(ts, http.MethodGet, "/todos", "", todos.AdminLogin, ""),
+		},
+		{
+			name:           "2: CreateTodo with the X-User header of the administrator, should return Unauthorized",
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       "a valid bearer token or API key is required",
+			r:              newRequest(ts, http.MethodPost, "/todos", `{"task":"`+defaultNewTask+`"}`, todos.AdminLogin, ""),
+		},
+		{
+			name:           "3: CreateUser with the X-User header of the administrator, should return Unauthorized",
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       "a valid bearer token or API key is required",
+			r:              newRequest(ts, http.MethodPost, "/users", `{"login":"mallory"}`, todos.AdminLogin, ""),
+		},
+		{
+			name:           "4: CreateTodo with a todos:write API key, should return a valid Todo",
+			wantStatusCode: http.StatusCreated,
+			wantBody:       `"task":"` + defaultNewTask + `"`,
+			r:              newRequest(ts, http.MethodPost, "/todos", `{"task":"`+defaultNewTask+`"}`, "", writeKey.Key),
+		},
+		{
+			name:           "5: Get of the swagger ui without user, should return the static file",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "swagger",
+			r:              newRequest(ts, http.MethodGet, "/index.html", "", "", ""),
+		},
+	})
+}
+
+func Test_goTodoServer_TodosAdminApiKey(t *testing.T) {
+	// without AUTH_TRUST_USER_HEADER nor tokens, the key of ADMIN_API_KEY is the only way in of a new store
+	if err := os.Unsetenv("AUTH_TRUST_USER_HEADER"); err != nil {
+		t.Fatalf("Unable to unset env variable AUTH_TRUST_USER_HEADER")
+	}
+	defer os.Setenv("AUTH_TRUST_USER_HEADER", "true")
+	const adminApiKey = "todos_Choose_your_own_admin_api_key"
+	forEachStorage(t, func(t *testing.T, store todos.Storage) {
+		created, err := todos.BootstrapAdminApiKey(context.Background(), store, adminApiKey)
+		assert.NoError(t, err)
+		assert.True(t, created, "BootstrapAdminApiKey should create the key in a new store")
+		created, err = todos.BootstrapAdminApiKey(context.Background(), store, adminApiKey)
+		assert.NoError(t, err)
+		assert.False(t, created, "BootstrapAdminApiKey should not create the key again at the next start")
+		ts := httptest.NewServer(GetNewServer(log.New(ioutil.Discard, appName, 0), store))
+		defer ts.Close()
+		newRequest := func(method, url, body, apiKey string) *http.Request {
+			r, err := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("### ERROR http.NewRequest %s on [%s] \n", method, url)
+			}
+			r.Header.Set(todos.HeaderApiKey, apiKey)
+			r.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			return r
+		}
+		runTestScenarios(t, []testScenario{
+			{
+				name:           "1: CreateTodo with the key of ADMIN_API_KEY, should return a valid Todo",
+				wantStatusCode: http.StatusCreated,
+				wantBody:       `"task":"` + defaultNewTask + `"`,
+				r:              newRequest(http.MethodPost, "/todos", `{"task":"`+defaultNewTask+`"}`, adminApiKey),
+			},
+			{
+				name:           "2: GetApiKeys with the key of ADMIN_API_KEY, should return it",
+				wantStatusCode: http.StatusOK,
+				wantBody:       `"name":"admin key of the config","prefix":"todos_Choose"`,
+				r:              newRequest(http.MethodGet, "/admin/apikeys", "", adminApiKey),
+			},
+			{
+				name:           "3: GetTodos with another key, should return Unauthorized",
+				wantStatusCode: http.StatusUnauthorized,
+				wantBody:       "the API key does not exist or was revoked",
+				r:              newRequest(http.MethodGet, "/todos", "", adminApiKey+"_"),
+			},
+		})
+	})
+}
+
+func Test_goTodoServer_TodosApiKeys(t *testing.T) {
+	forEachStore(t, runApiKeyScenarios)
+}
+
+func runApiKeyScenarios(t *testing.T, ts *httptest.Server) {
+	newRequest := func(method, url, body, login, apiKey string) *http.Request {
+		r, err := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("### ERROR http.NewRequest %s on [%s] \n", method, url)
+		}
+		if login != "" {
+			r.Header.Set(todos.HeaderUser, login)
+		}
+		if apiKey != "" {
+			r.Header.Set(todos.HeaderApiKey, apiKey)
+		}
+		r.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		return r
+	}
+	// the keys are only returned once, when they are created
+	createApiKey := func(body string) todos.ApiKeyCreated {
+		resp, err := http.DefaultClient.Do(newRequest(http.MethodPost, "/admin/apikeys", body, todos.AdminLogin, ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var created todos.ApiKeyCreated
+		if resp.StatusCode != http.StatusCreated || json.NewDecoder(resp.Body).Decode(&created) != nil {
+			t.Fatalf("CreateApiKey %s should return a new API key, got status %d", body, resp.StatusCode)
+		}
+		return created
+	}
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "1: CreateUser bob, should return the new User",
+			wantStatusCode: http.StatusCreated,
+			wantBody:       `"login":"bob"`,
+			r:              newRequest(http.MethodPost, "/users", `{"login":"bob"}`, todos.AdminLogin, ""),
+		},
+	})
+	readKey := createApiKey(`{"name":"reader","scopes":["todos:read"]}`)
+	writeKey := createApiKey(`{"name":"writer","scopes":["todos:read","todos:write"]}`)
+	adminKey := createApiKey(`{"name":"admin","scopes":["admin"]}`)
+	bobKey := createApiKey(`{"name":"bob script","login":"bob","scopes":["todos:read"]}`)
+	expiringKey := createApiKey(`{"name":"expiring","scopes":["todos:read"],"expires_at":"` +
+		time.Now().Add(500*time.Millisecond).Format(time.RFC3339Nano) + `"}`)
+	assert.True(t, strings.HasPrefix(readKey.Key, readKey.Prefix), "the prefix should be the start of the key")
+	assert.Equal(t, todos.AdminLogin, readKey.Login, "the key should act for the administrator creating it")
+	assert.Equal(t, "bob", bobKey.Login, "the key should act for the user given in login")
+	time.Sleep(600 * time.Millisecond)
+
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "2: CreateTodo with a todos:read API key, should return Forbidden",
+			wantStatusCode: http.StatusForbidden,
+			wantBody:       "the API key does not have the todos:write scope",
+			r:              newRequest(http.MethodPost, "/todos", `{"task":"`+defaultNewTask+`"}`, "", readKey.Key),
+		},
+		{
+			name:           "3: CreateTodo with a todos:write API key, should return a valid Todo",
+			wantStatusCode: http.StatusCreated,
+			r:              newRequest(http.MethodPost, "/todos", `{"task":"`+defaultNewTask+`"}`, "", writeKey.Key),
+		},
+		{
+			name:           "4: GetTodos with a todos:read API key, should return the todos of its user",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"task":"` + defaultNewTask + `"`,
+			r:              newRequest(http.MethodGet, "/todos", "", "", readKey.Key),
+		},
+		{
+			name:           "5: GetMaxId with a todos:read API key, should return the max id",
+			wantStatusCode: http.StatusOK,
+			r:              newRequest(http.MethodGet, "/todos/maxid", "", "", readKey.Key),
+		},
+		{
+			name:           "6: GetEvents with a todos:read API key, should record the user of the key as actor",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"actor":"admin"`,
+			r:              newRequest(http.MethodGet, "/events", "", "", readKey.Key),
+		},
+		{
+			name:           "7: GetUsers with a todos:write API key of an administrator, should return Forbidden",
+			wantStatusCode: http.StatusForbidden,
+			wantBody:       "the API key does not have the admin scope",
+			r:              newRequest(http.MethodGet, "/users", "", "", writeKey.Key),
+		},
+		{
+			name:           "8: GetTodos all_owners with a todos:read API key of an administrator, should return Forbidden",
+			wantStatusCode: http.StatusForbidden,
+			wantBody:       "GetTodos all_owners is only allowed to the administrators",
+			r:              newRequest(http.MethodGet, "/todos?all_owners=true", "", "", readKey.Key),
+		},
+		{
+			name:           "9: GetUsers with an admin API key, should return the users",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"login":"bob"`,
+			r:              newRequest(http.MethodGet, "/users", "", "", adminKey.Key),
+		},
+		{
+			name:           "10: GetTodos with the API key of another user, should return only the todos of this user",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "[]",
+			r:              newRequest(http.MethodGet, "/todos", "", "", bobKey.Key),
+		},
+		{
+			name:           "11: GetTodos with an unknown API key, should return Unauthorized",
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       "the API key does not exist or was revoked",
+			r:              newRequest(http.MethodGet, "/todos", "", "", "todos_nope"),
+		},
+		{
+			name:           "12: GetTodos with an expired API key, should return Unauthorized",
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       "the API key is expired",
+			r:              newRequest(http.MethodGet, "/todos", "", "", expiringKey.Key),
+		},
+		{
+			name:           "13: GetApiKeys with a todos:read API key, should return Forbidden",
+			wantStatusCode: http.StatusForbidden,
+			wantBody:       "the API key does not have the admin scope",
+			r:              newRequest(http.MethodGet, "/admin/apikeys", "", "", readKey.Key),
+		},
+		{
+			name:           "14: GetApiKeys by the administrator, should return the keys with their last use but without the key",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"login":"admin","name":"reader","prefix":"` + readKey.Prefix + `","scopes":["todos:read"]}`,
+			r:              newRequest(http.MethodGet, "/admin/apikeys", "", todos.AdminLogin, ""),
+		},
+		{
+			name:           "15: GetApiKeys by the administrator, should return when the keys were used",
+			wantStatusCode: http.StatusOK,
+			wantBody:       fmt.Sprintf(`"id":%d,"last_used_at":"`, readKey.Id),
+			r:              newRequest(http.MethodGet, "/admin/apikeys", "", todos.AdminLogin, ""),
+		},
+		{
+			name:           "16: CreateApiKey with an unknown scope, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "nope is not one of todos:read, todos:write, admin",
+			r:              newRequest(http.MethodPost, "/admin/apikeys", `{"name":"nope","scopes":["nope"]}`, todos.AdminLogin, ""),
+		},
+		{
+			name:           "17: CreateApiKey without scopes, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "scopes cannot be empty",
+			r:              newRequest(http.MethodPost, "/admin/apikeys", `{"name":"nope","scopes":[]}`, todos.AdminLogin, ""),
+		},
+		{
+			name:           "18: CreateApiKey with an expiry in the past, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "expires_at must be in the future",
+			r:              newRequest(http.MethodPost, "/admin/apikeys", `{"name":"old","scopes":["admin"],"expires_at":"2020-01-01T00:00:00Z"}`, todos.AdminLogin, ""),
+		},
+		{
+			name:           "19: CreateApiKey for an unknown user, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "CreateApiKey user nobody does not exist",
+			r:              newRequest(http.MethodPost, "/admin/apikeys", `{"name":"nope","login":"nobody","scopes":["admin"]}`, todos.AdminLogin, ""),
+		},
+		{
+			name:           "20: CreateApiKey by a user who is not an administrator, should return Forbidden",
+			wantStatusCode: http.StatusForbidden,
+			wantBody:       `"operation":"CreateApiKey","permission":"admin","role":"editor"}`,
+			r:              newRequest(http.MethodPost, "/admin/apikeys", `{"name":"mine","scopes":["todos:read"]}`, "bob", ""),
+		},
+		{
+			name:           "21: RevokeApiKey with an existing id, should return No Content",
+			wantStatusCode: http.StatusNoContent,
+			r:              newRequest(http.MethodDelete, fmt.Sprintf("/admin/apikeys/%d", readKey.Id), "", todos.AdminLogin, ""),
+		},
+		{
+			name:           "22: GetTodos with a revoked API key, should return Unauthorized immediately",
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       "the API key does not exist or was revoked",
+			r:              newRequest(http.MethodGet, "/todos", "", "", readKey.Key),
+		},
+		{
+			name:           "23: RevokeApiKey with an unknown id, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "api key id : 999 does not exist",
+			r:              newRequest(http.MethodDelete, "/admin/apikeys/999", "", todos.AdminLogin, ""),
+		},
+	})
+}
+
+func Test_goTodoServer_TodosLists(t *testing.T) {
+	forEachStore(t, runListScenarios)
+}
+
+func runListScenarios(t *testing.T, ts *httptest.Server) {
+	bob := newTestClient(t, ts, "bob")
+	admin, dashboard := bob.as(todos.AdminLogin), bob.as("dashboard")
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "1: CreateUser bob, should return the new User",
+			wantStatusCode: http.StatusCreated,
+			wantBody:       `"id":2,`,
+			r:              admin.newRequest(http.MethodPost, "/users", `{"login":"bob"}`),
+		},
+		{
+			name:           "2: CreateUser dashboard with the viewer role, should return the new User",
+			wantStatusCode: http.StatusCreated,
+			wantBody:       `"role":"viewer"`,
+			r:              admin.newRequest(http.MethodPost, "/users", `{"login":"dashboard","role":"viewer"}`),
+		},
+	})
+	var groceries, work todos.TodoList
+	var milk, bread, report todos.Todo
+	bob.create("/lists", `{"name":"groceries"}`, &groceries)
+	bob.create("/lists", `{"name":"work"}`, &work)
+	bob.create(fmt.Sprintf("/lists/%d/todos", groceries.Id), `{"task":"buy some milk"}`, &milk)
+	bob.create("/todos", fmt.Sprintf(`{"task":"buy some bread","list_id":%d}`, groceries.Id), &bread)
+	bob.create("/todos", `{"task":"write the report"}`, &report)
+	assert.Equal(t, int32(2), groceries.OwnerId, "the list should be owned by the user creating it")
+	assert.Equal(t, groceries.Id, *milk.ListId, "the todo created in a list should be in this list")
+	assert.Equal(t, groceries.Id, *bread.ListId, "the todo created with a list_id should be in this list")
+	assert.Nil(t, report.ListId, "the todo created without list_id should not be in a list")
+
+	todoUrl := func(todo todos.Todo) string {
+		return fmt.Sprintf("/todos/%d", todo.Id)
+	}
+	listUrl := func(list todos.TodoList) string {
+		return fmt.Sprintf("/lists/%d", list.Id)
+	}
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "3: CreateList with a name already used by the user, should return Conflict",
+			wantStatusCode: http.StatusConflict,
+			wantBody:       "CreateList",
+			r:              bob.newRequest(http.MethodPost, "/lists", `{"name":"groceries"}`),
+		},
+		{
+			name:           "4: CreateList with a name used by another user, should return the new list",
+			wantStatusCode: http.StatusCreated,
+			wantBody:       `"name":"groceries","owner_id":1,"todos_count":0`,
+			r:              admin.newRequest(http.MethodPost, "/lists", `{"name":"groceries"}`),
+		},
+		{
+			name:           "5: CreateList with an empty name, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "CreateList name cannot be empty",
+			r:              bob.newRequest(http.MethodPost, "/lists", `{"name":" "}`),
+		},
+		{
+			name:           "6: GetLists, should return the lists of the user with the number of their todos",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"name":"groceries","owner_id":2,"todos_count":2`,
+			r:              bob.newRequest(http.MethodGet, "/lists", ""),
+		},
+		{
+			name:           "7: GetList of another user, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       fmt.Sprintf("list id : %d does not exist", groceries.Id),
+			r:              admin.newRequest(http.MethodGet, listUrl(groceries), ""),
+		},
+		{
+			name:           "8: PatchTodo completed of a todo in a list, should return OK",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"completed":true`,
+			r:              bob.newRequest(http.MethodPatch, todoUrl(milk), `{"completed":true}`),
+		},
+		{
+			name:           "9: GetList, should count the completed todos of the list",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"completed_count":1,`,
+			r:              bob.newRequest(http.MethodGet, listUrl(groceries), ""),
+		},
+		{
+			name:           "10: PatchTodo list_id, should move the todo to the list",
+			wantStatusCode: http.StatusOK,
+			wantBody:       fmt.Sprintf(`"list_id":%d,`, work.Id),
+			r:              bob.newRequest(http.MethodPatch, todoUrl(report), fmt.Sprintf(`{"list_id":%d}`, work.Id)),
+		},
+		{
+			name:           "11: GetListTodos, should return the todos of the list",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"task":"write the report"`,
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "1"},
+			r:              bob.newRequest(http.MethodGet, listUrl(work)+"/todos", ""),
+		},
+		{
+			name:           "12: GetListTodos with completed=false, should return the todos of the list not completed",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"task":"buy some bread"`,
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "1"},
+			r:              bob.newRequest(http.MethodGet, listUrl(groceries)+"/todos?completed=false", ""),
+		},
+		{
+			name:           "13: PatchTodo list_id null, should remove the todo from its list",
+			wantStatusCode: http.StatusOK,
+			wantBody:       fmt.Sprintf(`"id":%d,"owner_id":2,`, report.Id),
+			r:              bob.newRequest(http.MethodPatch, todoUrl(report), `{"list_id":null}`),
+		},
+		{
+			name:           "14: PatchTodo list_id of a list that does not exist, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "PatchTodo list_id list 999 does not exist",
+			r:              bob.newRequest(http.MethodPatch, todoUrl(report), `{"list_id":999}`),
+		},
+		{
+			name:           "15: CreateTodo in the list of another user, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       fmt.Sprintf("CreateTodo list_id list %d does not exist", groceries.Id),
+			r:              admin.newRequest(http.MethodPost, "/todos", fmt.Sprintf(`{"task":"%s","list_id":%d}`, defaultNewTask, groceries.Id)),
+		},
+		{
+			name:           "16: CreateListTodo in a list that does not exist, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "list id : 999 does not exist",
+			r:              bob.newRequest(http.MethodPost, "/lists/999/todos", `{"task":"`+defaultNewTask+`"}`),
+		},
+		{
+			name:           "17: UpdateTodo with a list_id, should move the todo to the list",
+			wantStatusCode: http.StatusOK,
+			wantBody:       fmt.Sprintf(`"list_id":%d,`, work.Id),
+			r: bob.newRequest(http.MethodPut, todoUrl(report),
+				fmt.Sprintf(`{"id":%d,"task":"write the report","completed":false,"list_id":%d}`, report.Id, work.Id)),
+		},
+		{
+			name:           "18: BatchTodos update with list_id 0, should remove the todo from its list",
+			wantStatusCode: http.StatusOK,
+			wantBody:       fmt.Sprintf(`"id":%d,"owner_id":2,`, report.Id),
+			r:              bob.newRequest(http.MethodPost, "/todos:batch", fmt.Sprintf(`[{"op":"update","id":%d,"list_id":0}]`, report.Id)),
+		},
+		{
+			name:           "19: BatchTodos complete with a list_id, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "BatchTodos operation 0 list_id cannot be given to complete a todo",
+			r:              bob.newRequest(http.MethodPost, "/todos:batch", fmt.Sprintf(`[{"op":"complete","id":%d,"list_id":%d}]`, report.Id, work.Id)),
+		},
+		{
+			name:           "20: UpdateList, should rename the list",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"name":"shopping"`,
+			r:              bob.newRequest(http.MethodPut, listUrl(groceries), `{"name":"shopping"}`),
+		},
+		{
+			name:           "21: UpdateList with the name of another list of the user, should return Conflict",
+			wantStatusCode: http.StatusConflict,
+			wantBody:       "UpdateList",
+			r:              bob.newRequest(http.MethodPut, listUrl(work), `{"name":"shopping"}`),
+		},
+		{
+			name:           "22: DeleteList by a viewer, should return Forbidden",
+			wantStatusCode: http.StatusForbidden,
+			wantBody:       `"operation":"DeleteList","permission":"todos:delete","role":"viewer"}`,
+			r:              dashboard.newRequest(http.MethodDelete, listUrl(groceries), ""),
+		},
+		{
+			name:           "23: DeleteTodo of a todo in a list, should move it to the trash",
+			wantStatusCode: http.StatusNoContent,
+			r:              bob.newRequest(http.MethodDelete, todoUrl(bread), ""),
+		},
+		{
+			name:           "24: DeleteList, should return No Content",
+			wantStatusCode: http.StatusNoContent,
+			r:              bob.newRequest(http.MethodDelete, listUrl(groceries), ""),
+		},
+		{
+			name:           "25: GetList of a deleted list, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       fmt.Sprintf("list id : %d does not exist", groceries.Id),
+			r:              bob.newRequest(http.MethodGet, listUrl(groceries), ""),
+		},
+		{
+			name:           "26: GetTodo of a todo of a deleted list, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       fmt.Sprintf("todo id : %d does not exist", milk.Id),
+			r:              bob.newRequest(http.MethodGet, todoUrl(milk), ""),
+		},
+		{
+			name:           "27: GetTrash, should not return the trashed todos of a deleted list",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "[]",
+			r:              bob.newRequest(http.MethodGet, "/todos/trash", ""),
+		},
+		{
+			name:           "28: GetTodoHistory of a todo of a deleted list, should end with its purge",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"type":"purged"`,
+			r:              bob.newRequest(http.MethodGet, todoUrl(milk)+"/history", ""),
+		},
+		{
+			name:           "29: GetTodo of a todo of another list, should keep it",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"task":"write the report"`,
+			r:              bob.newRequest(http.MethodGet, todoUrl(report), ""),
+		},
+	})
+}
+
+func Test_goTodoServer_TodosTags(t *testing.T) {
+	forEachStore(t, runTagScenarios)
+}
+
+func runTagScenarios(t *testing.T, ts *httptest.Server) {
+	bob := newTestClient(t, ts, "bob")
+	admin, dashboard := bob.as(todos.AdminLogin), bob.as("dashboard")
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "1: CreateUser bob, should return the new User",
+			wantStatusCode: http.StatusCreated,
+			wantBody:       `"id":2,`,
+			r:              admin.newRequest(http.MethodPost, "/users", `{"login":"bob"}`),
+		},
+		{
+			name:           "2: CreateUser dashboard with the viewer role, should return the new User",
+			wantStatusCode: http.StatusCreated,
+			wantBody:       `"role":"viewer"`,
+			r:              admin.newRequest(http.MethodPost, "/users", `{"login":"dashboard","role":"viewer"}`),
+		},
+	})
+	var milk, report, walk todos.Todo
+	bob.create("/todos", `{"task":"buy some milk","tags":["#Home","urgent","home"]}`, &milk)
+	bob.create("/todos", `{"task":"write the report","tags":["work","urgent"]}`, &report)
+	bob.create("/todos", `{"task":"walk the dog"}`, &walk)
+	assert.Equal(t, []string{"home", "urgent"}, *milk.Tags, "the tags should be stored in lowercase, without # and duplicates")
+	assert.Nil(t, walk.Tags, "the todo created without tags should not have tags")
+
+	todoUrl := func(todo todos.Todo) string {
+		return fmt.Sprintf("/todos/%d", todo.Id)
+	}
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "3: CreateTodo with a tag containing a space, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "CreateTodo tags tag my tag cannot contain spaces",
+			r:              bob.newRequest(http.MethodPost, "/todos", `{"task":"learn Go","tags":["my tag"]}`),
+		},
+		{
+			name:           "4: GetTodo, should return the tags of the todo in alphabetical order",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"owner_id":2,"tags":["urgent","work"]`,
+			r:              bob.newRequest(http.MethodGet, todoUrl(report), ""),
+		},
+		{
+			name:           "5: GetTodos with a tag, should return the todos having it",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"task":"write the report"`,
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "2"},
+			r:              bob.newRequest(http.MethodGet, "/todos?tag=urgent", ""),
+		},
+		{
+			name:           "6: GetTodos with two tags, should return the todos having all of them",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "[]",
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "0"},
+			r:              bob.newRequest(http.MethodGet, "/todos?tag=home&tag=work", ""),
+		},
+		{
+			name:           "7: GetTodos with two tags and tag_match any, should return the todos having one of them",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"task":"buy some milk"`,
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "2"},
+			r:              bob.newRequest(http.MethodGet, "/todos?tag=%23Home&tag=work&tag_match=any", ""),
+		},
+		{
+			name:           "8: GetTodos with an invalid tag_match, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			r:              bob.newRequest(http.MethodGet, "/todos?tag=home&tag_match=some", ""),
+		},
+		{
+			name:           "9: GetTags, should return the tags of the user with the number of their todos",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `[{"name":"home","todos_count":1},{"name":"urgent","todos_count":2},{"name":"work","todos_count":1}]`,
+			r:              bob.newRequest(http.MethodGet, "/tags", ""),
+		},
+		{
+			name:           "10: GetTags of another user, should not return the tags of bob",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "[]",
+			r:              admin.newRequest(http.MethodGet, "/tags", ""),
+		},
+		{
+			name:           "11: PatchTodo with tags, should replace all the tags of the todo",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"tags":["errands","home"]`,
+			r:              bob.newRequest(http.MethodPatch, todoUrl(milk), `{"tags":["Home","errands"]}`),
+		},
+		{
+			name:           "12: UpdateTodo without tags, should remove all the tags of the todo",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"owner_id":2,"task":"write the report"`,
+			r: bob.newRequest(http.MethodPut, todoUrl(report),
+				fmt.Sprintf(`{"id":%d,"task":"write the report","completed":false}`, report.Id)),
+		},
+		{
+			name:           "13: PatchTodo with null tags on a todo without tags, should keep its version",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"version":2`,
+			r:              bob.newRequest(http.MethodPatch, todoUrl(report), `{"tags":null}`),
+		},
+		{
+			name:           "14: PatchTodo with tags, should add tags to a todo without tags",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"tags":["work"]`,
+			r:              bob.newRequest(http.MethodPatch, todoUrl(walk), `{"tags":["work"]}`),
+		},
+		{
+			name:           "15: DeleteTodo, should move the todo to the trash",
+			wantStatusCode: http.StatusNoContent,
+			r:              bob.newRequest(http.MethodDelete, todoUrl(milk), ""),
+		},
+		{
+			name:           "16: GetTags, should not count the todos in the trash",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `[{"name":"work","todos_count":1}]`,
+			r:              bob.newRequest(http.MethodGet, "/tags", ""),
+		},
+		{
+			name:           "17: RenameTag of a tag only used in the trash to an existing tag, should merge them",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"name":"work","todos_count":1}`,
+			r:              bob.newRequest(http.MethodPut, "/tags/home", `{"name":"Work"}`),
+		},
+		{
+			name:           "18: RestoreTodo, should return the todo with the renamed tag",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"tags":["errands","work"]`,
+			r:              bob.newRequest(http.MethodPost, todoUrl(milk)+"/restore", ""),
+		},
+		{
+			name:           "19: RenameTag, should rename the tag in all the todos having it",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"name":"job","todos_count":2}`,
+			r:              bob.newRequest(http.MethodPut, "/tags/work", `{"name":"#job"}`),
+		},
+		{
+			name:           "20: GetTodos with the renamed tag, should return the todos having it",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"tags":["errands","job"]`,
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "2"},
+			r:              bob.newRequest(http.MethodGet, "/todos?tag=job", ""),
+		},
+		{
+			name:           "21: GetTodoHistory of a renamed todo, should end with the change of its tags",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"after":{"completed":false,`,
+			r:              bob.newRequest(http.MethodGet, todoUrl(walk)+"/history", ""),
+		},
+		{
+			name:           "22: RenameTag of an unknown tag, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "tag : work does not exist",
+			r:              bob.newRequest(http.MethodPut, "/tags/work", `{"name":"job"}`),
+		},
+		{
+			name:           "23: RenameTag of a tag of another user, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "tag : job does not exist",
+			r:              admin.newRequest(http.MethodPut, "/tags/job", `{"name":"work"}`),
+		},
+		{
+			name:           "24: RenameTag to an invalid name, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "RenameTag tags cannot contain an empty tag",
+			r:              bob.newRequest(http.MethodPut, "/tags/job", `{"name":"#"}`),
+		},
+		{
+			name:           "25: RenameTag by a viewer, should return Forbidden",
+			wantStatusCode: http.StatusForbidden,
+			wantBody:       `"operation":"RenameTag","permission":"todos:write","role":"viewer"}`,
+			r:              dashboard.newRequest(http.MethodPut, "/tags/job", `{"name":"work"}`),
+		},
+	})
+}
+
+func Test_goTodoServer_TodosDueDates(t *testing.T) {
+	forEachStore(t, runDueDateScenarios)
+}
+
+func runDueDateScenarios(t *testing.T, ts *httptest.Server) {
+	admin := newTestClient(t, ts, todos.AdminLogin)
+	created := make(map[int32]bool)
+	// create creates the todo and remembers its id, the scenarios only look at the todos they created
+	create := func(body string) todos.Todo {
+		res := admin.createTodo(body)
+		created[res.Id] = true
+		return res
+	}
+	// getIds returns the ids of the todos created by the scenarios returned by the GET request in the order they were returned
+	getIds := func(url string) []int32 {
+		var list []todos.Todo
+		admin.send(http.MethodGet, url, "", http.StatusOK, &list)
+		ids := make([]int32, 0, len(list))
+		for _, todo := range list {
+			if created[todo.Id] {
+				ids = append(ids, todo.Id)
+			}
+		}
+		return ids
+	}
+	dueIn := func(d time.Duration) string {
+		return time.Now().Add(d).Format(time.RFC3339)
+	}
+	taxes := create(fmt.Sprintf(`{"task":"pay the taxes","due_at":"%s","priority":2}`, dueIn(-48*time.Hour)))
+	rent := create(fmt.Sprintf(`{"task":"pay the rent","due_at":"%s","priority":1}`, taxes.DueAt.Format(time.RFC3339Nano)))
+	call := create(fmt.Sprintf(`{"task":"call the plumber","due_at":"%s","priority":3}`, dueIn(2*time.Hour)))
+	report := create(fmt.Sprintf(`{"task":"write the report","due_at":"%s"}`, dueIn(48*time.Hour)))
+	trip := create(fmt.Sprintf(`{"task":"book the trip","due_at":"%s","priority":4}`, dueIn(30*24*time.Hour)))
+	reading := create(`{"task":"read a novel"}`)
+	assert.True(t, *taxes.Overdue, "a todo not completed whose due date is past should be overdue")
+	assert.False(t, *call.Overdue, "a todo whose due date is not past should not be overdue")
+	assert.Nil(t, reading.Overdue, "a todo without due date should not have the overdue flag")
+
+	assert.Equal(t, []int32{rent.Id, taxes.Id}, getIds("/todos/overdue"), "the overdue todos with the same due date should be sorted by priority")
+	assert.Equal(t, []int32{rent.Id, taxes.Id, call.Id, report.Id, trip.Id, reading.Id}, getIds("/todos?sort=due_at"),
+		"the todos should be sorted by due date then priority, the ones without due date last")
+	assert.Equal(t, []int32{trip.Id, call.Id, taxes.Id, rent.Id, reading.Id, report.Id}, getIds("/todos?sort=-priority"),
+		"the todos should be sorted by priority, the ones without priority last")
+
+	// revalidate returns the status of a conditional GET of url with the entity tag etag, and its new entity tag
+	revalidate := func(url, etag string) (int, string) {
+		r := admin.newRequest(http.MethodGet, url, "")
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode, resp.Header.Get(todos.HeaderETag)
+	}
+	soon := create(fmt.Sprintf(`{"task":"take out the trash","due_at":"%s"}`, time.Now().Add(500*time.Millisecond).Format(time.RFC3339Nano)))
+	soonUrl := fmt.Sprintf("/todos/%d", soon.Id)
+	_, soonETag := revalidate(soonUrl, "")
+	_, listETag := revalidate("/todos", "")
+	status, _ := revalidate(soonUrl, soonETag)
+	assert.Equal(t, http.StatusNotModified, status, "an unchanged todo should not be sent again before its due date")
+	status, _ = revalidate("/todos", listETag)
+	assert.Equal(t, http.StatusNotModified, status, "an unchanged list should not be sent again before a due date")
+	time.Sleep(600 * time.Millisecond)
+	status, soonETag = revalidate(soonUrl, soonETag)
+	assert.Equal(t, http.StatusOK, status, "a todo should be sent again once it is overdue")
+	status, _ = revalidate(soonUrl, soonETag)
+	assert.Equal(t, http.StatusNotModified, status, "an overdue todo should not be sent again while it does not change")
+	status, _ = revalidate("/todos", listETag)
+	assert.Equal(t, http.StatusOK, status, "the list should be sent again once a todo is overdue")
+	r := admin.newRequest(http.MethodPatch, soonUrl, `{"priority":1}`)
+	r.Header.Set(todos.HeaderIfMatch, soonETag)
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "the ETag of an overdue todo should match in If-Match")
+	// the scenarios count the overdue todos, so this one is moved to the trash
+	admin.send(http.MethodDelete, soonUrl, "", http.StatusNoContent, nil)
+
+	todoUrl := func(todo todos.Todo) string {
+		return fmt.Sprintf("/todos/%d", todo.Id)
+	}
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "1: CreateTodo with a priority out of range, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "CreateTodo priority must be between 1 and 4",
+			r:              admin.newRequest(http.MethodPost, "/todos", `{"task":"learn Go","priority":5}`),
+		},
+		{
+			name:           "2: GetOverdueTodos, should return the todos not completed whose due date is past",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"overdue":true`,
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "2"},
+			r:              admin.newRequest(http.MethodGet, "/todos/overdue", ""),
+		},
+		{
+			name:           "3: GetUpcomingTodos, should return the todos due in the next 24 hours",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"priority":3,"task":"call the plumber"`,
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "1"},
+			r:              admin.newRequest(http.MethodGet, "/todos/upcoming", ""),
+		},
+		{
+			name:           "4: GetUpcomingTodos within 72h, should return the todos due in the next 72 hours",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"task":"write the report"`,
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "2"},
+			r:              admin.newRequest(http.MethodGet, "/todos/upcoming?within=72h", ""),
+		},
+		{
+			name:           "5: GetUpcomingTodos within 31 days, should return the todos due in the next 31 days",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"task":"book the trip"`,
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "3"},
+			r:              admin.newRequest(http.MethodGet, "/todos/upcoming?within=31d", ""),
+		},
+		{
+			name:           "6: GetUpcomingTodos with an invalid within, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "GetUpcomingTodos within must be a positive duration",
+			r:              admin.newRequest(http.MethodGet, "/todos/upcoming?within=-1h", ""),
+		},
+		{
+			name:           "7: PatchTodo completing an overdue todo, should clear its overdue flag",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"overdue":false`,
+			r:              admin.newRequest(http.MethodPatch, todoUrl(taxes), `{"completed":true}`),
+		},
+		{
+			name:           "8: GetOverdueTodos, should not return the completed todos",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"task":"pay the rent"`,
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "1"},
+			r:              admin.newRequest(http.MethodGet, "/todos/overdue", ""),
+		},
+		{
+			name:           "9: PatchTodo with a due date in another time zone, should store it in UTC",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"due_at":"2030-01-01T08:00:00Z"`,
+			r:              admin.newRequest(http.MethodPatch, todoUrl(report), `{"due_at":"2030-01-01T10:00:00+02:00","priority":2}`),
+		},
+		{
+			name:           "10: PatchTodo with a null due date, should remove the due date of the todo",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"owner_id":1,"priority":3,"task":"call the plumber"`,
+			r:              admin.newRequest(http.MethodPatch, todoUrl(call), `{"due_at":null}`),
+		},
+		{
+			name:           "11: GetUpcomingTodos, should not return a todo without due date",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "[]",
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "0"},
+			r:              admin.newRequest(http.MethodGet, "/todos/upcoming", ""),
+		},
+		{
+			name:           "12: PatchTodo with a priority out of range, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "PatchTodo priority must be between 1 and 4",
+			r:              admin.newRequest(http.MethodPatch, todoUrl(call), `{"priority":9}`),
+		},
+		{
+			name:           "13: PatchTodo with an invalid due date, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "PatchTodo due_at must be a date-time",
+			r:              admin.newRequest(http.MethodPatch, todoUrl(call), `{"due_at":"tomorrow"}`),
+		},
+		{
+			name:           "14: PatchTodo of the overdue flag, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "PatchTodo overdue cannot be modified",
+			r:              admin.newRequest(http.MethodPatch, todoUrl(rent), `{"overdue":false}`),
+		},
+		{
+			name:           "15: UpdateTodo without due date and priority, should remove them",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"owner_id":1,"task":"book the trip"`,
+			r: admin.newRequest(http.MethodPut, todoUrl(trip),
+				fmt.Sprintf(`{"id":%d,"task":"book the trip","completed":false}`, trip.Id)),
+		},
+		{
+			name:           "16: UpdateTodo with a due date and a priority, should set them",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"overdue":false,"owner_id":1,"priority":1,"task":"read a novel"`,
+			r: admin.newRequest(http.MethodPut, todoUrl(reading),
+				fmt.Sprintf(`{"id":%d,"task":"read a novel","completed":false,"due_at":"2030-06-01T00:00:00Z","priority":1}`, reading.Id)),
+		},
+	})
+}
+
+func Test_goTodoServer_TodosRecurrence(t *testing.T) {
+	forEachStore(t, runRecurrenceScenarios)
+}
+
+func runRecurrenceScenarios(t *testing.T, ts *httptest.Server) {
+	admin := newTestClient(t, ts, todos.AdminLogin)
+	complete := func(todo todos.Todo, completed bool) {
+		admin.send(http.MethodPatch, fmt.Sprintf("/todos/%d", todo.Id), fmt.Sprintf(`{"completed":%v}`, completed), http.StatusOK, nil)
+	}
+	getSeries := func(todo todos.Todo) []todos.Todo {
+		var res []todos.Todo
+		admin.send(http.MethodGet, fmt.Sprintf("/todos/%d/series", todo.Id), "", http.StatusOK, &res)
+		return res
+	}
+
+	chore := admin.createTodo(`{"task":"water the plants","due_at":"2030-01-07T09:00:00Z","priority":2,"tags":["home"],"recurrence":"rrule:freq=weekly;byday=th,mo,th"}`)
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,TH", *chore.Recurrence, "the recurrence rule should be stored in its canonical form")
+	assert.Equal(t, chore.Id, *chore.SeriesId, "a new recurring todo should start its own series")
+	complete(chore, true)
+	series := getSeries(chore)
+	assert.Len(t, series, 2, "completing a recurring todo should create its next occurrence")
+	next := series[1]
+	assert.Equal(t, "2030-01-10T09:00:00Z", next.DueAt.Format(time.RFC3339), "the next occurrence should be due on the next day of the rule")
+	assert.Equal(t, chore.Task, next.Task)
+	assert.Equal(t, chore.Priority, next.Priority)
+	assert.Equal(t, chore.Tags, next.Tags)
+	assert.Equal(t, chore.Recurrence, next.Recurrence)
+	assert.Equal(t, chore.Id, *next.SeriesId, "the next occurrence should be in the series of the completed todo")
+	assert.False(t, next.Completed)
+	complete(chore, false)
+	complete(chore, true)
+	assert.Len(t, getSeries(chore), 2, "completing again a todo should not create another occurrence")
+
+	rent := admin.createTodo(`{"task":"pay the rent","due_at":"2030-01-31T08:00:00Z","recurrence":"FREQ=MONTHLY;BYMONTHDAY=31"}`)
+	admin.send(http.MethodPut, fmt.Sprintf("/todos/%d", rent.Id),
+		fmt.Sprintf(`{"id":%d,"task":"pay the rent","completed":true,"due_at":"2030-01-31T08:00:00Z","recurrence":"FREQ=MONTHLY;BYMONTHDAY=31"}`, rent.Id),
+		http.StatusOK, nil)
+	series = getSeries(rent)
+	if assert.Len(t, series, 2, "completing a recurring todo with Update should create its next occurrence") {
+		assert.Equal(t, "2030-02-28T08:00:00Z", series[1].DueAt.Format(time.RFC3339), "the next occurrence should be due on the last day of a shorter month")
+	}
+
+	filter := admin.createTodo(`{"task":"change the water filter","recurrence":"FREQ=DAILY;INTERVAL=3;X-FROM=COMPLETION"}`)
+	admin.send(http.MethodPost, "/todos:batch", fmt.Sprintf(`[{"op":"complete","id":%d}]`, filter.Id), http.StatusOK, nil)
+	series = getSeries(filter)
+	if assert.Len(t, series, 2, "completing a recurring todo in a batch should create its next occurrence") {
+		assert.WithinDuration(t, time.Now().AddDate(0, 0, 3), *series[1].DueAt, time.Minute, "the next occurrence should be due 3 days after the completion")
+	}
+
+	trash := admin.createTodo(`{"task":"take out the trash","due_at":"2020-01-06T10:00:00+01:00","recurrence":"FREQ=WEEKLY"}`)
+	complete(trash, true)
+	wantDueAt := trash.DueAt.UTC()
+	for !wantDueAt.After(time.Now()) {
+		wantDueAt = wantDueAt.AddDate(0, 0, 7)
+	}
+	series = getSeries(trash)
+	if assert.Len(t, series, 2, "completing a late recurring todo should create its next occurrence") {
+		assert.Equal(t, wantDueAt.Format(time.RFC3339), series[1].DueAt.Format(time.RFC3339),
+			"the next occurrence of a todo completed late should be the first one due after the completion")
+	}
+
+	once := admin.createTodo(`{"task":"renew the passport"}`)
+	plain := admin.createTodo(`{"task":"read a novel"}`)
+	var patched todos.Todo
+	admin.send(http.MethodPatch, fmt.Sprintf("/todos/%d", plain.Id), `{"recurrence":"FREQ=DAILY;INTERVAL=1"}`, http.StatusOK, &patched)
+	assert.Equal(t, "FREQ=DAILY", *patched.Recurrence)
+	assert.Equal(t, plain.Id, *patched.SeriesId, "a todo getting a recurrence rule should start its own series")
+
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "1: CreateTodo with an unsupported frequency, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "CreateTodo recurrence FREQ must be DAILY, WEEKLY or MONTHLY",
+			r:              admin.newRequest(http.MethodPost, "/todos", `{"task":"learn Go","recurrence":"FREQ=YEARLY"}`),
+		},
+		{
+			name:           "2: CreateTodo with BYDAY in a daily rule, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "CreateTodo recurrence can only contain BYDAY with FREQ=WEEKLY",
+			r:              admin.newRequest(http.MethodPost, "/todos", `{"task":"learn Go","recurrence":"FREQ=DAILY;BYDAY=MO"}`),
+		},
+		{
+			name:           "3: CreateTodo with an unsupported part of RRULE, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "CreateTodo recurrence cannot contain COUNT",
+			r:              admin.newRequest(http.MethodPost, "/todos", `{"task":"learn Go","recurrence":"FREQ=DAILY;COUNT=3"}`),
+		},
+		{
+			name:           "4: PatchTodo of the series, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "PatchTodo series_id cannot be modified",
+			r:              admin.newRequest(http.MethodPatch, fmt.Sprintf("/todos/%d", next.Id), `{"series_id":999}`),
+		},
+		{
+			name:           "5: GetTodoSeries of a todo that never recurred, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       fmt.Sprintf("todo id : %d never recurred", once.Id),
+			r:              admin.newRequest(http.MethodGet, fmt.Sprintf("/todos/%d/series", once.Id), ""),
+		},
+		{
+			name:           "6: GetTodoSeries of a todo that does not exist, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "todo id : 9999 does not exist",
+			r:              admin.newRequest(http.MethodGet, "/todos/9999/series", ""),
+		},
+		{
+			name:           "7: UpdateTodoSeries with an invalid rule, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "UpdateTodoSeries recurrence INTERVAL must be between 1 and 366",
+			r:              admin.newRequest(http.MethodPut, fmt.Sprintf("/todos/%d/series", chore.Id), `{"recurrence":"FREQ=WEEKLY;INTERVAL=0"}`),
+		},
+		{
+			name:           "8: UpdateTodoSeries from a completed occurrence, should change the rule of the next occurrence",
+			wantStatusCode: http.StatusOK,
+			wantBody:       fmt.Sprintf(`"recurrence":"FREQ=WEEKLY;INTERVAL=2;BYDAY=FR","series_id":%d,"tags":["home"],"task":"water the plants"`, chore.Id),
+			r:              admin.newRequest(http.MethodPut, fmt.Sprintf("/todos/%d/series", chore.Id), `{"recurrence":"FREQ=WEEKLY;INTERVAL=2;BYDAY=FR"}`),
+		},
+		{
+			name:           "9: StopTodoSeries, should return No Content",
+			wantStatusCode: http.StatusNoContent,
+			r:              admin.newRequest(http.MethodDelete, fmt.Sprintf("/todos/%d/series", next.Id), ""),
+		},
+		{
+			name:           "10: PatchTodo completing an occurrence of a stopped series, should not create another occurrence",
+			wantStatusCode: http.StatusOK,
+			wantBody:       fmt.Sprintf(`"series_id":%d,"tags":["home"]`, chore.Id),
+			r:              admin.newRequest(http.MethodPatch, fmt.Sprintf("/todos/%d", next.Id), `{"completed":true}`),
+		},
+		{
+			name:           "11: UpdateTodoSeries when all the occurrences are completed, should return Conflict",
+			wantStatusCode: http.StatusConflict,
+			wantBody:       fmt.Sprintf("all the occurrences of the series %d are completed", chore.Id),
+			r:              admin.newRequest(http.MethodPut, fmt.Sprintf("/todos/%d/series", chore.Id), `{"recurrence":"FREQ=DAILY"}`),
+		},
+		{
+			name:           "12: PatchTodo with a null recurrence, should stop the recurrence of the todo",
+			wantStatusCode: http.StatusOK,
+			wantBody:       fmt.Sprintf(`"owner_id":1,"series_id":%d,"task":"read a novel"`, plain.Id),
+			r:              admin.newRequest(http.MethodPatch, fmt.Sprintf("/todos/%d", plain.Id), `{"recurrence":null}`),
+		},
+	})
+	assert.Len(t, getSeries(chore), 2, "completing an occurrence of a stopped series should not create another occurrence")
+}
+
+func Test_goTodoServer_TodosSubtasks(t *testing.T) {
+	forEachStore(t, runSubtaskScenarios)
+}
+
+func runSubtaskScenarios(t *testing.T, ts *httptest.Server) {
+	admin := newTestClient(t, ts, todos.AdminLogin)
+	get := func(todo todos.Todo) todos.Todo {
+		var res todos.Todo
+		admin.send(http.MethodGet, fmt.Sprintf("/todos/%d", todo.Id), "", http.StatusOK, &res)
+		return res
+	}
+	getChildren := func(todo todos.Todo) []todos.Todo {
+		var res []todos.Todo
+		admin.send(http.MethodGet, fmt.Sprintf("/todos/%d/children", todo.Id), "", http.StatusOK, &res)
+		return res
+	}
+
+	move := admin.createTodo(`{"task":"move to the new flat"}`)
+	pack := admin.createTodo(fmt.Sprintf(`{"task":"pack the boxes","parent_id":%d}`, move.Id))
+	books := admin.createTodo(fmt.Sprintf(`{"task":"pack the books","parent_id":%d}`, pack.Id))
+	dishes := admin.createTodo(fmt.Sprintf(`{"task":"pack the dishes","parent_id":%d}`, pack.Id))
+	keys := admin.createTodo(fmt.Sprintf(`{"task":"return the keys","parent_id":%d}`, move.Id))
+	assert.Equal(t, pack.Id, *books.ParentId)
+	move = get(move)
+	if assert.NotNil(t, move.ChildrenCount, "a todo with children should return their number") {
+		assert.Equal(t, int32(2), *move.ChildrenCount)
+		assert.Equal(t, int32(0), *move.ChildrenCompleted)
+	}
+	assert.Nil(t, keys.ChildrenCount, "a todo without children should not return their number")
+	children := getChildren(move)
+	if assert.Len(t, children, 2) {
+		assert.Equal(t, pack.Id, children[0].Id)
+		assert.Equal(t, int32(2), *children[0].ChildrenCount, "the children should return the number of their own children")
+		assert.Equal(t, keys.Id, children[1].Id)
+	}
+
+	var tree []todos.Todo
+	admin.send(http.MethodGet, "/todos?tree=true&limit=100", "", http.StatusOK, &tree)
+	var root *todos.Todo
+	for i := range tree {
+		assert.Nil(t, tree[i].ParentId, "the tree should only list the root todos")
+		if tree[i].Id == move.Id {
+			root = &tree[i]
+		}
+	}
+	if assert.NotNil(t, root, "the tree should list the root todo") && assert.NotNil(t, root.Children) {
+		nested := *root.Children
+		if assert.Len(t, nested, 2) && assert.NotNil(t, nested[0].Children, "the tree should nest the grandchildren") {
+			assert.Equal(t, books.Id, (*nested[0].Children)[0].Id)
+			assert.Equal(t, dishes.Id, (*nested[0].Children)[1].Id)
+		}
+	}
+
+	admin.send(http.MethodPatch, fmt.Sprintf("/todos/%d", pack.Id), `{"auto_complete":true}`, http.StatusOK, nil)
+	admin.send(http.MethodPatch, fmt.Sprintf("/todos/%d", books.Id), `{"completed":true}`, http.StatusOK, nil)
+	pack = get(pack)
+	assert.False(t, pack.Completed, "a todo with auto_complete should wait for all its children")
+	assert.Equal(t, int32(1), *pack.ChildrenCompleted)
+	admin.send(http.MethodPatch, fmt.Sprintf("/todos/%d", dishes.Id), `{"completed":true}`, http.StatusOK, nil)
+	assert.True(t, get(pack).Completed, "a todo with auto_complete should be completed with its last child")
+	assert.False(t, get(move).Completed, "a todo without auto_complete should not be completed with its children")
+	assert.Equal(t, int32(1), *get(move).ChildrenCompleted)
+
+	admin.send(http.MethodDelete, fmt.Sprintf("/todos/%d", pack.Id), "", http.StatusNoContent, nil)
+	children = getChildren(move)
+	if assert.Len(t, children, 3, "the children of a deleted todo should be moved to its parent") {
+		assert.Equal(t, books.Id, children[0].Id)
+		assert.Equal(t, dishes.Id, children[1].Id)
+	}
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "1: GetTodoChildren with limit and offset, should return one page of the children",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"task":"pack the dishes"`,
+			wantHeaders:    map[string]string{todos.HeaderTotalCount: "3", todos.HeaderLink: `offset=0>; rel="prev"`},
+			r:              admin.newRequest(http.MethodGet, fmt.Sprintf("/todos/%d/children?limit=1&offset=1", move.Id), ""),
+		},
+		{
+			name:           "2: GetTodoChildren with a limit greater than the maximum, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "GetTodoChildren limit must be between 1 and 1000",
+			r:              admin.newRequest(http.MethodGet, fmt.Sprintf("/todos/%d/children?limit=1001", move.Id), ""),
+		},
+	})
+	admin.send(http.MethodDelete, fmt.Sprintf("/todos/%d?children=cascade", move.Id), "", http.StatusNoContent, nil)
+	var trash []todos.Todo
+	admin.send(http.MethodGet, "/todos/trash?limit=100", "", http.StatusOK, &trash)
+	trashed := make(map[int32]bool)
+	for _, t := range trash {
+		trashed[t.Id] = true
+	}
+	for _, todo := range []todos.Todo{move, books, dishes, keys} {
+		assert.True(t, trashed[todo.Id], "the children of a todo deleted with children=cascade should be moved to the trash")
+	}
+	admin.send(http.MethodPost, fmt.Sprintf("/todos/%d/restore", keys.Id), "", http.StatusOK, nil)
+	assert.Nil(t, get(keys).ParentId, "a todo restored without its parent should become a root todo")
+
+	// revalidate returns the status of a conditional GET of the todo with the entity tag etag, and its new entity tag
+	revalidate := func(todo todos.Todo, etag string) (int, string) {
+		r := admin.newRequest(http.MethodGet, fmt.Sprintf("/todos/%d", todo.Id), "")
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode, resp.Header.Get(todos.HeaderETag)
+	}
+	garden := admin.createTodo(`{"task":"tidy the garden"}`)
+	shed := admin.createTodo(`{"task":"tidy the shed"}`)
+	_, gardenETag := revalidate(garden, "")
+	_, shedETag := revalidate(shed, "")
+	firstShedETag := shedETag
+	status, _ := revalidate(garden, gardenETag)
+	assert.Equal(t, http.StatusNotModified, status, "an unchanged todo should not be sent again")
+	mow := admin.createTodo(fmt.Sprintf(`{"task":"mow the lawn","parent_id":%d}`, garden.Id))
+	status, gardenETag = revalidate(garden, gardenETag)
+	assert.Equal(t, http.StatusOK, status, "a todo should be sent again when a child is created under it")
+	admin.send(http.MethodPatch, fmt.Sprintf("/todos/%d", mow.Id), `{"completed":true}`, http.StatusOK, nil)
+	status, gardenETag = revalidate(garden, gardenETag)
+	assert.Equal(t, http.StatusOK, status, "a todo should be sent again when a child is completed")
+	admin.send(http.MethodPatch, fmt.Sprintf("/todos/%d", mow.Id), fmt.Sprintf(`{"parent_id":%d}`, shed.Id), http.StatusOK, nil)
+	status, gardenETag = revalidate(garden, gardenETag)
+	assert.Equal(t, http.StatusOK, status, "a todo should be sent again when a child is moved to another parent")
+	status, shedETag = revalidate(shed, shedETag)
+	assert.Equal(t, http.StatusOK, status, "a todo should be sent again when a child is moved under it")
+	assert.Equal(t, int32(1), *get(shed).ChildrenCompleted)
+	admin.send(http.MethodDelete, fmt.Sprintf("/todos/%d", mow.Id), "", http.StatusNoContent, nil)
+	status, shedETag = revalidate(shed, shedETag)
+	assert.Equal(t, http.StatusOK, status, "a todo should be sent again when a child is moved to the trash")
+	admin.send(http.MethodPost, fmt.Sprintf("/todos/%d/restore", mow.Id), "", http.StatusOK, nil)
+	status, _ = revalidate(shed, shedETag)
+	assert.Equal(t, http.StatusOK, status, "a todo should be sent again when a child is restored")
+	status, _ = revalidate(garden, gardenETag)
+	assert.Equal(t, http.StatusNotModified, status, "a todo should not be sent again when the former children change")
+	var history []todos.TodoEvent
+	admin.send(http.MethodGet, fmt.Sprintf("/todos/%d/history", shed.Id), "", http.StatusOK, &history)
+	assert.Len(t, history, 1, "the changes of the children should not be recorded in the history of the parent")
+	r := admin.newRequest(http.MethodPatch, fmt.Sprintf("/todos/%d", shed.Id), `{"priority":1}`)
+	r.Header.Set(todos.HeaderIfMatch, firstShedETag)
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "the changes of the children should not change the version of the parent")
+
+	list := admin.createTodo(`{"task":"plan the holidays"}`)
+	trip := admin.createTodo(fmt.Sprintf(`{"task":"book the trip","parent_id":%d}`, list.Id))
+
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "3: CreateTodo under a todo that does not exist, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "CreateTodo parent_id todo 9999 does not exist",
+			r:              admin.newRequest(http.MethodPost, "/todos", `{"task":"learn Go","parent_id":9999}`),
+		},
+		{
+			name:           "4: CreateTodo under a todo in the trash, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       fmt.Sprintf("CreateTodo parent_id todo %d does not exist", move.Id),
+			r:              admin.newRequest(http.MethodPost, "/todos", fmt.Sprintf(`{"task":"learn Go","parent_id":%d}`, move.Id)),
+		},
+		{
+			name:           "5: PatchTodo moving a todo under itself, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       fmt.Sprintf("PatchTodo parent_id todo %d cannot be its own parent", list.Id),
+			r:              admin.newRequest(http.MethodPatch, fmt.Sprintf("/todos/%d", list.Id), fmt.Sprintf(`{"parent_id":%d}`, list.Id)),
+		},
+		{
+			name:           "6: PatchTodo moving a todo under its child, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       fmt.Sprintf("PatchTodo parent_id todo %d is a descendant of todo %d, it cannot be its parent", trip.Id, list.Id),
+			r:              admin.newRequest(http.MethodPatch, fmt.Sprintf("/todos/%d", list.Id), fmt.Sprintf(`{"parent_id":%d}`, trip.Id)),
+		},
+		{
+			name:           "7: PatchTodo with an invalid parent_id, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "PatchTodo parent_id must be the id of a todo",
+			r:              admin.newRequest(http.MethodPatch, fmt.Sprintf("/todos/%d", trip.Id), `{"parent_id":"holidays"}`),
+		},
+		{
+			name:           "8: PatchTodo of the number of children, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "PatchTodo children_count cannot be modified",
+			r:              admin.newRequest(http.MethodPatch, fmt.Sprintf("/todos/%d", list.Id), `{"children_count":3}`),
+		},
+		{
+			name:           "9: PatchTodo with a null parent_id, should make the todo a root todo",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"task":"book the trip"`,
+			r:              admin.newRequest(http.MethodPatch, fmt.Sprintf("/todos/%d", trip.Id), `{"parent_id":null}`),
+		},
+		{
+			name:           "10: GetTodoChildren of a todo that does not exist, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "todo id : 9999 does not exist",
+			r:              admin.newRequest(http.MethodGet, "/todos/9999/children", ""),
+		},
+		{
+			name:           "11: DeleteTodo with an invalid children mode, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "DeleteTodo children must be cascade or reparent",
+			r:              admin.newRequest(http.MethodDelete, fmt.Sprintf("/todos/%d?children=orphan", list.Id), ""),
+		},
+	})
+	assert.Nil(t, get(trip).ParentId, "a todo patched with a null parent_id should become a root todo")
+	assert.Empty(t, getChildren(list))
+}
+
+func Test_goTodoServer_TodosDependencies(t *testing.T) {
+	l := log.New(ioutil.Discard, appName, 0)
+	forEachStore(t, runDependencyScenarios)
+	t.Run("memory durable", func(t *testing.T) {
+		dataDir := t.TempDir()
+		startServer := func() (todos.Storage, *httptest.Server) {
+			store, err := todos.GetStorageInstance("memory", dataDir, l)
+			if err != nil {
+				t.Fatalf(fmt.Sprintf("error getting durable memory storage. error : %v ", err))
+			}
+			return store, httptest.NewServer(GetNewServer(l, store))
+		}
+		store, ts := startServer()
+		admin := newTestClient(t, ts, todos.AdminLogin)
+		blocked := admin.createTodo(`{"task":"ship the release"}`)
+		blocker := admin.createTodo(`{"task":"run the tests"}`)
+		admin.create(fmt.Sprintf("/todos/%d/dependencies", blocked.Id), fmt.Sprintf(`{"blocker_id":%d}`, blocker.Id), nil)
+		ts.Close()
+		store.Close()
+
+		store, ts = startServer()
+		defer store.Close()
+		defer ts.Close()
+		var blockers []todos.Todo
+		newTestClient(t, ts, todos.AdminLogin).send(http.MethodGet, fmt.Sprintf("/todos/%d/dependencies", blocked.Id), "", http.StatusOK, &blockers)
+		if assert.Len(t, blockers, 1, "the dependencies should be restored with the durable store") {
+			assert.Equal(t, blocker.Id, blockers[0].Id)
+		}
+	})
+}
+
+func runDependencyScenarios(t *testing.T, ts *httptest.Server) {
+	admin := newTestClient(t, ts, todos.AdminLogin)
+	get := func(todo todos.Todo) todos.Todo {
+		var res todos.Todo
+		admin.send(http.MethodGet, fmt.Sprintf("/todos/%d", todo.Id), "", http.StatusOK, &res)
+		return res
+	}
+	block := func(todo, blocker todos.Todo) []todos.Todo {
+		var res []todos.Todo
+		admin.send(http.MethodPost, fmt.Sprintf("/todos/%d/dependencies", todo.Id), fmt.Sprintf(`{"blocker_id":%d}`, blocker.Id), http.StatusCreated, &res)
+		return res
+	}
+	// getReady returns the ids of the ready todos among the given ones
+	getReady := func(among ...todos.Todo) []int32 {
+		var list []todos.Todo
+		admin.send(http.MethodGet, "/todos/ready?limit=1000", "", http.StatusOK, &list)
+		res := make([]int32, 0)
+		for _, t := range list {
+			for _, todo := range among {
+				if t.Id == todo.Id {
+					res = append(res, t.Id)
+				}
+			}
+		}
+		return res
+	}
+
+	release := admin.createTodo(`{"task":"publish the release","due_at":"2030-03-01T10:00:00Z"}`)
+	build := admin.createTodo(`{"task":"build the binaries"}`)
+	tests := admin.createTodo(`{"task":"run the integration tests"}`)
+	notes := admin.createTodo(`{"task":"write the release notes"}`)
+	block(release, build)
+	blockers := block(release, tests)
+	if assert.Len(t, blockers, 2, "the response should list all the blockers of the todo") {
+		assert.Equal(t, build.Id, blockers[0].Id)
+		assert.Equal(t, tests.Id, blockers[1].Id)
+	}
+	assert.Len(t, block(release, tests), 2, "adding an existing dependency should do nothing")
+	block(tests, build)
+	assert.True(t, *get(release).Blocked, "a todo with open blockers should be blocked")
+	assert.Nil(t, get(build).Blocked, "a todo without blockers should not have the blocked flag")
+	assert.Equal(t, []int32{build.Id, notes.Id}, getReady(release, build, tests, notes),
+		"only the open todos without open blockers should be ready")
+
+	var conflict todos.TodoBlocked
+	admin.send(http.MethodPut, fmt.Sprintf("/todos/%d", release.Id),
+		fmt.Sprintf(`{"id":%d,"task":"publish the release","completed":true}`, release.Id), http.StatusConflict, &conflict)
+	assert.Equal(t, int32(http.StatusConflict), conflict.Code)
+	assert.Equal(t, release.Id, conflict.TodoId)
+	assert.Equal(t, []int32{build.Id, tests.Id}, conflict.Blockers, "the conflict should list the open blockers")
+	assert.False(t, get(release).Completed, "a blocked todo should not be completed")
+
+	admin.send(http.MethodDelete, fmt.Sprintf("/todos/%d/dependencies/%d", release.Id, build.Id), "", http.StatusNoContent, nil)
+	admin.send(http.MethodPatch, fmt.Sprintf("/todos/%d", build.Id), `{"completed":true}`, http.StatusOK, nil)
+	assert.Equal(t, []int32{tests.Id, notes.Id}, getReady(release, build, tests, notes),
+		"a todo should be ready once its blockers are completed")
+	admin.send(http.MethodPatch, fmt.Sprintf("/todos/%d", tests.Id), `{"completed":true}`, http.StatusOK, nil)
+	assert.False(t, *get(release).Blocked, "a todo whose blockers are completed should not be blocked")
+	admin.send(http.MethodPatch, fmt.Sprintf("/todos/%d", release.Id), `{"completed":true}`, http.StatusOK, nil)
+
+	deploy := admin.createTodo(`{"task":"deploy to production"}`)
+	block(deploy, notes)
+	admin.send(http.MethodPost, "/todos:batch", fmt.Sprintf(`[{"op":"complete","id":%d}]`, deploy.Id), http.StatusConflict, nil)
+	admin.send(http.MethodDelete, fmt.Sprintf("/todos/%d", notes.Id), "", http.StatusNoContent, nil)
+	assert.Nil(t, get(deploy).Blocked, "a todo whose blockers are in the trash should not be blocked")
+	assert.Equal(t, []int32{deploy.Id}, getReady(deploy))
+
+	// revalidate returns the status of a conditional GET of url with the entity tag etag, and its new entity tag
+	revalidate := func(url, etag string) (int, string) {
+		r := admin.newRequest(http.MethodGet, url, "")
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode, resp.Header.Get(todos.HeaderETag)
+	}
+	paint := admin.createTodo(`{"task":"paint the fence"}`)
+	sand := admin.createTodo(`{"task":"sand the fence"}`)
+	paintURL := fmt.Sprintf("/todos/%d", paint.Id)
+	_, paintETag := revalidate(paintURL, "")
+	firstPaintETag := paintETag
+	_, listETag := revalidate("/todos", "")
+	block(paint, sand)
+	status, paintETag := revalidate(paintURL, paintETag)
+	assert.Equal(t, http.StatusOK, status, "a todo should be sent again when a blocker is added")
+	status, _ = revalidate("/todos", listETag)
+	assert.Equal(t, http.StatusOK, status, "the list should be sent again when a blocker is added")
+	admin.send(http.MethodPatch, fmt.Sprintf("/todos/%d", sand.Id), `{"completed":true}`, http.StatusOK, nil)
+	status, paintETag = revalidate(paintURL, paintETag)
+	assert.Equal(t, http.StatusOK, status, "a todo should be sent again when its blocker is completed")
+	admin.send(http.MethodPatch, fmt.Sprintf("/todos/%d", sand.Id), `{"completed":false}`, http.StatusOK, nil)
+	status, paintETag = revalidate(paintURL, paintETag)
+	assert.Equal(t, http.StatusOK, status, "a todo should be sent again when its blocker is reopened")
+	admin.send(http.MethodDelete, fmt.Sprintf("/todos/%d", sand.Id), "", http.StatusNoContent, nil)
+	status, paintETag = revalidate(paintURL, paintETag)
+	assert.Equal(t, http.StatusOK, status, "a todo should be sent again when its blocker is moved to the trash")
+	admin.send(http.MethodPost, fmt.Sprintf("/todos/%d/restore", sand.Id), "", http.StatusOK, nil)
+	status, paintETag = revalidate(paintURL, paintETag)
+	assert.Equal(t, http.StatusOK, status, "a todo should be sent again when its blocker is restored")
+	_, listETag = revalidate("/todos", "")
+	admin.send(http.MethodDelete, fmt.Sprintf("/todos/%d/dependencies/%d", paint.Id, sand.Id), "", http.StatusNoContent, nil)
+	status, paintETag = revalidate(paintURL, paintETag)
+	assert.Equal(t, http.StatusOK, status, "a todo should be sent again when a blocker is removed")
+	assert.Nil(t, get(paint).Blocked)
+	status, _ = revalidate("/todos", listETag)
+	assert.Equal(t, http.StatusOK, status, "the list should be sent again when a blocker is removed")
+	admin.send(http.MethodPatch, fmt.Sprintf("/todos/%d", sand.Id), `{"task":"sand the old fence"}`, http.StatusOK, nil)
+	status, _ = revalidate(paintURL, paintETag)
+	assert.Equal(t, http.StatusNotModified, status, "a todo should not be sent again when a former blocker changes")
+	var history []todos.TodoEvent
+	admin.send(http.MethodGet, paintURL+"/history", "", http.StatusOK, &history)
+	assert.Len(t, history, 1, "the changes of the blockers should not be recorded in the history of the todo")
+	r := admin.newRequest(http.MethodPatch, paintURL, `{"priority":1}`)
+	r.Header.Set(todos.HeaderIfMatch, firstPaintETag)
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "the changes of the blockers should not change the version of the todo")
+
+	first := admin.createTodo(`{"task":"draft the plan"}`)
+	second := admin.createTodo(`{"task":"review the plan"}`)
+	third := admin.createTodo(`{"task":"approve the plan"}`)
+	block(second, first)
+	block(third, second)
+
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "1: AddTodoDependency on the todo itself, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       fmt.Sprintf("AddTodoDependency blocker_id todo %d cannot block itself", first.Id),
+			r:              admin.newRequest(http.MethodPost, fmt.Sprintf("/todos/%d/dependencies", first.Id), fmt.Sprintf(`{"blocker_id":%d}`, first.Id)),
+		},
+		{
+			name:           "2: AddTodoDependency creating a direct cycle, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       fmt.Sprintf("AddTodoDependency blocker_id todo %d is blocked by todo %d, it cannot block it", second.Id, first.Id),
+			r:              admin.newRequest(http.MethodPost, fmt.Sprintf("/todos/%d/dependencies", first.Id), fmt.Sprintf(`{"blocker_id":%d}`, second.Id)),
+		},
+		{
+			name:           "3: AddTodoDependency creating an indirect cycle, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       fmt.Sprintf("AddTodoDependency blocker_id todo %d is blocked by todo %d, it cannot block it", third.Id, first.Id),
+			r:              admin.newRequest(http.MethodPost, fmt.Sprintf("/todos/%d/dependencies", first.Id), fmt.Sprintf(`{"blocker_id":%d}`, third.Id)),
+		},
+		{
+			name:           "4: AddTodoDependency with a blocker that does not exist, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "AddTodoDependency blocker_id todo 9999 does not exist",
+			r:              admin.newRequest(http.MethodPost, fmt.Sprintf("/todos/%d/dependencies", first.Id), `{"blocker_id":9999}`),
+		},
+		{
+			name:           "5: AddTodoDependency with a blocker in the trash, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       fmt.Sprintf("AddTodoDependency blocker_id todo %d does not exist", notes.Id),
+			r:              admin.newRequest(http.MethodPost, fmt.Sprintf("/todos/%d/dependencies", first.Id), fmt.Sprintf(`{"blocker_id":%d}`, notes.Id)),
+		},
+		{
+			name:           "6: AddTodoDependency on a todo that does not exist, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "todo id : 9999 does not exist",
+			r:              admin.newRequest(http.MethodPost, "/todos/9999/dependencies", fmt.Sprintf(`{"blocker_id":%d}`, first.Id)),
+		},
+		{
+			name:           "7: PatchTodo completing a blocked todo, should return Conflict with its blockers",
+			wantStatusCode: http.StatusConflict,
+			wantBody:       fmt.Sprintf(`"blockers":[%d]`, first.Id),
+			r:              admin.newRequest(http.MethodPatch, fmt.Sprintf("/todos/%d", second.Id), `{"completed":true}`),
+		},
+		{
+			name:           "8: PatchTodo of the blocked flag, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "PatchTodo blocked cannot be modified",
+			r:              admin.newRequest(http.MethodPatch, fmt.Sprintf("/todos/%d", second.Id), `{"blocked":false}`),
+		},
+		{
+			name:           "9: GetTodoDependencies, should return the blockers of the todo",
+			wantStatusCode: http.StatusOK,
+			wantBody:       fmt.Sprintf(`"id":%d`, second.Id),
+			r:              admin.newRequest(http.MethodGet, fmt.Sprintf("/todos/%d/dependencies", third.Id), ""),
+		},
+		{
+			name:           "10: GetTodoDependencies of a todo that does not exist, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "todo id : 9999 does not exist",
+			r:              admin.newRequest(http.MethodGet, "/todos/9999/dependencies", ""),
+		},
+		{
+			name:           "11: RemoveTodoDependency of a todo which is not a blocker, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       fmt.Sprintf("todo id : %d is not blocked by todo %d", third.Id, first.Id),
+			r:              admin.newRequest(http.MethodDelete, fmt.Sprintf("/todos/%d/dependencies/%d", third.Id, first.Id), ""),
+		},
+		{
+			name:           "12: GetReadyTodos with an invalid limit, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "GetReadyTodos limit must be between 1 and 1000",
+			r:              admin.newRequest(http.MethodGet, "/todos/ready?limit=0", ""),
+		},
+	})
+}
+
+// recordingNotifier records the ids of the todos whose reminder it sends, it fails while err is not nil
+type recordingNotifier struct {
+	ids []int32
+	err error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, reminder todos.Reminder) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.ids = append(n.ids, reminder.Todo.Id)
+	return nil
+}
+
+func Test_goTodoServer_TodosReminders(t *testing.T) {
+	l := log.New(ioutil.Discard, appName, 0)
+	forEachStorage(t, runReminderScenarios)
+	t.Run("memory durable", func(t *testing.T) {
+		dataDir := t.TempDir()
+		getStore := func() todos.Storage {
+			store, err := todos.GetStorageInstance("memory", dataDir, l)
+			if err != nil {
+				t.Fatalf(fmt.Sprintf("error getting durable memory storage. error : %v ", err))
+			}
+			return store
+		}
+		store := getStore()
+		ts := httptest.NewServer(GetNewServer(l, store))
+		r, _ := http.NewRequest(http.MethodPost, ts.URL+"/todos", strings.NewReader(`{"task":"water the plants","remind_at":"2030-01-01T08:00:00Z"}`))
+		r.Header.Set(todos.HeaderUser, todos.AdminLogin)
+		r.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		ts.Close()
+		notifier := &recordingNotifier{}
+		now := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+		sent, err := todos.SendReminders(context.Background(), store, notifier, now, l)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, sent, "the reminder reached should be sent")
+		store.Close()
+
+		store = getStore()
+		defer store.Close()
+		sent, err = todos.SendReminders(context.Background(), store, notifier, now, l)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, sent, "a reminder sent before a restart should not be sent again")
+	})
+	t.Run("webhook", func(t *testing.T) {
+		var received []todos.Reminder
+		status := http.StatusNoContent
+		hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var reminder todos.Reminder
+			if r.Method == http.MethodPost && json.NewDecoder(r.Body).Decode(&reminder) == nil {
+				received = append(received, reminder)
+			}
+			w.WriteHeader(status)
+		}))
+		defer hook.Close()
+		notifier := todos.NewWebhookNotifier(hook.URL)
+		remindAt := time.Date(2030, 1, 1, 8, 0, 0, 0, time.UTC)
+		reminder := todos.Reminder{RemindAt: remindAt, Todo: todos.Todo{Id: 42, Task: "water the plants"}}
+		assert.NoError(t, notifier.Notify(context.Background(), reminder))
+		if assert.Len(t, received, 1, "the webhook should receive the reminder") {
+			assert.Equal(t, int32(42), received[0].Todo.Id)
+			assert.True(t, remindAt.Equal(received[0].RemindAt))
+		}
+		status = http.StatusServiceUnavailable
+		assert.Error(t, notifier.Notify(context.Background(), reminder), "a webhook answering an error status should not send the reminder")
+	})
+	t.Run("scheduler", func(t *testing.T) {
+		store, err := todos.GetStorageInstance("memory", "", l)
+		if err != nil {
+			t.Fatalf(fmt.Sprintf("error getting memory storage. error : %v ", err))
+		}
+		defer store.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
+		go func() {
+			todos.RunReminderScheduler(ctx, store, &recordingNotifier{}, time.Hour, l)
+			close(stopped)
+		}()
+		cancel()
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the reminder scheduler should stop when its context is done")
+		}
+	})
+}
+
+func runReminderScenarios(t *testing.T, store todos.Storage) {
+	ts := httptest.NewServer(GetNewServer(log.New(ioutil.Discard, appName, 0), store))
+	defer ts.Close()
+	admin := newTestClient(t, ts, todos.AdminLogin)
+	notifier := &recordingNotifier{}
+	// sendReminders sends the reminders reached at now and returns the ids of the todos reminded
+	sendReminders := func(now time.Time) []int32 {
+		notifier.ids = nil
+		_, err := todos.SendReminders(context.Background(), store, notifier, now, log.New(ioutil.Discard, appName, 0))
+		assert.NoError(t, err)
+		return notifier.ids
+	}
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	plants := admin.createTodo(`{"task":"water the plants","remind_at":"2030-01-01T08:00:00Z"}`)
+	if assert.NotNil(t, plants.RemindAt, "the reminder should be returned by CreateTodo") {
+		assert.True(t, time.Date(2030, 1, 1, 8, 0, 0, 0, time.UTC).Equal(*plants.RemindAt))
+	}
+	taxes := admin.createTodo(`{"task":"pay the taxes","due_at":"2030-01-01T10:00:00Z"}`)
+	later := admin.createTodo(`{"task":"book the trip","due_at":"2030-01-01T10:00:00Z","remind_at":"2030-01-02T08:00:00Z"}`)
+	done := admin.createTodo(`{"task":"read the mail","due_at":"2030-01-01T09:00:00Z"}`)
+	admin.send(http.MethodPatch, fmt.Sprintf("/todos/%d", done.Id), `{"completed":true}`, http.StatusOK, nil)
+	reading := admin.createTodo(`{"task":"read a novel"}`)
+	assert.Equal(t, []int32{plants.Id, taxes.Id}, sendReminders(now),
+		"the reminders reached should be sent in the order of their time, a remind_at replacing the due date")
+	assert.Empty(t, sendReminders(now), "a reminder should be sent only once")
+
+	notifier.err = fmt.Errorf("the notifier is unavailable")
+	assert.Empty(t, sendReminders(now.Add(24*time.Hour)))
+	notifier.err = nil
+	assert.Equal(t, []int32{later.Id}, sendReminders(now.Add(24*time.Hour)), "a reminder that could not be sent should be sent again")
+
+	var snoozed todos.Todo
+	admin.send(http.MethodPost, fmt.Sprintf("/todos/%d/snooze", plants.Id), `{"until":"2030-01-03T08:00:00Z"}`, http.StatusOK, &snoozed)
+	if assert.NotNil(t, snoozed.RemindAt) {
+		assert.True(t, time.Date(2030, 1, 3, 8, 0, 0, 0, time.UTC).Equal(*snoozed.RemindAt), "the snooze should move the reminder")
+	}
+	assert.Empty(t, sendReminders(now.Add(24*time.Hour)), "a snoozed reminder should not be sent before its new time")
+	assert.Equal(t, []int32{plants.Id}, sendReminders(now.Add(48*time.Hour)), "a snoozed reminder should be sent again at its new time")
+
+	admin.send(http.MethodPatch, fmt.Sprintf("/todos/%d", taxes.Id), `{"remind_at":"2030-01-04T08:00:00Z"}`, http.StatusOK, nil)
+	admin.send(http.MethodPut, fmt.Sprintf("/todos/%d", reading.Id),
+		fmt.Sprintf(`{"id":%d,"task":"read a novel","remind_at":"2030-01-04T09:00:00Z"}`, reading.Id), http.StatusOK, nil)
+	assert.Equal(t, []int32{taxes.Id, reading.Id}, sendReminders(now.Add(72*time.Hour)),
+		"a reminder modified by PatchTodo or UpdateTodo should be sent at its new time")
+	var unset todos.Todo
+	admin.send(http.MethodPatch, fmt.Sprintf("/todos/%d", taxes.Id), `{"remind_at":null}`, http.StatusOK, &unset)
+	assert.Nil(t, unset.RemindAt, "a reminder removed by PatchTodo should be reminded at the due date")
+
+	runTestScenarios(t, []testScenario{
+		{
+			name:           "1: SnoozeTodo with a duration, should return the Todo with its new reminder",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"remind_at":`,
+			r:              admin.newRequest(http.MethodPost, fmt.Sprintf("/todos/%d/snooze", later.Id), `{"duration":"2h"}`),
+		},
+		{
+			name:           "2: SnoozeTodo without duration nor until, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "SnoozeTodo duration or until must be given, but not both",
+			r:              admin.newRequest(http.MethodPost, fmt.Sprintf("/todos/%d/snooze", later.Id), `{}`),
+		},
+		{
+			name:           "3: SnoozeTodo with a duration and until, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "SnoozeTodo duration or until must be given, but not both",
+			r: admin.newRequest(http.MethodPost, fmt.Sprintf("/todos/%d/snooze", later.Id),
+				`{"duration":"2h","until":"2099-01-01T08:00:00Z"}`),
+		},
+		{
+			name:           "4: SnoozeTodo until a past date, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "SnoozeTodo until must be in the future",
+			r:              admin.newRequest(http.MethodPost, fmt.Sprintf("/todos/%d/snooze", later.Id), `{"until":"2001-01-01T08:00:00Z"}`),
+		},
+		{
+			name:           "5: SnoozeTodo with an invalid duration, should return Bad Request",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "SnoozeTodo duration must be a positive duration like 90m, 2h or 1d, of at most 366 days",
+			r:              admin.newRequest(http.MethodPost, fmt.Sprintf("/todos/%d/snooze", later.Id), `{"duration":"-2h"}`),
+		},
+		{
+			name:           "6: SnoozeTodo of a completed todo, should return Conflict",
+			wantStatusCode: http.StatusConflict,
+			wantBody:       fmt.Sprintf("todo id %d is completed, it has no reminder", done.Id),
+			r:              admin.newRequest(http.MethodPost, fmt.Sprintf("/todos/%d/snooze", done.Id), `{"duration":"1d"}`),
+		},
+		{
+			name:           "7: SnoozeTodo of a todo that does not exist, should return Not Found",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "todo id : 9999 does not exist",
+			r:              admin.newRequest(http.MethodPost, "/todos/9999/snooze", `{"duration":"1d"}`),
+		},
+	})
 }
@@ -0,0 +1,4 @@
+drop index if exists public.todos_task_tsv_idx;
+
+alter table public.todos
+    drop column if exists task_tsv;
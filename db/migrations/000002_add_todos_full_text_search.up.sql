@@ -0,0 +1,4 @@
+alter table public.todos
+    add column task_tsv tsvector generated always as (to_tsvector('simple', coalesce(task, ''))) stored;
+
+create index todos_task_tsv_idx on public.todos using gin (task_tsv);
@@ -0,0 +1,2 @@
+alter table public.todos
+    drop column if exists version;
@@ -0,0 +1,5 @@
+alter table public.todos
+    add column version integer not null default 1;
+
+comment
+on column public.todos.version is 'incremented at every change of the todo, used for optimistic concurrency';
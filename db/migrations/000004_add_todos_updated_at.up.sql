@@ -0,0 +1,8 @@
+alter table public.todos
+    add column updated_at timestamp;
+
+update public.todos
+set updated_at = coalesce(completed_at, created_at);
+
+comment
+on column public.todos.updated_at is 'time of the last change of the todo, used for http caching';
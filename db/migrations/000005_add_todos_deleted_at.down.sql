@@ -0,0 +1,4 @@
+drop index if exists public.todos_deleted_at_idx;
+
+alter table public.todos
+    drop column if exists deleted_at;
@@ -0,0 +1,7 @@
+alter table public.todos
+    add column deleted_at timestamp;
+
+create index todos_deleted_at_idx on public.todos (deleted_at) where deleted_at is not null;
+
+comment
+on column public.todos.deleted_at is 'time when the todo was moved to the trash, null for the live todos';
@@ -0,0 +1,20 @@
+create table public.todo_events
+(
+    id          bigserial,
+    todo_id     int       not null,
+    type        text      not null,
+    actor       text      not null,
+    occurred_at timestamp not null default now(),
+    before      jsonb,
+    after       jsonb
+);
+
+comment
+on table public.todo_events is 'history of the changes of the todos, the events are kept after the todos are purged';
+
+alter table public.todo_events
+    add constraint todo_events_pk primary key (id);
+
+create index todo_events_todo_id_idx on public.todo_events (todo_id, id);
+
+create index todo_events_occurred_at_idx on public.todo_events (occurred_at);
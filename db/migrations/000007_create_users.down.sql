@@ -0,0 +1,9 @@
+alter table public.todo_events
+    drop column if exists owner_id;
+
+drop index if exists public.todos_owner_id_idx;
+
+alter table public.todos
+    drop column if exists owner_id;
+
+drop table if exists public.users;
@@ -0,0 +1,43 @@
+create table public.users
+(
+    id         serial,
+    login      text      not null,
+    name       text,
+    is_admin   boolean   not null default false,
+    created_at timestamp not null default now()
+);
+
+comment
+on table public.users is 'users owning the todos, only the owner of a todo can see and change it';
+
+alter table public.users
+    add constraint users_pk primary key (id);
+
+alter table public.users
+    add constraint users_login_uk unique (login);
+
+insert into public.users (login, is_admin)
+values ('admin', true);
+
+alter table public.todos
+    add column owner_id int;
+
+update public.todos
+set owner_id = (select id from public.users where login = 'admin');
+
+alter table public.todos
+    alter column owner_id set not null;
+
+alter table public.todos
+    add constraint todos_owner_id_fk foreign key (owner_id) references public.users (id);
+
+create index todos_owner_id_idx on public.todos (owner_id);
+
+alter table public.todo_events
+    add column owner_id int;
+
+update public.todo_events
+set owner_id = (select id from public.users where login = 'admin');
+
+alter table public.todo_events
+    alter column owner_id set not null;
@@ -0,0 +1,24 @@
+create table public.api_keys
+(
+    id           serial,
+    name         text      not null,
+    prefix       text      not null,
+    hash         text      not null,
+    user_id      int       not null,
+    scopes       text      not null,
+    created_at   timestamp not null default now(),
+    expires_at   timestamp,
+    last_used_at timestamp
+);
+
+comment
+on table public.api_keys is 'API keys of the scripts acting for a user, only the sha-256 hash of the keys is stored';
+
+alter table public.api_keys
+    add constraint api_keys_pk primary key (id);
+
+alter table public.api_keys
+    add constraint api_keys_hash_uk unique (hash);
+
+alter table public.api_keys
+    add constraint api_keys_user_id_fk foreign key (user_id) references public.users (id);
@@ -0,0 +1,12 @@
+alter table public.users
+    add column is_admin boolean not null default false;
+
+update public.users
+set is_admin = true
+where role = 'admin';
+
+alter table public.users
+    drop constraint if exists users_role_ck;
+
+alter table public.users
+    drop column if exists role;
@@ -0,0 +1,15 @@
+alter table public.users
+    add column role text not null default 'editor';
+
+alter table public.users
+    add constraint users_role_ck check (role in ('viewer', 'editor', 'admin'));
+
+update public.users
+set role = 'admin'
+where is_admin;
+
+alter table public.users
+    drop column is_admin;
+
+comment
+on column public.users.role is 'viewer can only read the todos, editor can also change them, admin can also manage the users and the API keys';
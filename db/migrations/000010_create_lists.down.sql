@@ -0,0 +1,9 @@
+drop index if exists public.todos_list_id_idx;
+
+alter table public.todos
+    drop constraint if exists todos_list_id_fk;
+
+alter table public.todos
+    drop column if exists list_id;
+
+drop table if exists public.lists;
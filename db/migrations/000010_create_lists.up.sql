@@ -0,0 +1,31 @@
+create table public.lists
+(
+    id         serial,
+    name       text      not null,
+    owner_id   int       not null,
+    created_at timestamp not null default now(),
+    updated_at timestamp not null default now()
+);
+
+comment
+on table public.lists is 'named lists of todos, the names of the lists of a user are unique';
+
+alter table public.lists
+    add constraint lists_pk primary key (id);
+
+alter table public.lists
+    add constraint lists_owner_id_name_uk unique (owner_id, name);
+
+alter table public.lists
+    add constraint lists_owner_id_fk foreign key (owner_id) references public.users (id);
+
+alter table public.todos
+    add column list_id int;
+
+alter table public.todos
+    add constraint todos_list_id_fk foreign key (list_id) references public.lists (id) on delete cascade;
+
+create index todos_list_id_idx on public.todos (list_id);
+
+comment
+on column public.todos.list_id is 'list containing the todo, the todos of a list are removed with it';
@@ -0,0 +1,3 @@
+drop table if exists public.todo_tags;
+
+drop table if exists public.tags;
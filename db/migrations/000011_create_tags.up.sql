@@ -0,0 +1,38 @@
+create table public.tags
+(
+    id       serial,
+    owner_id int  not null,
+    name     text not null
+);
+
+comment
+on table public.tags is 'tags of the todos of a user, stored in lowercase and without their leading hash sign';
+
+alter table public.tags
+    add constraint tags_pk primary key (id);
+
+alter table public.tags
+    add constraint tags_owner_id_name_uk unique (owner_id, name);
+
+alter table public.tags
+    add constraint tags_owner_id_fk foreign key (owner_id) references public.users (id);
+
+create table public.todo_tags
+(
+    todo_id int not null,
+    tag_id  int not null
+);
+
+comment
+on table public.todo_tags is 'tags of every todo, they are removed with the todo or the tag';
+
+alter table public.todo_tags
+    add constraint todo_tags_pk primary key (todo_id, tag_id);
+
+alter table public.todo_tags
+    add constraint todo_tags_todo_id_fk foreign key (todo_id) references public.todos (id) on delete cascade;
+
+alter table public.todo_tags
+    add constraint todo_tags_tag_id_fk foreign key (tag_id) references public.tags (id) on delete cascade;
+
+create index todo_tags_tag_id_idx on public.todo_tags (tag_id);
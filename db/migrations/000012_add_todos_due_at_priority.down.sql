@@ -0,0 +1,12 @@
+drop index if exists public.todos_priority_idx;
+
+drop index if exists public.todos_due_at_priority_idx;
+
+alter table public.todos
+    drop constraint if exists todos_priority_ck;
+
+alter table public.todos
+    drop column if exists priority;
+
+alter table public.todos
+    drop column if exists due_at;
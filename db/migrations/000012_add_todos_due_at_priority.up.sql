@@ -0,0 +1,21 @@
+alter table public.todos
+    add column due_at timestamptz;
+
+alter table public.todos
+    add column priority int;
+
+alter table public.todos
+    add constraint todos_priority_ck check (priority between 1 and 4);
+
+comment
+on column public.todos.due_at is 'date-time when the todo must be completed';
+
+comment
+on column public.todos.priority is 'priority of the todo, from 1 for the most urgent to 4';
+
+create index todos_due_at_priority_idx on public.todos (due_at, priority) where deleted_at is null and not completed;
+
+comment
+on index public.todos_due_at_priority_idx is 'the overdue and upcoming todos are the live todos not completed sorted by due date then priority';
+
+create index todos_priority_idx on public.todos (priority);
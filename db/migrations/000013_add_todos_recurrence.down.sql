@@ -0,0 +1,7 @@
+drop index if exists public.todos_series_id_idx;
+
+alter table public.todos
+    drop column if exists series_id;
+
+alter table public.todos
+    drop column if exists recurrence;
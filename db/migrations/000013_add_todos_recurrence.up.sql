@@ -0,0 +1,13 @@
+alter table public.todos
+    add column recurrence text;
+
+alter table public.todos
+    add column series_id int;
+
+comment
+on column public.todos.recurrence is 'recurrence rule of the todo, a subset of the iCalendar RRULE in its canonical form';
+
+comment
+on column public.todos.series_id is 'id of the first todo of the series of occurrences of a recurring todo';
+
+create index todos_series_id_idx on public.todos (series_id);
@@ -0,0 +1,7 @@
+drop index if exists public.todos_parent_id_idx;
+
+alter table public.todos
+    drop column if exists auto_complete;
+
+alter table public.todos
+    drop column if exists parent_id;
@@ -0,0 +1,14 @@
+alter table public.todos
+    add column parent_id int references public.todos (id) on delete set null,
+    add constraint todos_parent_id_check check (parent_id <> id);
+
+alter table public.todos
+    add column auto_complete boolean;
+
+comment
+on column public.todos.parent_id is 'id of the parent of the todo, null for a root todo';
+
+comment
+on column public.todos.auto_complete is 'true when the todo is completed as soon as all its children are completed, null otherwise';
+
+create index todos_parent_id_idx on public.todos (parent_id);
@@ -0,0 +1,22 @@
+create table public.todo_dependencies
+(
+    todo_id    int not null,
+    blocker_id int not null
+);
+
+comment
+on table public.todo_dependencies is 'blocking dependencies, a todo cannot be completed while one of its blockers is open. they are removed with the todos';
+
+alter table public.todo_dependencies
+    add constraint todo_dependencies_pk primary key (todo_id, blocker_id);
+
+alter table public.todo_dependencies
+    add constraint todo_dependencies_check check (todo_id <> blocker_id);
+
+alter table public.todo_dependencies
+    add constraint todo_dependencies_todo_id_fk foreign key (todo_id) references public.todos (id) on delete cascade;
+
+alter table public.todo_dependencies
+    add constraint todo_dependencies_blocker_id_fk foreign key (blocker_id) references public.todos (id) on delete cascade;
+
+create index todo_dependencies_blocker_id_idx on public.todo_dependencies (blocker_id);
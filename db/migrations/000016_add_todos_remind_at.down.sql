@@ -0,0 +1,7 @@
+drop index if exists public.todos_reminder_idx;
+
+alter table public.todos
+    drop column if exists reminded_at;
+
+alter table public.todos
+    drop column if exists remind_at;
@@ -0,0 +1,21 @@
+alter table public.todos
+    add column remind_at timestamptz;
+
+alter table public.todos
+    add column reminded_at timestamptz;
+
+comment
+on column public.todos.remind_at is 'date-time when the owner of the todo is reminded of it, the due date is used when it is null';
+
+comment
+on column public.todos.reminded_at is 'reminder date-time of the last reminder sent, the reminder is sent again when remind_at or due_at changes';
+
+-- the todos already overdue do not get a reminder for a due date that passed before the reminders existed
+update public.todos
+set reminded_at = due_at
+where due_at <= now();
+
+create index todos_reminder_idx on public.todos (coalesce(remind_at, due_at)) where deleted_at is null and not completed;
+
+comment
+on index public.todos_reminder_idx is 'the reminders are sent for the live todos not completed in the order of their reminder date-time';
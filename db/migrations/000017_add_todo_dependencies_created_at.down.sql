@@ -0,0 +1,2 @@
+alter table public.todo_dependencies
+    drop column if exists created_at;
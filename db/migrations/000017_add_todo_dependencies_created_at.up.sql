@@ -0,0 +1,6 @@
+-- the dependencies already there get the time of the migration
+alter table public.todo_dependencies
+    add column created_at timestamptz not null default now();
+
+comment
+on column public.todo_dependencies.created_at is 'date-time when the dependency was added, the cached lists of todos are validated with the last one';
@@ -0,0 +1,5 @@
+alter table public.todos
+    alter column created_at type timestamp using created_at at time zone 'UTC',
+    alter column completed_at type timestamp using completed_at at time zone 'UTC',
+    alter column updated_at type timestamp using updated_at at time zone 'UTC',
+    alter column deleted_at type timestamp using deleted_at at time zone 'UTC';
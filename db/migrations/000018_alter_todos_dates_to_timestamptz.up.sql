@@ -0,0 +1,7 @@
+-- the dates of the todos were stored without time zone in UTC, they get a time zone like their due date
+-- so they are compared with it and with now() whatever the time zone of the session
+alter table public.todos
+    alter column created_at type timestamptz using created_at at time zone 'UTC',
+    alter column completed_at type timestamptz using completed_at at time zone 'UTC',
+    alter column updated_at type timestamptz using updated_at at time zone 'UTC',
+    alter column deleted_at type timestamptz using deleted_at at time zone 'UTC';
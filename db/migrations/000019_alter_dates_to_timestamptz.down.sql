@@ -0,0 +1,14 @@
+alter table public.lists
+    alter column created_at type timestamp using created_at at time zone 'UTC',
+    alter column updated_at type timestamp using updated_at at time zone 'UTC';
+
+alter table public.api_keys
+    alter column created_at type timestamp using created_at at time zone 'UTC',
+    alter column expires_at type timestamp using expires_at at time zone 'UTC',
+    alter column last_used_at type timestamp using last_used_at at time zone 'UTC';
+
+alter table public.users
+    alter column created_at type timestamp using created_at at time zone 'UTC';
+
+alter table public.todo_events
+    alter column occurred_at type timestamp using occurred_at at time zone 'UTC';
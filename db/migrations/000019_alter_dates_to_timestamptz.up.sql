@@ -0,0 +1,16 @@
+-- like the todos, all the dates are stored with time zone, so remind_at and due_at are compared with them
+-- and with now() whatever the time zone of the session
+alter table public.todo_events
+    alter column occurred_at type timestamptz using occurred_at at time zone 'UTC';
+
+alter table public.users
+    alter column created_at type timestamptz using created_at at time zone 'UTC';
+
+alter table public.api_keys
+    alter column created_at type timestamptz using created_at at time zone 'UTC',
+    alter column expires_at type timestamptz using expires_at at time zone 'UTC',
+    alter column last_used_at type timestamptz using last_used_at at time zone 'UTC';
+
+alter table public.lists
+    alter column created_at type timestamptz using created_at at time zone 'UTC',
+    alter column updated_at type timestamptz using updated_at at time zone 'UTC';
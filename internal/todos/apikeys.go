@@ -0,0 +1,182 @@
+package todos
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderApiKey is the request header containing the API key of the scripts
+	HeaderApiKey = "X-API-Key"
+	// MaxApiKeyNameLength is the greatest number of characters of the name of an API key
+	MaxApiKeyNameLength = 100
+	// apiKeyPrefix starts all the keys, so they are easy to find in the scripts and in the logs
+	apiKeyPrefix = "todos_"
+	// apiKeyPrefixLength is the number of characters of the key kept in ApiKey.Prefix
+	apiKeyPrefixLength = 12
+	// apiKeyRandomBytes is the number of random bytes of a key
+	apiKeyRandomBytes = 32
+	// apiKeyLastUsedResolution is the smallest delay between two updates of ApiKey.LastUsedAt
+	apiKeyLastUsedResolution = time.Minute
+)
+
+// ErrApiKeyNotFound is returned when the API key does not exist in the store, or was revoked
+var ErrApiKeyNotFound = errors.New("api key does not exist")
+
+// apiKeyKey is the key of the API key of the request in a context
+type apiKeyKey struct{}
+
+// withApiKey returns a copy of ctx carrying the API key used to make the request
+func withApiKey(ctx context.Context, apiKey *ApiKey) context.Context {
+	return context.WithValue(ctx, apiKeyKey{}, apiKey)
+}
+
+// apiKeyFromContext returns the API key carried by ctx, or nil when the request was not made with an API key
+func apiKeyFromContext(ctx context.Context) *ApiKey {
+	apiKey, _ := ctx.Value(apiKeyKey{}).(*ApiKey)
+	return apiKey
+}
+
+// hasScope returns true if the request was not made with an API key, or with an API key having the scope.
+// the admin scope includes all the others
+func hasScope(ctx context.Context, scope ApiKeyScope) bool {
+	apiKey := apiKeyFromContext(ctx)
+	if apiKey == nil {
+		return true
+	}
+	for _, s := range apiKey.Scopes {
+		if s == scope || s == ApiKeyScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// generateApiKey returns a new random key and its hash, only the hash is kept in the store
+func generateApiKey() (string, string, error) {
+	random := make([]byte, apiKeyRandomBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", err
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(random)
+	return key, hashApiKey(key), nil
+}
+
+// hashApiKey returns the hash of the key stored instead of the key, the keys are random
+// so a fast hash is enough to prevent them from being read in the store
+func hashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// BootstrapAdminApiKey stores key as an admin API key of the administrator when it is not in store yet,
+// so a server without tokens accepts the requests of its first script. it returns true when the key was created.
+// a revoked key is created again at the next start, the key must be removed from the config to revoke it for good
+func BootstrapAdminApiKey(ctx context.Context, store Storage, key string) (bool, error) {
+	hash := hashApiKey(key)
+	_, err := store.GetApiKey(ctx, hash)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, ErrApiKeyNotFound) {
+		return false, err
+	}
+	prefix := key
+	if len(prefix) > apiKeyPrefixLength {
+		prefix = prefix[:apiKeyPrefixLength]
+	}
+	if _, err := store.CreateApiKey(ctx, ApiKey{
+		Login:  AdminLogin,
+		Name:   "admin key of the config",
+		Prefix: prefix,
+		Scopes: []ApiKeyScope{ApiKeyScopeAdmin},
+	}, hash); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// encodeScopes returns the scopes as stored in the sql databases, separated by spaces
+func encodeScopes(scopes []ApiKeyScope) string {
+	values := make([]string, len(scopes))
+	for i, scope := range scopes {
+		values[i] = string(scope)
+	}
+	return strings.Join(values, " ")
+}
+
+// decodeScopes returns the scopes stored by encodeScopes
+func decodeScopes(scopes string) []ApiKeyScope {
+	res := make([]ApiKeyScope, 0)
+	for _, scope := range strings.Fields(scopes) {
+		res = append(res, ApiKeyScope(scope))
+	}
+	return res
+}
+
+// apiKeyRow is an ApiKey as stored in the sql databases, with its scopes separated by spaces
+type apiKeyRow struct {
+	Id         int32
+	Name       string
+	Prefix     string
+	Login      string
+	Scopes     string
+	CreatedAt  *time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+// toApiKey converts the row read from a sql database to an ApiKey
+func (r *apiKeyRow) toApiKey() *ApiKey {
+	return &ApiKey{
+		CreatedAt:  r.CreatedAt,
+		ExpiresAt:  r.ExpiresAt,
+		Id:         r.Id,
+		LastUsedAt: r.LastUsedAt,
+		Login:      r.Login,
+		Name:       r.Name,
+		Prefix:     r.Prefix,
+		Scopes:     decodeScopes(r.Scopes),
+	}
+}
+
+// toApiKeys converts the rows read from a sql database to ApiKeys
+func toApiKeys(rows []*apiKeyRow) []*ApiKey {
+	res := make([]*ApiKey, 0, len(rows))
+	for _, r := range rows {
+		res = append(res, r.toApiKey())
+	}
+	return res
+}
+
+// validateNewApiKey checks the business rules of a new API key
+func validateNewApiKey(apiKey NewApiKey) error {
+	name := strings.TrimSpace(apiKey.Name)
+	if len(name) < 1 {
+		return &ValidationError{Field: "name", Message: "cannot be empty"}
+	}
+	if len(name) > MaxApiKeyNameLength {
+		return &ValidationError{Field: "name", Message: "maxLength is 100"}
+	}
+	if len(apiKey.Scopes) < 1 {
+		return &ValidationError{Field: "scopes", Message: "cannot be empty"}
+	}
+	for _, scope := range apiKey.Scopes {
+		switch scope {
+		case ApiKeyScopeTodosRead, ApiKeyScopeTodosWrite, ApiKeyScopeAdmin:
+		default:
+			return &ValidationError{Field: "scopes", Message: fmt.Sprintf("%s is not one of todos:read, todos:write, admin", scope)}
+		}
+	}
+	if apiKey.ExpiresAt != nil && !apiKey.ExpiresAt.After(time.Now()) {
+		return &ValidationError{Field: "expires_at", Message: "must be in the future"}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package todos
+
+import (
+	"context"
+	"fmt"
+)
+
+// MaxBatchSize is the greatest number of operations that can be applied by a single Storage.Batch call
+const MaxBatchSize = 1000
+
+// BatchError is returned by Storage.Batch when one of the operations fails, none of the operations is applied then
+type BatchError struct {
+	// Index is the position of the failed operation in the batch
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d failed : %v", e.Index, e.Err)
+}
+
+// Unwrap allows errors.Is and errors.As to test the error of the failed operation
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// todoWriter applies the changes of the Storage write methods. the stores implement it on top of a transaction,
+// so the operations of a batch are applied atomically by the same code as a single change.
+// previous is the occurrence of a recurring todo whose next occurrence is created, nil for the other todos.
+// delete and purge only change the todo itself, deleteTodo and purgeTodo also take care of its children.
+type todoWriter interface {
+	// lock returns the todo with given id, an error is returned if a write requiring the given state and matchVersion
+	// cannot be applied to it
+	lock(ctx context.Context, operation string, id int32, state todoState, matchVersion int32) (*Todo, error)
+	create(ctx context.Context, todo NewTodo, previous *Todo) (*Todo, error)
+	patch(ctx context.Context, id int32, patch TodoPatch, matchVersion int32) (*Todo, error)
+	delete(ctx context.Context, id int32, matchVersion int32) error
+	restore(ctx context.Context, id int32, matchVersion int32) (*Todo, error)
+	purge(ctx context.Context, id int32, matchVersion int32) error
+	// openOccurrences returns the ids of the live todos of the series not yet completed
+	openOccurrences(ctx context.Context, seriesId int32) ([]int32, error)
+	// children returns the children of the todo in the given state in the order of their id
+	children(ctx context.Context, parentId int32, state todoState) ([]*Todo, error)
+	// reparent moves the todo with given id to the parent, nil for a root todo, even if it is in the trash
+	reparent(ctx context.Context, id int32, parentId *int32) (*Todo, error)
+	// openBlockers returns the ids of the live blockers of the todo not yet completed, in ascending order
+	openBlockers(ctx context.Context, id int32) ([]int32, error)
+	// dependsOn returns true when the todo with given id is blocked by blockerId, directly or through other blockers,
+	// whatever the state of the todos between them
+	dependsOn(ctx context.Context, id int32, blockerId int32) (bool, error)
+	// addDependency blocks the todo with given id by blockerId, adding an existing dependency does nothing
+	addDependency(ctx context.Context, id int32, blockerId int32) error
+}
+
+// validateBatchOperation checks that the operation has the fields required by its op, before anything is written
+func validateBatchOperation(operation BatchOperation) error {
+	switch operation.Op {
+	case BatchOperationOpCreate:
+		if operation.Id != nil {
+			return &ValidationError{Field: "id", Message: "cannot be given to create a todo"}
+		}
+		if operation.Completed != nil {
+			return &ValidationError{Field: "completed", Message: "cannot be given to create a todo"}
+		}
+		if operation.Version != nil {
+			return &ValidationError{Field: "version", Message: "cannot be given to create a todo"}
+		}
+		if operation.Task == nil {
+			return &ValidationError{Field: "task", Message: "is required to create a todo"}
+		}
+		return validateTask(*operation.Task)
+	case BatchOperationOpUpdate:
+		if operation.Id == nil {
+			return &ValidationError{Field: "id", Message: "is required to update a todo"}
+		}
+		if operation.Task == nil && operation.Completed == nil && operation.ListId == nil {
+			return &ValidationError{Field: "task", Message: "completed or list_id is required to update a todo"}
+		}
+		if operation.Task != nil {
+			return validateTask(*operation.Task)
+		}
+		return nil
+	case BatchOperationOpComplete, BatchOperationOpDelete:
+		if operation.Id == nil {
+			return &ValidationError{Field: "id", Message: fmt.Sprintf("is required to %s a todo", operation.Op)}
+		}
+		if operation.Task != nil || operation.Completed != nil {
+			return &ValidationError{Field: "task", Message: fmt.Sprintf("and completed cannot be given to %s a todo", operation.Op)}
+		}
+		if operation.ListId != nil {
+			return &ValidationError{Field: "list_id", Message: fmt.Sprintf("cannot be given to %s a todo", operation.Op)}
+		}
+		return nil
+	default:
+		return &ValidationError{Field: "op", Message: fmt.Sprintf("%q is not one of create, update, complete, delete", operation.Op)}
+	}
+}
+
+// runBatch validates all the operations, and then applies them in order with the writer, stopping at the first failure.
+// the caller is responsible for discarding the changes already applied by the writer when an error is returned.
+func runBatch(ctx context.Context, w todoWriter, operations []BatchOperation) ([]*Todo, error) {
+	if len(operations) < 1 || len(operations) > MaxBatchSize {
+		return nil, &ValidationError{Field: "operations", Message: fmt.Sprintf("must contain between 1 and %d operations", MaxBatchSize)}
+	}
+	for i, operation := range operations {
+		if err := validateBatchOperation(operation); err != nil {
+			return nil, &BatchError{Index: i, Err: err}
+		}
+	}
+	res := make([]*Todo, len(operations))
+	for i, operation := range operations {
+		var matchVersion int32 = 0
+		if operation.Version != nil {
+			matchVersion = *operation.Version
+		}
+		var err error
+		switch operation.Op {
+		case BatchOperationOpCreate:
+			res[i], err = w.create(ctx, NewTodo{Task: *operation.Task, ListId: operation.ListId}, nil)
+		case BatchOperationOpUpdate:
+			res[i], err = w.patch(ctx, *operation.Id, TodoPatch{Task: operation.Task, Completed: operation.Completed, ListId: operation.ListId}, matchVersion)
+		case BatchOperationOpComplete:
+			completed := true
+			res[i], err = w.patch(ctx, *operation.Id, TodoPatch{Completed: &completed}, matchVersion)
+		case BatchOperationOpDelete:
+			err = deleteTodo(ctx, w, *operation.Id, matchVersion, false)
+		}
+		if err != nil {
+			return nil, &BatchError{Index: i, Err: err}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,120 @@
+package todos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrDependencyNotFound is returned when the todo is not blocked by the given blocker
+var ErrDependencyNotFound = errors.New("todo is not blocked by this todo")
+
+// BlockedError is returned when a todo is completed while some of its blockers are not completed,
+// it matches ErrConflict with errors.Is
+type BlockedError struct {
+	TodoId int32
+	// Blockers contains the ids of the blockers not yet completed, in ascending order
+	Blockers []int32
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("todo %d is blocked by the todos %v which are not completed", e.TodoId, e.Blockers)
+}
+
+// Is allows errors.Is(err, ErrConflict) to match any BlockedError
+func (e *BlockedError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// blockerRow is a dependency of a todo on a live blocker, as read by the query of newOpenBlockersQuery
+type blockerRow struct {
+	TodoId    int32
+	BlockerId int32
+	Open      bool
+}
+
+// toOpenBlockers returns by id of the todo the ids of the open blockers of the rows read by the query of newOpenBlockersQuery,
+// the todos having only completed blockers get an empty slice
+func toOpenBlockers(rows []*blockerRow) map[int32][]int32 {
+	res := make(map[int32][]int32, len(rows))
+	for _, row := range rows {
+		if _, found := res[row.TodoId]; !found {
+			res[row.TodoId] = []int32{}
+		}
+		if row.Open {
+			res[row.TodoId] = append(res[row.TodoId], row.BlockerId)
+		}
+	}
+	return res
+}
+
+// errUnknownBlocker is the ValidationError returned when a todo is blocked by a todo that does not exist,
+// that is in the trash or that is not owned by the owner of the todo
+func errUnknownBlocker(blockerId int32) error {
+	return &ValidationError{Field: "blocker_id", Message: fmt.Sprintf("todo %d does not exist", blockerId)}
+}
+
+// errDependencyCycle is the ValidationError returned when a todo is blocked by itself or by one of the todos it blocks
+func errDependencyCycle(id, blockerId int32) error {
+	if id == blockerId {
+		return &ValidationError{Field: "blocker_id", Message: fmt.Sprintf("todo %d cannot block itself", id)}
+	}
+	return &ValidationError{Field: "blocker_id", Message: fmt.Sprintf("todo %d is blocked by todo %d, it cannot block it", blockerId, id)}
+}
+
+// addDependency blocks with w the todo with given id by the todo blockerId, after checking that they are live todos
+// of the same owner and that the dependency does not create a cycle
+func addDependency(ctx context.Context, w todoWriter, id int32, blockerId int32) error {
+	t, err := w.lock(ctx, "AddDependency", id, liveTodo, 0)
+	if err != nil {
+		return err
+	}
+	if id == blockerId {
+		return errDependencyCycle(id, blockerId)
+	}
+	blocker, err := w.lock(ctx, "AddDependency", blockerId, liveTodo, 0)
+	if errors.Is(err, ErrNotFound) || (err == nil && blocker.OwnerId != t.OwnerId) {
+		return errUnknownBlocker(blockerId)
+	}
+	if err != nil {
+		return err
+	}
+	cycle, err := w.dependsOn(ctx, blockerId, id)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return errDependencyCycle(id, blockerId)
+	}
+	return w.addDependency(ctx, id, blockerId)
+}
+
+// checkBlockers returns a *BlockedError when the patch completes the todo while some of its blockers are not completed
+func checkBlockers(ctx context.Context, w todoWriter, before *Todo, patch TodoPatch) error {
+	if patch.Completed == nil || !*patch.Completed || before.Completed {
+		return nil
+	}
+	blockers, err := w.openBlockers(ctx, before.Id)
+	if err != nil {
+		return err
+	}
+	if len(blockers) > 0 {
+		return &BlockedError{TodoId: before.Id, Blockers: blockers}
+	}
+	return nil
+}
+
+// withBlocked returns copies of the todos with their blocked flag set from the open blockers returned by Storage.OpenBlockers,
+// the todos are not modified because the stores may share them
+func withBlocked(todos []*Todo, openBlockers map[int32][]int32) []*Todo {
+	res := make([]*Todo, len(todos))
+	for i, t := range todos {
+		todo := *t
+		if blockers, found := openBlockers[t.Id]; found {
+			blocked := len(blockers) > 0
+			todo.Blocked = &blocked
+		}
+		res[i] = &todo
+	}
+	return res
+}
@@ -0,0 +1,128 @@
+package todos
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MinPriority is the priority of the most urgent todos
+	MinPriority = 1
+	// MaxPriority is the priority of the least urgent todos
+	MaxPriority = 4
+	// DefaultUpcomingWithin is the duration from now in which the todos returned by GetUpcomingTodos are due
+	DefaultUpcomingWithin = 24 * time.Hour
+	// MaxUpcomingWithin is the greatest duration accepted by GetUpcomingTodos
+	MaxUpcomingWithin = 366 * 24 * time.Hour
+)
+
+// validatePriority checks the business rules of the priority field, shared by all the stores
+func validatePriority(priority *int32) error {
+	if priority != nil && (*priority < MinPriority || *priority > MaxPriority) {
+		return &ValidationError{Field: "priority", Message: fmt.Sprintf("must be between %d and %d", MinPriority, MaxPriority)}
+	}
+	return nil
+}
+
+// normalizeDueAt returns the due date stored for t : in UTC and with the millisecond precision of all the stores
+func normalizeDueAt(t *time.Time) *time.Time {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	dueAt := t.UTC().Truncate(time.Millisecond)
+	return &dueAt
+}
+
+// getPatchDueAt returns the due_at column value of a todo after the patch, nil when it has no due date.
+// patch.DueAt is nil when the due date does not change and the zero time when it is removed
+func getPatchDueAt(current *time.Time, patch TodoPatch) *time.Time {
+	if patch.DueAt == nil {
+		return current
+	}
+	return normalizeDueAt(patch.DueAt)
+}
+
+// getPatchPriority returns the priority column value of a todo after the patch, nil when it has no priority.
+// patch.Priority is nil when the priority does not change and 0 when it is removed
+func getPatchPriority(current *int32, patch TodoPatch) *int32 {
+	switch {
+	case patch.Priority == nil:
+		return current
+	case *patch.Priority == 0:
+		return nil
+	default:
+		return patch.Priority
+	}
+}
+
+// validatePatchPriority checks the priority of a patch, 0 removes the priority of the todo
+func validatePatchPriority(patch TodoPatch) error {
+	if patch.Priority != nil && *patch.Priority == 0 {
+		return nil
+	}
+	return validatePriority(patch.Priority)
+}
+
+// getUpdateDueAt returns the TodoPatch.DueAt replacing the due date of a todo by the one of todo, the zero time when it has none
+func getUpdateDueAt(todo Todo) *time.Time {
+	dueAt := time.Time{}
+	if todo.DueAt != nil {
+		dueAt = *todo.DueAt
+	}
+	return &dueAt
+}
+
+// getUpdatePriority returns the TodoPatch.Priority replacing the priority of a todo by the one of todo, 0 when it has none
+func getUpdatePriority(todo Todo) *int32 {
+	priority := int32(0)
+	if todo.Priority != nil {
+		priority = *todo.Priority
+	}
+	return &priority
+}
+
+// isOverdue returns true only if the todo is not completed and its due date is before now
+func isOverdue(t *Todo, now time.Time) bool {
+	return t.DueAt != nil && !t.Completed && t.DueAt.Before(now)
+}
+
+// plainTodo is a Todo without the MarshalJSON method, to encode its fields
+type plainTodo Todo
+
+// MarshalJSON encodes the todo with its overdue flag computed at the time of the encoding
+func (t Todo) MarshalJSON() ([]byte, error) {
+	res := plainTodo(t)
+	res.Overdue = nil
+	if t.DueAt != nil {
+		overdue := isOverdue(&t, time.Now())
+		res.Overdue = &overdue
+	}
+	return json.Marshal(res)
+}
+
+// parseDuration returns the duration given as a Go duration like 90m or 72h, or as a number of days like 7d,
+// ok is false when it is not a positive duration of at most max
+func parseDuration(value string, max time.Duration) (res time.Duration, ok bool) {
+	var err error
+	if days := strings.TrimSuffix(value, "d"); days != value {
+		var n int
+		n, err = strconv.Atoi(days)
+		res = time.Duration(n) * 24 * time.Hour
+	} else {
+		res, err = time.ParseDuration(value)
+	}
+	return res, err == nil && res > 0 && res <= max
+}
+
+// parseWithin returns the duration of the within parameter of GetUpcomingTodos,
+// it is a Go duration like 90m or 72h, or a number of days like 7d
+func parseWithin(within string) (time.Duration, error) {
+	res, ok := parseDuration(within, MaxUpcomingWithin)
+	if !ok {
+		return 0, fmt.Errorf("within must be a positive duration like 90m, 72h or 7d, of at most %d days", MaxUpcomingWithin/(24*time.Hour))
+	}
+	return res, nil
+}
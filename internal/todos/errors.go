@@ -0,0 +1,45 @@
+package todos
+
+import (
+	"errors"
+	"fmt"
+)
+
+// the errors returned by every Storage implementation, they can be tested with errors.Is
+var (
+	// ErrNotFound is returned when the todo with the given id does not exist in the store
+	ErrNotFound = errors.New("todo with this id does not exist")
+	// ErrValidation is matched by every ValidationError
+	ErrValidation = errors.New("todo is not valid")
+	// ErrConflict is returned when the change conflicts with the current content of the store
+	ErrConflict = errors.New("todo conflicts with the current content of the store")
+	// ErrPreconditionFailed is returned when the todo does not have the version required for the change anymore
+	ErrPreconditionFailed = errors.New("todo has been modified since the given version")
+)
+
+// ValidationError is returned when a field of a todo does not respect the business rules,
+// use errors.As to get the field details
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+// Is allows errors.Is(err, ErrValidation) to match any ValidationError
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// validateTask checks the business rules of the task field, shared by all the stores
+func validateTask(task string) error {
+	if len(task) < 1 {
+		return &ValidationError{Field: "task", Message: "cannot be empty"}
+	}
+	if len(task) < 6 {
+		return &ValidationError{Field: "task", Message: "minLength is 5"}
+	}
+	return nil
+}
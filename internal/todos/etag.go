@@ -0,0 +1,142 @@
+package todos
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderETag         = "ETag"
+	HeaderIfMatch      = "If-Match"
+	HeaderLastModified = "Last-Modified"
+	anyETag            = "*"
+	weakETagPrefix     = "W/"
+)
+
+// ETag returns the strong entity tag of the given version of a todo
+func ETag(version int32) string {
+	return fmt.Sprintf("\"%d\"", version)
+}
+
+// todoValidators returns the strong entity tag and the last modification time of the representation of the todo at now.
+// the computed fields change without a new version : the entity tag is the version followed by them, like "3-overdue"
+// or "3-children-2-1-blocked", and the last modification of an overdue todo is at least its due date. the children counts
+// and the blocked flag change with the other todos, so there is no last modification time for a todo having them.
+func todoValidators(todo *Todo, now time.Time) (string, *time.Time) {
+	tag := strconv.Itoa(int(todo.Version))
+	lastModified := todoLastModified(todo)
+	if todo.ChildrenCount != nil && todo.ChildrenCompleted != nil {
+		tag += fmt.Sprintf("-children-%d-%d", *todo.ChildrenCount, *todo.ChildrenCompleted)
+	}
+	if todo.Blocked != nil {
+		if *todo.Blocked {
+			tag += "-blocked"
+		} else {
+			tag += "-unblocked"
+		}
+	}
+	if isOverdue(todo, now) {
+		tag += "-overdue"
+		if lastModified == nil || lastModified.Before(*todo.DueAt) {
+			lastModified = todo.DueAt
+		}
+	}
+	if todo.ChildrenCount != nil || todo.Blocked != nil {
+		lastModified = nil
+	}
+	return fmt.Sprintf("\"%s\"", tag), lastModified
+}
+
+// etagVersion returns the version of the todo in a strong entity tag returned by todoValidators
+func etagVersion(tag string) (int32, bool) {
+	if len(tag) < 2 || !strings.HasPrefix(tag, "\"") || !strings.HasSuffix(tag, "\"") {
+		return 0, false
+	}
+	version := strings.SplitN(tag[1:len(tag)-1], "-", 2)[0]
+	res, err := strconv.ParseInt(version, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int32(res), true
+}
+
+// matchETag returns true if the If-Match header value contains a strong entity tag of the given version or *,
+// whatever the computed fields in the entity tag : a change is only refused when the todo itself was changed.
+// weak entity tags (W/"1") never match, as required by the strong comparison of RFC 7232.
+func matchETag(ifMatch string, version int32) bool {
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == anyETag {
+			return true
+		}
+		if tagVersion, ok := etagVersion(tag); ok && tagVersion == version {
+			return true
+		}
+	}
+	return false
+}
+
+// fingerprintETag returns the weak entity tag of the lists of todos for the given store fingerprint
+func fingerprintETag(fingerprint *Fingerprint) string {
+	var lastModified, dependenciesModified int64 = 0, 0
+	if fingerprint.LastModified != nil {
+		lastModified = fingerprint.LastModified.UnixNano()
+	}
+	if fingerprint.DependenciesModified != nil {
+		dependenciesModified = fingerprint.DependenciesModified.UnixNano()
+	}
+	return fmt.Sprintf("%s\"%d-%d-%x-%d-%d-%x\"", weakETagPrefix, fingerprint.MaxId, fingerprint.Count, lastModified,
+		fingerprint.Overdue, fingerprint.Dependencies, dependenciesModified)
+}
+
+// setETag sends the entity tag of the todo in the response headers
+func setETag(ctx echo.Context, todo *Todo) {
+	etag, _ := todoValidators(todo, time.Now())
+	ctx.Response().Header().Set(HeaderETag, etag)
+}
+
+// noneMatchETag returns true if the If-None-Match header value contains * or the given entity tag,
+// using the weak comparison of RFC 7232 : W/"1" and "1" are considered equal.
+func noneMatchETag(ifNoneMatch string, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == anyETag || strings.TrimPrefix(tag, weakETagPrefix) == strings.TrimPrefix(etag, weakETagPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNotModified evaluates the conditional GET headers in the order of RFC 7232 section 6 :
+// If-Modified-Since is only used when there is no If-None-Match, and when lastModified is known.
+// a date that cannot be parsed is ignored, like the RFC requires.
+func isNotModified(ifNoneMatch, ifModifiedSince *string, etag string, lastModified *time.Time) bool {
+	if ifNoneMatch != nil {
+		return noneMatchETag(*ifNoneMatch, etag)
+	}
+	if ifModifiedSince == nil || lastModified == nil {
+		return false
+	}
+	since, err := http.ParseTime(*ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	// http dates have a precision of one second
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// setCacheHeaders sends the validators and the caching policy of a GET response
+func setCacheHeaders(ctx echo.Context, etag string, lastModified *time.Time, cacheControl string) {
+	header := ctx.Response().Header()
+	header.Set(HeaderETag, etag)
+	if lastModified != nil {
+		header.Set(HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	}
+	if cacheControl != "" {
+		header.Set(echo.HeaderCacheControl, cacheControl)
+	}
+}
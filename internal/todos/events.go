@@ -0,0 +1,149 @@
+package todos
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const (
+	// DefaultEventsLimit is the number of events returned by Events when no limit is given
+	DefaultEventsLimit = 100
+	// MaxEventsLimit is the greatest number of events that can be returned by a single Events call
+	MaxEventsLimit = 1000
+	// UnknownActor is the actor of the changes made with a context without actor
+	UnknownActor = "unknown"
+	// TrashJanitorActor is the actor of the purges made automatically at the end of the trash retention period
+	TrashJanitorActor = "trash janitor"
+)
+
+// actorKey is the key of the actor in a context
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the actor, the stores record it in the events of the changes made with ctx
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// GetActor returns the login of the user carried by ctx (see WithUser), else the actor carried by ctx, or UnknownActor
+func GetActor(ctx context.Context) string {
+	if user := UserFromContext(ctx); user != nil {
+		return user.Login
+	}
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return UnknownActor
+}
+
+// getEventType returns the type of the change of a todo from before to after, before is nil for a creation
+// and after is nil for a purge. when completed changes, the change is a completion even if the task changed too.
+func getEventType(before, after *Todo) TodoEventType {
+	switch {
+	case before == nil:
+		return TodoEventTypeCreated
+	case after == nil:
+		return TodoEventTypePurged
+	case before.DeletedAt == nil && after.DeletedAt != nil:
+		return TodoEventTypeDeleted
+	case before.DeletedAt != nil && after.DeletedAt == nil:
+		return TodoEventTypeRestored
+	case !before.Completed && after.Completed:
+		return TodoEventTypeCompleted
+	case before.Completed && !after.Completed:
+		return TodoEventTypeUncompleted
+	default:
+		return TodoEventTypeUpdated
+	}
+}
+
+// newTodoEvent returns the event of the change of a todo made by the actor of ctx, its id is given by the store
+func newTodoEvent(ctx context.Context, before, after *Todo) *TodoEvent {
+	event := &TodoEvent{
+		Actor:      GetActor(ctx),
+		After:      after,
+		Before:     before,
+		OccurredAt: time.Now(),
+		Type:       getEventType(before, after),
+	}
+	if after != nil {
+		event.TodoId = after.Id
+		event.OwnerId = after.OwnerId
+	} else {
+		event.TodoId = before.Id
+		event.OwnerId = before.OwnerId
+	}
+	return event
+}
+
+// todoEventRow is a TodoEvent as stored in the sql databases, with the todos in json
+type todoEventRow struct {
+	Id         int64
+	TodoId     int32
+	OwnerId    int32
+	Type       string
+	Actor      string
+	OccurredAt time.Time
+	Before     *string
+	After      *string
+}
+
+// todoJSON returns the json of the todo to store in a todoEventRow, nil for a nil todo
+func todoJSON(t *Todo) (*string, error) {
+	if t == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	res := string(data)
+	return &res, nil
+}
+
+// parseTodoJSON returns the todo stored in json in a todoEventRow, nil for a nil json
+func parseTodoJSON(data *string) (*Todo, error) {
+	if data == nil {
+		return nil, nil
+	}
+	t := &Todo{}
+	if err := json.Unmarshal([]byte(*data), t); err != nil {
+		return nil, GetErrorF("error : invalid todo in event", err)
+	}
+	return t, nil
+}
+
+// toTodoEvent converts the row read from a sql database to a TodoEvent
+func (r *todoEventRow) toTodoEvent() (*TodoEvent, error) {
+	before, err := parseTodoJSON(r.Before)
+	if err != nil {
+		return nil, err
+	}
+	after, err := parseTodoJSON(r.After)
+	if err != nil {
+		return nil, err
+	}
+	return &TodoEvent{
+		Actor:      r.Actor,
+		After:      after,
+		Before:     before,
+		Id:         r.Id,
+		OccurredAt: r.OccurredAt,
+		OwnerId:    r.OwnerId,
+		TodoId:     r.TodoId,
+		Type:       TodoEventType(r.Type),
+	}, nil
+}
+
+// toTodoEvents converts the rows read from a sql database to TodoEvents
+func toTodoEvents(rows []*todoEventRow) ([]*TodoEvent, error) {
+	res := make([]*TodoEvent, 0, len(rows))
+	for _, r := range rows {
+		event, err := r.toTodoEvent()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, event)
+	}
+	return res, nil
+}
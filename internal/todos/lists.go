@@ -0,0 +1,64 @@
+package todos
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxListNameLength is the greatest number of characters of the name of a list
+const MaxListNameLength = 100
+
+// ErrListNotFound is returned when the list with the given id does not exist in the store, or belongs to another user
+var ErrListNotFound = errors.New("list with this id does not exist")
+
+// validateNewTodoList checks the business rules of a new or renamed list, shared by all the stores
+func validateNewTodoList(list NewTodoList) error {
+	name := strings.TrimSpace(list.Name)
+	if len(name) < 1 {
+		return &ValidationError{Field: "name", Message: "cannot be empty"}
+	}
+	if name != list.Name {
+		return &ValidationError{Field: "name", Message: "cannot start or end with spaces"}
+	}
+	if len(name) > MaxListNameLength {
+		return &ValidationError{Field: "name", Message: "maxLength is 100"}
+	}
+	return nil
+}
+
+// errUnknownList is the ValidationError returned when a todo is created in, or moved to, a list that does not exist
+// or that is not owned by the owner of the todo
+func errUnknownList(listId int32) error {
+	return &ValidationError{Field: "list_id", Message: fmt.Sprintf("list %d does not exist", listId)}
+}
+
+// getPatchListId returns the list_id column value of a todo after the patch, nil when it is not in a list.
+// patch.ListId is nil when the list does not change and 0 when the todo is removed from its list
+func getPatchListId(current *int32, patch TodoPatch) *int32 {
+	switch {
+	case patch.ListId == nil:
+		return current
+	case *patch.ListId == 0:
+		return nil
+	default:
+		return patch.ListId
+	}
+}
+
+// getUpdateListId returns the TodoPatch.ListId replacing the list of a todo by the one of todo, 0 when it is not in a list
+func getUpdateListId(todo Todo) *int32 {
+	listId := int32(0)
+	if todo.ListId != nil {
+		listId = *todo.ListId
+	}
+	return &listId
+}
+
+// getNewTodoListId returns the list_id of a new todo, nil when it is not in a list
+func getNewTodoListId(todo NewTodo) *int32 {
+	if todo.ListId == nil || *todo.ListId == 0 {
+		return nil
+	}
+	return todo.ListId
+}
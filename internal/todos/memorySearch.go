@@ -0,0 +1,125 @@
+package todos
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	headlineStartSel = "<b>"
+	headlineStopSel  = "</b>"
+)
+
+// searchIndex is an inverted index of the words used in the todos tasks.
+// it mimics the postgres 'simple' text search configuration : words are lower-cased and not stemmed.
+type searchIndex struct {
+	// postings contains for each word the number of occurrences in the task of each todo id
+	postings map[string]map[int32]int
+	// lengths contains the number of words in the task of each todo id
+	lengths map[int32]int
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]map[int32]int),
+		lengths:  make(map[int32]int),
+	}
+}
+
+// isWordRune returns true for the characters that are part of a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// tokenize splits a text in lower-cased words
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !isWordRune(r)
+	})
+}
+
+// add indexes the words of the todo task
+func (idx *searchIndex) add(t *Todo) {
+	words := tokenize(t.Task)
+	idx.lengths[t.Id] = len(words)
+	for _, word := range words {
+		if idx.postings[word] == nil {
+			idx.postings[word] = make(map[int32]int)
+		}
+		idx.postings[word][t.Id]++
+	}
+}
+
+// remove deletes the words of the todo task from the index
+func (idx *searchIndex) remove(t *Todo) {
+	delete(idx.lengths, t.Id)
+	for _, word := range tokenize(t.Task) {
+		delete(idx.postings[word], t.Id)
+		if len(idx.postings[word]) == 0 {
+			delete(idx.postings, word)
+		}
+	}
+}
+
+// search returns the rank of every todo id containing all the words of the query
+func (idx *searchIndex) search(query string) map[int32]float32 {
+	ranks := make(map[int32]float32)
+	words := tokenize(query)
+	for i, word := range words {
+		postings := idx.postings[word]
+		if i == 0 {
+			for id := range postings {
+				ranks[id] = 0
+			}
+		}
+		for id := range ranks {
+			occurrences, found := postings[id]
+			if !found {
+				delete(ranks, id)
+				continue
+			}
+			ranks[id] += float32(occurrences) / float32(idx.lengths[id])
+		}
+	}
+	return ranks
+}
+
+// headline returns the task with the words of the query surrounded by <b> and </b> like ts_headline does
+func headline(task string, query string) string {
+	words := make(map[string]bool)
+	for _, word := range tokenize(query) {
+		words[word] = true
+	}
+	var sb strings.Builder
+	runes := []rune(task)
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			sb.WriteRune(runes[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && isWordRune(runes[j]) {
+			j++
+		}
+		word := string(runes[i:j])
+		if words[strings.ToLower(word)] {
+			sb.WriteString(headlineStartSel + word + headlineStopSel)
+		} else {
+			sb.WriteString(word)
+		}
+		i = j
+	}
+	return sb.String()
+}
+
+// sortSearchResults orders the results by decreasing rank, then by id
+func sortSearchResults(res []*TodoSearchResult) {
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Rank != res[j].Rank {
+			return res[i].Rank > res[j].Rank
+		}
+		return res[i].Todo.Id < res[j].Todo.Id
+	})
+}
@@ -1,7 +1,10 @@
 package todos
 
 import (
-	"errors"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
 	"sort"
 	"sync"
 	"time"
@@ -12,72 +15,496 @@ const DefaultMaxId = 2
 type memoryStore struct {
 	Todos map[int32]*Todo
 	maxId int32
-	lock  sync.RWMutex
+	index *searchIndex
+	// events contains the events of all the changes, in the order they occurred
+	events []*TodoEvent
+	// users contains the users by id, maxUserId is the greatest id given to a user
+	users     map[int32]*User
+	maxUserId int32
+	// apiKeys contains the API keys by id, maxApiKeyId is the greatest id given to an API key
+	apiKeys     map[int32]*memoryApiKey
+	maxApiKeyId int32
+	// lists contains the lists of todos by id without their counts, maxListId is the greatest id given to a list
+	lists     map[int32]*TodoList
+	maxListId int32
+	// dependencies contains the ids of the blockers of the todos in ascending order, by id of the todo
+	dependencies map[int32][]int32
+	// dependenciesModified is the time of the last change of the dependencies, or of the start of the store
+	// because it is not saved with them
+	dependenciesModified time.Time
+	// reminded contains the reminder time of the last reminder sent, by id of the todo
+	reminded map[int32]time.Time
+	// wal is nil when the store is not durable
+	wal  *memoryWal
+	log  *log.Logger
+	lock sync.RWMutex
 }
 
-//Create will store the new task in the store
-func (m *memoryStore) Create(todo NewTodo) (*Todo, error) {
-	if len(todo.Task) < 1 {
-		return nil, errors.New("todo task cannot be empty")
+// putTodo stores the todo in the map and in the search index, it must be called with the write lock held
+func (m *memoryStore) putTodo(t *Todo) {
+	if t.Version < 1 {
+		// todos restored from a snapshot or a log written before the version field existed
+		t.Version = 1
+	}
+	if existingTodo, exist := m.Todos[t.Id]; exist {
+		m.index.remove(existingTodo)
+	}
+	m.Todos[t.Id] = t
+	// the todos in the trash cannot be found by Search
+	if t.DeletedAt == nil {
+		m.index.add(t)
+	}
+	if t.Id > m.maxId {
+		m.maxId = t.Id
+	}
+}
+
+// removeTodo removes the todo from the map, from the search index, from the dependencies and from the reminders sent,
+// it must be called with the write lock held
+func (m *memoryStore) removeTodo(id int32) {
+	if existingTodo, exist := m.Todos[id]; exist {
+		m.index.remove(existingTodo)
+		delete(m.Todos, id)
+	}
+	delete(m.dependencies, id)
+	delete(m.reminded, id)
+	for todoId, blockers := range m.dependencies {
+		if containsId(blockers, id) {
+			m.putDependencies(todoId, removeId(blockers, id))
+		}
+	}
+}
+
+// putDependencies stores the blockers of the todo, an empty slice removes its dependencies.
+// it must be called with the write lock held
+func (m *memoryStore) putDependencies(id int32, blockers []int32) {
+	m.dependenciesModified = time.Now()
+	if len(blockers) == 0 {
+		delete(m.dependencies, id)
+		return
+	}
+	m.dependencies[id] = blockers
+}
+
+// isReady returns true only if the live blockers of the todo are all completed, it must be called with the lock held
+func (m *memoryStore) isReady(t *Todo) bool {
+	for _, blockerId := range m.dependencies[t.Id] {
+		if blocker, exist := m.Todos[blockerId]; exist && blocker.DeletedAt == nil && !blocker.Completed {
+			return false
+		}
+	}
+	return true
+}
+
+// putUser stores the user in the map, it must be called with the write lock held
+func (m *memoryStore) putUser(u *User) {
+	if u.Role == "" {
+		// the users saved before the roles existed were only administrators or not
+		u.Role = UserRoleEditor
+		if u.IsAdmin {
+			u.Role = UserRoleAdmin
+		}
+	}
+	m.users[u.Id] = u
+	if u.Id > m.maxUserId {
+		m.maxUserId = u.Id
 	}
-	if len(todo.Task) < 6 {
-		return nil, errors.New("CreateTodo task minLength is 5")
+}
+
+// memoryApiKey is an API key of the memory store with the hash of its key
+type memoryApiKey struct {
+	ApiKey
+	Hash string `json:"hash"`
+}
+
+// putApiKey stores the API key in the map, it must be called with the write lock held
+func (m *memoryStore) putApiKey(k *memoryApiKey) {
+	m.apiKeys[k.Id] = k
+	if k.Id > m.maxApiKeyId {
+		m.maxApiKeyId = k.Id
+	}
+}
+
+// putList stores the list in the map, it must be called with the write lock held
+func (m *memoryStore) putList(l *TodoList) {
+	m.lists[l.Id] = l
+	if l.Id > m.maxListId {
+		m.maxListId = l.Id
+	}
+}
+
+// lastEventId returns the id of the last event recorded, it must be called with the lock held
+func (m *memoryStore) lastEventId() int64 {
+	if len(m.events) == 0 {
+		return 0
+	}
+	return m.events[len(m.events)-1].Id
+}
+
+// persist appends the change to the write-ahead log of a durable store, and compacts the log when needed.
+// it must be called with the write lock held, before applying the change in memory.
+func (m *memoryStore) persist(op, kind string, id int32, value interface{}) error {
+	if m.wal == nil {
+		return nil
+	}
+	if err := m.wal.append(op, kind, id, value); err != nil {
+		return GetErrorF("error : change could not be written to the write-ahead log", err)
+	}
+	return nil
+}
+
+// compact writes a snapshot of a durable store when its write-ahead log is big enough,
+// it must be called with the write lock held, after applying the change in memory.
+func (m *memoryStore) compact(force bool) {
+	if m.wal == nil || !(force || m.wal.needsSnapshot()) {
+		return
+	}
+	if err := m.wal.snapshot(&memorySnapshot{MaxId: m.maxId, Todos: m.Todos, Events: m.events, Users: m.sortedUsers(), ApiKeys: m.sortedApiKeys(),
+		Lists: m.sortedLists(0), Dependencies: m.dependencies, Reminded: m.reminded}); err != nil {
+		// the write-ahead log still contains all the changes, so no data is lost
+		m.log.Printf("error : memory store snapshot failed, error : %v", err)
+	}
+}
+
+// replay applies a record of the write-ahead log to the store
+func (m *memoryStore) replay(record walRecord) error {
+	if record.Op == walOpBatch {
+		var records []walRecord
+		if err := json.Unmarshal(record.Data, &records); err != nil {
+			return err
+		}
+		for _, r := range records {
+			if err := m.replay(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	switch record.Kind {
+	case walKindTodo:
+		switch record.Op {
+		case walOpPut:
+			t := &Todo{}
+			if err := json.Unmarshal(record.Data, t); err != nil {
+				return err
+			}
+			m.putTodo(t)
+		case walOpDelete:
+			m.removeTodo(record.Id)
+		default:
+			return fmt.Errorf("unknown operation %s", record.Op)
+		}
+	case walKindEvent:
+		if record.Op != walOpPut {
+			return fmt.Errorf("unknown operation %s", record.Op)
+		}
+		event := &TodoEvent{}
+		if err := json.Unmarshal(record.Data, event); err != nil {
+			return err
+		}
+		// the log is replayed a second time over the snapshot when the process stopped before emptying it,
+		// the other records only put the same values again but the events must not be appended twice
+		if event.Id <= m.lastEventId() {
+			return nil
+		}
+		m.events = append(m.events, event)
+	case walKindUser:
+		if record.Op != walOpPut {
+			return fmt.Errorf("unknown operation %s", record.Op)
+		}
+		u := &User{}
+		if err := json.Unmarshal(record.Data, u); err != nil {
+			return err
+		}
+		m.putUser(u)
+	case walKindApiKey:
+		switch record.Op {
+		case walOpPut:
+			k := &memoryApiKey{}
+			if err := json.Unmarshal(record.Data, k); err != nil {
+				return err
+			}
+			m.putApiKey(k)
+		case walOpDelete:
+			delete(m.apiKeys, record.Id)
+		default:
+			return fmt.Errorf("unknown operation %s", record.Op)
+		}
+	case walKindList:
+		switch record.Op {
+		case walOpPut:
+			l := &TodoList{}
+			if err := json.Unmarshal(record.Data, l); err != nil {
+				return err
+			}
+			m.putList(l)
+		case walOpDelete:
+			delete(m.lists, record.Id)
+		default:
+			return fmt.Errorf("unknown operation %s", record.Op)
+		}
+	case walKindDependency:
+		if record.Op != walOpPut {
+			return fmt.Errorf("unknown operation %s", record.Op)
+		}
+		var blockers []int32
+		if err := json.Unmarshal(record.Data, &blockers); err != nil {
+			return err
+		}
+		m.putDependencies(record.Id, blockers)
+	case walKindReminder:
+		switch record.Op {
+		case walOpPut:
+			var remindedAt time.Time
+			if err := json.Unmarshal(record.Data, &remindedAt); err != nil {
+				return err
+			}
+			m.reminded[record.Id] = remindedAt
+		case walOpDelete:
+			delete(m.reminded, record.Id)
+		default:
+			return fmt.Errorf("unknown operation %s", record.Op)
+		}
+	default:
+		return fmt.Errorf("unknown kind %s", record.Kind)
+	}
+	return nil
+}
+
+//Create will store the new task in the store
+func (m *memoryStore) Create(ctx context.Context, todo NewTodo) (*Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 	m.lock.Lock()
 	defer m.lock.Unlock()
-	now := time.Now()
-	m.maxId++
-	t := &Todo{
-		Completed:   false,
-		CompletedAt: nil,
-		CreatedAt:   &now,
-		Id:          m.maxId,
-		Task:        todo.Task,
+	tx := m.begin(ctx)
+	t, err := tx.create(ctx, todo, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.commit(); err != nil {
+		return nil, err
 	}
-	m.Todos[t.Id] = t
 	return t, nil
 }
 
-func (m *memoryStore) List(offset, limit int) ([]*Todo, error) {
+// matchFilter returns true only if the todo satisfies all the criteria of the filter
+func matchFilter(filter TodoFilter, t *Todo) bool {
+	if (t.DeletedAt != nil) != filter.Trashed {
+		return false
+	}
+	if filter.Ids != nil && !containsId(filter.Ids, t.Id) {
+		return false
+	}
+	if filter.ListId != nil && (t.ListId == nil || *t.ListId != *filter.ListId) {
+		return false
+	}
+	if filter.SeriesId != nil && (t.SeriesId == nil || *t.SeriesId != *filter.SeriesId) {
+		return false
+	}
+	if filter.ParentId != nil && (t.ParentId == nil || *t.ParentId != *filter.ParentId) {
+		return false
+	}
+	if filter.Roots && t.ParentId != nil {
+		return false
+	}
+	if len(filter.Tags) > 0 && !hasTags(t, filter.Tags, filter.AllTags) {
+		return false
+	}
+	if filter.Completed != nil && t.Completed != *filter.Completed {
+		return false
+	}
+	if filter.CreatedAfter != nil && (t.CreatedAt == nil || t.CreatedAt.Before(*filter.CreatedAfter)) {
+		return false
+	}
+	if filter.CreatedBefore != nil && (t.CreatedAt == nil || !t.CreatedAt.Before(*filter.CreatedBefore)) {
+		return false
+	}
+	if filter.CompletedAfter != nil && (t.CompletedAt == nil || t.CompletedAt.Before(*filter.CompletedAfter)) {
+		return false
+	}
+	if filter.CompletedBefore != nil && (t.CompletedAt == nil || !t.CompletedAt.Before(*filter.CompletedBefore)) {
+		return false
+	}
+	if filter.DueAfter != nil && (t.DueAt == nil || t.DueAt.Before(*filter.DueAfter)) {
+		return false
+	}
+	if filter.DueBefore != nil && (t.DueAt == nil || !t.DueAt.Before(*filter.DueBefore)) {
+		return false
+	}
+	return true
+}
+
+// containsId returns true only if id is one of ids
+func containsId(ids []int32, id int32) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// removeId returns a copy of ids without id
+func removeId(ids []int32, id int32) []int32 {
+	res := make([]int32, 0, len(ids))
+	for _, i := range ids {
+		if i != id {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+// compareTime orders two optional times, nil values are always placed last
+func compareTime(a, b *time.Time, descending bool) (less bool, equal bool) {
+	switch {
+	case a == nil && b == nil:
+		return false, true
+	case a == nil:
+		return false, false
+	case b == nil:
+		return true, false
+	case a.Equal(*b):
+		return false, true
+	case descending:
+		return a.After(*b), false
+	default:
+		return a.Before(*b), false
+	}
+}
+
+// comparePriority orders two optional priorities, nil values are always placed last
+func comparePriority(a, b *int32, descending bool) (less bool, equal bool) {
+	switch {
+	case a == nil && b == nil:
+		return false, true
+	case a == nil:
+		return false, false
+	case b == nil:
+		return true, false
+	case *a == *b:
+		return false, true
+	default:
+		return (*a < *b) != descending, false
+	}
+}
+
+// sortTodos sorts the todos in place on the given field, like the ORDER BY field, id of the sql stores
+func sortTodos(res []*Todo, field string, descending bool) {
+	sort.SliceStable(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		less, equal := false, true
+		switch field {
+		case "task":
+			if a.Task != b.Task {
+				less, equal = (a.Task < b.Task) != descending, false
+			}
+		case "created_at":
+			less, equal = compareTime(a.CreatedAt, b.CreatedAt, descending)
+		case "completed_at":
+			less, equal = compareTime(a.CompletedAt, b.CompletedAt, descending)
+		case "due_at":
+			less, equal = compareTime(a.DueAt, b.DueAt, descending)
+			if equal {
+				less, equal = comparePriority(a.Priority, b.Priority, false)
+			}
+		case "priority":
+			less, equal = comparePriority(a.Priority, b.Priority, descending)
+		}
+		if equal {
+			return (a.Id < b.Id) != descending
+		}
+		return less
+	})
+}
+
+// List returns the todos matching params.Filter sorted on params.Sort,
+// skipping params.Offset todos or the ones up to params.AfterId
+func (m *memoryStore) List(ctx context.Context, params ListParams) ([]*Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	field, descending, err := ParseSort(params.Sort)
+	if err != nil {
+		return nil, err
+	}
 	m.lock.RLock()
 	defer m.lock.RUnlock()
-	var res []*Todo
-	if offset > 0 && offset < len(m.Todos) {
-		// handle offset
-	} else {
-		if limit > len(m.Todos) {
-			//return all
-			keys := make([]int, 0, len(m.Todos))
-			for k := range m.Todos {
-				keys = append(keys, int(k))
-			}
-			sort.Ints(keys)
+	ownerId := getOwnerId(ctx)
+	matching := make([]*Todo, 0, len(m.Todos))
+	for _, t := range m.Todos {
+		if t.Id > params.AfterId && isOwnedBy(t, ownerId) && matchFilter(params.Filter, t) && (!params.Filter.Ready || m.isReady(t)) {
+			matching = append(matching, t)
+		}
+	}
+	sortTodos(matching, field, descending)
+	res := make([]*Todo, 0)
+	if params.AfterId < 1 {
+		if params.Offset >= len(matching) {
+			return res, nil
+		}
+		matching = matching[params.Offset:]
+	}
+	for _, t := range matching {
+		if len(res) >= params.Limit {
+			break
+		}
+		res = append(res, t)
+	}
+	return res, nil
+}
 
-			for _, k := range keys {
-				res = append(res, m.Todos[int32(k)])
-			}
+// Search returns at most limit todos whose task contains all the words of query, the most relevant first
+func (m *memoryStore) Search(ctx context.Context, query string, limit int) ([]*TodoSearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	ownerId := getOwnerId(ctx)
+	res := make([]*TodoSearchResult, 0)
+	for id, rank := range m.index.search(query) {
+		if !isOwnedBy(m.Todos[id], ownerId) {
+			continue
 		}
+		res = append(res, &TodoSearchResult{
+			Headline: headline(m.Todos[id].Task, query),
+			Rank:     rank,
+			Todo:     *m.Todos[id],
+		})
+	}
+	sortSearchResults(res)
+	if len(res) > limit {
+		res = res[:limit]
 	}
 	return res, nil
 }
 
-func (m *memoryStore) Get(id int32) (*Todo, error) {
-	if m.Exist(id) {
-		m.lock.RLock()
-		defer m.lock.RUnlock()
-		existingTodo := m.Todos[id]
+func (m *memoryStore) Get(ctx context.Context, id int32) (*Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if existingTodo, exist := m.Todos[id]; exist && isOwnedBy(existingTodo, getOwnerId(ctx)) {
 		return existingTodo, nil
 	}
-	return nil, errors.New("todo with this id does not exist")
+	return nil, ErrNotFound
 }
 
 // GetMaxId returns the maximum value of todos id existing in store.
-func (m *memoryStore) GetMaxId() (int32, error) {
+func (m *memoryStore) GetMaxId(ctx context.Context) (int32, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 	m.lock.RLock()
 	defer m.lock.RUnlock()
+	ownerId := getOwnerId(ctx)
 	existingMaxId := int32(0)
 	for _, t := range m.Todos {
-		if t.Id > existingMaxId {
+		if t.Id > existingMaxId && isOwnedBy(t, ownerId) {
 			existingMaxId = t.Id
 		}
 	}
@@ -85,70 +512,895 @@ func (m *memoryStore) GetMaxId() (int32, error) {
 }
 
 // Exist returns true only if a todos with the specified id exists in store.
-func (m *memoryStore) Exist(id int32) bool {
+func (m *memoryStore) Exist(ctx context.Context, id int32) bool {
+	if ctx.Err() != nil {
+		return false
+	}
 	m.lock.RLock()
 	defer m.lock.RUnlock()
-	if m.Todos[id] == nil {
+	if m.Todos[id] == nil || m.Todos[id].DeletedAt != nil || !isOwnedBy(m.Todos[id], getOwnerId(ctx)) {
 		return false
 	}
 	return true
 }
 
-func (m *memoryStore) Count() (int32, error) {
-	return int32(len(m.Todos)), nil
-}
-
-func (m *memoryStore) Update(id int32, todo Todo) (*Todo, error) {
-	if m.Exist(id) {
-		m.lock.Lock()
-		defer m.lock.Unlock()
-		existingTodo := m.Todos[id]
-		now := time.Now()
-		// cannot override id field
-		todo.Id = existingTodo.Id
-		// cannot override CreatedAt field
-		todo.CreatedAt = existingTodo.CreatedAt
-		switch todo.Completed {
-		case true:
-			if existingTodo.Completed == false {
-				todo.CompletedAt = &now
+// Count returns the number of todos matching the filter
+func (m *memoryStore) Count(ctx context.Context, filter TodoFilter) (int32, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	ownerId := getOwnerId(ctx)
+	var count int32 = 0
+	for _, t := range m.Todos {
+		if isOwnedBy(t, ownerId) && matchFilter(filter, t) && (!filter.Ready || m.isReady(t)) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Fingerprint returns the greatest id, the number of todos, the time of the most recent change, the number of overdue todos
+// and the number of dependencies with the time of their last change
+func (m *memoryStore) Fingerprint(ctx context.Context, now time.Time) (*Fingerprint, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	ownerId := getOwnerId(ctx)
+	res := &Fingerprint{}
+	for _, t := range m.Todos {
+		if !isOwnedBy(t, ownerId) {
+			continue
+		}
+		res.Count++
+		if t.Id > res.MaxId {
+			res.MaxId = t.Id
+		}
+		if lastModified := todoLastModified(t); lastModified != nil {
+			if res.LastModified == nil || lastModified.After(*res.LastModified) {
+				res.LastModified = lastModified
 			}
-		case false:
-			if existingTodo.Completed == true {
-				// task was completed, but user changed it to not completed
-				todo.CompletedAt = nil
+		}
+		if isOverdue(t, now) {
+			res.Overdue++
+		}
+		res.Dependencies += int32(len(m.dependencies[t.Id]))
+	}
+	dependenciesModified := m.dependenciesModified
+	res.DependenciesModified = &dependenciesModified
+	return res, nil
+}
+
+func (m *memoryStore) Update(ctx context.Context, id int32, todo Todo, matchVersion int32) (*Todo, error) {
+	return m.Patch(ctx, id, TodoPatch{Task: &todo.Task, Completed: &todo.Completed, ListId: getUpdateListId(todo),
+		Tags: getUpdateTags(todo), DueAt: getUpdateDueAt(todo), Priority: getUpdatePriority(todo),
+		Recurrence: getUpdateRecurrence(todo), ParentId: getUpdateParentId(todo), AutoComplete: getUpdateAutoComplete(todo),
+		RemindAt: getUpdateRemindAt(todo)}, matchVersion)
+}
+
+func (m *memoryStore) Patch(ctx context.Context, id int32, patch TodoPatch, matchVersion int32) (*Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	tx := m.begin(ctx)
+	t, err := tx.patch(ctx, id, patch, matchVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.commit(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (m *memoryStore) Delete(ctx context.Context, id int32, matchVersion int32, cascade bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	tx := m.begin(ctx)
+	if err := deleteTodo(ctx, tx, id, matchVersion, cascade); err != nil {
+		return err
+	}
+	return tx.commit()
+}
+
+func (m *memoryStore) Restore(ctx context.Context, id int32, matchVersion int32) (*Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	tx := m.begin(ctx)
+	t, err := restoreTodo(ctx, tx, id, matchVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.commit(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (m *memoryStore) Purge(ctx context.Context, id int32, matchVersion int32, cascade bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	tx := m.begin(ctx)
+	if err := purgeTodo(ctx, tx, id, matchVersion, cascade); err != nil {
+		return err
+	}
+	return tx.commit()
+}
+
+// PurgeTrash removes the todos trashed before the given time in a single transaction
+func (m *memoryStore) PurgeTrash(ctx context.Context, trashedBefore time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	ownerId := getOwnerId(ctx)
+	var expiredIds []int32
+	for id, t := range m.Todos {
+		if t.DeletedAt != nil && t.DeletedAt.Before(trashedBefore) && isOwnedBy(t, ownerId) {
+			expiredIds = append(expiredIds, id)
+		}
+	}
+	// the todos are purged in the order of their id, like in the sql stores
+	sort.Slice(expiredIds, func(i, j int) bool { return expiredIds[i] < expiredIds[j] })
+	tx := m.begin(ctx)
+	for _, id := range expiredIds {
+		if err := purgeTodo(ctx, tx, id, 0, false); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.commit(); err != nil {
+		return 0, err
+	}
+	return len(expiredIds), nil
+}
+
+// History returns the events of the todo with given id in the order they occurred
+func (m *memoryStore) History(ctx context.Context, id int32) ([]*TodoEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	ownerId := getOwnerId(ctx)
+	res := make([]*TodoEvent, 0)
+	for _, event := range m.events {
+		if event.TodoId == id && (ownerId == 0 || event.OwnerId == ownerId) {
+			res = append(res, event)
+		}
+	}
+	return res, nil
+}
+
+// Events returns at most limit events that occurred at or after since, in the order they occurred
+func (m *memoryStore) Events(ctx context.Context, since time.Time, limit int) ([]*TodoEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	// the events are appended in the order they occurred, so the first one at or after since can be found by a binary search
+	first := sort.Search(len(m.events), func(i int) bool { return !m.events[i].OccurredAt.Before(since) })
+	ownerId := getOwnerId(ctx)
+	res := make([]*TodoEvent, 0)
+	for _, event := range m.events[first:] {
+		if len(res) >= limit {
+			break
+		}
+		if ownerId == 0 || event.OwnerId == ownerId {
+			res = append(res, event)
+		}
+	}
+	return res, nil
+}
+
+// sortedUsers returns the users in the order of their id, it must be called with the lock held
+func (m *memoryStore) sortedUsers() []*User {
+	res := make([]*User, 0, len(m.users))
+	for _, u := range m.users {
+		res = append(res, u)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Id < res[j].Id })
+	return res
+}
+
+// createUser saves a new user, it must be called with the write lock held
+func (m *memoryStore) createUser(user NewUser) (*User, error) {
+	if err := validateNewUser(user); err != nil {
+		return nil, err
+	}
+	for _, u := range m.users {
+		if u.Login == user.Login {
+			return nil, fmt.Errorf("%w : login %s is already used", ErrConflict, user.Login)
+		}
+	}
+	now := time.Now()
+	u := &User{
+		CreatedAt: &now,
+		Id:        m.maxUserId + 1,
+		IsAdmin:   getNewUserRole(user) == UserRoleAdmin,
+		Login:     user.Login,
+		Name:      user.Name,
+		Role:      getNewUserRole(user),
+	}
+	if err := m.persist(walOpPut, walKindUser, u.Id, u); err != nil {
+		return nil, err
+	}
+	m.putUser(u)
+	m.compact(false)
+	return u, nil
+}
+
+// CreateUser saves a new user, the logins are unique
+func (m *memoryStore) CreateUser(ctx context.Context, user NewUser) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.createUser(user)
+}
+
+// GetUser returns the user with the given login
+func (m *memoryStore) GetUser(ctx context.Context, login string) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	for _, u := range m.users {
+		if u.Login == login {
+			return u, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+// ListUsers returns all the users in the order of their id
+func (m *memoryStore) ListUsers(ctx context.Context) ([]*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.sortedUsers(), nil
+}
+
+// sortedApiKeys returns the API keys in the order of their id, it must be called with the lock held
+func (m *memoryStore) sortedApiKeys() []*memoryApiKey {
+	res := make([]*memoryApiKey, 0, len(m.apiKeys))
+	for _, k := range m.apiKeys {
+		res = append(res, k)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Id < res[j].Id })
+	return res
+}
+
+// CreateApiKey saves a new API key with the hash of its key
+func (m *memoryStore) CreateApiKey(ctx context.Context, apiKey ApiKey, hash string) (*ApiKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	userExist := false
+	for _, u := range m.users {
+		if u.Login == apiKey.Login {
+			userExist = true
+			break
+		}
+	}
+	if !userExist {
+		return nil, ErrUserNotFound
+	}
+	now := time.Now()
+	k := &memoryApiKey{ApiKey: apiKey, Hash: hash}
+	k.Id = m.maxApiKeyId + 1
+	k.CreatedAt = &now
+	k.LastUsedAt = nil
+	if err := m.persist(walOpPut, walKindApiKey, k.Id, k); err != nil {
+		return nil, err
+	}
+	m.putApiKey(k)
+	m.compact(false)
+	res := k.ApiKey
+	return &res, nil
+}
+
+// GetApiKey returns the API key whose key has the given hash
+func (m *memoryStore) GetApiKey(ctx context.Context, hash string) (*ApiKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	for _, k := range m.apiKeys {
+		if k.Hash == hash {
+			res := k.ApiKey
+			return &res, nil
+		}
+	}
+	return nil, ErrApiKeyNotFound
+}
+
+// ListApiKeys returns all the API keys in the order of their id
+func (m *memoryStore) ListApiKeys(ctx context.Context) ([]*ApiKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	res := make([]*ApiKey, 0, len(m.apiKeys))
+	for _, k := range m.sortedApiKeys() {
+		apiKey := k.ApiKey
+		res = append(res, &apiKey)
+	}
+	return res, nil
+}
+
+// RevokeApiKey removes the API key with given ID
+func (m *memoryStore) RevokeApiKey(ctx context.Context, id int32) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, exist := m.apiKeys[id]; !exist {
+		return ErrApiKeyNotFound
+	}
+	if err := m.persist(walOpDelete, walKindApiKey, id, nil); err != nil {
+		return err
+	}
+	delete(m.apiKeys, id)
+	m.compact(false)
+	return nil
+}
+
+// TouchApiKey records the time of the last use of the API key with given ID
+func (m *memoryStore) TouchApiKey(ctx context.Context, id int32, usedAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	existing, exist := m.apiKeys[id]
+	if !exist {
+		return ErrApiKeyNotFound
+	}
+	// the stored API keys are never modified, since GetApiKey returns copies sharing their fields
+	k := *existing
+	k.LastUsedAt = &usedAt
+	if err := m.persist(walOpPut, walKindApiKey, k.Id, &k); err != nil {
+		return err
+	}
+	m.putApiKey(&k)
+	m.compact(false)
+	return nil
+}
+
+// sortedLists returns the lists of ownerId, or of all the users when it is 0, in the order of their id
+// and with the number of their todos, it must be called with the lock held
+func (m *memoryStore) sortedLists(ownerId int32) []*TodoList {
+	res := make([]*TodoList, 0)
+	for _, l := range m.lists {
+		if ownerId == 0 || l.OwnerId == ownerId {
+			res = append(res, l)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Id < res[j].Id })
+	return res
+}
+
+// countTodos returns a copy of the list with the number of its todos and of its completed todos,
+// it must be called with the lock held
+func (m *memoryStore) countTodos(l *TodoList) *TodoList {
+	res := *l
+	res.TodosCount, res.CompletedCount = 0, 0
+	for _, t := range m.Todos {
+		if t.ListId != nil && *t.ListId == l.Id && t.DeletedAt == nil {
+			res.TodosCount++
+			if t.Completed {
+				res.CompletedCount++
 			}
-		// in all other cases the value of CompletedAt should not be changed
-		default:
-			todo.CompletedAt = existingTodo.CompletedAt
 		}
+	}
+	return &res
+}
 
-		m.Todos[id] = &todo
-		return &todo, nil
+// getList returns the list with given id if it is accessible with ctx, it must be called with the lock held
+func (m *memoryStore) getList(ctx context.Context, id int32) (*TodoList, error) {
+	ownerId := getOwnerId(ctx)
+	l, exist := m.lists[id]
+	if !exist || (ownerId != 0 && l.OwnerId != ownerId) {
+		return nil, ErrListNotFound
 	}
-	return nil, errors.New("todo with this id does not exist")
+	return l, nil
 }
 
-func (m *memoryStore) Delete(id int32) error {
-	if m.Exist(id) {
-		m.lock.Lock()
-		defer m.lock.Unlock()
-		delete(m.Todos, id)
+// checkListName returns ErrConflict if the owner already has another list with the name, it must be called with the lock held
+func (m *memoryStore) checkListName(ownerId, id int32, name string) error {
+	for _, l := range m.lists {
+		if l.OwnerId == ownerId && l.Name == name && l.Id != id {
+			return fmt.Errorf("%w : list %s already exists", ErrConflict, name)
+		}
+	}
+	return nil
+}
+
+// ListTodoLists returns the lists in the order of their id, with the number of their todos
+func (m *memoryStore) ListTodoLists(ctx context.Context) ([]*TodoList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	res := make([]*TodoList, 0)
+	for _, l := range m.sortedLists(getOwnerId(ctx)) {
+		res = append(res, m.countTodos(l))
+	}
+	return res, nil
+}
+
+// GetTodoList returns the list with given ID and the number of its todos
+func (m *memoryStore) GetTodoList(ctx context.Context, id int32) (*TodoList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	l, err := m.getList(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return m.countTodos(l), nil
+}
+
+// CreateTodoList saves a new list owned by the user of the context, the names of the lists of a user are unique
+func (m *memoryStore) CreateTodoList(ctx context.Context, list NewTodoList) (*TodoList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := validateNewTodoList(list); err != nil {
+		return nil, err
+	}
+	owner := UserFromContext(ctx)
+	if owner == nil {
+		return nil, errNoOwner
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if err := m.checkListName(owner.Id, 0, list.Name); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	l := &TodoList{CreatedAt: &now, Id: m.maxListId + 1, Name: list.Name, OwnerId: owner.Id, UpdatedAt: &now}
+	if err := m.persist(walOpPut, walKindList, l.Id, l); err != nil {
+		return nil, err
+	}
+	m.putList(l)
+	m.compact(false)
+	return m.countTodos(l), nil
+}
+
+// UpdateTodoList renames the list with given ID
+func (m *memoryStore) UpdateTodoList(ctx context.Context, id int32, list NewTodoList) (*TodoList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := validateNewTodoList(list); err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	existing, err := m.getList(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkListName(existing.OwnerId, id, list.Name); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	// the stored lists are never modified, since they are shared with the snapshots
+	l := *existing
+	l.Name = list.Name
+	l.UpdatedAt = &now
+	if err := m.persist(walOpPut, walKindList, l.Id, &l); err != nil {
+		return nil, err
+	}
+	m.putList(&l)
+	m.compact(false)
+	return m.countTodos(&l), nil
+}
+
+// DeleteTodoList purges the todos of the list with given ID and removes it in a single transaction
+func (m *memoryStore) DeleteTodoList(ctx context.Context, id int32) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, err := m.getList(ctx, id); err != nil {
+		return err
+	}
+	var todoIds []int32
+	for todoId, t := range m.Todos {
+		if t.ListId != nil && *t.ListId == id {
+			todoIds = append(todoIds, todoId)
+		}
+	}
+	// the todos are purged in the order of their id, like in the sql stores
+	sort.Slice(todoIds, func(i, j int) bool { return todoIds[i] < todoIds[j] })
+	tx := m.begin(ctx)
+	for _, todoId := range todoIds {
+		if err := purgeTodo(ctx, tx, todoId, 0, false); err != nil {
+			return err
+		}
+	}
+	tx.deletedLists = append(tx.deletedLists, id)
+	return tx.commit()
+}
+
+// countTags returns the tags of the live todos of ownerId, or of all the users when it is 0,
+// with the number of live todos having them, it must be called with the lock held
+func (m *memoryStore) countTags(ownerId int32) map[string]int32 {
+	counts := make(map[string]int32)
+	for _, t := range m.Todos {
+		if t.DeletedAt == nil && isOwnedBy(t, ownerId) {
+			for _, tag := range getTodoTags(t) {
+				counts[tag]++
+			}
+		}
+	}
+	return counts
+}
+
+// ListTags returns the tags of the live todos in alphabetical order, with the number of live todos having them
+func (m *memoryStore) ListTags(ctx context.Context) ([]*Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	res := make([]*Tag, 0)
+	for name, count := range m.countTags(getOwnerId(ctx)) {
+		res = append(res, &Tag{Name: name, TodosCount: count})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+// RenameTag replaces the tag name by newName in all the todos having it in a single transaction
+func (m *memoryStore) RenameTag(ctx context.Context, name, newName string) (*Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	from := tagName(name)
+	to, err := normalizeTag(newName)
+	if err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	tx := m.begin(ctx)
+	var todoIds []int32
+	for todoId, t := range m.Todos {
+		if isOwnedBy(t, tx.ownerId) && hasTags(t, []string{from}, true) {
+			todoIds = append(todoIds, todoId)
+		}
+	}
+	if len(todoIds) == 0 {
+		return nil, ErrTagNotFound
+	}
+	if from != to {
+		// the todos are changed in the order of their id, like in the sql stores
+		sort.Slice(todoIds, func(i, j int) bool { return todoIds[i] < todoIds[j] })
+		for _, todoId := range todoIds {
+			if err := tx.retag(ctx, todoId, from, to); err != nil {
+				return nil, err
+			}
+		}
+		if err := tx.commit(); err != nil {
+			return nil, err
+		}
+	}
+	return &Tag{Name: to, TodosCount: m.countTags(tx.ownerId)[to]}, nil
+}
+
+// Batch applies all the operations while holding the write lock, they are staged and only applied when all succeed
+func (m *memoryStore) UpdateSeries(ctx context.Context, id int32, recurrence string) ([]*Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	tx := m.begin(ctx)
+	t, err := tx.getInState(id, liveTodo, 0)
+	if err != nil {
+		return nil, err
+	}
+	res, err := updateSeries(ctx, tx, t, recurrence)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.commit(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// CountChildren returns the numbers of live children of the todos with given ids, counted in a single pass on the todos
+func (m *memoryStore) CountChildren(ctx context.Context, ids []int32) (map[int32]ChildrenStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	ownerId := getOwnerId(ctx)
+	parents := make(map[int32]bool, len(ids))
+	for _, id := range ids {
+		parents[id] = true
+	}
+	res := make(map[int32]ChildrenStats)
+	for _, t := range m.Todos {
+		if t.ParentId == nil || t.DeletedAt != nil || !isOwnedBy(t, ownerId) || !parents[*t.ParentId] {
+			continue
+		}
+		stats := res[*t.ParentId]
+		stats.Count++
+		if t.Completed {
+			stats.Completed++
+		}
+		res[*t.ParentId] = stats
+	}
+	return res, nil
+}
+
+// Descendants returns the live descendants of the todos with given ids, found level by level in the order of their id
+func (m *memoryStore) Descendants(ctx context.Context, ids []int32) ([]*Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	ownerId := getOwnerId(ctx)
+	children := make(map[int32][]*Todo)
+	for _, t := range m.Todos {
+		if t.ParentId != nil && t.DeletedAt == nil && isOwnedBy(t, ownerId) {
+			children[*t.ParentId] = append(children[*t.ParentId], t)
+		}
+	}
+	res := make([]*Todo, 0)
+	for level := ids; len(level) > 0; {
+		var next []int32
+		for _, id := range level {
+			for _, child := range children[id] {
+				res = append(res, child)
+				next = append(next, child.Id)
+			}
+		}
+		level = next
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Id < res[j].Id })
+	return res, nil
+}
+
+// OpenBlockers returns the ids of the open blockers of the todos with given ids
+func (m *memoryStore) OpenBlockers(ctx context.Context, ids []int32) (map[int32][]int32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	ownerId := getOwnerId(ctx)
+	res := make(map[int32][]int32)
+	for _, id := range ids {
+		for _, blockerId := range m.dependencies[id] {
+			blocker, exist := m.Todos[blockerId]
+			if !exist || blocker.DeletedAt != nil || !isOwnedBy(blocker, ownerId) {
+				continue
+			}
+			if _, found := res[id]; !found {
+				res[id] = []int32{}
+			}
+			if !blocker.Completed {
+				res[id] = append(res[id], blockerId)
+			}
+		}
+	}
+	return res, nil
+}
+
+// Blockers returns the live blockers of the todo with given id, in the order of their id
+func (m *memoryStore) Blockers(ctx context.Context, id int32) ([]*Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	ownerId := getOwnerId(ctx)
+	res := make([]*Todo, 0)
+	for _, blockerId := range m.dependencies[id] {
+		if blocker, exist := m.Todos[blockerId]; exist && blocker.DeletedAt == nil && isOwnedBy(blocker, ownerId) {
+			res = append(res, blocker)
+		}
+	}
+	return res, nil
+}
+
+// AddDependency blocks the todo with given id by the todo blockerId
+func (m *memoryStore) AddDependency(ctx context.Context, id int32, blockerId int32) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	tx := m.begin(ctx)
+	if err := addDependency(ctx, tx, id, blockerId); err != nil {
+		return err
+	}
+	return tx.commit()
+}
+
+// RemoveDependency removes the blocker blockerId of the todo with given id
+func (m *memoryStore) RemoveDependency(ctx context.Context, id int32, blockerId int32) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	t, exist := m.Todos[id]
+	if !exist || !isOwnedBy(t, getOwnerId(ctx)) || !containsId(m.dependencies[id], blockerId) {
+		return ErrDependencyNotFound
+	}
+	blockers := removeId(m.dependencies[id], blockerId)
+	if err := m.persist(walOpPut, walKindDependency, id, blockers); err != nil {
+		return err
+	}
+	m.putDependencies(id, blockers)
+	m.compact(false)
+	return nil
+}
+
+// ClaimReminders records as sent and returns the reminders of the todos reached at now
+func (m *memoryStore) ClaimReminders(ctx context.Context, now time.Time, limit int) ([]*Reminder, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	ownerId := getOwnerId(ctx)
+	todos := make([]*Todo, 0)
+	for _, t := range m.Todos {
+		remindAt := getReminderTime(t)
+		if t.DeletedAt != nil || t.Completed || !isOwnedBy(t, ownerId) || remindAt == nil || remindAt.After(now) {
+			continue
+		}
+		if remindedAt, found := m.reminded[t.Id]; found && remindedAt.Equal(*remindAt) {
+			continue
+		}
+		todos = append(todos, t)
+	}
+	res := toReminders(todos)
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	if len(res) == 0 {
+		return res, nil
+	}
+	records := make([]walRecord, 0, len(res))
+	for _, reminder := range res {
+		data, err := json.Marshal(reminder.RemindAt)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, walRecord{Op: walOpPut, Kind: walKindReminder, Id: reminder.Todo.Id, Data: data})
+	}
+	// all the reminders are claimed on a single line of the log, like the changes of a transaction
+	if err := m.persist(walOpBatch, walKindReminder, 0, records); err != nil {
+		return nil, err
+	}
+	for _, reminder := range res {
+		m.reminded[reminder.Todo.Id] = reminder.RemindAt
+	}
+	m.compact(false)
+	return res, nil
+}
+
+// ReleaseReminder records as not sent the reminder of the todo with given id for the reminder time remindAt
+func (m *memoryStore) ReleaseReminder(ctx context.Context, id int32, remindAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	t, exist := m.Todos[id]
+	remindedAt, found := m.reminded[id]
+	if !exist || !isOwnedBy(t, getOwnerId(ctx)) || !found || !remindedAt.Equal(remindAt) {
 		return nil
 	}
-	return errors.New("todo with this id does not exist")
+	if err := m.persist(walOpDelete, walKindReminder, id, nil); err != nil {
+		return err
+	}
+	delete(m.reminded, id)
+	m.compact(false)
+	return nil
+}
+
+func (m *memoryStore) Batch(ctx context.Context, operations []BatchOperation) ([]*Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	tx := m.begin(ctx)
+	res, err := runBatch(ctx, tx, operations)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.commit(); err != nil {
+		return nil, err
+	}
+	return res, nil
 }
 
-// Close : will do cleanup for all todos stored in memory
 func (m *memoryStore) Close() {
 	m.lock.Lock()
 	defer m.lock.Unlock()
+	if m.wal != nil {
+		m.compact(true)
+		if err := m.wal.close(); err != nil {
+			m.log.Printf("error : closing memory store write-ahead log failed, error : %v", err)
+		}
+		m.wal = nil
+	}
 	for idx, _ := range m.Todos {
 		delete(m.Todos, idx)
 	}
+	m.events = nil
+	m.users = make(map[int32]*User)
+	m.apiKeys = make(map[int32]*memoryApiKey)
+	m.lists = make(map[int32]*TodoList)
+	m.dependencies = make(map[int32][]int32)
+	m.reminded = make(map[int32]time.Time)
+	m.index = newSearchIndex()
 	return
 }
 
+// adoptOrphans gives to the administrator the todos and the events restored from a store written before the users existed
+func (m *memoryStore) adoptOrphans() {
+	var adminId int32
+	for _, u := range m.sortedUsers() {
+		if u.Login == AdminLogin {
+			adminId = u.Id
+			break
+		}
+	}
+	for _, t := range m.Todos {
+		if t.OwnerId == 0 {
+			t.OwnerId = adminId
+		}
+	}
+	for _, event := range m.events {
+		if event.OwnerId == 0 {
+			event.OwnerId = adminId
+		}
+	}
+}
+
 // initializeStorage initialize some dummy data to get some results back
 func initializeStorage() *memoryStore {
 
@@ -160,25 +1412,104 @@ func initializeStorage() *memoryStore {
 			CompletedAt: &someTimeCompleted,
 			CreatedAt:   &someTimeCreated,
 			Id:          1,
+			OwnerId:     1,
 			Task:        "Learn GO",
+			Version:     1,
 		},
 		2: {
 			Completed:   false,
 			CompletedAt: nil,
 			CreatedAt:   &someTimeCreated,
 			Id:          2,
+			OwnerId:     1,
 			Task:        "Learn OpenAPI",
+			Version:     1,
 		},
 	}
 
+	admin := &User{CreatedAt: &someTimeCreated, Id: 1, IsAdmin: true, Login: AdminLogin, Role: UserRoleAdmin}
+
+	index := newSearchIndex()
+	for _, t := range defaultInitialData {
+		index.add(t)
+	}
 	return &memoryStore{
-		Todos: defaultInitialData,
-		maxId: DefaultMaxId,
-		lock:  sync.RWMutex{},
+		Todos:                defaultInitialData,
+		maxId:                DefaultMaxId,
+		index:                index,
+		users:                map[int32]*User{admin.Id: admin},
+		maxUserId:            admin.Id,
+		apiKeys:              make(map[int32]*memoryApiKey),
+		lists:                make(map[int32]*TodoList),
+		dependencies:         make(map[int32][]int32),
+		reminded:             make(map[int32]time.Time),
+		lock:                 sync.RWMutex{},
+		dependenciesModified: time.Now(),
 	}
 }
 
-func NewMemoryDB() (Storage, error) {
-	DBinMemory := initializeStorage()
-	return DBinMemory, nil
+// NewMemoryDB returns a Storage keeping the todos in memory.
+// when dataDir is empty the store starts with some dummy data that is lost on Close.
+// otherwise the store is durable : it is restored from the snapshot and the write-ahead log kept in dataDir,
+// and every change is written to this log before being acknowledged.
+func NewMemoryDB(dataDir string, log *log.Logger) (Storage, error) {
+	if dataDir == "" {
+		DBinMemory := initializeStorage()
+		DBinMemory.log = log
+		return DBinMemory, nil
+	}
+	wal, snapshot, records, err := openMemoryWal(dataDir)
+	if err != nil {
+		return nil, GetErrorF("error : memory store could not be restored from "+dataDir, err)
+	}
+	m := &memoryStore{
+		Todos:                make(map[int32]*Todo),
+		maxId:                snapshot.MaxId,
+		index:                newSearchIndex(),
+		users:                make(map[int32]*User),
+		apiKeys:              make(map[int32]*memoryApiKey),
+		lists:                make(map[int32]*TodoList),
+		dependencies:         make(map[int32][]int32),
+		reminded:             make(map[int32]time.Time),
+		log:                  log,
+		lock:                 sync.RWMutex{},
+		dependenciesModified: time.Now(),
+	}
+	for _, u := range snapshot.Users {
+		m.putUser(u)
+	}
+	for _, k := range snapshot.ApiKeys {
+		m.putApiKey(k)
+	}
+	for _, l := range snapshot.Lists {
+		m.putList(l)
+	}
+	for _, t := range snapshot.Todos {
+		m.putTodo(t)
+	}
+	for id, blockers := range snapshot.Dependencies {
+		m.putDependencies(id, blockers)
+	}
+	for id, remindedAt := range snapshot.Reminded {
+		m.reminded[id] = remindedAt
+	}
+	m.events = snapshot.Events
+	for i, record := range records {
+		if err := m.replay(record); err != nil {
+			wal.close()
+			return nil, fmt.Errorf("error : memory store write-ahead log record %d could not be applied : %v", i+1, err)
+		}
+	}
+	m.wal = wal
+	if len(m.users) == 0 {
+		// like the sql migrations, the administrator is created with the store
+		role := UserRoleAdmin
+		if _, err := m.createUser(NewUser{Login: AdminLogin, Role: &role}); err != nil {
+			wal.close()
+			return nil, err
+		}
+	}
+	m.adoptOrphans()
+	log.Printf("SUCCESS memory store restored from %s with %d todos (%d records replayed)", dataDir, len(m.Todos), len(records))
+	return m, nil
 }
@@ -0,0 +1,462 @@
+package todos
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// memoryTx is the todoWriter of the memory store : the changes are staged and only applied to the store by commit,
+// so a failed batch leaves the store untouched. it must be used with the write lock held.
+type memoryTx struct {
+	m     *memoryStore
+	maxId int32
+	// ownerId is the id of the user whose todos can be changed by the transaction, 0 for all the users
+	ownerId int32
+	// staged contains the new state of the todos changed by the transaction, nil for a deleted todo
+	staged map[int32]*Todo
+	// order contains the ids of the staged todos in the order of their first change
+	order []int32
+	// events contains the events of the staged changes in the order they were made
+	events []*TodoEvent
+	// deletedLists contains the ids of the lists removed by the transaction
+	deletedLists []int32
+	// dependencies contains the new blockers of the todos whose dependencies are changed by the transaction
+	dependencies map[int32][]int32
+}
+
+// begin starts a transaction on the store for the owner of ctx, it must be called with the write lock held
+func (m *memoryStore) begin(ctx context.Context) *memoryTx {
+	return &memoryTx{m: m, maxId: m.maxId, ownerId: getOwnerId(ctx), staged: make(map[int32]*Todo), dependencies: make(map[int32][]int32)}
+}
+
+// get returns the todo with the given id, as changed by the transaction
+func (tx *memoryTx) get(id int32) (*Todo, bool) {
+	if t, staged := tx.staged[id]; staged {
+		return t, t != nil
+	}
+	t, exist := tx.m.Todos[id]
+	return t, exist
+}
+
+// getInState returns the todo with the given id if a write requiring the given state and matchVersion can be applied to it
+func (tx *memoryTx) getInState(id int32, state todoState, matchVersion int32) (*Todo, error) {
+	existingTodo, exist := tx.get(id)
+	if !exist || !isOwnedBy(existingTodo, tx.ownerId) {
+		return nil, ErrNotFound
+	}
+	if err := checkTodoState(existingTodo, state, matchVersion); err != nil {
+		return nil, err
+	}
+	return existingTodo, nil
+}
+
+// lock returns the todo with given id if a write requiring the given state and matchVersion can be applied to it,
+// the write lock of the store is already held by the transaction
+func (tx *memoryTx) lock(ctx context.Context, operation string, id int32, state todoState, matchVersion int32) (*Todo, error) {
+	return tx.getInState(id, state, matchVersion)
+}
+
+// checkParent returns the ValidationError of errUnknownParent if the todo with given id, 0 for a new todo, of ownerId
+// cannot have this parent, or the one of errParentCycle if the parent is the todo itself or one of its descendants
+func (tx *memoryTx) checkParent(parentId *int32, ownerId int32, id int32) error {
+	if parentId == nil {
+		return nil
+	}
+	parent, exist := tx.get(*parentId)
+	if !exist || parent.OwnerId != ownerId || parent.DeletedAt != nil {
+		return errUnknownParent(*parentId)
+	}
+	// the ancestors of the parent are walked up to the root, the todo must not be one of them
+	for ancestor := parent; ancestor != nil; {
+		if ancestor.Id == id {
+			return errParentCycle(id, *parentId)
+		}
+		if ancestor.ParentId == nil {
+			break
+		}
+		ancestor, _ = tx.get(*ancestor.ParentId)
+	}
+	return nil
+}
+
+// checkList returns the ValidationError of errUnknownList if the todos of ownerId cannot be in the list
+func (tx *memoryTx) checkList(listId *int32, ownerId int32) error {
+	if listId == nil {
+		return nil
+	}
+	if l, exist := tx.m.lists[*listId]; !exist || l.OwnerId != ownerId {
+		return errUnknownList(*listId)
+	}
+	return nil
+}
+
+// stage records the new state of the todo with the given id, nil for a purged todo, and the event of this change
+func (tx *memoryTx) stage(ctx context.Context, id int32, t *Todo) {
+	before, _ := tx.get(id)
+	event := newTodoEvent(ctx, before, t)
+	event.Id = tx.m.lastEventId() + int64(len(tx.events)) + 1
+	tx.events = append(tx.events, event)
+	if _, staged := tx.staged[id]; !staged {
+		tx.order = append(tx.order, id)
+	}
+	tx.staged[id] = t
+}
+
+func (tx *memoryTx) create(ctx context.Context, todo NewTodo, previous *Todo) (*Todo, error) {
+	if err := validateTask(todo.Task); err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(todo.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePriority(todo.Priority); err != nil {
+		return nil, err
+	}
+	recurrence, err := normalizeRecurrence(todo.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	ownerId, err := getNewTodoOwnerId(ctx, previous)
+	if err != nil {
+		return nil, err
+	}
+	listId := getNewTodoListId(todo)
+	if err := tx.checkList(listId, ownerId); err != nil {
+		return nil, err
+	}
+	parentId := getNewTodoParentId(todo)
+	if err := tx.checkParent(parentId, ownerId, 0); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	t := &Todo{
+		AutoComplete: normalizeAutoComplete(todo.AutoComplete),
+		Completed:    false,
+		CompletedAt:  nil,
+		CreatedAt:    &now,
+		DueAt:        normalizeDueAt(todo.DueAt),
+		Id:           tx.maxId + 1,
+		ListId:       listId,
+		OwnerId:      ownerId,
+		ParentId:     parentId,
+		Priority:     todo.Priority,
+		Recurrence:   recurrence,
+		RemindAt:     normalizeDueAt(todo.RemindAt),
+		SeriesId:     getNewTodoSeriesId(previous),
+		Tags:         tagsValue(tags),
+		Task:         todo.Task,
+		UpdatedAt:    &now,
+		Version:      1,
+	}
+	if t.SeriesId == nil && recurrence != nil {
+		// a recurring todo starts its own series
+		seriesId := t.Id
+		t.SeriesId = &seriesId
+	}
+	tx.maxId = t.Id
+	tx.stage(ctx, t.Id, t)
+	return t, nil
+}
+
+func (tx *memoryTx) patch(ctx context.Context, id int32, patch TodoPatch, matchVersion int32) (*Todo, error) {
+	if patch.Task != nil {
+		if err := validateTask(*patch.Task); err != nil {
+			return nil, err
+		}
+	}
+	tags, err := normalizeTags(patch.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePatchPriority(patch); err != nil {
+		return nil, err
+	}
+	if patch.Recurrence, err = normalizePatchRecurrence(patch); err != nil {
+		return nil, err
+	}
+	existingTodo, err := tx.getInState(id, liveTodo, matchVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBlockers(ctx, tx, existingTodo, patch); err != nil {
+		return nil, err
+	}
+	listId := getPatchListId(existingTodo.ListId, patch)
+	if err := tx.checkList(listId, existingTodo.OwnerId); err != nil {
+		return nil, err
+	}
+	parentId := getPatchParentId(existingTodo.ParentId, patch)
+	if patch.ParentId != nil {
+		if err := tx.checkParent(parentId, existingTodo.OwnerId, id); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+	// id, CreatedAt and the other fields not given in patch keep their value
+	todo := *existingTodo
+	if patch.Task != nil {
+		todo.Task = *patch.Task
+	}
+	todo.ListId = listId
+	todo.DueAt = getPatchDueAt(existingTodo.DueAt, patch)
+	todo.RemindAt = getPatchRemindAt(existingTodo.RemindAt, patch)
+	todo.Priority = getPatchPriority(existingTodo.Priority, patch)
+	todo.Recurrence = getPatchRecurrence(existingTodo.Recurrence, patch)
+	todo.SeriesId = getPatchSeriesId(existingTodo, patch)
+	todo.ParentId = parentId
+	todo.AutoComplete = getPatchAutoComplete(existingTodo.AutoComplete, patch)
+	if patch.Tags != nil {
+		todo.Tags = tagsValue(tags)
+	}
+	if patch.Completed != nil {
+		switch {
+		case *patch.Completed && !existingTodo.Completed:
+			todo.CompletedAt = &now
+		case !*patch.Completed && existingTodo.Completed:
+			// task was completed, but user changed it to not completed
+			todo.CompletedAt = nil
+		}
+		// in all other cases the value of CompletedAt should not be changed
+		todo.Completed = *patch.Completed
+	}
+	todo.UpdatedAt = &now
+	todo.Version = existingTodo.Version + 1
+	tx.stage(ctx, id, &todo)
+	if err := afterPatch(ctx, tx, existingTodo, &todo); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// openOccurrences returns the ids of the live todos of the series not yet completed, as changed by the transaction
+func (tx *memoryTx) openOccurrences(ctx context.Context, seriesId int32) ([]int32, error) {
+	var res []int32
+	isOpen := func(t *Todo) bool {
+		return t.SeriesId != nil && *t.SeriesId == seriesId && !t.Completed && t.DeletedAt == nil
+	}
+	for id, t := range tx.m.Todos {
+		if _, staged := tx.staged[id]; !staged && isOpen(t) {
+			res = append(res, id)
+		}
+	}
+	for id, t := range tx.staged {
+		if t != nil && isOpen(t) {
+			res = append(res, id)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res, nil
+}
+
+// children returns the children of the todo in the given state in the order of their id, as changed by the transaction
+func (tx *memoryTx) children(ctx context.Context, parentId int32, state todoState) ([]*Todo, error) {
+	var res []*Todo
+	isChild := func(t *Todo) bool {
+		return t.ParentId != nil && *t.ParentId == parentId && (state == anyTodo || t.DeletedAt == nil)
+	}
+	for id, t := range tx.m.Todos {
+		if _, staged := tx.staged[id]; !staged && isChild(t) {
+			res = append(res, t)
+		}
+	}
+	for _, t := range tx.staged {
+		if t != nil && isChild(t) {
+			res = append(res, t)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Id < res[j].Id })
+	return res, nil
+}
+
+// reparent moves the todo to the parent, nil for a root todo, even if it is in the trash
+func (tx *memoryTx) reparent(ctx context.Context, id int32, parentId *int32) (*Todo, error) {
+	existingTodo, err := tx.getInState(id, anyTodo, 0)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	todo := *existingTodo
+	todo.ParentId = parentId
+	todo.UpdatedAt = &now
+	todo.Version = existingTodo.Version + 1
+	tx.stage(ctx, id, &todo)
+	return &todo, nil
+}
+
+// blockers returns the ids of the blockers of the todo in ascending order, as changed by the transaction
+func (tx *memoryTx) blockers(id int32) []int32 {
+	if blockers, staged := tx.dependencies[id]; staged {
+		return blockers
+	}
+	return tx.m.dependencies[id]
+}
+
+// openBlockers returns the ids of the live blockers of the todo not yet completed, as changed by the transaction
+func (tx *memoryTx) openBlockers(ctx context.Context, id int32) ([]int32, error) {
+	var res []int32
+	for _, blockerId := range tx.blockers(id) {
+		if blocker, exist := tx.get(blockerId); exist && blocker.DeletedAt == nil && !blocker.Completed {
+			res = append(res, blockerId)
+		}
+	}
+	return res, nil
+}
+
+// dependsOn returns true when the todo with given id is blocked by blockerId, directly or through other blockers
+func (tx *memoryTx) dependsOn(ctx context.Context, id int32, blockerId int32) (bool, error) {
+	visited := map[int32]bool{id: true}
+	for pending := []int32{id}; len(pending) > 0; {
+		var next []int32
+		for _, todoId := range pending {
+			for _, b := range tx.blockers(todoId) {
+				if b == blockerId {
+					return true, nil
+				}
+				if !visited[b] {
+					visited[b] = true
+					next = append(next, b)
+				}
+			}
+		}
+		pending = next
+	}
+	return false, nil
+}
+
+// addDependency blocks the todo with given id by blockerId, the blockers are kept in ascending order
+func (tx *memoryTx) addDependency(ctx context.Context, id int32, blockerId int32) error {
+	blockers := tx.blockers(id)
+	if containsId(blockers, blockerId) {
+		return nil
+	}
+	res := append(make([]int32, 0, len(blockers)+1), blockers...)
+	res = append(res, blockerId)
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	tx.dependencies[id] = res
+	return nil
+}
+
+// retag replaces the tag from by to in the tags of the todo, even if it is in the trash
+func (tx *memoryTx) retag(ctx context.Context, id int32, from, to string) error {
+	existingTodo, err := tx.getInState(id, anyTodo, 0)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	todo := *existingTodo
+	todo.Tags = tagsValue(replaceTag(getTodoTags(existingTodo), from, to))
+	todo.UpdatedAt = &now
+	todo.Version = existingTodo.Version + 1
+	tx.stage(ctx, id, &todo)
+	return nil
+}
+
+// delete moves the todo to the trash
+func (tx *memoryTx) delete(ctx context.Context, id int32, matchVersion int32) error {
+	existingTodo, err := tx.getInState(id, liveTodo, matchVersion)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	todo := *existingTodo
+	todo.DeletedAt = &now
+	todo.UpdatedAt = &now
+	todo.Version = existingTodo.Version + 1
+	tx.stage(ctx, id, &todo)
+	return nil
+}
+
+func (tx *memoryTx) restore(ctx context.Context, id int32, matchVersion int32) (*Todo, error) {
+	existingTodo, err := tx.getInState(id, trashedTodo, matchVersion)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	todo := *existingTodo
+	todo.DeletedAt = nil
+	todo.UpdatedAt = &now
+	todo.Version = existingTodo.Version + 1
+	tx.stage(ctx, id, &todo)
+	return &todo, nil
+}
+
+func (tx *memoryTx) purge(ctx context.Context, id int32, matchVersion int32) error {
+	if _, err := tx.getInState(id, anyTodo, matchVersion); err != nil {
+		return err
+	}
+	tx.stage(ctx, id, nil)
+	return nil
+}
+
+// commit writes the staged changes and their events to the write-ahead log in a single record, and then applies them to the store
+func (tx *memoryTx) commit() error {
+	m := tx.m
+	if len(tx.order) == 0 && len(tx.deletedLists) == 0 && len(tx.dependencies) == 0 {
+		return nil
+	}
+	// the dependencies are written before the todos, so they are removed with the todos purged by the transaction
+	dependencyIds := make([]int32, 0, len(tx.dependencies))
+	for id := range tx.dependencies {
+		dependencyIds = append(dependencyIds, id)
+	}
+	sort.Slice(dependencyIds, func(i, j int) bool { return dependencyIds[i] < dependencyIds[j] })
+	if m.wal != nil {
+		records := make([]walRecord, 0, len(dependencyIds)+len(tx.order)+len(tx.events))
+		for _, id := range dependencyIds {
+			data, err := json.Marshal(tx.dependencies[id])
+			if err != nil {
+				return err
+			}
+			records = append(records, walRecord{Op: walOpPut, Kind: walKindDependency, Id: id, Data: data})
+		}
+		for _, id := range tx.order {
+			record := walRecord{Op: walOpDelete, Kind: walKindTodo, Id: id}
+			if t := tx.staged[id]; t != nil {
+				record.Op = walOpPut
+				data, err := json.Marshal(t)
+				if err != nil {
+					return err
+				}
+				record.Data = data
+			}
+			records = append(records, record)
+		}
+		for _, event := range tx.events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			records = append(records, walRecord{Op: walOpPut, Kind: walKindEvent, Id: event.TodoId, Data: data})
+		}
+		for _, id := range tx.deletedLists {
+			records = append(records, walRecord{Op: walOpDelete, Kind: walKindList, Id: id})
+		}
+		// a batch is a single line of the log, so after a crash it is replayed entirely or not at all
+		if err := m.persist(walOpBatch, walKindTodo, 0, records); err != nil {
+			return err
+		}
+	}
+	if tx.maxId > m.maxId {
+		// the ids of the todos created and deleted by the same batch are not reused
+		m.maxId = tx.maxId
+	}
+	for _, id := range dependencyIds {
+		m.putDependencies(id, tx.dependencies[id])
+	}
+	for _, id := range tx.order {
+		if t := tx.staged[id]; t != nil {
+			m.putTodo(t)
+		} else {
+			m.removeTodo(id)
+		}
+	}
+	m.events = append(m.events, tx.events...)
+	for _, id := range tx.deletedLists {
+		delete(m.lists, id)
+	}
+	m.compact(false)
+	return nil
+}
@@ -0,0 +1,224 @@
+package todos
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	walFileName      = "todos.wal"
+	snapshotFileName = "todos.snapshot.json"
+	// snapshotEvery is the number of records appended to the write-ahead log before it is compacted in a snapshot
+	snapshotEvery = 1000
+	walOpPut      = "put"
+	walOpDelete   = "delete"
+	walOpBatch    = "batch"
+	walKindTodo   = "todo"
+	walKindEvent  = "event"
+	walKindUser   = "user"
+	walKindApiKey = "apikey"
+	walKindList   = "list"
+	// walKindDependency records contain all the blockers of the todo of their id
+	walKindDependency = "dependency"
+	// walKindReminder records contain the reminder time of the last reminder sent for the todo of their id
+	walKindReminder = "reminder"
+)
+
+// walRecord is one change of the memory store, as written on a line of the write-ahead log
+type walRecord struct {
+	Op   string          `json:"op"`
+	Kind string          `json:"kind"`
+	Id   int32           `json:"id"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// memorySnapshot is the compacted content of the memory store
+type memorySnapshot struct {
+	MaxId  int32           `json:"max_id"`
+	Todos  map[int32]*Todo `json:"todos"`
+	Events []*TodoEvent    `json:"events,omitempty"`
+	Users  []*User         `json:"users,omitempty"`
+	// ApiKeys only contains the hash of the keys
+	ApiKeys []*memoryApiKey `json:"api_keys,omitempty"`
+	Lists   []*TodoList     `json:"lists,omitempty"`
+	// Dependencies contains the ids of the blockers of the todos by id of the todo
+	Dependencies map[int32][]int32 `json:"dependencies,omitempty"`
+	// Reminded contains the reminder time of the last reminder sent by id of the todo
+	Reminded map[int32]time.Time `json:"reminded,omitempty"`
+}
+
+// walFile is the file of the write-ahead log, the tests replace it to make the appends fail
+type walFile interface {
+	io.Writer
+	io.Seeker
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+// memoryWal persists the changes of a memory store in a directory : every change is appended and fsync'ed
+// to the write-ahead log before being applied in memory, and the log is regularly compacted in a snapshot.
+type memoryWal struct {
+	dir     string
+	file    walFile
+	records int
+}
+
+// openMemoryWal loads the snapshot and returns it with the records of the write-ahead log written after it
+func openMemoryWal(dir string) (*memoryWal, *memorySnapshot, []walRecord, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, nil, nil, err
+	}
+	snapshot := &memorySnapshot{Todos: make(map[int32]*Todo)}
+	data, err := os.ReadFile(filepath.Join(dir, snapshotFileName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, snapshot); err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid snapshot %s : %v", snapshotFileName, err)
+		}
+		if snapshot.Todos == nil {
+			snapshot.Todos = make(map[int32]*Todo)
+		}
+	}
+	file, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_RDWR|os.O_CREATE, 0o640)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	records, validSize, err := readWalRecords(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, nil, err
+	}
+	// a crash during an append can leave an incomplete last line, it was never acknowledged so it is dropped
+	if err := file.Truncate(validSize); err != nil {
+		file.Close()
+		return nil, nil, nil, err
+	}
+	if _, err := file.Seek(validSize, io.SeekStart); err != nil {
+		file.Close()
+		return nil, nil, nil, err
+	}
+	return &memoryWal{dir: dir, file: file, records: len(records)}, snapshot, records, nil
+}
+
+// readWalRecords decodes all the complete records of the log and returns the size of the valid part of the file
+func readWalRecords(file *os.File) ([]walRecord, int64, error) {
+	var records []walRecord
+	var validSize int64 = 0
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// the line was not terminated : the append was interrupted
+			return records, validSize, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		var record walRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, 0, fmt.Errorf("invalid record in %s at offset %d : %v", walFileName, validSize, err)
+		}
+		records = append(records, record)
+		validSize += int64(len(line))
+	}
+}
+
+// append writes the record at the end of the log and waits until it is on disk.
+// when the write or the sync fails, the log is truncated back to its previous end so the line is never followed by other records.
+func (w *memoryWal) append(op, kind string, id int32, value interface{}) error {
+	record := walRecord{Op: op, Kind: kind, Id: id}
+	if value != nil {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		record.Data = data
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	offset, err := w.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	_, err = w.file.Write(append(line, '\n'))
+	if err == nil {
+		err = w.file.Sync()
+	}
+	if err != nil {
+		return w.rollback(offset, err)
+	}
+	w.records++
+	return nil
+}
+
+// rollback removes what a failed append wrote after offset and returns the error of the append
+func (w *memoryWal) rollback(offset int64, err error) error {
+	if truncErr := w.file.Truncate(offset); truncErr != nil {
+		return fmt.Errorf("%v, and the log could not be truncated : %v", err, truncErr)
+	}
+	if _, seekErr := w.file.Seek(offset, io.SeekStart); seekErr != nil {
+		return fmt.Errorf("%v, and the log could not be truncated : %v", err, seekErr)
+	}
+	return err
+}
+
+// needsSnapshot returns true when the log contains enough records to be compacted
+func (w *memoryWal) needsSnapshot() bool {
+	return w.records >= snapshotEvery
+}
+
+// snapshot atomically replaces the snapshot file with the given content, and then empties the log.
+// if the process stops between the two steps, the records are applied a second time on the next start,
+// which changes nothing since the events already in the snapshot are skipped by memoryStore.replay.
+func (w *memoryWal) snapshot(content *memorySnapshot) error {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	tmpPath := filepath.Join(w.dir, snapshotFileName+".tmp")
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, filepath.Join(w.dir, snapshotFileName)); err != nil {
+		return err
+	}
+	if dir, err := os.Open(w.dir); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	w.records = 0
+	return w.file.Sync()
+}
+
+func (w *memoryWal) close() error {
+	return w.file.Close()
+}
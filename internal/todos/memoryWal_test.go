@@ -0,0 +1,107 @@
+package todos
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// failingWalFile writes only half of the data given to Write and then fails, like a full disk
+type failingWalFile struct {
+	walFile
+}
+
+func (f failingWalFile) Write(p []byte) (int, error) {
+	n, _ := f.walFile.Write(p[:len(p)/2])
+	return n, errors.New("no space left on device")
+}
+
+func TestMemoryWal_ReplayAfterSnapshot(t *testing.T) {
+	l := log.New(ioutil.Discard, "", 0)
+	dataDir := t.TempDir()
+	store, err := NewMemoryDB(dataDir, l)
+	if err != nil {
+		t.Fatalf("error getting durable memory storage. error : %v ", err)
+	}
+	m := store.(*memoryStore)
+	admin, err := m.GetUser(context.Background(), AdminLogin)
+	if err != nil {
+		t.Fatalf("error getting the administrator. error : %v ", err)
+	}
+	ctx := WithUser(context.Background(), admin)
+	todo, err := m.Create(ctx, NewTodo{Task: "water the plants"})
+	assert.NoError(t, err)
+	_, err = m.Update(ctx, todo.Id, Todo{Task: "water the plants", Completed: true}, 0)
+	assert.NoError(t, err)
+	events := len(m.events)
+
+	// the process stops after the snapshot was written but before the log was emptied
+	logPath := filepath.Join(dataDir, walFileName)
+	logContent, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("error reading the write-ahead log. error : %v ", err)
+	}
+	m.lock.Lock()
+	m.compact(true)
+	m.lock.Unlock()
+	assert.NoError(t, m.wal.close())
+	if err := os.WriteFile(logPath, logContent, 0o640); err != nil {
+		t.Fatalf("error restoring the write-ahead log. error : %v ", err)
+	}
+
+	store, err = NewMemoryDB(dataDir, l)
+	if err != nil {
+		t.Fatalf("the store should be restored from the snapshot and the log, error : %v ", err)
+	}
+	defer store.Close()
+	restored := store.(*memoryStore)
+	assert.Len(t, restored.events, events, "the events of the log already in the snapshot should not be replayed")
+	history, err := restored.History(ctx, todo.Id)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2, "the todo should have one created and one updated event")
+	assert.Equal(t, 1, len(restored.Todos), "the todos of the log already in the snapshot should be put once")
+}
+
+func TestMemoryWal_FailedAppend(t *testing.T) {
+	l := log.New(ioutil.Discard, "", 0)
+	dataDir := t.TempDir()
+	store, err := NewMemoryDB(dataDir, l)
+	if err != nil {
+		t.Fatalf("error getting durable memory storage. error : %v ", err)
+	}
+	m := store.(*memoryStore)
+	admin, err := m.GetUser(context.Background(), AdminLogin)
+	if err != nil {
+		t.Fatalf("error getting the administrator. error : %v ", err)
+	}
+	ctx := WithUser(context.Background(), admin)
+	_, err = m.Create(ctx, NewTodo{Task: "water the plants"})
+	assert.NoError(t, err)
+
+	file := m.wal.file
+	m.wal.file = failingWalFile{walFile: file}
+	_, err = m.Create(ctx, NewTodo{Task: "pay the taxes"})
+	assert.Error(t, err, "a change that could not be written to the log should fail")
+	m.wal.file = file
+	_, err = m.Create(ctx, NewTodo{Task: "call the plumber"})
+	assert.NoError(t, err, "the log should accept new changes after a failed append")
+	// the process stops without Close, so the store is restored from the log and not from a snapshot
+	assert.NoError(t, m.wal.close())
+
+	store, err = NewMemoryDB(dataDir, l)
+	if err != nil {
+		t.Fatalf("the store should be restored after a failed append, error : %v ", err)
+	}
+	defer store.Close()
+	var tasks []string
+	for _, todo := range store.(*memoryStore).Todos {
+		tasks = append(tasks, todo.Task)
+	}
+	assert.ElementsMatch(t, []string{"water the plants", "call the plumber"}, tasks,
+		"only the changes written to the log should be restored")
+}
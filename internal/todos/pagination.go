@@ -0,0 +1,151 @@
+package todos
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	cursorPrefix           = "todo:"
+	HeaderTotalCount       = "X-Total-Count"
+	HeaderLink             = "Link"
+	errInvalidCursorMsg    = "GetTodos after parameter is not a valid cursor"
+	errOffsetAndAfterMsg   = "GetTodos offset and after parameters cannot be used together"
+	errAfterNeedsSortIdMsg = "GetTodos after parameter can only be used with sort=id"
+	errLimitOutOfRangeMsg  = "GetTodos limit must be between 1 and %d"
+)
+
+// EncodeCursor returns the opaque cursor pointing after the todo with the given id
+func EncodeCursor(id int32) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s%d", cursorPrefix, id)))
+}
+
+// DecodeCursor returns the todo id contained in an opaque cursor created by EncodeCursor
+func DecodeCursor(cursor string) (int32, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	if !strings.HasPrefix(string(raw), cursorPrefix) {
+		return 0, errors.New("cursor has an unknown format")
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(string(raw), cursorPrefix), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	if id < 1 {
+		return 0, errors.New("cursor contains an invalid id")
+	}
+	return int32(id), nil
+}
+
+// getListParams validates the paging, filtering and sorting query parameters and converts them to ListParams
+func getListParams(params GetTodosParams) (ListParams, error) {
+	listParams := ListParams{
+		Offset: 0,
+		Limit:  DefaultListLimit,
+		Filter: TodoFilter{
+			Completed:       params.Completed,
+			CreatedAfter:    params.CreatedAfter,
+			CreatedBefore:   params.CreatedBefore,
+			CompletedAfter:  params.CompletedAfter,
+			CompletedBefore: params.CompletedBefore,
+		},
+	}
+	if params.Ids != nil {
+		if len(*params.Ids) > MaxListLimit {
+			return listParams, fmt.Errorf("GetTodos ids cannot contain more than %d ids", MaxListLimit)
+		}
+		listParams.Filter.Ids = *params.Ids
+	}
+	if params.Tag != nil {
+		tags, err := normalizeTags(params.Tag)
+		if err != nil {
+			return listParams, fmt.Errorf("GetTodos %v", err)
+		}
+		listParams.Filter.Tags = tags
+	}
+	listParams.Filter.AllTags = params.TagMatch == nil || *params.TagMatch == GetTodosParamsTagMatchAll
+	if params.TagMatch != nil && *params.TagMatch != GetTodosParamsTagMatchAll && *params.TagMatch != GetTodosParamsTagMatchAny {
+		return listParams, fmt.Errorf("GetTodos tag_match must be %s or %s", GetTodosParamsTagMatchAll, GetTodosParamsTagMatchAny)
+	}
+	if params.Sort != nil {
+		if _, _, err := ParseSort(*params.Sort); err != nil {
+			return listParams, fmt.Errorf("GetTodos %v", err)
+		}
+		listParams.Sort = *params.Sort
+	}
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > MaxListLimit {
+			return listParams, fmt.Errorf(errLimitOutOfRangeMsg, MaxListLimit)
+		}
+		listParams.Limit = int(*params.Limit)
+	}
+	if params.Offset != nil {
+		if *params.Offset < 0 {
+			return listParams, errors.New("GetTodos offset cannot be negative")
+		}
+		listParams.Offset = int(*params.Offset)
+	}
+	if params.After != nil {
+		if params.Offset != nil {
+			return listParams, errors.New(errOffsetAndAfterMsg)
+		}
+		if listParams.Sort != "" && listParams.Sort != "id" {
+			return listParams, errors.New(errAfterNeedsSortIdMsg)
+		}
+		afterId, err := DecodeCursor(*params.After)
+		if err != nil {
+			return listParams, errors.New(errInvalidCursorMsg)
+		}
+		listParams.AfterId = afterId
+	}
+	return listParams, nil
+}
+
+// setPaginationHeaders adds the X-Total-Count and the RFC 8288 Link headers to the response.
+// offset paging gets first, prev and next links, cursor paging gets first and next links.
+func setPaginationHeaders(ctx echo.Context, params ListParams, list []*Todo, total int32) {
+	ctx.Response().Header().Set(HeaderTotalCount, strconv.Itoa(int(total)))
+	var links []string
+	pageLink := func(rel string, changes map[string]string) {
+		u := url.URL{
+			Scheme: ctx.Scheme(),
+			Host:   ctx.Request().Host,
+			Path:   ctx.Request().URL.Path,
+		}
+		q := ctx.Request().URL.Query()
+		for _, name := range []string{"offset", "after"} {
+			q.Del(name)
+		}
+		q.Set("limit", strconv.Itoa(params.Limit))
+		for name, value := range changes {
+			q.Set(name, value)
+		}
+		u.RawQuery = q.Encode()
+		links = append(links, fmt.Sprintf("<%s>; rel=\"%s\"", u.String(), rel))
+	}
+	pageLink("first", nil)
+	if params.AfterId > 0 {
+		if len(list) == params.Limit {
+			pageLink("next", map[string]string{"after": EncodeCursor(list[len(list)-1].Id)})
+		}
+	} else {
+		if params.Offset > 0 {
+			prevOffset := params.Offset - params.Limit
+			if prevOffset < 0 {
+				prevOffset = 0
+			}
+			pageLink("prev", map[string]string{"offset": strconv.Itoa(prevOffset)})
+		}
+		if params.Offset+len(list) < int(total) && len(list) > 0 {
+			pageLink("next", map[string]string{"offset": strconv.Itoa(params.Offset + len(list))})
+		}
+	}
+	ctx.Response().Header().Set(HeaderLink, strings.Join(links, ", "))
+}
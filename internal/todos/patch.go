@@ -0,0 +1,275 @@
+package todos
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// MIMEApplicationMergePatch is the content type of a JSON Merge Patch (RFC 7396)
+	MIMEApplicationMergePatch = "application/merge-patch+json"
+	// MIMEApplicationJSONPatch is the content type of a JSON Patch (RFC 6902)
+	MIMEApplicationJSONPatch = "application/json-patch+json"
+)
+
+// todoDocument is the json representation of a todo as a generic object, the patches are applied on it
+// and the changes are then compared with the original document to build a TodoPatch
+type todoDocument map[string]interface{}
+
+// newTodoDocument returns the json representation of the todo
+func newTodoDocument(t *Todo) (todoDocument, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	doc := todoDocument{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// applyMergePatch applies the JSON Merge Patch (RFC 7396) in body to doc, a null member removes the field
+func applyMergePatch(doc todoDocument, body []byte) error {
+	var patch interface{}
+	if err := json.Unmarshal(body, &patch); err != nil {
+		return &ValidationError{Field: "patch", Message: fmt.Sprintf("is not valid json : %v", err)}
+	}
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		// a patch that is not an object replaces the whole target, which is never a valid todo
+		return &ValidationError{Field: "patch", Message: "must be a json object"}
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(doc, name)
+		} else {
+			// the fields of a todo are not objects, so the members do not need to be merged recursively
+			doc[name] = value
+		}
+	}
+	return nil
+}
+
+// applyJSONPatch applies the operations of the JSON Patch (RFC 6902) in body to doc in order,
+// it stops at the first operation that fails. only the top level fields of the todo can be addressed.
+func applyJSONPatch(doc todoDocument, body []byte) error {
+	var operations []JSONPatchOperation
+	if err := json.Unmarshal(body, &operations); err != nil {
+		return &ValidationError{Field: "patch", Message: fmt.Sprintf("is not a valid json patch : %v", err)}
+	}
+	for i, operation := range operations {
+		name, err := parseJSONPointer(operation.Path)
+		if err != nil {
+			return err
+		}
+		_, exist := doc[name]
+		switch operation.Op {
+		case JSONPatchOperationOpAdd, JSONPatchOperationOpReplace:
+			if operation.Value == nil {
+				return &ValidationError{Field: operation.Path, Message: fmt.Sprintf("needs a value for operation %d %s", i, operation.Op)}
+			}
+			if operation.Op == JSONPatchOperationOpReplace && !exist {
+				return &ValidationError{Field: operation.Path, Message: fmt.Sprintf("does not exist for operation %d %s", i, operation.Op)}
+			}
+			doc[name] = *operation.Value
+		case JSONPatchOperationOpRemove:
+			if !exist {
+				return &ValidationError{Field: operation.Path, Message: fmt.Sprintf("does not exist for operation %d %s", i, operation.Op)}
+			}
+			delete(doc, name)
+		case JSONPatchOperationOpMove, JSONPatchOperationOpCopy:
+			if operation.From == nil {
+				return &ValidationError{Field: operation.Path, Message: fmt.Sprintf("needs a from for operation %d %s", i, operation.Op)}
+			}
+			from, err := parseJSONPointer(*operation.From)
+			if err != nil {
+				return err
+			}
+			value, found := doc[from]
+			if !found {
+				return &ValidationError{Field: *operation.From, Message: fmt.Sprintf("does not exist for operation %d %s", i, operation.Op)}
+			}
+			if operation.Op == JSONPatchOperationOpMove {
+				delete(doc, from)
+			}
+			doc[name] = value
+		case JSONPatchOperationOpTest:
+			var expected interface{}
+			if operation.Value != nil {
+				expected = *operation.Value
+			}
+			if !exist || !reflect.DeepEqual(doc[name], expected) {
+				return fmt.Errorf("%w : test operation %d failed, %s is not %v", ErrConflict, i, operation.Path, expected)
+			}
+		default:
+			return &ValidationError{Field: "patch", Message: fmt.Sprintf("operation %d has an unknown op %q", i, operation.Op)}
+		}
+	}
+	return nil
+}
+
+// parseJSONPointer returns the name of the field of the todo referenced by the JSON Pointer (RFC 6901)
+func parseJSONPointer(pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "/") || strings.Count(pointer, "/") > 1 {
+		return "", &ValidationError{Field: pointer, Message: "is not the path of a field of todo"}
+	}
+	// a ~ can only be escaped as ~0 and a / as ~1
+	if strings.Contains(strings.NewReplacer("~0", "", "~1", "").Replace(pointer[1:]), "~") {
+		return "", &ValidationError{Field: pointer, Message: "contains a ~ that is not followed by 0 or 1"}
+	}
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(pointer[1:]), nil
+}
+
+// getTodoPatch compares the patched document with the original todo document and returns the changes,
+// only the task, completed, list_id, tags, due_at, remind_at, priority, recurrence, parent_id and auto_complete fields can be modified
+func getTodoPatch(original, patched todoDocument) (TodoPatch, error) {
+	names := make([]string, 0, len(patched))
+	for name := range patched {
+		names = append(names, name)
+	}
+	for name := range original {
+		if _, found := patched[name]; !found {
+			names = append(names, name)
+		}
+	}
+	// the fields are checked in a stable order, so the same patch always gives the same error
+	sort.Strings(names)
+	var res TodoPatch
+	for _, name := range names {
+		before, wasPresent := original[name]
+		after, isPresent := patched[name]
+		if wasPresent == isPresent && reflect.DeepEqual(before, after) {
+			continue
+		}
+		if !isPresent && (name == "task" || name == "completed") {
+			return TodoPatch{}, &ValidationError{Field: name, Message: "cannot be removed"}
+		}
+		switch name {
+		case "task":
+			task, ok := after.(string)
+			if !ok {
+				return TodoPatch{}, &ValidationError{Field: name, Message: "must be a string"}
+			}
+			res.Task = &task
+		case "completed":
+			completed, ok := after.(bool)
+			if !ok {
+				return TodoPatch{}, &ValidationError{Field: name, Message: "must be a boolean"}
+			}
+			res.Completed = &completed
+		case "list_id":
+			// a removed list_id takes the todo out of its list
+			listId := int32(0)
+			if isPresent && after != nil {
+				number, ok := after.(float64)
+				if !ok || number != float64(int32(number)) || number < 1 {
+					return TodoPatch{}, &ValidationError{Field: name, Message: "must be the id of a list"}
+				}
+				listId = int32(number)
+			}
+			res.ListId = &listId
+		case "tags":
+			// removed tags, or null, remove all the tags of the todo
+			tags := make([]string, 0)
+			if isPresent && after != nil {
+				values, ok := after.([]interface{})
+				if !ok {
+					return TodoPatch{}, &ValidationError{Field: name, Message: "must be an array of strings"}
+				}
+				for _, value := range values {
+					tag, ok := value.(string)
+					if !ok {
+						return TodoPatch{}, &ValidationError{Field: name, Message: "must be an array of strings"}
+					}
+					tags = append(tags, tag)
+				}
+			}
+			res.Tags = &tags
+		case "due_at":
+			// a removed due_at, or null, removes the due date of the todo
+			dueAt, err := getPatchTime(name, isPresent, after)
+			if err != nil {
+				return TodoPatch{}, err
+			}
+			res.DueAt = &dueAt
+		case "remind_at":
+			// a removed remind_at, or null, sends the reminder of the todo at its due date
+			remindAt, err := getPatchTime(name, isPresent, after)
+			if err != nil {
+				return TodoPatch{}, err
+			}
+			res.RemindAt = &remindAt
+		case "priority":
+			// a removed priority, or null, removes the priority of the todo
+			priority := int32(0)
+			if isPresent && after != nil {
+				number, ok := after.(float64)
+				if !ok || number != float64(int32(number)) || number < MinPriority || number > MaxPriority {
+					return TodoPatch{}, &ValidationError{Field: name, Message: fmt.Sprintf("must be between %d and %d", MinPriority, MaxPriority)}
+				}
+				priority = int32(number)
+			}
+			res.Priority = &priority
+		case "recurrence":
+			// a removed recurrence, or null, stops the recurrence of the todo
+			recurrence := ""
+			if isPresent && after != nil {
+				value, ok := after.(string)
+				if !ok || value == "" {
+					return TodoPatch{}, &ValidationError{Field: name, Message: "must be a recurrence rule"}
+				}
+				recurrence = value
+			}
+			res.Recurrence = &recurrence
+		case "parent_id":
+			// a removed parent_id makes the todo a root todo
+			parentId := int32(0)
+			if isPresent && after != nil {
+				number, ok := after.(float64)
+				if !ok || number != float64(int32(number)) || number < 1 {
+					return TodoPatch{}, &ValidationError{Field: name, Message: "must be the id of a todo"}
+				}
+				parentId = int32(number)
+			}
+			res.ParentId = &parentId
+		case "auto_complete":
+			// a removed auto_complete, or null, stops the completion of the todo with its children
+			autoComplete := false
+			if isPresent && after != nil {
+				value, ok := after.(bool)
+				if !ok {
+					return TodoPatch{}, &ValidationError{Field: name, Message: "must be a boolean"}
+				}
+				autoComplete = value
+			}
+			res.AutoComplete = &autoComplete
+		case "id", "created_at", "completed_at", "updated_at", "deleted_at", "version", "owner_id", "overdue", "series_id",
+			"children_count", "children_completed", "children", "blocked":
+			return TodoPatch{}, &ValidationError{Field: name, Message: "cannot be modified"}
+		default:
+			return TodoPatch{}, &ValidationError{Field: name, Message: "is not a field of todo"}
+		}
+	}
+	return res, nil
+}
+
+// getPatchTime returns the value of a date-time field of the patched document, the zero time when it is removed or null
+func getPatchTime(name string, isPresent bool, after interface{}) (time.Time, error) {
+	if !isPresent || after == nil {
+		return time.Time{}, nil
+	}
+	value, ok := after.(string)
+	if !ok {
+		return time.Time{}, &ValidationError{Field: name, Message: "must be a date-time"}
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil || parsed.IsZero() {
+		return time.Time{}, &ValidationError{Field: name, Message: "must be a date-time"}
+	}
+	return parsed, nil
+}
@@ -0,0 +1,230 @@
+package todos
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// newPatchTestDocument returns the document of a todo with a due date and a priority, the patches are applied on it
+func newPatchTestDocument(t *testing.T) todoDocument {
+	dueAt := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+	priority := int32(2)
+	doc, err := newTodoDocument(&Todo{Id: 1, Task: "water the plants", DueAt: &dueAt, Priority: &priority, Version: 1})
+	if err != nil {
+		t.Fatalf("error getting the document of the todo. error : %v ", err)
+	}
+	return doc
+}
+
+func TestParseJSONPointer(t *testing.T) {
+	tests := []struct {
+		name    string
+		pointer string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "should return the name of a field",
+			pointer: "/task",
+			want:    "task",
+		},
+		{
+			name:    "should unescape ~1 to / and ~0 to ~",
+			pointer: "/a~1b~0c",
+			want:    "a/b~c",
+		},
+		{
+			name:    "should unescape ~01 to ~1",
+			pointer: "/a~01",
+			want:    "a~1",
+		},
+		{
+			name:    "should reject a pointer without leading /",
+			pointer: "task",
+			wantErr: true,
+		},
+		{
+			name:    "should reject the pointer of a nested value",
+			pointer: "/tags/0",
+			wantErr: true,
+		},
+		{
+			name:    "should reject the invalid escape ~2",
+			pointer: "/ta~2sk",
+			wantErr: true,
+		},
+		{
+			name:    "should reject a ~ at the end",
+			pointer: "/task~",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseJSONPointer(tt.pointer)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrValidation)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    map[string]interface{}
+		wantErr error
+	}{
+		{
+			name: "should apply the operations when the test operation succeeds",
+			body: `[{"op":"test","path":"/task","value":"water the plants"},{"op":"replace","path":"/task","value":"water the roses"}]`,
+			want: map[string]interface{}{"task": "water the roses"},
+		},
+		{
+			name:    "should return a conflict when the test operation fails",
+			body:    `[{"op":"test","path":"/task","value":"pay the taxes"},{"op":"replace","path":"/task","value":"water the roses"}]`,
+			wantErr: ErrConflict,
+		},
+		{
+			name:    "should return a conflict when the test operation is on a missing path",
+			body:    `[{"op":"test","path":"/recurrence","value":"FREQ=DAILY"}]`,
+			wantErr: ErrConflict,
+		},
+		{
+			name: "should remove a field",
+			body: `[{"op":"remove","path":"/due_at"}]`,
+			want: map[string]interface{}{"due_at": nil},
+		},
+		{
+			name:    "should reject the remove of a missing path",
+			body:    `[{"op":"remove","path":"/recurrence"}]`,
+			wantErr: ErrValidation,
+		},
+		{
+			name:    "should reject the replace of a missing path",
+			body:    `[{"op":"replace","path":"/recurrence","value":"FREQ=DAILY"}]`,
+			wantErr: ErrValidation,
+		},
+		{
+			name: "should add a missing field",
+			body: `[{"op":"add","path":"/recurrence","value":"FREQ=DAILY"}]`,
+			want: map[string]interface{}{"recurrence": "FREQ=DAILY"},
+		},
+		{
+			name: "should move a field",
+			body: `[{"op":"move","from":"/due_at","path":"/remind_at"}]`,
+			want: map[string]interface{}{"due_at": nil, "remind_at": "2030-01-07T09:00:00Z"},
+		},
+		{
+			name:    "should reject the move from a missing path",
+			body:    `[{"op":"move","from":"/recurrence","path":"/task"}]`,
+			wantErr: ErrValidation,
+		},
+		{
+			name:    "should reject a path with the invalid escape ~2",
+			body:    `[{"op":"add","path":"/ta~2sk","value":"water the roses"}]`,
+			wantErr: ErrValidation,
+		},
+		{
+			name:    "should reject an unknown op",
+			body:    `[{"op":"merge","path":"/task","value":"water the roses"}]`,
+			wantErr: ErrValidation,
+		},
+		{
+			name:    "should reject a patch that is not an array",
+			body:    `{"op":"remove","path":"/due_at"}`,
+			wantErr: ErrValidation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := newPatchTestDocument(t)
+			err := applyJSONPatch(doc, []byte(tt.body))
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "applyJSONPatch() error = %v, want %v", err, tt.wantErr)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			for name, want := range tt.want {
+				value, exist := doc[name]
+				if want == nil {
+					assert.False(t, exist, "the field %s should be removed", name)
+				} else {
+					assert.Equal(t, want, value, "the field %s should be changed", name)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    TodoPatch
+		wantErr bool
+	}{
+		{
+			name: "should change a field",
+			body: `{"priority":1}`,
+			want: TodoPatch{Priority: func() *int32 { p := int32(1); return &p }()},
+		},
+		{
+			name: "should clear the due date with null",
+			body: `{"due_at":null}`,
+			want: TodoPatch{DueAt: &time.Time{}},
+		},
+		{
+			name: "should clear the priority with null",
+			body: `{"priority":null}`,
+			want: TodoPatch{Priority: func() *int32 { p := int32(0); return &p }()},
+		},
+		{
+			name: "should not change a field missing in the todo with null",
+			body: `{"recurrence":null}`,
+			want: TodoPatch{},
+		},
+		{
+			name:    "should reject null for the task",
+			body:    `{"task":null}`,
+			wantErr: true,
+		},
+		{
+			name:    "should reject a patch that is not an object",
+			body:    `["task"]`,
+			wantErr: true,
+		},
+		{
+			name:    "should reject invalid json",
+			body:    `{"task":`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := newPatchTestDocument(t)
+			patched := newPatchTestDocument(t)
+			err := applyMergePatch(patched, []byte(tt.body))
+			var got TodoPatch
+			if err == nil {
+				got, err = getTodoPatch(original, patched)
+			}
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrValidation)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
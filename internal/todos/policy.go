@@ -0,0 +1,199 @@
+package todos
+
+import (
+	"context"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+// Permission is an action allowed to the roles of the users, the operations of the API each require one
+type Permission string
+
+const (
+	PermissionTodosRead   Permission = "todos:read"
+	PermissionTodosWrite  Permission = "todos:write"
+	PermissionTodosDelete Permission = "todos:delete"
+	PermissionAdmin       Permission = "admin"
+)
+
+// rolePermissions are the permissions granted to each role of the users
+var rolePermissions = map[UserRole][]Permission{
+	UserRoleViewer: {PermissionTodosRead},
+	UserRoleEditor: {PermissionTodosRead, PermissionTodosWrite, PermissionTodosDelete},
+	UserRoleAdmin:  {PermissionTodosRead, PermissionTodosWrite, PermissionTodosDelete, PermissionAdmin},
+}
+
+// permissionScopes are the scopes an API key needs to use each permission of its user
+var permissionScopes = map[Permission]ApiKeyScope{
+	PermissionTodosRead:   ApiKeyScopeTodosRead,
+	PermissionTodosWrite:  ApiKeyScopeTodosWrite,
+	PermissionTodosDelete: ApiKeyScopeTodosWrite,
+	PermissionAdmin:       ApiKeyScopeAdmin,
+}
+
+// Policy is the permission required to call an operation of the ServerInterface on its route
+type Policy struct {
+	Operation  string
+	Method     string
+	Path       string
+	Permission Permission
+}
+
+// Policies are the policies of all the operations, a route without policy cannot be registered by NewAuthorizedRouter
+var Policies = []Policy{
+	{Operation: "GetApiKeys", Method: http.MethodGet, Path: "/admin/apikeys", Permission: PermissionAdmin},
+	{Operation: "CreateApiKey", Method: http.MethodPost, Path: "/admin/apikeys", Permission: PermissionAdmin},
+	{Operation: "RevokeApiKey", Method: http.MethodDelete, Path: "/admin/apikeys/:apiKeyId", Permission: PermissionAdmin},
+	{Operation: "GetEvents", Method: http.MethodGet, Path: "/events", Permission: PermissionTodosRead},
+	{Operation: "GetLists", Method: http.MethodGet, Path: "/lists", Permission: PermissionTodosRead},
+	{Operation: "CreateList", Method: http.MethodPost, Path: "/lists", Permission: PermissionTodosWrite},
+	{Operation: "DeleteList", Method: http.MethodDelete, Path: "/lists/:listId", Permission: PermissionTodosDelete},
+	{Operation: "GetList", Method: http.MethodGet, Path: "/lists/:listId", Permission: PermissionTodosRead},
+	{Operation: "UpdateList", Method: http.MethodPut, Path: "/lists/:listId", Permission: PermissionTodosWrite},
+	{Operation: "GetListTodos", Method: http.MethodGet, Path: "/lists/:listId/todos", Permission: PermissionTodosRead},
+	{Operation: "CreateListTodo", Method: http.MethodPost, Path: "/lists/:listId/todos", Permission: PermissionTodosWrite},
+	{Operation: "GetTags", Method: http.MethodGet, Path: "/tags", Permission: PermissionTodosRead},
+	{Operation: "RenameTag", Method: http.MethodPut, Path: "/tags/:tagName", Permission: PermissionTodosWrite},
+	{Operation: "GetTodos", Method: http.MethodGet, Path: "/todos", Permission: PermissionTodosRead},
+	{Operation: "CreateTodo", Method: http.MethodPost, Path: "/todos", Permission: PermissionTodosWrite},
+	{Operation: "GetMaxId", Method: http.MethodGet, Path: "/todos/maxid", Permission: PermissionTodosRead},
+	{Operation: "GetOverdueTodos", Method: http.MethodGet, Path: "/todos/overdue", Permission: PermissionTodosRead},
+	{Operation: "GetReadyTodos", Method: http.MethodGet, Path: "/todos/ready", Permission: PermissionTodosRead},
+	{Operation: "SearchTodos", Method: http.MethodGet, Path: "/todos/search", Permission: PermissionTodosRead},
+	{Operation: "GetTrash", Method: http.MethodGet, Path: "/todos/trash", Permission: PermissionTodosRead},
+	{Operation: "GetUpcomingTodos", Method: http.MethodGet, Path: "/todos/upcoming", Permission: PermissionTodosRead},
+	{Operation: "DeleteTodo", Method: http.MethodDelete, Path: "/todos/:todoId", Permission: PermissionTodosDelete},
+	{Operation: "GetTodo", Method: http.MethodGet, Path: "/todos/:todoId", Permission: PermissionTodosRead},
+	{Operation: "PatchTodo", Method: http.MethodPatch, Path: "/todos/:todoId", Permission: PermissionTodosWrite},
+	{Operation: "UpdateTodo", Method: http.MethodPut, Path: "/todos/:todoId", Permission: PermissionTodosWrite},
+	{Operation: "GetTodoChildren", Method: http.MethodGet, Path: "/todos/:todoId/children", Permission: PermissionTodosRead},
+	{Operation: "GetTodoDependencies", Method: http.MethodGet, Path: "/todos/:todoId/dependencies", Permission: PermissionTodosRead},
+	{Operation: "AddTodoDependency", Method: http.MethodPost, Path: "/todos/:todoId/dependencies", Permission: PermissionTodosWrite},
+	{Operation: "RemoveTodoDependency", Method: http.MethodDelete, Path: "/todos/:todoId/dependencies/:blockerId", Permission: PermissionTodosWrite},
+	{Operation: "GetTodoHistory", Method: http.MethodGet, Path: "/todos/:todoId/history", Permission: PermissionTodosRead},
+	{Operation: "RestoreTodo", Method: http.MethodPost, Path: "/todos/:todoId/restore", Permission: PermissionTodosWrite},
+	{Operation: "StopTodoSeries", Method: http.MethodDelete, Path: "/todos/:todoId/series", Permission: PermissionTodosWrite},
+	{Operation: "GetTodoSeries", Method: http.MethodGet, Path: "/todos/:todoId/series", Permission: PermissionTodosRead},
+	{Operation: "UpdateTodoSeries", Method: http.MethodPut, Path: "/todos/:todoId/series", Permission: PermissionTodosWrite},
+	{Operation: "SnoozeTodo", Method: http.MethodPost, Path: "/todos/:todoId/snooze", Permission: PermissionTodosWrite},
+	{Operation: "BatchTodos", Method: http.MethodPost, Path: "/todos\\:batch", Permission: PermissionTodosWrite},
+	{Operation: "GetUsers", Method: http.MethodGet, Path: "/users", Permission: PermissionAdmin},
+	{Operation: "CreateUser", Method: http.MethodPost, Path: "/users", Permission: PermissionAdmin},
+}
+
+// getPolicy returns the policy of the operation, or of the route when operation is empty
+func getPolicy(operation, method, path string) (Policy, bool) {
+	for _, policy := range Policies {
+		if policy.Operation == operation || (operation == "" && policy.Method == method && policy.Path == path) {
+			return policy, true
+		}
+	}
+	return Policy{}, false
+}
+
+// hasPermission returns true only if the role of the user making the request grants the permission,
+// and the request is not made with an API key without the scope of the permission
+func hasPermission(ctx context.Context, permission Permission) bool {
+	return checkPermission(ctx, "", permission) == nil
+}
+
+// checkPermission returns the 403 Forbidden AccessDenied error when the user making the request cannot use the permission
+func checkPermission(ctx context.Context, operation string, permission Permission) error {
+	denied := AccessDenied{Code: http.StatusForbidden, Operation: operation, Permission: string(permission)}
+	user := UserFromContext(ctx)
+	if user == nil {
+		denied.Message = fmt.Sprintf("%s requires an authenticated user", operation)
+		return echo.NewHTTPError(http.StatusForbidden, denied)
+	}
+	denied.Role = &user.Role
+	granted := false
+	for _, p := range rolePermissions[user.Role] {
+		if p == permission {
+			granted = true
+			break
+		}
+	}
+	if !granted {
+		denied.Message = fmt.Sprintf("%s requires the %s permission, the %s role does not have it", operation, permission, user.Role)
+		return echo.NewHTTPError(http.StatusForbidden, denied)
+	}
+	if scope := permissionScopes[permission]; !hasScope(ctx, scope) {
+		denied.Message = fmt.Sprintf("%s requires the %s permission, the API key does not have the %s scope", operation, permission, scope)
+		return echo.NewHTTPError(http.StatusForbidden, denied)
+	}
+	return nil
+}
+
+// Authorize returns the route middleware rejecting with 403 the requests of the users who cannot call the operation,
+// it panics if the operation has no policy
+func Authorize(operation string) echo.MiddlewareFunc {
+	policy, exist := getPolicy(operation, "", "")
+	if !exist {
+		panic(fmt.Sprintf("todos.Authorize: operation %s has no policy", operation))
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if err := checkPermission(ctx.Request().Context(), policy.Operation, policy.Permission); err != nil {
+				return err
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// authorizedRouter is an EchoRouter adding to every route the Authorize middleware of its policy
+type authorizedRouter struct {
+	router EchoRouter
+}
+
+// NewAuthorizedRouter returns the router to give to RegisterHandlers so the Policies are enforced on every route,
+// registering a route without policy panics
+func NewAuthorizedRouter(router EchoRouter) EchoRouter {
+	return authorizedRouter{router: router}
+}
+
+// authorized prepends the Authorize middleware of the route to its middlewares
+func (r authorizedRouter) authorized(method, path string, m []echo.MiddlewareFunc) []echo.MiddlewareFunc {
+	policy, exist := getPolicy("", method, path)
+	if !exist {
+		panic(fmt.Sprintf("todos.NewAuthorizedRouter: route %s %s has no policy", method, path))
+	}
+	return append([]echo.MiddlewareFunc{Authorize(policy.Operation)}, m...)
+}
+
+func (r authorizedRouter) CONNECT(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.CONNECT(path, h, r.authorized(http.MethodConnect, path, m)...)
+}
+
+func (r authorizedRouter) DELETE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.DELETE(path, h, r.authorized(http.MethodDelete, path, m)...)
+}
+
+func (r authorizedRouter) GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.GET(path, h, r.authorized(http.MethodGet, path, m)...)
+}
+
+func (r authorizedRouter) HEAD(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.HEAD(path, h, r.authorized(http.MethodHead, path, m)...)
+}
+
+func (r authorizedRouter) OPTIONS(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.OPTIONS(path, h, r.authorized(http.MethodOptions, path, m)...)
+}
+
+func (r authorizedRouter) PATCH(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.PATCH(path, h, r.authorized(http.MethodPatch, path, m)...)
+}
+
+func (r authorizedRouter) POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.POST(path, h, r.authorized(http.MethodPost, path, m)...)
+}
+
+func (r authorizedRouter) PUT(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.PUT(path, h, r.authorized(http.MethodPut, path, m)...)
+}
+
+func (r authorizedRouter) TRACE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.TRACE(path, h, r.authorized(http.MethodTrace, path, m)...)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"log"
@@ -12,19 +13,190 @@ import (
 )
 
 const (
-	getPGVersion    = "SELECT version();"
-	todosList       = "SELECT id, task, completed, created_at, completed_at FROM todos ORDER BY id;"
-	todosGet        = "SELECT id, task, completed, created_at, completed_at FROM todos WHERE id=$1;"
-	todosCompleted  = "SELECT completed FROM todos WHERE id=$1"
-	todosExist      = "SELECT COUNT(*) FROM todos WHERE id=$1"
-	todosCount      = "SELECT COUNT(*) FROM todos"
-	todosMaxId      = "SELECT MAX(id) FROM todos"
-	todosCreate     = "INSERT INTO todos (task) VALUES($1) RETURNING id;"
-	todosUpdate     = "UPDATE todos SET task=$1, completed=$2, completed_at=$3 WHERE id=$4"
-	todosUpdateTask = "UPDATE todos SET task=$1 WHERE id=$2"
-	todosDelete     = "DELETE FROM todos WHERE id = $1"
+	getPGVersion = "SELECT version();"
+	todoColumns  = "id, task, completed, created_at, completed_at, updated_at, deleted_at, version, owner_id, list_id, due_at, priority, recurrence, series_id, parent_id, auto_complete, remind_at"
+	todosSelect  = "SELECT " + todoColumns + " FROM todos"
+	todosGet     = todosSelect + " WHERE id=$1 AND ($2 = 0 OR owner_id = $2);"
+	todosLock    = todosSelect + " WHERE id=$1 AND ($2 = 0 OR owner_id = $2) FOR UPDATE;"
+	todosExist   = "SELECT COUNT(*) FROM todos WHERE id=$1 AND deleted_at IS NULL AND ($2 = 0 OR owner_id = $2)"
+	todosCount   = "SELECT COUNT(*) FROM todos"
+	todosMaxId   = "SELECT COALESCE(MAX(id), 0) FROM todos WHERE ($1 = 0 OR owner_id = $1)"
+	todosCreate  = "INSERT INTO todos (task, owner_id, list_id, due_at, priority, recurrence, series_id, parent_id, auto_complete, remind_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, now()) RETURNING " + todoColumns + ";"
+	todosPurge   = "DELETE FROM todos WHERE id = $1 AND ($2 = 0 OR version = $2) RETURNING id;"
+	todosTrashed = "SELECT id FROM todos WHERE deleted_at < $1 AND ($2 = 0 OR owner_id = $2) ORDER BY id FOR UPDATE;"
+	// todos imported without updated_at were last changed when they were completed or created
+	todosFingerprint = `SELECT COALESCE(MAX(id), 0) AS max_id, COUNT(*) AS count,
+       MAX(COALESCE(updated_at, completed_at, created_at)) AS last_modified,
+       COUNT(CASE WHEN NOT completed AND due_at < $2 THEN 1 END) AS overdue,
+       (SELECT COUNT(*) FROM todo_dependencies d JOIN todos t ON t.id = d.todo_id WHERE $1 = 0 OR t.owner_id = $1) AS dependencies,
+       (SELECT MAX(d.created_at) FROM todo_dependencies d JOIN todos t ON t.id = d.todo_id WHERE $1 = 0 OR t.owner_id = $1) AS dependencies_modified
+FROM todos WHERE ($1 = 0 OR owner_id = $1);`
+	// todosPatch changes only the fields whose parameter is not NULL and implements the completed_at business rule
+	// in a single statement : it is set when the todo becomes completed, cleared when it is not completed anymore
+	// and left untouched otherwise. the row is only updated if $4 is 0 or the current version of the todo.
+	// the todo is removed from its list when $5 is 0, its due date is set to $7 only when $6 is true,
+	// its priority is removed when $8 is 0 and its recurrence is stopped when $9 is empty.
+	// a todo joins its own series the first time it gets a recurrence rule.
+	// it becomes a root todo when $10 is 0, and it is not completed with its children anymore when $11 is false.
+	// its reminder time is set to $13 only when $12 is true.
+	todosPatch = `UPDATE todos SET task = COALESCE($1, task), completed = COALESCE($2, completed),
+    completed_at = CASE WHEN COALESCE($2, completed) AND NOT completed THEN now()
+        WHEN NOT COALESCE($2, completed) THEN NULL ELSE completed_at END,
+    list_id = CASE WHEN $5::integer IS NULL THEN list_id WHEN $5::integer = 0 THEN NULL ELSE $5::integer END,
+    due_at = CASE WHEN $6::boolean THEN $7::timestamptz ELSE due_at END,
+    priority = CASE WHEN $8::integer IS NULL THEN priority WHEN $8::integer = 0 THEN NULL ELSE $8::integer END,
+    recurrence = CASE WHEN $9::text IS NULL THEN recurrence WHEN $9::text = '' THEN NULL ELSE $9::text END,
+    series_id = COALESCE(series_id, CASE WHEN NULLIF($9::text, '') IS NOT NULL THEN id END),
+    parent_id = CASE WHEN $10::integer IS NULL THEN parent_id WHEN $10::integer = 0 THEN NULL ELSE $10::integer END,
+    auto_complete = CASE WHEN $11::boolean IS NULL THEN auto_complete WHEN $11::boolean THEN true END,
+    remind_at = CASE WHEN $12::boolean THEN $13::timestamptz ELSE remind_at END,
+    updated_at = now(), version = version + 1
+WHERE id=$3 AND deleted_at IS NULL AND ($4 = 0 OR version = $4) RETURNING ` + todoColumns + ";"
+	// todosDelete moves a live todo to the trash, todosRestore moves it back
+	todosDelete = `UPDATE todos SET deleted_at = now(), updated_at = now(), version = version + 1
+WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2) RETURNING ` + todoColumns + ";"
+	todosRestore = `UPDATE todos SET deleted_at = NULL, updated_at = now(), version = version + 1
+WHERE id = $1 AND deleted_at IS NOT NULL AND ($2 = 0 OR version = $2) RETURNING ` + todoColumns + ";"
 )
 
+// the occurrences of a recurring todo share the id of the first one as series_id
+const (
+	// todosStartSeries makes a new recurring todo the first occurrence of its series
+	todosStartSeries = "UPDATE todos SET series_id = id WHERE id = $1 RETURNING " + todoColumns + ";"
+	todosOpenSeries  = "SELECT id FROM todos WHERE series_id = $1 AND deleted_at IS NULL AND NOT completed ORDER BY id FOR UPDATE;"
+)
+
+// the children of a todo have its id as parent_id, the trees are walked with recursive queries
+const (
+	// todosParentCheck walks up the ancestors of the live todo $1 owned by $2, found is 0 when this todo cannot be a parent
+	// and cycles is greater than 0 when $3 is one of the ancestors
+	todosParentCheck = `WITH RECURSIVE ancestors(id, parent_id) AS (
+    SELECT id, parent_id FROM todos WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL
+    UNION
+    SELECT t.id, t.parent_id FROM todos t JOIN ancestors a ON t.id = a.parent_id
+)
+SELECT COUNT(*) AS found, COUNT(CASE WHEN id = $3 THEN 1 END) AS cycles FROM ancestors;`
+	// todosChildren returns the children of $1, even the ones in the trash when $2 is true, and locks them
+	todosChildren = todosSelect + " WHERE parent_id = $1 AND ($2 OR deleted_at IS NULL) ORDER BY id FOR UPDATE;"
+	// todosReparent moves a todo to another parent, even if it is in the trash
+	todosReparent = "UPDATE todos SET parent_id = $2, updated_at = now(), version = version + 1 WHERE id = $1 RETURNING " + todoColumns + ";"
+)
+
+// a todo is blocked by the todos of its rows in todo_dependencies, they are removed with the todos
+const (
+	todosOpenBlockers = `SELECT b.id FROM todo_dependencies d JOIN todos b ON b.id = d.blocker_id
+WHERE d.todo_id = $1 AND b.deleted_at IS NULL AND NOT b.completed ORDER BY b.id;`
+	todosBlockers = todosSelect + " WHERE id IN (SELECT blocker_id FROM todo_dependencies WHERE todo_id = $1) AND deleted_at IS NULL AND ($2 = 0 OR owner_id = $2) ORDER BY id;"
+	// todosDependsOn walks the blockers of the todo $1 and of their own blockers, found is greater than 0 when $2 is one of them
+	todosDependsOn = `WITH RECURSIVE blockers(id) AS (
+    SELECT blocker_id FROM todo_dependencies WHERE todo_id = $1
+    UNION
+    SELECT d.blocker_id FROM todo_dependencies d JOIN blockers b ON d.todo_id = b.id
+)
+SELECT COUNT(*) AS found FROM blockers WHERE id = $2;`
+	todosDependencyAdd    = "INSERT INTO todo_dependencies (todo_id, blocker_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;"
+	todosDependencyRemove = "DELETE FROM todo_dependencies WHERE todo_id = $1 AND blocker_id = $2 AND todo_id IN (SELECT id FROM todos WHERE $3 = 0 OR owner_id = $3);"
+)
+
+// reminded_at is the reminder time, remind_at or else due_at, of the last reminder sent for a todo
+const (
+	// todosRemindersClaim records as sent the reminders reached at $1 and returns the todos, the rows locked by
+	// another server are skipped so every reminder is claimed by a single server
+	todosRemindersClaim = `UPDATE todos SET reminded_at = COALESCE(remind_at, due_at)
+WHERE id IN (SELECT id FROM todos WHERE deleted_at IS NULL AND NOT completed AND COALESCE(remind_at, due_at) <= $1
+        AND reminded_at IS DISTINCT FROM COALESCE(remind_at, due_at) AND ($3 = 0 OR owner_id = $3)
+    ORDER BY COALESCE(remind_at, due_at), id LIMIT $2 FOR UPDATE SKIP LOCKED)
+RETURNING ` + todoColumns + ";"
+	todosReminderRelease = "UPDATE todos SET reminded_at = NULL WHERE id = $1 AND reminded_at = $2 AND ($3 = 0 OR owner_id = $3);"
+)
+
+// the todos of the events are stored in jsonb, they are read as text to be decoded like the other stores
+const (
+	todoEventColumns = "id, todo_id, owner_id, type, actor, occurred_at, before::text AS before, after::text AS after"
+	todoEventsInsert = `INSERT INTO todo_events (todo_id, owner_id, type, actor, occurred_at, before, after)
+VALUES($1, $2, $3, $4, $5, $6::jsonb, $7::jsonb);`
+	todoEventsHistory = "SELECT " + todoEventColumns + " FROM todo_events WHERE todo_id = $1 AND ($2 = 0 OR owner_id = $2) ORDER BY id;"
+	todoEventsSince   = "SELECT " + todoEventColumns + " FROM todo_events WHERE occurred_at >= $1 AND ($3 = 0 OR owner_id = $3) ORDER BY id LIMIT $2;"
+)
+
+const (
+	userColumns = "id, login, name, role, role = 'admin' AS is_admin, created_at"
+	usersCreate = "INSERT INTO users (login, name, role) VALUES($1, $2, $3) RETURNING " + userColumns + ";"
+	usersGet    = "SELECT " + userColumns + " FROM users WHERE login = $1;"
+	usersList   = "SELECT " + userColumns + " FROM users ORDER BY id;"
+)
+
+// the lists are read with the number of their todos and of their completed todos, the todos in the trash are not counted
+const (
+	listColumns = `l.id, l.name, l.owner_id, l.created_at, l.updated_at,
+       (SELECT COUNT(*) FROM todos t WHERE t.list_id = l.id AND t.deleted_at IS NULL) AS todos_count,
+       (SELECT COUNT(*) FROM todos t WHERE t.list_id = l.id AND t.deleted_at IS NULL AND t.completed) AS completed_count`
+	listsSelect = "SELECT " + listColumns + " FROM lists l"
+	listsGet    = listsSelect + " WHERE l.id = $1 AND ($2 = 0 OR l.owner_id = $2);"
+	listsList   = listsSelect + " WHERE ($1 = 0 OR l.owner_id = $1) ORDER BY l.id;"
+	listsOwned  = "SELECT COUNT(*) FROM lists WHERE id = $1 AND owner_id = $2"
+	listsCreate = "INSERT INTO lists (name, owner_id) VALUES($1, $2) RETURNING id;"
+	listsUpdate = "UPDATE lists SET name = $2, updated_at = now() WHERE id = $1 AND ($3 = 0 OR owner_id = $3) RETURNING id;"
+	listsLock   = "SELECT id FROM lists WHERE id = $1 AND ($2 = 0 OR owner_id = $2) FOR UPDATE;"
+	listsTodos  = "SELECT id FROM todos WHERE list_id = $1 ORDER BY id FOR UPDATE;"
+	listsDelete = "DELETE FROM lists WHERE id = $1;"
+)
+
+// the tags of a user are shared by its todos, only the tags of the live todos are listed and counted
+const (
+	tagsList = `SELECT tg.name, COUNT(*) AS todos_count FROM tags tg
+    JOIN todo_tags tt ON tt.tag_id = tg.id JOIN todos t ON t.id = tt.todo_id
+WHERE t.deleted_at IS NULL AND ($1 = 0 OR tg.owner_id = $1) GROUP BY tg.name ORDER BY tg.name;`
+	tagsCount = `SELECT COUNT(*) FROM tags tg JOIN todo_tags tt ON tt.tag_id = tg.id JOIN todos t ON t.id = tt.todo_id
+WHERE tg.name = $1 AND t.deleted_at IS NULL AND ($2 = 0 OR tg.owner_id = $2)`
+	tagsTodos     = "SELECT tt.todo_id FROM todo_tags tt JOIN tags tg ON tg.id = tt.tag_id WHERE tg.name = $1 AND ($2 = 0 OR tg.owner_id = $2) ORDER BY tt.todo_id;"
+	tagsCreate    = "INSERT INTO tags (owner_id, name) VALUES($1, $2) ON CONFLICT (owner_id, name) DO NOTHING;"
+	tagsDelete    = "DELETE FROM tags WHERE name = $1 AND ($2 = 0 OR owner_id = $2);"
+	todoTagsClear = "DELETE FROM todo_tags WHERE todo_id = $1;"
+	todoTagsAdd   = "INSERT INTO todo_tags (todo_id, tag_id) SELECT $1, id FROM tags WHERE owner_id = $2 AND name = $3;"
+	// todosTouch records a change of the tags of a todo, even if it is in the trash
+	todosTouch = "UPDATE todos SET updated_at = now(), version = version + 1 WHERE id = $1 RETURNING " + todoColumns + ";"
+)
+
+// the API keys are read with the login of the user they act for, their scopes are separated by spaces
+const (
+	apiKeyColumns = "k.id, k.name, k.prefix, u.login, k.scopes, k.created_at, k.expires_at, k.last_used_at"
+	apiKeysSelect = "SELECT " + apiKeyColumns + " FROM api_keys k JOIN users u ON u.id = k.user_id"
+	apiKeysCreate = `INSERT INTO api_keys (name, prefix, hash, user_id, scopes, expires_at)
+SELECT $1, $2, $3, id, $5, $6 FROM users WHERE login = $4 RETURNING id;`
+	apiKeysGet    = apiKeysSelect + " WHERE k.id = $1;"
+	apiKeysByHash = apiKeysSelect + " WHERE k.hash = $1;"
+	apiKeysList   = apiKeysSelect + " ORDER BY k.id;"
+	apiKeysRevoke = "DELETE FROM api_keys WHERE id = $1;"
+	apiKeysTouch  = "UPDATE api_keys SET last_used_at = $2 WHERE id = $1;"
+)
+
+// todosSearch returns the todos matching the query, the rank and the headline are computed with the simple text search configuration
+const todosSearch = `SELECT id, task, completed, created_at, completed_at, updated_at, version, owner_id, list_id, due_at, priority,
+       recurrence, series_id, parent_id, auto_complete, remind_at,
+       ts_rank(task_tsv, query) AS rank, ts_headline('simple', task, query) AS headline
+FROM todos, plainto_tsquery('simple', $1) query
+WHERE task_tsv @@ query AND deleted_at IS NULL AND ($3 = 0 OR owner_id = $3)
+ORDER BY rank DESC, id
+LIMIT $2;`
+
+// pgUniqueViolation is the postgres error code of a unique constraint violation
+const pgUniqueViolation = "23505"
+
+// pgError converts the postgres errors having a meaning for the callers to the errors of the Storage interface
+func pgError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return fmt.Errorf("%w : %s", ErrConflict, pgErr.Detail)
+	}
+	return GetErrorF("error : todos could not be saved", err)
+}
+
+// pgTimeValue converts a time for the postgres date columns, they are all stored with time zone and the times are sent in UTC
+func pgTimeValue(t time.Time) interface{} {
+	return t.UTC()
+}
+
 type PGX struct {
 	Conn *pgxpool.Pool
 	log  *log.Logger
@@ -79,8 +251,8 @@ func NewPgxDB(dbConnectionString string, maxConnectionsInPool int, log *log.Logg
 }
 
 // getQueryInt is a postgres helper function for a query expecting an integer result
-func (db *PGX) getQueryInt(sql string, arguments ...interface{}) (result int, err error) {
-	err = db.Conn.QueryRow(context.Background(), sql, arguments...).Scan(&result)
+func (db *PGX) getQueryInt(ctx context.Context, sql string, arguments ...interface{}) (result int, err error) {
+	err = db.Conn.QueryRow(ctx, sql, arguments...).Scan(&result)
 	if err != nil {
 		db.log.Printf("error : getQueryInt(%s) queryRow unexpectedly failed. args : (%v), error : %v\n", sql, arguments, err)
 		return 0, err
@@ -88,19 +260,9 @@ func (db *PGX) getQueryInt(sql string, arguments ...interface{}) (result int, er
 	return result, err
 }
 
-// getQueryBool is a postgres helper function for a query expecting an integer result
-func (db *PGX) getQueryBool(sql string, arguments ...interface{}) (result bool, err error) {
-	err = db.Conn.QueryRow(context.Background(), sql, arguments...).Scan(&result)
-	if err != nil {
-		db.log.Printf("error : getQueryBool(%s) queryRow unexpectedly failed. args : (%v), error : %v\n", sql, arguments, err)
-		return false, err
-	}
-	return result, err
-}
-
 // execActionQuery is a postgres helper function for an action query, returning the numbers of rows affected
-func (db *PGX) execActionQuery(sql string, arguments ...interface{}) (rowsAffected int, err error) {
-	commandTag, err := db.Conn.Exec(context.Background(), sql, arguments...)
+func (db *PGX) execActionQuery(ctx context.Context, sql string, arguments ...interface{}) (rowsAffected int, err error) {
+	commandTag, err := db.Conn.Exec(ctx, sql, arguments...)
 	if err != nil {
 		db.log.Printf("execActionQuery unexpectedly failed with sql: %v . Args(%+v), error : %v", sql, arguments, err)
 		return 0, err
@@ -108,80 +270,459 @@ func (db *PGX) execActionQuery(sql string, arguments ...interface{}) (rowsAffect
 	return int(commandTag.RowsAffected()), err
 }
 
-func (db *PGX) Close() {
-	db.Conn.Close()
-	return
+// pgxQuerier is implemented by the connection pool and by a transaction, so the same statements can run in both
+type pgxQuerier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
 }
 
-//Create will store the new task in the store
-func (db *PGX) Create(todo NewTodo) (*Todo, error) {
-	db.log.Printf("info : Entering Create(%#v)", todo)
-	if len(todo.Task) < 1 {
-		return nil, errors.New("todo task cannot be empty")
+// pgxWriter is the todoWriter running the write statements in a transaction, with the event of each change
+type pgxWriter struct {
+	db *PGX
+	q  pgxQuerier
+}
+
+// lock reads the todo with given id and locks it until the end of the transaction,
+// an error is returned if a write requiring the given state and matchVersion cannot be applied to it
+func (w pgxWriter) lock(ctx context.Context, operation string, id int32, state todoState, matchVersion int32) (*Todo, error) {
+	t := &Todo{}
+	if err := pgxscan.Get(ctx, w.q, t, todosLock, id, getOwnerId(ctx)); err != nil {
+		if pgxscan.NotFound(err) {
+			w.db.log.Printf("info : %s(%d) id does not exist", operation, id)
+			return nil, ErrNotFound
+		}
+		return nil, GetErrorF("error : todos could not be read", err)
+	}
+	if err := w.db.loadTags(ctx, w.q, []*Todo{t}); err != nil {
+		return nil, err
+	}
+	if err := checkTodoState(t, state, matchVersion); err != nil {
+		w.db.log.Printf("info : %s(%d) cannot be applied : %v", operation, id, err)
+		return nil, err
+	}
+	return t, nil
+}
+
+// checkList returns the ValidationError of errUnknownList if the todos of ownerId cannot be in the list
+func (w pgxWriter) checkList(ctx context.Context, listId *int32, ownerId int32) error {
+	if listId == nil {
+		return nil
+	}
+	var count int
+	if err := w.q.QueryRow(ctx, listsOwned, *listId, ownerId).Scan(&count); err != nil {
+		return GetErrorF("error : lists could not be read", err)
+	}
+	if count < 1 {
+		return errUnknownList(*listId)
+	}
+	return nil
+}
+
+// checkParent returns the ValidationError of errUnknownParent if the todo with given id, 0 for a new todo, of ownerId
+// cannot have this parent, or the one of errParentCycle if the parent is the todo itself or one of its descendants
+func (w pgxWriter) checkParent(ctx context.Context, parentId *int32, ownerId int32, id int32) error {
+	if parentId == nil {
+		return nil
+	}
+	var found, cycles int
+	if err := w.q.QueryRow(ctx, todosParentCheck, *parentId, ownerId, id).Scan(&found, &cycles); err != nil {
+		return GetErrorF("error : todos could not be read", err)
+	}
+	if found < 1 {
+		return errUnknownParent(*parentId)
+	}
+	if cycles > 0 {
+		return errParentCycle(id, *parentId)
+	}
+	return nil
+}
+
+// setTags replaces the tags of the todo by the normalized tags, the missing tags of the owner are created
+func (w pgxWriter) setTags(ctx context.Context, todoId, ownerId int32, tags []string) error {
+	if _, err := w.q.Exec(ctx, todoTagsClear, todoId); err != nil {
+		return GetErrorF("error : tags could not be saved", err)
+	}
+	for _, tag := range tags {
+		if _, err := w.q.Exec(ctx, tagsCreate, ownerId, tag); err != nil {
+			return GetErrorF("error : tags could not be saved", err)
+		}
+		if _, err := w.q.Exec(ctx, todoTagsAdd, todoId, ownerId, tag); err != nil {
+			return GetErrorF("error : tags could not be saved", err)
+		}
+	}
+	return nil
+}
+
+// record saves the event of the change of a todo from before to after
+func (w pgxWriter) record(ctx context.Context, before, after *Todo) error {
+	event := newTodoEvent(ctx, before, after)
+	beforeJSON, err := todoJSON(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := todoJSON(after)
+	if err != nil {
+		return err
+	}
+	_, err = w.q.Exec(ctx, todoEventsInsert, event.TodoId, event.OwnerId, string(event.Type), event.Actor,
+		pgTimeValue(event.OccurredAt), beforeJSON, afterJSON)
+	if err != nil {
+		return GetErrorF("error : todo event could not be saved", err)
+	}
+	return nil
+}
+
+func (w pgxWriter) create(ctx context.Context, todo NewTodo, previous *Todo) (*Todo, error) {
+	if err := validateTask(todo.Task); err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(todo.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePriority(todo.Priority); err != nil {
+		return nil, err
+	}
+	recurrence, err := normalizeRecurrence(todo.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	ownerId, err := getNewTodoOwnerId(ctx, previous)
+	if err != nil {
+		return nil, err
+	}
+	listId := getNewTodoListId(todo)
+	if err := w.checkList(ctx, listId, ownerId); err != nil {
+		return nil, err
 	}
-	if len(todo.Task) < 6 {
-		return nil, errors.New("CreateTodo task minLength is 5")
+	parentId := getNewTodoParentId(todo)
+	if err := w.checkParent(ctx, parentId, ownerId, 0); err != nil {
+		return nil, err
 	}
-	var lastInsertId int = 0
-	err := db.Conn.QueryRow(context.Background(), todosCreate, todo.Task).Scan(&lastInsertId)
+	res := &Todo{}
+	err = pgxscan.Get(ctx, w.q, res, todosCreate, todo.Task, ownerId, listId, normalizeDueAt(todo.DueAt), todo.Priority,
+		recurrence, getNewTodoSeriesId(previous), parentId, normalizeAutoComplete(todo.AutoComplete), normalizeDueAt(todo.RemindAt))
 	if err != nil {
-		db.log.Printf("error : Create(%v) unexpectedly failed. error : %v", todo.Task, err)
+		w.db.log.Printf("error : Create(%v) unexpectedly failed. error : %v", todo.Task, err)
+		return nil, pgError(err)
+	}
+	if res.SeriesId == nil && recurrence != nil {
+		if err := pgxscan.Get(ctx, w.q, res, todosStartSeries, res.Id); err != nil {
+			return nil, pgError(err)
+		}
+	}
+	if err := w.setTags(ctx, res.Id, ownerId, tags); err != nil {
 		return nil, err
 	}
-	db.log.Printf("info : Create(%v) created with id : %v", todo.Task, lastInsertId)
+	res.Tags = tagsValue(tags)
+	w.db.log.Printf("info : Create(%v) created with id : %v", todo.Task, res.Id)
+	return res, w.record(ctx, nil, res)
+}
 
-	// if we get to here all is good, so let's retrieve a fresh copy to send it back
-	createdTodo, err := db.Get(int32(lastInsertId))
+func (w pgxWriter) patch(ctx context.Context, id int32, patch TodoPatch, matchVersion int32) (*Todo, error) {
+	// first check business rules for task field
+	if patch.Task != nil {
+		if err := validateTask(*patch.Task); err != nil {
+			return nil, err
+		}
+	}
+	tags, err := normalizeTags(patch.Tags)
 	if err != nil {
-		return nil, GetErrorF("error : todos was created, but can not be retrieved", err)
+		return nil, err
+	}
+	if err := validatePatchPriority(patch); err != nil {
+		return nil, err
+	}
+	if patch.Recurrence, err = normalizePatchRecurrence(patch); err != nil {
+		return nil, err
+	}
+	before, err := w.lock(ctx, "Patch", id, liveTodo, matchVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBlockers(ctx, w, before, patch); err != nil {
+		return nil, err
+	}
+	if err := w.checkList(ctx, getPatchListId(before.ListId, patch), before.OwnerId); err != nil {
+		return nil, err
 	}
-	return createdTodo, nil
+	if patch.ParentId != nil {
+		if err := w.checkParent(ctx, getPatchParentId(before.ParentId, patch), before.OwnerId, id); err != nil {
+			return nil, err
+		}
+	}
+	res := &Todo{}
+	err = pgxscan.Get(ctx, w.q, res, todosPatch, patch.Task, patch.Completed, id, matchVersion, patch.ListId,
+		patch.DueAt != nil, normalizeDueAt(patch.DueAt), patch.Priority, patch.Recurrence, patch.ParentId, patch.AutoComplete,
+		patch.RemindAt != nil, normalizeDueAt(patch.RemindAt))
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, errModifiedDuring("Patch", id)
+		}
+		return nil, pgError(err)
+	}
+	res.Tags = before.Tags
+	if patch.Tags != nil {
+		if err := w.setTags(ctx, id, before.OwnerId, tags); err != nil {
+			return nil, err
+		}
+		res.Tags = tagsValue(tags)
+	}
+	if err := w.record(ctx, before, res); err != nil {
+		return nil, err
+	}
+	return res, afterPatch(ctx, w, before, res)
 }
 
-func (db *PGX) List(offset, limit int) ([]*Todo, error) {
+// openOccurrences returns the ids of the live todos of the series not yet completed, and locks them
+func (w pgxWriter) openOccurrences(ctx context.Context, seriesId int32) ([]int32, error) {
+	var res []int32
+	if err := pgxscan.Select(ctx, w.q, &res, todosOpenSeries, seriesId); err != nil {
+		return nil, GetErrorF("error : todos could not be read", err)
+	}
+	return res, nil
+}
+
+// children returns the children of the todo in the given state in the order of their id, and locks them
+func (w pgxWriter) children(ctx context.Context, parentId int32, state todoState) ([]*Todo, error) {
 	var res []*Todo
+	if err := pgxscan.Select(ctx, w.q, &res, todosChildren, parentId, state == anyTodo); err != nil {
+		return nil, GetErrorF("error : todos could not be read", err)
+	}
+	return res, w.db.loadTags(ctx, w.q, res)
+}
 
-	err := pgxscan.Select(context.Background(), db.Conn, &res, todosList)
+// reparent moves the todo to the parent, nil for a root todo, even if it is in the trash
+func (w pgxWriter) reparent(ctx context.Context, id int32, parentId *int32) (*Todo, error) {
+	before, err := w.lock(ctx, "Reparent", id, anyTodo, 0)
 	if err != nil {
-		db.log.Printf("error : List pgxscan.Select unexpectedly failed, error : %v", err)
 		return nil, err
 	}
-	if res == nil {
-		db.log.Println("info : List returned no results ")
-		return nil, errors.New("records not found")
+	res := &Todo{}
+	if err := pgxscan.Get(ctx, w.q, res, todosReparent, id, parentId); err != nil {
+		return nil, pgError(err)
 	}
+	res.Tags = before.Tags
+	return res, w.record(ctx, before, res)
+}
 
+// openBlockers returns the ids of the live blockers of the todo not yet completed, in ascending order
+func (w pgxWriter) openBlockers(ctx context.Context, id int32) ([]int32, error) {
+	var res []int32
+	if err := pgxscan.Select(ctx, w.q, &res, todosOpenBlockers, id); err != nil {
+		return nil, GetErrorF("error : todos could not be read", err)
+	}
 	return res, nil
 }
 
-func (db *PGX) Get(id int32) (*Todo, error) {
-	db.log.Printf("info : Get(%d) entering...", id)
-	if db.Exist(id) == true {
-		res := &Todo{
-			Completed:   false,
-			CompletedAt: nil,
-			CreatedAt:   nil,
-			Id:          0,
-			Task:        "",
+// dependsOn returns true when the todo with given id is blocked by blockerId, directly or through other blockers
+func (w pgxWriter) dependsOn(ctx context.Context, id int32, blockerId int32) (bool, error) {
+	var found int
+	if err := w.q.QueryRow(ctx, todosDependsOn, id, blockerId).Scan(&found); err != nil {
+		return false, GetErrorF("error : todo dependencies could not be read", err)
+	}
+	return found > 0, nil
+}
+
+// addDependency blocks the todo with given id by blockerId
+func (w pgxWriter) addDependency(ctx context.Context, id int32, blockerId int32) error {
+	if _, err := w.q.Exec(ctx, todosDependencyAdd, id, blockerId); err != nil {
+		w.db.log.Printf("error : AddDependency(%d, %d) unexpectedly failed. error : %v", id, blockerId, err)
+		return pgError(err)
+	}
+	return nil
+}
+
+// delete moves the todo to the trash
+func (w pgxWriter) delete(ctx context.Context, id int32, matchVersion int32) error {
+	before, err := w.lock(ctx, "Delete", id, liveTodo, matchVersion)
+	if err != nil {
+		return err
+	}
+	after := &Todo{}
+	err = pgxscan.Get(ctx, w.q, after, todosDelete, id, matchVersion)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return errModifiedDuring("Delete", id)
 		}
-		err := pgxscan.Get(context.Background(), db.Conn, res, todosGet, id)
-		if err != nil {
-			db.log.Printf("error : Get(%d) pgxscan.Select unexpectedly failed, error : %v", id, err)
-			return nil, err
+		return GetErrorF("error : todos could not be deleted", err)
+	}
+	after.Tags = before.Tags
+	return w.record(ctx, before, after)
+}
+
+func (w pgxWriter) restore(ctx context.Context, id int32, matchVersion int32) (*Todo, error) {
+	before, err := w.lock(ctx, "Restore", id, trashedTodo, matchVersion)
+	if err != nil {
+		return nil, err
+	}
+	res := &Todo{}
+	err = pgxscan.Get(ctx, w.q, res, todosRestore, id, matchVersion)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, errModifiedDuring("Restore", id)
 		}
-		if res == nil {
-			db.log.Printf("info : Get(%d) returned no results ", id)
-			return nil, errors.New("records not found")
+		return nil, pgError(err)
+	}
+	res.Tags = before.Tags
+	return res, w.record(ctx, before, res)
+}
+
+func (w pgxWriter) purge(ctx context.Context, id int32, matchVersion int32) error {
+	before, err := w.lock(ctx, "Purge", id, anyTodo, matchVersion)
+	if err != nil {
+		return err
+	}
+	var purgedId int32
+	err = w.q.QueryRow(ctx, todosPurge, id, matchVersion).Scan(&purgedId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errModifiedDuring("Purge", id)
 		}
-		return res, nil
+		return GetErrorF("error : todos could not be purged", err)
+	}
+	return w.record(ctx, before, nil)
+}
+
+// retag replaces the tag from by to in the tags of the todo, even if it is in the trash
+func (w pgxWriter) retag(ctx context.Context, id int32, from, to string) error {
+	before, err := w.lock(ctx, "RenameTag", id, anyTodo, 0)
+	if err != nil {
+		return err
+	}
+	tags := replaceTag(getTodoTags(before), from, to)
+	if err := w.setTags(ctx, id, before.OwnerId, tags); err != nil {
+		return err
+	}
+	after := &Todo{}
+	if err := pgxscan.Get(ctx, w.q, after, todosTouch, id); err != nil {
+		return pgError(err)
+	}
+	after.Tags = tagsValue(tags)
+	return w.record(ctx, before, after)
+}
+
+// inTx runs f with a writer in a transaction, which is committed only if f succeeds,
+// so a change and its event are saved together, and all the operations of a batch or none
+func (db *PGX) inTx(ctx context.Context, f func(w pgxWriter) error) error {
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return GetErrorF("error : transaction could not be started", err)
 	}
-	db.log.Printf("info : Get(%d) id does not exist", id)
-	return nil, errors.New("todo with this id does not exist")
+	// Rollback does nothing once the transaction is committed
+	defer tx.Rollback(ctx)
+	if err := f(pgxWriter{db: db, q: tx}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return pgError(err)
+	}
+	return nil
+}
+
+func (db *PGX) Close() {
+	db.Conn.Close()
+	return
+}
+
+// loadTags reads with q the tags of the todos and sets their Tags
+func (db *PGX) loadTags(ctx context.Context, q pgxQuerier, todos []*Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	var rows []*todoTagRow
+	sql, args := newTodoTagsQuery(todos)
+	if err := pgxscan.Select(ctx, q, &rows, sql, args...); err != nil {
+		db.log.Printf("error : loadTags pgxscan.Select unexpectedly failed, error : %v", err)
+		return GetErrorF("error : tags could not be read", err)
+	}
+	setTodosTags(todos, rows)
+	return nil
+}
+
+//Create will store the new task in the store
+func (db *PGX) Create(ctx context.Context, todo NewTodo) (*Todo, error) {
+	db.log.Printf("info : Entering Create(%#v)", todo)
+	var res *Todo
+	err := db.inTx(ctx, func(w pgxWriter) (err error) {
+		res, err = w.create(ctx, todo, nil)
+		return err
+	})
+	return res, err
+}
+
+// List returns the todos matching params.Filter sorted on params.Sort,
+// skipping params.Offset todos or the ones up to params.AfterId
+func (db *PGX) List(ctx context.Context, params ListParams) ([]*Todo, error) {
+	res := make([]*Todo, 0)
+	q := newFilterQuery(params.Filter, getOwnerId(ctx), pgTimeValue)
+	offset := params.Offset
+	if params.AfterId > 0 {
+		q.where("id > ?", params.AfterId)
+		offset = 0
+	}
+	orderBy, err := orderByClause(params.Sort)
+	if err != nil {
+		return nil, err
+	}
+	sql := todosSelect + q.whereClause() + orderBy + fmt.Sprintf(" LIMIT %s OFFSET %s", q.arg(params.Limit), q.arg(offset))
+	err = pgxscan.Select(ctx, db.Conn, &res, sql, q.args...)
+	if err != nil {
+		db.log.Printf("error : List pgxscan.Select unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return res, db.loadTags(ctx, db.Conn, res)
+}
+
+// Search returns at most limit todos whose task contains all the words of query, the most relevant first
+func (db *PGX) Search(ctx context.Context, query string, limit int) ([]*TodoSearchResult, error) {
+	var rows []*struct {
+		Todo
+		Rank     float32
+		Headline string
+	}
+	err := pgxscan.Select(ctx, db.Conn, &rows, todosSearch, query, limit, getOwnerId(ctx))
+	if err != nil {
+		db.log.Printf("error : Search(%s) pgxscan.Select unexpectedly failed, error : %v", query, err)
+		return nil, err
+	}
+	todos := make([]*Todo, 0, len(rows))
+	for _, row := range rows {
+		todos = append(todos, &row.Todo)
+	}
+	if err := db.loadTags(ctx, db.Conn, todos); err != nil {
+		return nil, err
+	}
+	res := make([]*TodoSearchResult, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, &TodoSearchResult{
+			Headline: row.Headline,
+			Rank:     row.Rank,
+			Todo:     row.Todo,
+		})
+	}
+	return res, nil
+}
+
+func (db *PGX) Get(ctx context.Context, id int32) (*Todo, error) {
+	db.log.Printf("info : Get(%d) entering...", id)
+	res := &Todo{}
+	err := pgxscan.Get(ctx, db.Conn, res, todosGet, id, getOwnerId(ctx))
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			db.log.Printf("info : Get(%d) id does not exist", id)
+			return nil, ErrNotFound
+		}
+		db.log.Printf("error : Get(%d) pgxscan.Get unexpectedly failed, error : %v", id, err)
+		return nil, err
+	}
+	return res, db.loadTags(ctx, db.Conn, []*Todo{res})
 }
 
 // GetMaxId returns the maximum value of todos id existing in store.
-func (db *PGX) GetMaxId() (int32, error) {
-	existingMaxId, err := db.getQueryInt(todosMaxId)
+func (db *PGX) GetMaxId(ctx context.Context) (int32, error) {
+	existingMaxId, err := db.getQueryInt(ctx, todosMaxId, getOwnerId(ctx))
 	if err != nil {
 		db.log.Printf("getMaxId() could not be retrieved from DB. failed db.Query err: %v", err)
 		return 0, err
@@ -190,8 +731,8 @@ func (db *PGX) GetMaxId() (int32, error) {
 }
 
 // Exist returns true only if a todos with the specified id exists in store.
-func (db *PGX) Exist(id int32) bool {
-	count, err := db.getQueryInt(todosExist, id)
+func (db *PGX) Exist(ctx context.Context, id int32) bool {
+	count, err := db.getQueryInt(ctx, todosExist, id, getOwnerId(ctx))
 	if err != nil {
 		db.log.Printf("exist(%d) could not be retrieved from DB. failed db.Query err: %v", id, err)
 		return false
@@ -205,9 +746,10 @@ func (db *PGX) Exist(id int32) bool {
 	}
 }
 
-// Count returns the number of todos stored in DB
-func (db *PGX) Count() (int32, error) {
-	count, err := db.getQueryInt(todosCount)
+// Count returns the number of todos stored in DB matching the filter
+func (db *PGX) Count(ctx context.Context, filter TodoFilter) (int32, error) {
+	q := newFilterQuery(filter, getOwnerId(ctx), pgTimeValue)
+	count, err := db.getQueryInt(ctx, todosCount+q.whereClause(), q.args...)
 	if err != nil {
 		db.log.Printf("count(*) could not be retrieved from DB. failed db.Query err: %v", err)
 		return 0, err
@@ -215,73 +757,443 @@ func (db *PGX) Count() (int32, error) {
 	return int32(count), nil
 }
 
+// Fingerprint returns the greatest id, the number of todos, the time of the most recent change, the number of overdue todos
+// and the number of dependencies with the time of the last one added
+func (db *PGX) Fingerprint(ctx context.Context, now time.Time) (*Fingerprint, error) {
+	res := &Fingerprint{}
+	err := pgxscan.Get(ctx, db.Conn, res, todosFingerprint, getOwnerId(ctx), pgTimeValue(now))
+	if err != nil {
+		db.log.Printf("error : Fingerprint() pgxscan.Get unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return res, nil
+}
+
 // Update the todos stored in DB with given id and other information in struct
-func (db *PGX) Update(id int32, todo Todo) (*Todo, error) {
-	if db.Exist(id) {
-		// first check business rules for task field
-		if len(todo.Task) < 1 {
-			return nil, errors.New("todo task cannot be empty")
+func (db *PGX) Update(ctx context.Context, id int32, todo Todo, matchVersion int32) (*Todo, error) {
+	return db.Patch(ctx, id, TodoPatch{Task: &todo.Task, Completed: &todo.Completed, ListId: getUpdateListId(todo),
+		Tags: getUpdateTags(todo), DueAt: getUpdateDueAt(todo), Priority: getUpdatePriority(todo),
+		Recurrence: getUpdateRecurrence(todo), ParentId: getUpdateParentId(todo), AutoComplete: getUpdateAutoComplete(todo),
+		RemindAt: getUpdateRemindAt(todo)}, matchVersion)
+}
+
+// Patch changes only the fields given in patch of the todos stored in DB with given id
+func (db *PGX) Patch(ctx context.Context, id int32, patch TodoPatch, matchVersion int32) (*Todo, error) {
+	var res *Todo
+	err := db.inTx(ctx, func(w pgxWriter) (err error) {
+		res, err = w.patch(ctx, id, patch, matchVersion)
+		return err
+	})
+	return res, err
+}
+
+// Delete moves to the trash the todos stored in DB with given id, with its children or not
+func (db *PGX) Delete(ctx context.Context, id int32, matchVersion int32, cascade bool) error {
+	return db.inTx(ctx, func(w pgxWriter) error {
+		return deleteTodo(ctx, w, id, matchVersion, cascade)
+	})
+}
+
+// Restore moves back the todos stored in DB with given id from the trash
+func (db *PGX) Restore(ctx context.Context, id int32, matchVersion int32) (*Todo, error) {
+	var res *Todo
+	err := db.inTx(ctx, func(w pgxWriter) (err error) {
+		res, err = restoreTodo(ctx, w, id, matchVersion)
+		return err
+	})
+	return res, err
+}
+
+// Purge removes permanently the todos stored in DB with given id, with its children or not
+func (db *PGX) Purge(ctx context.Context, id int32, matchVersion int32, cascade bool) error {
+	return db.inTx(ctx, func(w pgxWriter) error {
+		return purgeTodo(ctx, w, id, matchVersion, cascade)
+	})
+}
+
+// PurgeTrash removes permanently the todos stored in DB moved to the trash before the given time
+func (db *PGX) PurgeTrash(ctx context.Context, trashedBefore time.Time) (int, error) {
+	var ids []int32
+	err := db.inTx(ctx, func(w pgxWriter) error {
+		if err := pgxscan.Select(ctx, w.q, &ids, todosTrashed, pgTimeValue(trashedBefore), getOwnerId(ctx)); err != nil {
+			return GetErrorF("error : trash could not be read", err)
 		}
-		if len(todo.Task) < 6 {
-			return nil, errors.New("CreateTodo task minLength is 5")
+		// every todo is purged like with Purge, so it gets its event
+		for _, id := range ids {
+			if err := purgeTodo(ctx, w, id, 0, false); err != nil {
+				return err
+			}
 		}
-		updateAll := true
-		var rowsAffected int = 0
-		var err error
-		now := time.Now()
-		// implements basic Business Rules !
-		// let's first check if task was already completed in DB
-		alreadyCompleted, _ := db.getQueryBool(todosCompleted, id)
-		switch todo.Completed {
-		case true:
-			if alreadyCompleted == false {
-				// this task was not completed, now it is, so update CompletedAt
-				todo.CompletedAt = &now
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// History returns the events of the todos with given id in the order they occurred
+func (db *PGX) History(ctx context.Context, id int32) ([]*TodoEvent, error) {
+	var rows []*todoEventRow
+	if err := pgxscan.Select(ctx, db.Conn, &rows, todoEventsHistory, id, getOwnerId(ctx)); err != nil {
+		db.log.Printf("error : History(%d) pgxscan.Select unexpectedly failed, error : %v", id, err)
+		return nil, err
+	}
+	return toTodoEvents(rows)
+}
+
+// Events returns at most limit events that occurred at or after since, in the order they occurred
+func (db *PGX) Events(ctx context.Context, since time.Time, limit int) ([]*TodoEvent, error) {
+	var rows []*todoEventRow
+	if err := pgxscan.Select(ctx, db.Conn, &rows, todoEventsSince, pgTimeValue(since), limit, getOwnerId(ctx)); err != nil {
+		db.log.Printf("error : Events(%v) pgxscan.Select unexpectedly failed, error : %v", since, err)
+		return nil, err
+	}
+	return toTodoEvents(rows)
+}
+
+// ListTodoLists returns the lists stored in DB in the order of their id, with the number of their todos
+func (db *PGX) ListTodoLists(ctx context.Context) ([]*TodoList, error) {
+	res := make([]*TodoList, 0)
+	if err := pgxscan.Select(ctx, db.Conn, &res, listsList, getOwnerId(ctx)); err != nil {
+		db.log.Printf("error : ListTodoLists pgxscan.Select unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetTodoList returns the list stored in DB with given ID and the number of its todos
+func (db *PGX) GetTodoList(ctx context.Context, id int32) (*TodoList, error) {
+	res := &TodoList{}
+	if err := pgxscan.Get(ctx, db.Conn, res, listsGet, id, getOwnerId(ctx)); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, ErrListNotFound
+		}
+		db.log.Printf("error : GetTodoList(%d) pgxscan.Get unexpectedly failed, error : %v", id, err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// CreateTodoList saves in DB a new list owned by the user of the context, the names of the lists of a user are unique
+func (db *PGX) CreateTodoList(ctx context.Context, list NewTodoList) (*TodoList, error) {
+	if err := validateNewTodoList(list); err != nil {
+		return nil, err
+	}
+	owner := UserFromContext(ctx)
+	if owner == nil {
+		return nil, errNoOwner
+	}
+	var id int32
+	if err := db.Conn.QueryRow(ctx, listsCreate, list.Name, owner.Id).Scan(&id); err != nil {
+		db.log.Printf("error : CreateTodoList(%s) unexpectedly failed. error : %v", list.Name, err)
+		return nil, pgError(err)
+	}
+	return db.GetTodoList(ctx, id)
+}
+
+// UpdateTodoList renames the list stored in DB with given ID
+func (db *PGX) UpdateTodoList(ctx context.Context, id int32, list NewTodoList) (*TodoList, error) {
+	if err := validateNewTodoList(list); err != nil {
+		return nil, err
+	}
+	var updatedId int32
+	if err := db.Conn.QueryRow(ctx, listsUpdate, id, list.Name, getOwnerId(ctx)).Scan(&updatedId); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrListNotFound
+		}
+		db.log.Printf("error : UpdateTodoList(%d) unexpectedly failed. error : %v", id, err)
+		return nil, pgError(err)
+	}
+	return db.GetTodoList(ctx, id)
+}
+
+// DeleteTodoList purges the todos of the list stored in DB with given ID and removes it in a single transaction
+func (db *PGX) DeleteTodoList(ctx context.Context, id int32) error {
+	return db.inTx(ctx, func(w pgxWriter) error {
+		var lockedId int32
+		if err := w.q.QueryRow(ctx, listsLock, id, getOwnerId(ctx)).Scan(&lockedId); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrListNotFound
 			}
-		case false:
-			if alreadyCompleted == true {
-				// this task was completed, but user changed it to not completed so update with nil
-				todo.CompletedAt = nil
+			return GetErrorF("error : lists could not be read", err)
+		}
+		var todoIds []int32
+		if err := pgxscan.Select(ctx, w.q, &todoIds, listsTodos, id); err != nil {
+			return GetErrorF("error : todos could not be read", err)
+		}
+		// every todo is purged like with Purge, so it gets its event
+		for _, todoId := range todoIds {
+			if err := purgeTodo(ctx, w, todoId, 0, false); err != nil {
+				return err
 			}
-		default:
-			// in all other cases the values of Completed and CompletedAt fields should not be changed in DB
-			// so here let's update only the Task field
-			rowsAffected, err = db.execActionQuery(todosUpdateTask, todo.Task, id)
-			updateAll = false
 		}
-		if updateAll {
-			rowsAffected, err = db.execActionQuery(todosUpdate, todo.Task, todo.Completed, todo.CompletedAt, id)
+		if _, err := w.q.Exec(ctx, listsDelete, id); err != nil {
+			return GetErrorF("error : list could not be deleted", err)
 		}
-		if err != nil {
-			return nil, GetErrorF("error : todos could not be updated", err)
+		return nil
+	})
+}
+
+// ListTags returns the tags of the live todos stored in DB in alphabetical order, with the number of live todos having them
+func (db *PGX) ListTags(ctx context.Context) ([]*Tag, error) {
+	res := make([]*Tag, 0)
+	if err := pgxscan.Select(ctx, db.Conn, &res, tagsList, getOwnerId(ctx)); err != nil {
+		db.log.Printf("error : ListTags pgxscan.Select unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// RenameTag replaces the tag name by newName in all the todos stored in DB having it in a single transaction
+func (db *PGX) RenameTag(ctx context.Context, name, newName string) (*Tag, error) {
+	from := tagName(name)
+	to, err := normalizeTag(newName)
+	if err != nil {
+		return nil, err
+	}
+	res := &Tag{Name: to}
+	err = db.inTx(ctx, func(w pgxWriter) error {
+		var todoIds []int32
+		if err := pgxscan.Select(ctx, w.q, &todoIds, tagsTodos, from, getOwnerId(ctx)); err != nil {
+			return GetErrorF("error : tags could not be read", err)
 		}
-		if rowsAffected < 1 {
-			return nil, GetErrorF("error : todos was not updated", err)
+		if len(todoIds) == 0 {
+			return ErrTagNotFound
 		}
-		// if we get to here all is good, so let's retrieve a fresh copy to send it back
-		updatedTodo, err := db.Get(id)
-		if err != nil {
-			return nil, GetErrorF("error : todos was updated, but can not be retrieved", err)
+		if from != to {
+			// every todo is changed like with Patch, so it gets its event
+			for _, todoId := range todoIds {
+				if err := w.retag(ctx, todoId, from, to); err != nil {
+					return err
+				}
+			}
+			if _, err := w.q.Exec(ctx, tagsDelete, from, getOwnerId(ctx)); err != nil {
+				return GetErrorF("error : tag could not be deleted", err)
+			}
 		}
-		return updatedTodo, nil
+		return w.q.QueryRow(ctx, tagsCount, to, getOwnerId(ctx)).Scan(&res.TodosCount)
+	})
+	if err != nil {
+		return nil, err
 	}
-	db.log.Printf("info : Update(%d) id does not exist", id)
-	return nil, errors.New("todo with this id does not exist")
+	return res, nil
 }
 
-// Delete the todos stored in DB with given id
-func (db *PGX) Delete(id int32) error {
-	if db.Exist(id) {
-		rowsAffected, err := db.execActionQuery(todosDelete, id)
-		if err != nil {
-			return GetErrorF("error : todos could not be deleted", err)
+// CreateUser saves a new user in DB, the logins are unique
+func (db *PGX) CreateUser(ctx context.Context, user NewUser) (*User, error) {
+	if err := validateNewUser(user); err != nil {
+		return nil, err
+	}
+	res := &User{}
+	if err := pgxscan.Get(ctx, db.Conn, res, usersCreate, user.Login, user.Name, string(getNewUserRole(user))); err != nil {
+		db.log.Printf("error : CreateUser(%s) unexpectedly failed. error : %v", user.Login, err)
+		return nil, pgError(err)
+	}
+	return res, nil
+}
+
+// GetUser returns the user stored in DB with given login
+func (db *PGX) GetUser(ctx context.Context, login string) (*User, error) {
+	res := &User{}
+	if err := pgxscan.Get(ctx, db.Conn, res, usersGet, login); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, ErrUserNotFound
+		}
+		db.log.Printf("error : GetUser(%s) pgxscan.Get unexpectedly failed, error : %v", login, err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListUsers returns all the users stored in DB in the order of their id
+func (db *PGX) ListUsers(ctx context.Context) ([]*User, error) {
+	res := make([]*User, 0)
+	if err := pgxscan.Select(ctx, db.Conn, &res, usersList); err != nil {
+		db.log.Printf("error : ListUsers pgxscan.Select unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// CreateApiKey saves a new API key in DB with the hash of its key
+func (db *PGX) CreateApiKey(ctx context.Context, apiKey ApiKey, hash string) (*ApiKey, error) {
+	var expiresAt interface{}
+	if apiKey.ExpiresAt != nil {
+		expiresAt = pgTimeValue(*apiKey.ExpiresAt)
+	}
+	var id int32
+	err := db.Conn.QueryRow(ctx, apiKeysCreate, apiKey.Name, apiKey.Prefix, hash, apiKey.Login,
+		encodeScopes(apiKey.Scopes), expiresAt).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
 		}
-		if rowsAffected < 1 {
-			return GetErrorF("error : todos was not deleted", err)
+		db.log.Printf("error : CreateApiKey(%s) unexpectedly failed. error : %v", apiKey.Name, err)
+		return nil, pgError(err)
+	}
+	row := &apiKeyRow{}
+	if err := pgxscan.Get(ctx, db.Conn, row, apiKeysGet, id); err != nil {
+		db.log.Printf("error : CreateApiKey(%s) pgxscan.Get unexpectedly failed, error : %v", apiKey.Name, err)
+		return nil, err
+	}
+	return row.toApiKey(), nil
+}
+
+// GetApiKey returns the API key stored in DB whose key has the given hash
+func (db *PGX) GetApiKey(ctx context.Context, hash string) (*ApiKey, error) {
+	row := &apiKeyRow{}
+	if err := pgxscan.Get(ctx, db.Conn, row, apiKeysByHash, hash); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, ErrApiKeyNotFound
 		}
-		// if we get to here all is good
-		return nil
+		db.log.Printf("error : GetApiKey pgxscan.Get unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return row.toApiKey(), nil
+}
+
+// ListApiKeys returns all the API keys stored in DB in the order of their id
+func (db *PGX) ListApiKeys(ctx context.Context) ([]*ApiKey, error) {
+	var rows []*apiKeyRow
+	if err := pgxscan.Select(ctx, db.Conn, &rows, apiKeysList); err != nil {
+		db.log.Printf("error : ListApiKeys pgxscan.Select unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return toApiKeys(rows), nil
+}
+
+// RevokeApiKey removes the API key with given ID from DB
+func (db *PGX) RevokeApiKey(ctx context.Context, id int32) error {
+	res, err := db.Conn.Exec(ctx, apiKeysRevoke, id)
+	if err != nil {
+		db.log.Printf("error : RevokeApiKey(%d) unexpectedly failed. error : %v", id, err)
+		return err
 	}
-	db.log.Printf("info : Delete(%d) id does not exist", id)
-	return errors.New("todo with this id does not exist")
+	if res.RowsAffected() < 1 {
+		return ErrApiKeyNotFound
+	}
+	return nil
+}
+
+// TouchApiKey records in DB the time of the last use of the API key with given ID
+func (db *PGX) TouchApiKey(ctx context.Context, id int32, usedAt time.Time) error {
+	res, err := db.Conn.Exec(ctx, apiKeysTouch, id, pgTimeValue(usedAt))
+	if err != nil {
+		db.log.Printf("error : TouchApiKey(%d) unexpectedly failed. error : %v", id, err)
+		return err
+	}
+	if res.RowsAffected() < 1 {
+		return ErrApiKeyNotFound
+	}
+	return nil
+}
+
+// UpdateSeries changes the recurrence rule of the open occurrences of the series of the todo stored in DB with given id
+func (db *PGX) UpdateSeries(ctx context.Context, id int32, recurrence string) ([]*Todo, error) {
+	var res []*Todo
+	err := db.inTx(ctx, func(w pgxWriter) error {
+		t, err := w.lock(ctx, "UpdateSeries", id, liveTodo, 0)
+		if err != nil {
+			return err
+		}
+		res, err = updateSeries(ctx, w, t, recurrence)
+		return err
+	})
+	return res, err
+}
+
+// CountChildren returns the numbers of live children of the todos stored in DB with given ids
+func (db *PGX) CountChildren(ctx context.Context, ids []int32) (map[int32]ChildrenStats, error) {
+	var rows []*childrenStatsRow
+	sql, args := newChildrenStatsQuery(ids, getOwnerId(ctx))
+	if err := pgxscan.Select(ctx, db.Conn, &rows, sql, args...); err != nil {
+		db.log.Printf("error : CountChildren pgxscan.Select unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return toChildrenStats(rows), nil
+}
+
+// Descendants returns the live descendants of the todos stored in DB with given ids, found with a recursive query
+func (db *PGX) Descendants(ctx context.Context, ids []int32) ([]*Todo, error) {
+	res := make([]*Todo, 0)
+	sql, args := newDescendantsQuery(todosSelect, ids, getOwnerId(ctx))
+	if err := pgxscan.Select(ctx, db.Conn, &res, sql, args...); err != nil {
+		db.log.Printf("error : Descendants pgxscan.Select unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return res, db.loadTags(ctx, db.Conn, res)
+}
+
+// OpenBlockers returns the ids of the open blockers of the todos stored in DB with given ids
+func (db *PGX) OpenBlockers(ctx context.Context, ids []int32) (map[int32][]int32, error) {
+	var rows []*blockerRow
+	sql, args := newOpenBlockersQuery(ids, getOwnerId(ctx))
+	if err := pgxscan.Select(ctx, db.Conn, &rows, sql, args...); err != nil {
+		db.log.Printf("error : OpenBlockers pgxscan.Select unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return toOpenBlockers(rows), nil
+}
+
+// Blockers returns the live blockers of the todo stored in DB with given id
+func (db *PGX) Blockers(ctx context.Context, id int32) ([]*Todo, error) {
+	res := make([]*Todo, 0)
+	if err := pgxscan.Select(ctx, db.Conn, &res, todosBlockers, id, getOwnerId(ctx)); err != nil {
+		db.log.Printf("error : Blockers(%d) pgxscan.Select unexpectedly failed, error : %v", id, err)
+		return nil, err
+	}
+	return res, db.loadTags(ctx, db.Conn, res)
+}
+
+// AddDependency blocks the todo stored in DB with given id by the todo blockerId
+func (db *PGX) AddDependency(ctx context.Context, id int32, blockerId int32) error {
+	return db.inTx(ctx, func(w pgxWriter) error {
+		return addDependency(ctx, w, id, blockerId)
+	})
+}
+
+// RemoveDependency removes the blocker blockerId of the todo stored in DB with given id
+func (db *PGX) RemoveDependency(ctx context.Context, id int32, blockerId int32) error {
+	res, err := db.Conn.Exec(ctx, todosDependencyRemove, id, blockerId, getOwnerId(ctx))
+	if err != nil {
+		db.log.Printf("error : RemoveDependency(%d, %d) unexpectedly failed. error : %v", id, blockerId, err)
+		return err
+	}
+	if res.RowsAffected() < 1 {
+		return ErrDependencyNotFound
+	}
+	return nil
+}
+
+// ClaimReminders records as sent and returns the reminders of the todos stored in DB reached at now
+func (db *PGX) ClaimReminders(ctx context.Context, now time.Time, limit int) ([]*Reminder, error) {
+	todos := make([]*Todo, 0)
+	if err := pgxscan.Select(ctx, db.Conn, &todos, todosRemindersClaim, pgTimeValue(now), limit, getOwnerId(ctx)); err != nil {
+		db.log.Printf("error : ClaimReminders pgxscan.Select unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	if err := db.loadTags(ctx, db.Conn, todos); err != nil {
+		return nil, err
+	}
+	return toReminders(todos), nil
+}
+
+// ReleaseReminder records as not sent the reminder of the todo stored in DB with given id for the reminder time remindAt
+func (db *PGX) ReleaseReminder(ctx context.Context, id int32, remindAt time.Time) error {
+	if _, err := db.Conn.Exec(ctx, todosReminderRelease, id, pgTimeValue(remindAt), getOwnerId(ctx)); err != nil {
+		db.log.Printf("error : ReleaseReminder(%d) unexpectedly failed. error : %v", id, err)
+		return err
+	}
+	return nil
+}
+
+// Batch applies all the operations in a single transaction, it is rolled back when one of them fails
+func (db *PGX) Batch(ctx context.Context, operations []BatchOperation) ([]*Todo, error) {
+	var res []*Todo
+	err := db.inTx(ctx, func(w pgxWriter) (err error) {
+		res, err = runBatch(ctx, w, operations)
+		return err
+	})
+	return res, err
 }
@@ -0,0 +1,349 @@
+package todos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxRecurrenceLength is the greatest number of characters of a recurrence rule
+	MaxRecurrenceLength = 100
+	// MaxRecurrenceInterval is the greatest INTERVAL of a recurrence rule
+	MaxRecurrenceInterval = 366
+)
+
+// ErrNotRecurring is returned when the todo is not part of a series of occurrences
+var ErrNotRecurring = errors.New("todo never recurred")
+
+// the frequencies of the recurrence rules
+const (
+	recurDaily   = "DAILY"
+	recurWeekly  = "WEEKLY"
+	recurMonthly = "MONTHLY"
+)
+
+// weekdays contains the days of the BYDAY part of a recurrence rule, indexed by time.Weekday
+var weekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// recurrenceRule is a parsed recurrence rule, a subset of the RRULE of iCalendar (RFC 5545)
+type recurrenceRule struct {
+	freq     string
+	interval int
+	// byDay contains the days of a WEEKLY rule, empty for the day of the due date
+	byDay []time.Weekday
+	// byMonthDay is the day of a MONTHLY rule, 0 for the day of the due date
+	byMonthDay int
+	// fromCompletion is true when an occurrence of a DAILY rule is due interval days after the completion of the previous one
+	fromCompletion bool
+}
+
+func errInvalidRecurrence(format string, args ...interface{}) error {
+	return &ValidationError{Field: "recurrence", Message: fmt.Sprintf(format, args...)}
+}
+
+// parseRecurrence parses a rule like FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH, its RRULE: prefix is optional
+func parseRecurrence(rule string) (*recurrenceRule, error) {
+	res := &recurrenceRule{interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:"), ";") {
+		nameValue := strings.SplitN(part, "=", 2)
+		if len(nameValue) != 2 {
+			return nil, errInvalidRecurrence("must contain NAME=VALUE parts separated by ;")
+		}
+		name, value := nameValue[0], nameValue[1]
+		if seen[name] {
+			return nil, errInvalidRecurrence("cannot contain %s more than once", name)
+		}
+		seen[name] = true
+		switch name {
+		case "FREQ":
+			if value != recurDaily && value != recurWeekly && value != recurMonthly {
+				return nil, errInvalidRecurrence("FREQ must be DAILY, WEEKLY or MONTHLY")
+			}
+			res.freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > MaxRecurrenceInterval {
+				return nil, errInvalidRecurrence("INTERVAL must be between 1 and %d", MaxRecurrenceInterval)
+			}
+			res.interval = n
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				weekday := indexOf(weekdays, day)
+				if weekday < 0 {
+					return nil, errInvalidRecurrence("BYDAY must contain days like MO,TH")
+				}
+				if !containsWeekday(res.byDay, time.Weekday(weekday)) {
+					res.byDay = append(res.byDay, time.Weekday(weekday))
+				}
+			}
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 31 {
+				return nil, errInvalidRecurrence("BYMONTHDAY must be between 1 and 31")
+			}
+			res.byMonthDay = n
+		case "X-FROM":
+			if value != "COMPLETION" {
+				return nil, errInvalidRecurrence("X-FROM must be COMPLETION")
+			}
+			res.fromCompletion = true
+		default:
+			return nil, errInvalidRecurrence("cannot contain %s, only FREQ, INTERVAL, BYDAY, BYMONTHDAY and X-FROM are supported", name)
+		}
+	}
+	switch {
+	case res.freq == "":
+		return nil, errInvalidRecurrence("must contain FREQ")
+	case len(res.byDay) > 0 && res.freq != recurWeekly:
+		return nil, errInvalidRecurrence("can only contain BYDAY with FREQ=WEEKLY")
+	case res.byMonthDay > 0 && res.freq != recurMonthly:
+		return nil, errInvalidRecurrence("can only contain BYMONTHDAY with FREQ=MONTHLY")
+	case res.fromCompletion && res.freq != recurDaily:
+		return nil, errInvalidRecurrence("can only contain X-FROM with FREQ=DAILY")
+	}
+	// the days are kept in the order of the week starting on monday, like the default week start of iCalendar
+	sort.Slice(res.byDay, func(i, j int) bool { return weekdayIndex(res.byDay[i]) < weekdayIndex(res.byDay[j]) })
+	return res, nil
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func containsWeekday(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// weekdayIndex returns the position of the day in the week starting on monday
+func weekdayIndex(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+// String returns the canonical form of the rule, the one stored with the todos
+func (r *recurrenceRule) String() string {
+	parts := []string{"FREQ=" + r.freq}
+	if r.interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.interval))
+	}
+	if len(r.byDay) > 0 {
+		days := make([]string, 0, len(r.byDay))
+		for _, day := range r.byDay {
+			days = append(days, weekdays[day])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.byMonthDay > 0 {
+		parts = append(parts, fmt.Sprintf("BYMONTHDAY=%d", r.byMonthDay))
+	}
+	if r.fromCompletion {
+		parts = append(parts, "X-FROM=COMPLETION")
+	}
+	return strings.Join(parts, ";")
+}
+
+// after returns the first occurrence of the rule after the occurrence t, with the same time of the day.
+// a MONTHLY occurrence falls on the last day of the months shorter than its day
+func (r *recurrenceRule) after(t time.Time) time.Time {
+	switch r.freq {
+	case recurWeekly:
+		days := r.byDay
+		if len(days) == 0 {
+			days = []time.Weekday{t.Weekday()}
+		}
+		// only the days of one week every interval weeks, counted from the week of t, are occurrences
+		for d := 1; ; d++ {
+			next := t.AddDate(0, 0, d)
+			week := (weekdayIndex(t.Weekday()) + d) / 7
+			if week%r.interval == 0 && containsWeekday(days, next.Weekday()) {
+				return next
+			}
+		}
+	case recurMonthly:
+		day := r.byMonthDay
+		if day == 0 {
+			day = t.Day()
+		}
+		for months := 0; ; months += r.interval {
+			first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+			lastDay := first.AddDate(0, 1, -1).Day()
+			if day < lastDay {
+				lastDay = day
+			}
+			if next := first.AddDate(0, 0, lastDay-1); next.After(t) {
+				return next
+			}
+		}
+	default:
+		return t.AddDate(0, 0, r.interval)
+	}
+}
+
+// next returns the due date of the occurrence following the one due at dueAt and completed at completedAt,
+// the occurrences are computed in UTC from the due date, or from the completion when there is no due date.
+// an occurrence completed late is followed by the first occurrence due after its completion.
+func (r *recurrenceRule) next(dueAt *time.Time, completedAt time.Time) *time.Time {
+	completedAt = completedAt.UTC()
+	if r.fromCompletion || dueAt == nil {
+		next := r.after(completedAt)
+		return normalizeDueAt(&next)
+	}
+	next := r.after(dueAt.UTC())
+	for !next.After(completedAt) {
+		next = r.after(next)
+	}
+	return normalizeDueAt(&next)
+}
+
+// normalizeRecurrence returns the canonical form of the recurrence rule, nil when there is no rule
+func normalizeRecurrence(rule *string) (*string, error) {
+	if rule == nil {
+		return nil, nil
+	}
+	if len(*rule) > MaxRecurrenceLength {
+		return nil, errInvalidRecurrence("is longer than %d characters", MaxRecurrenceLength)
+	}
+	r, err := parseRecurrence(*rule)
+	if err != nil {
+		return nil, err
+	}
+	canonical := r.String()
+	return &canonical, nil
+}
+
+// normalizePatchRecurrence returns the TodoPatch.Recurrence with the canonical form of the rule of the patch,
+// nil when the recurrence does not change and empty when it is stopped
+func normalizePatchRecurrence(patch TodoPatch) (*string, error) {
+	if patch.Recurrence == nil || *patch.Recurrence == "" {
+		return patch.Recurrence, nil
+	}
+	return normalizeRecurrence(patch.Recurrence)
+}
+
+// getPatchRecurrence returns the recurrence column value of a todo after the patch, nil when it does not recur
+func getPatchRecurrence(current *string, patch TodoPatch) *string {
+	switch {
+	case patch.Recurrence == nil:
+		return current
+	case *patch.Recurrence == "":
+		return nil
+	default:
+		return patch.Recurrence
+	}
+}
+
+// getUpdateRecurrence returns the TodoPatch.Recurrence replacing the rule of a todo by the one of todo, empty when it has none
+func getUpdateRecurrence(todo Todo) *string {
+	recurrence := ""
+	if todo.Recurrence != nil {
+		recurrence = *todo.Recurrence
+	}
+	return &recurrence
+}
+
+// getPatchSeriesId returns the series_id column value of the todo t after the patch :
+// a todo joins its own series the first time it gets a recurrence rule, and then stays in it
+func getPatchSeriesId(t *Todo, patch TodoPatch) *int32 {
+	if t.SeriesId == nil && getPatchRecurrence(t.Recurrence, patch) != nil {
+		return &t.Id
+	}
+	return t.SeriesId
+}
+
+// getNewTodoOwnerId returns the owner of a new todo : the owner of the previous occurrence for the next occurrence
+// of a recurring todo, the user of ctx otherwise
+func getNewTodoOwnerId(ctx context.Context, previous *Todo) (int32, error) {
+	if previous != nil {
+		return previous.OwnerId, nil
+	}
+	owner := UserFromContext(ctx)
+	if owner == nil {
+		return 0, errNoOwner
+	}
+	return owner.Id, nil
+}
+
+// getNewTodoSeriesId returns the series of a new todo, the one of the previous occurrence for the next occurrence
+// of a recurring todo. it is nil for the other todos, the ones with a recurrence rule start their own series.
+func getNewTodoSeriesId(previous *Todo) *int32 {
+	if previous == nil {
+		return nil
+	}
+	return previous.SeriesId
+}
+
+// recur creates with w the next occurrence of the series of the todo changed from before to after,
+// when the change completes a recurring todo and the series has no other occurrence not yet completed
+func recur(ctx context.Context, w todoWriter, before, after *Todo) error {
+	if before.Completed || !after.Completed || after.Recurrence == nil || after.SeriesId == nil {
+		return nil
+	}
+	// a todo completed, then not completed and completed again already has its next occurrence
+	open, err := w.openOccurrences(ctx, *after.SeriesId)
+	if err != nil || len(open) > 0 {
+		return err
+	}
+	rule, err := parseRecurrence(*after.Recurrence)
+	if err != nil {
+		return err
+	}
+	completedAt := time.Now()
+	if after.CompletedAt != nil {
+		completedAt = *after.CompletedAt
+	}
+	dueAt := rule.next(after.DueAt, completedAt)
+	_, err = w.create(ctx, NewTodo{
+		DueAt:      dueAt,
+		ListId:     after.ListId,
+		Priority:   after.Priority,
+		Recurrence: after.Recurrence,
+		RemindAt:   getNextRemindAt(after, dueAt),
+		Tags:       after.Tags,
+		Task:       after.Task,
+	}, after)
+	return err
+}
+
+// updateSeries sets with w the recurrence rule of the occurrences not yet completed of the series of the todo t,
+// an empty rule stops the series. it returns the changed occurrences.
+func updateSeries(ctx context.Context, w todoWriter, t *Todo, recurrence string) ([]*Todo, error) {
+	if t.SeriesId == nil {
+		return nil, ErrNotRecurring
+	}
+	if recurrence != "" {
+		if _, err := normalizeRecurrence(&recurrence); err != nil {
+			return nil, err
+		}
+	}
+	ids, err := w.openOccurrences(ctx, *t.SeriesId)
+	if err != nil {
+		return nil, err
+	}
+	if recurrence != "" && len(ids) == 0 {
+		return nil, fmt.Errorf("%w : all the occurrences of the series %d are completed", ErrConflict, *t.SeriesId)
+	}
+	res := make([]*Todo, 0, len(ids))
+	for _, id := range ids {
+		occurrence, err := w.patch(ctx, id, TodoPatch{Recurrence: &recurrence}, 0)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, occurrence)
+	}
+	return res, nil
+}
@@ -0,0 +1,312 @@
+package todos
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// at returns the time of the RFC 3339 value, the tests only use valid values
+func at(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "should accept a daily rule",
+			rule: "FREQ=DAILY",
+			want: "FREQ=DAILY",
+		},
+		{
+			name: "should accept the RRULE prefix in lowercase with spaces around",
+			rule: " rrule:freq=weekly;interval=2 ",
+			want: "FREQ=WEEKLY;INTERVAL=2",
+		},
+		{
+			name: "should sort the days from monday and remove the duplicates",
+			rule: "FREQ=WEEKLY;BYDAY=SU,TH,MO,TH",
+			want: "FREQ=WEEKLY;BYDAY=MO,TH,SU",
+		},
+		{
+			name: "should not keep an interval of 1",
+			rule: "FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=31",
+			want: "FREQ=MONTHLY;BYMONTHDAY=31",
+		},
+		{
+			name: "should accept a daily rule from the completion",
+			rule: "X-FROM=COMPLETION;INTERVAL=3;FREQ=DAILY",
+			want: "FREQ=DAILY;INTERVAL=3;X-FROM=COMPLETION",
+		},
+		{
+			name:    "should reject a rule without FREQ",
+			rule:    "INTERVAL=2",
+			wantErr: "must contain FREQ",
+		},
+		{
+			name:    "should reject a yearly rule",
+			rule:    "FREQ=YEARLY",
+			wantErr: "FREQ must be DAILY, WEEKLY or MONTHLY",
+		},
+		{
+			name:    "should reject a part without value",
+			rule:    "FREQ=DAILY;INTERVAL",
+			wantErr: "must contain NAME=VALUE parts separated by ;",
+		},
+		{
+			name:    "should reject a part given twice",
+			rule:    "FREQ=DAILY;FREQ=WEEKLY",
+			wantErr: "cannot contain FREQ more than once",
+		},
+		{
+			name:    "should reject an interval of 0",
+			rule:    "FREQ=DAILY;INTERVAL=0",
+			wantErr: "INTERVAL must be between 1 and 366",
+		},
+		{
+			name:    "should reject an interval greater than the maximum",
+			rule:    "FREQ=DAILY;INTERVAL=367",
+			wantErr: "INTERVAL must be between 1 and 366",
+		},
+		{
+			name:    "should reject an unknown day",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,XX",
+			wantErr: "BYDAY must contain days like MO,TH",
+		},
+		{
+			name:    "should reject a day of the month greater than 31",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=32",
+			wantErr: "BYMONTHDAY must be between 1 and 31",
+		},
+		{
+			name:    "should reject BYDAY in a daily rule",
+			rule:    "FREQ=DAILY;BYDAY=MO",
+			wantErr: "can only contain BYDAY with FREQ=WEEKLY",
+		},
+		{
+			name:    "should reject BYMONTHDAY in a weekly rule",
+			rule:    "FREQ=WEEKLY;BYMONTHDAY=1",
+			wantErr: "can only contain BYMONTHDAY with FREQ=MONTHLY",
+		},
+		{
+			name:    "should reject X-FROM in a weekly rule",
+			rule:    "FREQ=WEEKLY;X-FROM=COMPLETION",
+			wantErr: "can only contain X-FROM with FREQ=DAILY",
+		},
+		{
+			name:    "should reject an unsupported part",
+			rule:    "FREQ=DAILY;COUNT=3",
+			wantErr: "cannot contain COUNT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRecurrence(tt.rule)
+			if tt.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got.String())
+			}
+		})
+	}
+}
+
+func TestRecurrenceRule_After(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		t    string
+		want string
+	}{
+		{
+			name: "should add the interval in days to a daily occurrence",
+			rule: "FREQ=DAILY;INTERVAL=3",
+			t:    "2030-01-07T09:00:00Z",
+			want: "2030-01-10T09:00:00Z",
+		},
+		{
+			name: "should keep the day of the week of a weekly occurrence without BYDAY",
+			rule: "FREQ=WEEKLY",
+			t:    "2030-01-09T09:00:00Z",
+			want: "2030-01-16T09:00:00Z",
+		},
+		{
+			name: "should return the next day of BYDAY in the same week",
+			rule: "FREQ=WEEKLY;BYDAY=MO,TH",
+			t:    "2030-01-07T09:00:00Z",
+			want: "2030-01-10T09:00:00Z",
+		},
+		{
+			name: "should return the first day of BYDAY of the next week",
+			rule: "FREQ=WEEKLY;BYDAY=MO,TH",
+			t:    "2030-01-10T09:00:00Z",
+			want: "2030-01-14T09:00:00Z",
+		},
+		{
+			name: "should return the next day of BYDAY in the same week with an interval",
+			rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+			t:    "2030-01-09T09:00:00Z",
+			want: "2030-01-11T09:00:00Z",
+		},
+		{
+			name: "should skip the weeks between two weeks of the interval",
+			rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+			t:    "2030-01-11T09:00:00Z",
+			want: "2030-01-21T09:00:00Z",
+		},
+		{
+			name: "should count the weeks from monday when the occurrence is a sunday",
+			rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU",
+			t:    "2030-01-13T09:00:00Z",
+			want: "2030-01-21T09:00:00Z",
+		},
+		{
+			name: "should keep the day of the month of a monthly occurrence without BYMONTHDAY",
+			rule: "FREQ=MONTHLY",
+			t:    "2030-01-15T09:00:00Z",
+			want: "2030-02-15T09:00:00Z",
+		},
+		{
+			name: "should return the day of BYMONTHDAY later in the same month",
+			rule: "FREQ=MONTHLY;BYMONTHDAY=20",
+			t:    "2030-01-15T09:00:00Z",
+			want: "2030-01-20T09:00:00Z",
+		},
+		{
+			name: "should clamp BYMONTHDAY=31 to the end of february",
+			rule: "FREQ=MONTHLY;BYMONTHDAY=31",
+			t:    "2030-01-31T09:00:00Z",
+			want: "2030-02-28T09:00:00Z",
+		},
+		{
+			name: "should clamp BYMONTHDAY=31 to the end of february of a leap year",
+			rule: "FREQ=MONTHLY;BYMONTHDAY=31",
+			t:    "2032-01-31T09:00:00Z",
+			want: "2032-02-29T09:00:00Z",
+		},
+		{
+			name: "should return BYMONTHDAY=31 again after a shorter month",
+			rule: "FREQ=MONTHLY;BYMONTHDAY=31",
+			t:    "2030-02-28T09:00:00Z",
+			want: "2030-03-31T09:00:00Z",
+		},
+		{
+			name: "should clamp BYMONTHDAY=31 to the end of a month of 30 days",
+			rule: "FREQ=MONTHLY;BYMONTHDAY=31",
+			t:    "2030-03-31T09:00:00Z",
+			want: "2030-04-30T09:00:00Z",
+		},
+		{
+			name: "should add the interval in months",
+			rule: "FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=31",
+			t:    "2030-01-31T09:00:00Z",
+			want: "2030-03-31T09:00:00Z",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := parseRecurrence(tt.rule)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, at(tt.want), r.after(at(tt.t)))
+		})
+	}
+}
+
+func TestRecurrenceRule_Next(t *testing.T) {
+	tests := []struct {
+		name        string
+		rule        string
+		dueAt       string
+		completedAt string
+		want        string
+	}{
+		{
+			name:        "should follow the due date when completed early",
+			rule:        "FREQ=WEEKLY",
+			dueAt:       "2030-01-07T09:00:00Z",
+			completedAt: "2030-01-06T18:00:00Z",
+			want:        "2030-01-14T09:00:00Z",
+		},
+		{
+			name:        "should follow the due date when completed before the next occurrence",
+			rule:        "FREQ=WEEKLY",
+			dueAt:       "2030-01-07T09:00:00Z",
+			completedAt: "2030-01-10T18:00:00Z",
+			want:        "2030-01-14T09:00:00Z",
+		},
+		{
+			name:        "should roll forward to the first occurrence after a late completion",
+			rule:        "FREQ=WEEKLY",
+			dueAt:       "2030-01-07T09:00:00Z",
+			completedAt: "2030-01-20T18:00:00Z",
+			want:        "2030-01-21T09:00:00Z",
+		},
+		{
+			name:        "should roll forward over the weeks skipped by the interval",
+			rule:        "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO",
+			dueAt:       "2030-01-07T09:00:00Z",
+			completedAt: "2030-01-22T18:00:00Z",
+			want:        "2030-02-04T09:00:00Z",
+		},
+		{
+			name:        "should roll forward a monthly occurrence clamped to the end of the month",
+			rule:        "FREQ=MONTHLY;BYMONTHDAY=31",
+			dueAt:       "2030-01-31T09:00:00Z",
+			completedAt: "2030-03-05T18:00:00Z",
+			want:        "2030-03-31T09:00:00Z",
+		},
+		{
+			name:        "should follow the completion of a rule from the completion",
+			rule:        "FREQ=DAILY;INTERVAL=3;X-FROM=COMPLETION",
+			dueAt:       "2030-01-07T09:00:00Z",
+			completedAt: "2030-01-10T18:00:00Z",
+			want:        "2030-01-13T18:00:00Z",
+		},
+		{
+			name:        "should follow the completion of a todo without due date",
+			rule:        "FREQ=DAILY",
+			completedAt: "2030-01-10T18:00:00Z",
+			want:        "2030-01-11T18:00:00Z",
+		},
+		{
+			name:        "should compute the occurrences in UTC",
+			rule:        "FREQ=DAILY",
+			dueAt:       "2030-01-07T23:30:00-02:00",
+			completedAt: "2030-01-07T12:00:00Z",
+			want:        "2030-01-09T01:30:00Z",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := parseRecurrence(tt.rule)
+			if !assert.NoError(t, err) {
+				return
+			}
+			var dueAt *time.Time
+			if tt.dueAt != "" {
+				d := at(tt.dueAt)
+				dueAt = &d
+			}
+			got := r.next(dueAt, at(tt.completedAt))
+			if assert.NotNil(t, got) {
+				assert.Equal(t, at(tt.want), *got)
+				assert.Equal(t, time.UTC, got.Location(), "the due date of the next occurrence should be in UTC")
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package todos
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	// MaxSnoozeDuration is the greatest duration accepted by SnoozeTodo
+	MaxSnoozeDuration = 366 * 24 * time.Hour
+	// reminderBatchSize is the greatest number of reminders claimed at once by SendReminders
+	reminderBatchSize = 100
+	// webhookTimeout is the delay after which a request of the WebhookNotifier is abandoned
+	webhookTimeout = 10 * time.Second
+	// releaseTimeout is the delay given to a store to release a reminder that could not be sent
+	releaseTimeout = 5 * time.Second
+)
+
+// Notifier sends the reminders of the todos to their owner
+type Notifier interface {
+	// Notify sends the reminder, an error is returned when it could not be sent so it is sent again later
+	Notify(ctx context.Context, reminder Reminder) error
+}
+
+// LogNotifier writes the reminders to a log
+type LogNotifier struct {
+	Log *log.Logger
+}
+
+func (n LogNotifier) Notify(ctx context.Context, reminder Reminder) error {
+	n.Log.Printf("info : reminder for user %d of todo %d %q, due at %v and reminded at %v", reminder.Todo.OwnerId,
+		reminder.Todo.Id, reminder.Todo.Task, reminder.Todo.DueAt, reminder.RemindAt)
+	return nil
+}
+
+// WebhookNotifier sends the reminders in the JSON body of a POST request to an URL,
+// the reminder is sent again later when the response status is not 2xx
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookNotifier returns the WebhookNotifier sending the reminders to url
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Client: &http.Client{Timeout: webhookTimeout}}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, reminder Reminder) error {
+	body, err := json.Marshal(reminder)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// the body is read so the connection can be reused
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s answered %s", n.URL, resp.Status)
+	}
+	return nil
+}
+
+// getReminderTime returns the time the owner of the todo is reminded of it : its remind_at or else its due_at,
+// nil when it has neither
+func getReminderTime(t *Todo) *time.Time {
+	if t.RemindAt != nil {
+		return t.RemindAt
+	}
+	return t.DueAt
+}
+
+// toReminders returns the reminders of the todos claimed by ClaimReminders, in the order of their reminder time
+func toReminders(todos []*Todo) []*Reminder {
+	res := make([]*Reminder, 0, len(todos))
+	for _, t := range todos {
+		if remindAt := getReminderTime(t); remindAt != nil {
+			res = append(res, &Reminder{RemindAt: *remindAt, Todo: *t})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if !res[i].RemindAt.Equal(res[j].RemindAt) {
+			return res[i].RemindAt.Before(res[j].RemindAt)
+		}
+		return res[i].Todo.Id < res[j].Todo.Id
+	})
+	return res
+}
+
+// getPatchRemindAt returns the remind_at column value of a todo after the patch, nil when it is reminded at its due date.
+// patch.RemindAt is nil when the reminder does not change and the zero time when it is removed
+func getPatchRemindAt(current *time.Time, patch TodoPatch) *time.Time {
+	if patch.RemindAt == nil {
+		return current
+	}
+	return normalizeDueAt(patch.RemindAt)
+}
+
+// getUpdateRemindAt returns the TodoPatch.RemindAt replacing the reminder of a todo by the one of todo, the zero time when it has none
+func getUpdateRemindAt(todo Todo) *time.Time {
+	remindAt := time.Time{}
+	if todo.RemindAt != nil {
+		remindAt = *todo.RemindAt
+	}
+	return &remindAt
+}
+
+// getNextRemindAt returns the remind_at of the next occurrence of the recurring todo t due at nextDueAt,
+// it is reminded as long before its due date as t
+func getNextRemindAt(t *Todo, nextDueAt *time.Time) *time.Time {
+	if t.RemindAt == nil || t.DueAt == nil || nextDueAt == nil {
+		return nil
+	}
+	remindAt := nextDueAt.Add(t.RemindAt.Sub(*t.DueAt))
+	return &remindAt
+}
+
+// getSnoozeUntil returns the new reminder time of a todo snoozed at now
+func getSnoozeUntil(snooze TodoSnooze, now time.Time) (time.Time, error) {
+	if (snooze.Duration == nil) == (snooze.Until == nil) {
+		return time.Time{}, &ValidationError{Field: "duration", Message: "or until must be given, but not both"}
+	}
+	if snooze.Until != nil {
+		if !snooze.Until.After(now) {
+			return time.Time{}, &ValidationError{Field: "until", Message: "must be in the future"}
+		}
+		return *snooze.Until, nil
+	}
+	duration, ok := parseDuration(*snooze.Duration, MaxSnoozeDuration)
+	if !ok {
+		return time.Time{}, &ValidationError{Field: "duration",
+			Message: fmt.Sprintf("must be a positive duration like 90m, 2h or 1d, of at most %d days", MaxSnoozeDuration/(24*time.Hour))}
+	}
+	return now.Add(duration), nil
+}
+
+// SendReminders sends with notifier the reminders claimed in store whose time is at or before now and returns their number.
+// the reminders that could not be sent are released, so they are sent again by the next call.
+func SendReminders(ctx context.Context, store Storage, notifier Notifier, now time.Time, log *log.Logger) (int, error) {
+	reminders, err := store.ClaimReminders(ctx, now, reminderBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, reminder := range reminders {
+		err := ctx.Err()
+		if err == nil {
+			err = notifier.Notify(ctx, *reminder)
+		}
+		if err == nil {
+			sent++
+			continue
+		}
+		log.Printf("error : reminder of todo %d could not be sent, it will be sent again, error : %v", reminder.Todo.Id, err)
+		// the reminder is released even when ctx is done, so it is sent after a restart
+		releaseCtx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+		if err := store.ReleaseReminder(releaseCtx, reminder.Todo.Id, reminder.RemindAt); err != nil {
+			log.Printf("error : reminder of todo %d could not be released, it will not be sent, error : %v", reminder.Todo.Id, err)
+		}
+		cancel()
+	}
+	return sent, nil
+}
+
+// RunReminderScheduler sends with notifier every interval the reminders whose time is reached, until ctx is done.
+// it does nothing when interval is not positive.
+func RunReminderScheduler(ctx context.Context, store Storage, notifier Notifier, interval time.Duration, log *log.Logger) {
+	if interval <= 0 {
+		log.Printf("info : reminder scheduler is disabled, no reminder is sent")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// the reminders are claimed by batches, a full batch is followed by the next one without waiting
+		sent := reminderBatchSize
+		for sent == reminderBatchSize && ctx.Err() == nil {
+			var err error
+			sent, err = SendReminders(ctx, store, notifier, time.Now(), log)
+			if err != nil {
+				log.Printf("error : reminder scheduler could not claim the reminders, error : %v", err)
+			} else if sent > 0 {
+				log.Printf("info : reminder scheduler sent %d reminders", sent)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			log.Printf("info : reminder scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
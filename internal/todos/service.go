@@ -1,16 +1,27 @@
 package todos
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/labstack/echo/v4"
+	"github.com/lao-tseu-is-alive/go-cloud-learning-01-http/pkg/jwtauth"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type Service struct {
 	Log   *log.Logger
 	Store Storage
+	// CacheControl is the value of the Cache-Control header sent with the todos, nothing is sent when it is empty
+	CacheControl string
+	// TrustUserHeader is true when the users are trusted from the X-User header of the requests without token nor API key,
+	// otherwise these requests are rejected
+	TrustUserHeader bool
 }
 
 type ErrorService struct {
@@ -23,41 +34,303 @@ func (e *ErrorService) Error() string {
 	return fmt.Sprintf("Status[%d] %s. error: %v", e.Status, e.Msg, e.Err)
 }
 
+// storeErrorStatus returns the http status code and the message corresponding to an error returned by the Storage :
+// 404 for ErrNotFound, 400 for a ValidationError, 409 for ErrConflict, 412 for ErrPreconditionFailed
+// and 500 for all the others
+func storeErrorStatus(operation string, todoId int32, err error) (int, string) {
+	var validationErr *ValidationError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound, fmt.Sprintf("todo id : %d does not exist", todoId)
+	case errors.As(err, &validationErr):
+		return http.StatusBadRequest, fmt.Sprintf("%s %s", operation, validationErr.Error())
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict, fmt.Sprintf("%s %v", operation, err)
+	case errors.Is(err, ErrPreconditionFailed):
+		return http.StatusPreconditionFailed,
+			fmt.Sprintf("%s todo id : %d was modified, the %s header does not match its current %s", operation, todoId, HeaderIfMatch, HeaderETag)
+	default:
+		return http.StatusInternalServerError, fmt.Sprintf("%s there was a problem with the store :%v", operation, err)
+	}
+}
+
+// storeError converts an error returned by the Storage to the response sent to the client, see storeErrorStatus.
+// a *BlockedError gets the 409 Conflict TodoBlocked response listing the blockers not yet completed
+func (s Service) storeError(ctx echo.Context, operation string, todoId int32, err error) error {
+	var blockedErr *BlockedError
+	if errors.As(err, &blockedErr) {
+		return echo.NewHTTPError(http.StatusConflict, TodoBlocked{Code: http.StatusConflict, Message: fmt.Sprintf("%s %v", operation, err),
+			TodoId: blockedErr.TodoId, Blockers: blockedErr.Blockers})
+	}
+	status, msg := storeErrorStatus(operation, todoId, err)
+	switch status {
+	case http.StatusNotFound:
+		return ctx.JSON(http.StatusNotFound, ErrorService{
+			Err:    err,
+			Status: http.StatusNotFound,
+			Msg:    msg,
+		})
+	case http.StatusInternalServerError:
+		s.Log.Printf("error : %s(%d) store unexpectedly failed, error : %v", operation, todoId, err)
+	}
+	return echo.NewHTTPError(status, msg)
+}
+
+// Authenticate is the echo middleware resolving the user making the request from its X-API-Key header, from the sub
+// claim of its token validated by jwtauth.Middleware, or from its X-User header when TrustUserHeader is true.
+// the handlers then only see and change the todos of this user. skipper selects the requests made anonymously.
+func (s Service) Authenticate(skipper func(ctx echo.Context) bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if skipper(ctx) {
+				return next(ctx)
+			}
+			if key := ctx.Request().Header.Get(HeaderApiKey); key != "" {
+				reqCtx, err := s.authenticateApiKey(ctx, key)
+				if err != nil {
+					return err
+				}
+				ctx.SetRequest(ctx.Request().WithContext(reqCtx))
+				return next(ctx)
+			}
+			var login string
+			if claims := jwtauth.ClaimsFromContext(ctx); claims != nil {
+				login, _ = claims["sub"].(string)
+				if login == "" {
+					return echo.NewHTTPError(http.StatusUnauthorized, "the token has no sub claim with the login of the user")
+				}
+			} else if !s.TrustUserHeader {
+				return echo.NewHTTPError(http.StatusUnauthorized, "a valid bearer token or API key is required")
+			} else {
+				login = ctx.Request().Header.Get(HeaderUser)
+				if login == "" {
+					return echo.NewHTTPError(http.StatusUnauthorized, fmt.Sprintf("the %s header is required", HeaderUser))
+				}
+			}
+			user, err := s.Store.GetUser(ctx.Request().Context(), login)
+			if err != nil {
+				if errors.Is(err, ErrUserNotFound) {
+					return echo.NewHTTPError(http.StatusUnauthorized, fmt.Sprintf("user %s does not exist", login))
+				}
+				s.Log.Printf("error : Authenticate(%s) store unexpectedly failed, error : %v", login, err)
+				return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.GetUser :%v", err))
+			}
+			ctx.SetRequest(ctx.Request().WithContext(WithUser(ctx.Request().Context(), user)))
+			return next(ctx)
+		}
+	}
+}
+
+// authenticateApiKey returns the context of a request made with the API key, carrying the key and the user it acts for.
+// the key is read from the store at every request, so a revoked key is rejected immediately
+func (s Service) authenticateApiKey(ctx echo.Context, key string) (context.Context, error) {
+	reqCtx := ctx.Request().Context()
+	apiKey, err := s.Store.GetApiKey(reqCtx, hashApiKey(key))
+	if err != nil {
+		if errors.Is(err, ErrApiKeyNotFound) {
+			return nil, echo.NewHTTPError(http.StatusUnauthorized, "the API key does not exist or was revoked")
+		}
+		s.Log.Printf("error : Authenticate API key store unexpectedly failed, error : %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.GetApiKey :%v", err))
+	}
+	now := time.Now()
+	if apiKey.ExpiresAt != nil && !now.Before(*apiKey.ExpiresAt) {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "the API key is expired")
+	}
+	user, err := s.Store.GetUser(reqCtx, apiKey.Login)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, echo.NewHTTPError(http.StatusUnauthorized, fmt.Sprintf("user %s does not exist", apiKey.Login))
+		}
+		s.Log.Printf("error : Authenticate(%s) store unexpectedly failed, error : %v", apiKey.Login, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.GetUser :%v", err))
+	}
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyLastUsedResolution {
+		// the request is not rejected when the last use cannot be recorded
+		if err := s.Store.TouchApiKey(reqCtx, apiKey.Id, now); err != nil {
+			s.Log.Printf("error : TouchApiKey(%d) store unexpectedly failed, error : %v", apiKey.Id, err)
+		}
+	}
+	return withApiKey(WithUser(reqCtx, user), apiKey), nil
+}
+
+// isAdmin returns true only if the role of the user making the request grants the admin permission,
+// and the request is not made with an API key without the admin scope
+func isAdmin(ctx echo.Context) bool {
+	return hasPermission(ctx.Request().Context(), PermissionAdmin)
+}
+
+// getMatchVersion returns the version the todo must still have in the store when the change is applied,
+// it is 0 when the request has no If-Match header. ErrPreconditionFailed is returned if ifMatch does not
+// contain the current ETag of the todo, after checking the todo is in the state required by the change.
+func (s Service) getMatchVersion(ctx echo.Context, todoId int32, state todoState, ifMatch *string) (int32, error) {
+	if ifMatch == nil {
+		return 0, nil
+	}
+	current, err := s.Store.Get(ctx.Request().Context(), todoId)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkTodoState(current, state, 0); err != nil {
+		return 0, err
+	}
+	if !matchETag(*ifMatch, current.Version) {
+		return 0, ErrPreconditionFailed
+	}
+	// the store checks the version again, so a change made by someone else in the meantime is also detected
+	return current.Version, nil
+}
+
+// addComputedFields returns copies of the todos with the fields computed from the other todos of the store :
+// the numbers of their live children and their blocked flag
+func (s Service) addComputedFields(ctx echo.Context, todos []*Todo) ([]*Todo, error) {
+	if len(todos) == 0 {
+		return todos, nil
+	}
+	ids := make([]int32, len(todos))
+	for i, t := range todos {
+		ids[i] = t.Id
+	}
+	stats, err := s.Store.CountChildren(ctx.Request().Context(), ids)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.CountChildren :%v", err))
+	}
+	openBlockers, err := s.Store.OpenBlockers(ctx.Request().Context(), ids)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.OpenBlockers :%v", err))
+	}
+	return withBlocked(withChildrenStats(todos, stats), openBlockers), nil
+}
+
+// getTree returns copies of the root todos with their live descendants nested in their children,
+// all of them with their computed fields
+func (s Service) getTree(ctx echo.Context, roots []*Todo) ([]*Todo, error) {
+	ids := make([]int32, len(roots))
+	for i, t := range roots {
+		ids[i] = t.Id
+	}
+	descendants, err := s.Store.Descendants(ctx.Request().Context(), ids)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.Descendants :%v", err))
+	}
+	todos, err := s.addComputedFields(ctx, append(append(make([]*Todo, 0, len(roots)+len(descendants)), roots...), descendants...))
+	if err != nil {
+		return nil, err
+	}
+	return buildTree(todos[:len(roots)], todos[len(roots):]), nil
+}
+
 // GetMaxId returns the greatest todos id used by now
 // curl -H "Content-Type: application/json" 'http://localhost:8080/todos/maxid'
 func (s Service) GetMaxId(ctx echo.Context) error {
 	s.Log.Println("# Entering GetMaxId()")
 	var maxTodoId int32 = 0
-	maxTodoId, _ = s.Store.GetMaxId()
+	maxTodoId, _ = s.Store.GetMaxId(ctx.Request().Context())
 	s.Log.Printf("# Exit GetMaxId() maxTodoId: %d", maxTodoId)
 	return ctx.JSON(http.StatusOK, maxTodoId)
 }
 
-func (s Service) GetTodo(ctx echo.Context, todoId int32) error {
+//GetTodo will retrieve the Todo with the given todoId, or 304 Not Modified when the client copy is still valid
+//curl -i -H "Content-Type: application/json" -H 'If-None-Match: "1"' 'http://localhost:8080/todos/1'
+func (s Service) GetTodo(ctx echo.Context, todoId int32, params GetTodoParams) error {
 	s.Log.Printf("# Entering GetTodo(%d)", todoId)
-	if s.Store.Exist(todoId) == false {
-		return ctx.JSON(http.StatusNotFound, ErrorService{
-			Err:    errors.New("not found"),
-			Status: http.StatusNotFound,
-			Msg:    fmt.Sprintf("todo id : %d does not exist", todoId),
-		})
+	todo, err := s.Store.Get(ctx.Request().Context(), todoId)
+	if err == nil {
+		// a todo in the trash is only visible with GetTrash
+		err = checkTodoState(todo, liveTodo, 0)
 	}
-	todo, err := s.Store.Get(todoId)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("problem retrieving todo :%v", err))
+		return s.storeError(ctx, "GetTodo", todoId, err)
+	}
+	// the validators depend on the computed fields
+	list, err := s.addComputedFields(ctx, []*Todo{todo})
+	if err != nil {
+		return err
+	}
+	etag, lastModified := todoValidators(list[0], time.Now())
+	setCacheHeaders(ctx, etag, lastModified, s.CacheControl)
+	if isNotModified(params.IfNoneMatch, params.IfModifiedSince, etag, lastModified) {
+		return ctx.NoContent(http.StatusNotModified)
 	}
-	return ctx.JSON(http.StatusOK, todo)
+	return ctx.JSON(http.StatusOK, list[0])
 }
 
-//GetTodos will retrieve all Todos in the store and return then
+//GetTodos will retrieve one page of the Todos matching the filter parameters in the store and return then
+//the total number of matching todos is sent in the X-Total-Count header, and links to other pages in the Link header
 //to test it with curl you can try :
 //curl -H "Content-Type: application/json" 'http://localhost:8080/todos' |json_pp
+//curl -i -H "Content-Type: application/json" 'http://localhost:8080/todos?limit=10&offset=20'
+//curl -H "Content-Type: application/json" 'http://localhost:8080/todos?completed=false&sort=-created_at' |json_pp
+//the ETag header is computed from a fingerprint of the store, send it back in If-None-Match to get 304 Not Modified
+//when no todo was created, updated, deleted or became overdue since
+//the administrators can get the todos of all the users with all_owners=true
+//curl -H "Content-Type: application/json" -H "X-User: admin" 'http://localhost:8080/todos?all_owners=true' |json_pp
+//with tree=true only the root todos are paginated, each one with its descendants nested in its children
+//curl -H "Content-Type: application/json" 'http://localhost:8080/todos?tree=true' |json_pp
 func (s Service) GetTodos(ctx echo.Context, params GetTodosParams) error {
 	s.Log.Printf("# Entering GetTodos() %v", params)
-	list, err := s.Store.List(0, 100)
+	listParams, err := getListParams(params)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	tree := params.Tree != nil && *params.Tree
+	listParams.Filter.Roots = tree
+	if params.AllOwners != nil && *params.AllOwners {
+		if !isAdmin(ctx) {
+			return echo.NewHTTPError(http.StatusForbidden, "GetTodos all_owners is only allowed to the administrators")
+		}
+		ctx.SetRequest(ctx.Request().WithContext(withAllOwners(ctx.Request().Context())))
+	}
+	// the fingerprint is read before the list, so a change made meanwhile can only make the ETag older than the list
+	fingerprint, err := s.Store.Fingerprint(ctx.Request().Context(), time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.Fingerprint :%v", err))
+	}
+	etag := fingerprintETag(fingerprint)
+	// Last-Modified is not sent for the lists, a deleted todo leaves no modification time behind
+	setCacheHeaders(ctx, etag, nil, s.CacheControl)
+	if isNotModified(params.IfNoneMatch, nil, etag, nil) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+	list, err := s.Store.List(ctx.Request().Context(), listParams)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.List :%v", err))
 	}
+	total, err := s.Store.Count(ctx.Request().Context(), listParams.Filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.Count :%v", err))
+	}
+	setPaginationHeaders(ctx, listParams, list, total)
+	if tree {
+		list, err = s.getTree(ctx, list)
+	} else {
+		list, err = s.addComputedFields(ctx, list)
+	}
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, list)
+}
+
+//SearchTodos will retrieve the Todos containing all the words of the query parameter q, the most relevant first
+//to test it with curl you can try :
+//curl -H "Content-Type: application/json" 'http://localhost:8080/todos/search?q=learn' |json_pp
+func (s Service) SearchTodos(ctx echo.Context, params SearchTodosParams) error {
+	s.Log.Printf("# Entering SearchTodos() %v", params)
+	if len(strings.TrimSpace(params.Q)) < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, "SearchTodos q cannot be empty")
+	}
+	limit := DefaultSearchLimit
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > MaxSearchLimit {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("SearchTodos limit must be between 1 and %d", MaxSearchLimit))
+		}
+		limit = int(*params.Limit)
+	}
+	list, err := s.Store.Search(ctx.Request().Context(), params.Q, limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.Search :%v", err))
+	}
 	return ctx.JSON(http.StatusOK, list)
 }
 
@@ -71,34 +344,24 @@ func (s Service) CreateTodo(ctx echo.Context) error {
 	if err := ctx.Bind(newTodo); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("CreateTodo has invalid format [%v]", err))
 	}
-	if len(newTodo.Task) < 1 {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprint("CreateTodo task cannot be empty"))
-	}
-	if len(newTodo.Task) < 6 {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprint("CreateTodo task minLength is 5"))
-	}
 	s.Log.Printf("# CreateTodo() newTodo : %#v\n", newTodo)
-	todoCreated, err := s.Store.Create(*newTodo)
+	todoCreated, err := s.Store.Create(ctx.Request().Context(), *newTodo)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("problem saving new todo :%v", err))
+		return s.storeError(ctx, "CreateTodo", 0, err)
 	}
 	s.Log.Printf("# CreateTodo() Todo %#v\n", todoCreated)
+	setETag(ctx, todoCreated)
 	return ctx.JSON(http.StatusCreated, todoCreated)
 
 }
 
-// UpdateTodo will store the modified information in the store for the given todoId
+// UpdateTodo will store the modified information in the store for the given todoId,
+// when the If-Match header is given the todo is only updated if it still has one of these ETag values,
+// a todo whose blockers are not all completed cannot be completed, 409 Conflict lists them then
 // curl -v -XPUT -H "Content-Type: application/json" -d '{"id": 3, "task":"learn Linux", "completed": true}'  'http://localhost:8080/todos/3'
-// curl -v -XPUT -H "Content-Type: application/json" -d '{"id": 3, "task":"learn Linux", "completed": false}'  'http://localhost:8080/todos/3'
-func (s Service) UpdateTodo(ctx echo.Context, todoId int32) error {
+// curl -v -XPUT -H "Content-Type: application/json" -H 'If-Match: "1"' -d '{"id": 3, "task":"learn Linux", "completed": false}'  'http://localhost:8080/todos/3'
+func (s Service) UpdateTodo(ctx echo.Context, todoId int32, params UpdateTodoParams) error {
 	s.Log.Printf("# Entering UpdateTodo(%d)", todoId)
-	if s.Store.Exist(todoId) == false {
-		return ctx.JSON(http.StatusNotFound, ErrorService{
-			Err:    errors.New("not found"),
-			Status: http.StatusNotFound,
-			Msg:    fmt.Sprintf("todo id : %d does not exist", todoId),
-		})
-	}
 	t := new(Todo)
 	if err := ctx.Bind(t); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("UpdateTodo has invalid format [%v]", err))
@@ -111,30 +374,796 @@ func (s Service) UpdateTodo(ctx echo.Context, todoId int32) error {
 		return echo.NewHTTPError(http.StatusBadRequest,
 			fmt.Sprintf("UpdateTodo id : [%d] and posted Id [%d] cannot differ ", todoId, t.Id))
 	}
+	matchVersion, err := s.getMatchVersion(ctx, todoId, liveTodo, params.IfMatch)
+	if err != nil {
+		return s.storeError(ctx, "UpdateTodo", todoId, err)
+	}
+	updatedTodo, err := s.Store.Update(ctx.Request().Context(), todoId, *t, matchVersion)
+	if err != nil {
+		return s.storeError(ctx, "UpdateTodo", todoId, err)
+	}
+	list, err := s.addComputedFields(ctx, []*Todo{updatedTodo})
+	if err != nil {
+		return err
+	}
+	setETag(ctx, list[0])
+	return ctx.JSON(http.StatusOK, list[0])
+}
+
+// PatchTodo will change only the task, completed, list_id, tags, due_at, remind_at, priority, recurrence, parent_id and auto_complete fields given in a JSON Merge Patch or a JSON Patch body
+// for the given todoId, when the If-Match header is given the todo is only patched if it still has one of these ETag values
+// curl -v -XPATCH -H "Content-Type: application/merge-patch+json" -d '{"completed": true}'  'http://localhost:8080/todos/3'
+// curl -v -XPATCH -H "Content-Type: application/json-patch+json" -d '[{"op":"test","path":"/completed","value":false},{"op":"replace","path":"/task","value":"learn Linux"}]'  'http://localhost:8080/todos/3'
+func (s Service) PatchTodo(ctx echo.Context, todoId int32, params PatchTodoParams) error {
+	s.Log.Printf("# Entering PatchTodo(%d)", todoId)
+	mediaType, _, err := mime.ParseMediaType(ctx.Request().Header.Get(echo.HeaderContentType))
+	if err != nil || (mediaType != MIMEApplicationMergePatch && mediaType != MIMEApplicationJSONPatch) {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType,
+			fmt.Sprintf("PatchTodo %s must be %s or %s", echo.HeaderContentType, MIMEApplicationMergePatch, MIMEApplicationJSONPatch))
+	}
+	body, err := io.ReadAll(ctx.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("PatchTodo body could not be read [%v]", err))
+	}
+	// the patch is applied on the current todo, so the test operations and the immutable fields can be checked
+	current, err := s.Store.Get(ctx.Request().Context(), todoId)
+	if err == nil {
+		err = checkTodoState(current, liveTodo, 0)
+	}
+	if err != nil {
+		return s.storeError(ctx, "PatchTodo", todoId, err)
+	}
+	if params.IfMatch != nil && !matchETag(*params.IfMatch, current.Version) {
+		return s.storeError(ctx, "PatchTodo", todoId, ErrPreconditionFailed)
+	}
+	original, err := newTodoDocument(current)
+	if err != nil {
+		return s.storeError(ctx, "PatchTodo", todoId, err)
+	}
+	patched, _ := newTodoDocument(current)
+	if mediaType == MIMEApplicationMergePatch {
+		err = applyMergePatch(patched, body)
+	} else {
+		err = applyJSONPatch(patched, body)
+	}
+	if err != nil {
+		return s.storeError(ctx, "PatchTodo", todoId, err)
+	}
+	todoPatch, err := getTodoPatch(original, patched)
+	if err != nil {
+		return s.storeError(ctx, "PatchTodo", todoId, err)
+	}
+	patchedTodo := current
+	if todoPatch.Task != nil || todoPatch.Completed != nil || todoPatch.ListId != nil || todoPatch.Tags != nil ||
+		todoPatch.DueAt != nil || todoPatch.RemindAt != nil || todoPatch.Priority != nil || todoPatch.Recurrence != nil ||
+		todoPatch.ParentId != nil || todoPatch.AutoComplete != nil {
+		// the store only applies the patch to the version it was computed from,
+		// when there is nothing to change the todo keeps its version
+		patchedTodo, err = s.Store.Patch(ctx.Request().Context(), todoId, todoPatch, current.Version)
+		if err != nil {
+			if params.IfMatch == nil && errors.Is(err, ErrPreconditionFailed) {
+				err = fmt.Errorf("%w : todo id %d was modified while the patch was applied", ErrConflict, todoId)
+			}
+			return s.storeError(ctx, "PatchTodo", todoId, err)
+		}
+	}
+	list, err := s.addComputedFields(ctx, []*Todo{patchedTodo})
+	if err != nil {
+		return err
+	}
+	setETag(ctx, list[0])
+	return ctx.JSON(http.StatusOK, list[0])
+}
 
-	updatedTodo, err := s.Store.Update(todoId, *t)
+//BatchTodos will apply atomically in the store the array of create, update, complete and delete operations,
+//the response contains the result of each operation in the same order. when an operation fails none is applied,
+//the response gets the status of this operation and the other operations get 424 Failed Dependency.
+//curl -XPOST -H "Content-Type: application/json" -d '[{"op":"create","task":"learn Linux"},{"op":"complete","id":1}]'  'http://localhost:8080/todos:batch'
+func (s Service) BatchTodos(ctx echo.Context) error {
+	s.Log.Println("# Entering BatchTodos()")
+	var operations BatchTodosJSONBody
+	if err := ctx.Bind(&operations); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("BatchTodos has invalid format [%v]", err))
+	}
+	for _, operation := range operations {
+		// the policy of BatchTodos only requires todos:write, a batch deleting todos also requires the permission of DeleteTodo
+		if operation.Op == BatchOperationOpDelete {
+			if err := checkPermission(ctx.Request().Context(), "BatchTodos", PermissionTodosDelete); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	list, err := s.Store.Batch(ctx.Request().Context(), operations)
+	results := make([]BatchResult, len(operations))
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("problem updating todo :%v", err))
+		var batchErr *BatchError
+		if !errors.As(err, &batchErr) {
+			return s.storeError(ctx, "BatchTodos", 0, err)
+		}
+		var todoId int32 = 0
+		if failed := operations[batchErr.Index]; failed.Id != nil {
+			todoId = *failed.Id
+		}
+		status, msg := storeErrorStatus(fmt.Sprintf("BatchTodos operation %d", batchErr.Index), todoId, batchErr.Err)
+		switch status {
+		case http.StatusPreconditionFailed:
+			msg = fmt.Sprintf("BatchTodos operation %d todo id : %d was modified, it does not have version %d anymore",
+				batchErr.Index, todoId, *operations[batchErr.Index].Version)
+		case http.StatusInternalServerError:
+			s.Log.Printf("error : BatchTodos operation %d store unexpectedly failed, error : %v", batchErr.Index, batchErr.Err)
+		}
+		for i := range results {
+			failedDependency := fmt.Sprintf("not applied because operation %d failed", batchErr.Index)
+			results[i] = BatchResult{Status: http.StatusFailedDependency, Error: &failedDependency}
+		}
+		results[batchErr.Index] = BatchResult{Status: int32(status), Error: &msg}
+		return ctx.JSON(status, results)
+	}
+	for i, operation := range operations {
+		switch operation.Op {
+		case BatchOperationOpCreate:
+			results[i] = BatchResult{Status: http.StatusCreated, Todo: list[i]}
+		case BatchOperationOpDelete:
+			results[i] = BatchResult{Status: http.StatusNoContent}
+		default:
+			results[i] = BatchResult{Status: http.StatusOK, Todo: list[i]}
+		}
 	}
-	return ctx.JSON(http.StatusOK, updatedTodo)
+	return ctx.JSON(http.StatusOK, results)
 }
 
-// DeleteTodo will remove the given todoID entry from the store, and if not present will return 400 Bad Request
-//curl -v -XDELETE -H "Content-Type: application/json" 'http://localhost:8080/todos/3' ->  204 No Content if present and delete it
-//curl -v -XDELETE -H "Content-Type: application/json" 'http://localhost:8080/todos/93333' -> 400 Bad Request
-func (s Service) DeleteTodo(ctx echo.Context, todoId int32) error {
+// DeleteTodo will move the given todoID entry to the trash, and if not present will return 404 Not Found,
+// with purge=true the todo is removed permanently from the store, even if it is in the trash,
+// its children are moved to its parent, or deleted with it with children=cascade
+//curl -v -XDELETE -H "Content-Type: application/json" 'http://localhost:8080/todos/3' ->  204 No Content if present and move it to the trash
+//curl -v -XDELETE -H "Content-Type: application/json" 'http://localhost:8080/todos/3?purge=true' ->  204 No Content if present and delete it
+//curl -v -XDELETE -H "Content-Type: application/json" 'http://localhost:8080/todos/3?children=cascade' ->  204 No Content and move it to the trash with its children
+//curl -v -XDELETE -H "Content-Type: application/json" 'http://localhost:8080/todos/93333' -> 404 Not Found
+//curl -v -XDELETE -H "Content-Type: application/json" -H 'If-Match: "1"' 'http://localhost:8080/todos/3' -> 412 if the todo was modified
+func (s Service) DeleteTodo(ctx echo.Context, todoId int32, params DeleteTodoParams) error {
 	s.Log.Printf("# Entering DeleteTodo(%d)", todoId)
-	if s.Store.Exist(todoId) == false {
+	purge := params.Purge != nil && *params.Purge
+	cascade := false
+	if params.Children != nil {
+		switch *params.Children {
+		case DeleteTodoParamsChildrenCascade:
+			cascade = true
+		case DeleteTodoParamsChildrenReparent:
+		default:
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("DeleteTodo children must be %s or %s",
+				DeleteTodoParamsChildrenCascade, DeleteTodoParamsChildrenReparent))
+		}
+	}
+	state := liveTodo
+	if purge {
+		state = anyTodo
+	}
+	matchVersion, err := s.getMatchVersion(ctx, todoId, state, params.IfMatch)
+	if err != nil {
+		return s.storeError(ctx, "DeleteTodo", todoId, err)
+	}
+	if purge {
+		err = s.Store.Purge(ctx.Request().Context(), todoId, matchVersion, cascade)
+	} else {
+		err = s.Store.Delete(ctx.Request().Context(), todoId, matchVersion, cascade)
+	}
+	if err != nil {
+		return s.storeError(ctx, "DeleteTodo", todoId, err)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+//GetTrash will retrieve one page of the Todos in the trash, in the order of their id
+//the total number of todos in the trash is sent in the X-Total-Count header, and links to other pages in the Link header
+//curl -i -H "Content-Type: application/json" 'http://localhost:8080/todos/trash?limit=10'
+func (s Service) GetTrash(ctx echo.Context, params GetTrashParams) error {
+	s.Log.Printf("# Entering GetTrash() %v", params)
+	listParams := ListParams{
+		Offset: 0,
+		Limit:  DefaultListLimit,
+		Filter: TodoFilter{Trashed: true},
+	}
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > MaxListLimit {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("GetTrash limit must be between 1 and %d", MaxListLimit))
+		}
+		listParams.Limit = int(*params.Limit)
+	}
+	if params.Offset != nil {
+		if *params.Offset < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "GetTrash offset cannot be negative")
+		}
+		listParams.Offset = int(*params.Offset)
+	}
+	list, err := s.Store.List(ctx.Request().Context(), listParams)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.List :%v", err))
+	}
+	total, err := s.Store.Count(ctx.Request().Context(), listParams.Filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.Count :%v", err))
+	}
+	setPaginationHeaders(ctx, listParams, list, total)
+	return ctx.JSON(http.StatusOK, list)
+}
+
+// listOpenTodos returns one page of the live todos not completed matching filter,
+// sorted by due date then priority, like GetTrash for the paging parameters
+func (s Service) listOpenTodos(ctx echo.Context, operation string, filter TodoFilter, limit, offset *int32) error {
+	completed := false
+	filter.Completed = &completed
+	listParams := ListParams{
+		Offset: 0,
+		Limit:  DefaultListLimit,
+		Filter: filter,
+		Sort:   "due_at",
+	}
+	if limit != nil {
+		if *limit < 1 || *limit > MaxListLimit {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s limit must be between 1 and %d", operation, MaxListLimit))
+		}
+		listParams.Limit = int(*limit)
+	}
+	if offset != nil {
+		if *offset < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s offset cannot be negative", operation))
+		}
+		listParams.Offset = int(*offset)
+	}
+	list, err := s.Store.List(ctx.Request().Context(), listParams)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.List :%v", err))
+	}
+	total, err := s.Store.Count(ctx.Request().Context(), listParams.Filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.Count :%v", err))
+	}
+	setPaginationHeaders(ctx, listParams, list, total)
+	if list, err = s.addComputedFields(ctx, list); err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, list)
+}
+
+//GetOverdueTodos will retrieve one page of the todos not completed whose due date is past, sorted by due date then priority
+//curl -i -H "Content-Type: application/json" 'http://localhost:8080/todos/overdue?limit=10'
+func (s Service) GetOverdueTodos(ctx echo.Context, params GetOverdueTodosParams) error {
+	s.Log.Printf("# Entering GetOverdueTodos() %v", params)
+	now := time.Now()
+	return s.listOpenTodos(ctx, "GetOverdueTodos", TodoFilter{DueBefore: &now}, params.Limit, params.Offset)
+}
+
+//GetUpcomingTodos will retrieve one page of the todos not completed which are due from now to the end of the within
+//duration, sorted by due date then priority
+//curl -i -H "Content-Type: application/json" 'http://localhost:8080/todos/upcoming?within=72h'
+func (s Service) GetUpcomingTodos(ctx echo.Context, params GetUpcomingTodosParams) error {
+	s.Log.Printf("# Entering GetUpcomingTodos() %v", params)
+	within := DefaultUpcomingWithin
+	if params.Within != nil {
+		var err error
+		if within, err = parseWithin(*params.Within); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("GetUpcomingTodos %v", err))
+		}
+	}
+	now := time.Now()
+	end := now.Add(within)
+	return s.listOpenTodos(ctx, "GetUpcomingTodos", TodoFilter{DueAfter: &now, DueBefore: &end}, params.Limit, params.Offset)
+}
+
+//GetReadyTodos will retrieve one page of the todos not completed whose live blockers are all completed,
+//sorted by due date then priority
+//curl -i -H "Content-Type: application/json" 'http://localhost:8080/todos/ready?limit=10'
+func (s Service) GetReadyTodos(ctx echo.Context, params GetReadyTodosParams) error {
+	s.Log.Printf("# Entering GetReadyTodos() %v", params)
+	return s.listOpenTodos(ctx, "GetReadyTodos", TodoFilter{Ready: true}, params.Limit, params.Offset)
+}
+
+// RestoreTodo will move back the given todoID entry from the trash, the restored todo gets a new version
+// curl -v -XPOST -H "Content-Type: application/json" 'http://localhost:8080/todos/3/restore' -> 409 if the todo is not in the trash
+func (s Service) RestoreTodo(ctx echo.Context, todoId int32, params RestoreTodoParams) error {
+	s.Log.Printf("# Entering RestoreTodo(%d)", todoId)
+	matchVersion, err := s.getMatchVersion(ctx, todoId, trashedTodo, params.IfMatch)
+	if err != nil {
+		return s.storeError(ctx, "RestoreTodo", todoId, err)
+	}
+	restoredTodo, err := s.Store.Restore(ctx.Request().Context(), todoId, matchVersion)
+	if err != nil {
+		return s.storeError(ctx, "RestoreTodo", todoId, err)
+	}
+	setETag(ctx, restoredTodo)
+	return ctx.JSON(http.StatusOK, restoredTodo)
+}
+
+//GetTodoChildren will retrieve one page of the live children of the Todo with the given todoId in the order of their id,
+//with the numbers of their own children
+//curl -i -H "Content-Type: application/json" 'http://localhost:8080/todos/1/children?limit=10'
+func (s Service) GetTodoChildren(ctx echo.Context, todoId int32, params GetTodoChildrenParams) error {
+	s.Log.Printf("# Entering GetTodoChildren(%d) %v", todoId, params)
+	listParams := ListParams{
+		Offset: 0,
+		Limit:  DefaultListLimit,
+		Filter: TodoFilter{ParentId: &todoId},
+	}
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > MaxListLimit {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("GetTodoChildren limit must be between 1 and %d", MaxListLimit))
+		}
+		listParams.Limit = int(*params.Limit)
+	}
+	if params.Offset != nil {
+		if *params.Offset < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "GetTodoChildren offset cannot be negative")
+		}
+		listParams.Offset = int(*params.Offset)
+	}
+	t, err := s.Store.Get(ctx.Request().Context(), todoId)
+	if err == nil {
+		err = checkTodoState(t, liveTodo, 0)
+	}
+	if err != nil {
+		return s.storeError(ctx, "GetTodoChildren", todoId, err)
+	}
+	list, err := s.Store.List(ctx.Request().Context(), listParams)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.List :%v", err))
+	}
+	total, err := s.Store.Count(ctx.Request().Context(), listParams.Filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.Count :%v", err))
+	}
+	setPaginationHeaders(ctx, listParams, list, total)
+	if list, err = s.addComputedFields(ctx, list); err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, list)
+}
+
+//GetTodoDependencies will retrieve the live blockers of the Todo with the given todoId in the order of their id
+//curl -H "Content-Type: application/json" 'http://localhost:8080/todos/3/dependencies' |json_pp
+func (s Service) GetTodoDependencies(ctx echo.Context, todoId int32) error {
+	s.Log.Printf("# Entering GetTodoDependencies(%d)", todoId)
+	t, err := s.Store.Get(ctx.Request().Context(), todoId)
+	if err == nil {
+		err = checkTodoState(t, liveTodo, 0)
+	}
+	if err != nil {
+		return s.storeError(ctx, "GetTodoDependencies", todoId, err)
+	}
+	return s.sendBlockers(ctx, http.StatusOK, todoId)
+}
+
+//AddTodoDependency will block the Todo with the given todoId by the todo blocker_id until it is completed,
+//the response contains all the live blockers of the todo
+//curl -XPOST -H "Content-Type: application/json" -d '{"blocker_id":2}' 'http://localhost:8080/todos/3/dependencies'
+func (s Service) AddTodoDependency(ctx echo.Context, todoId int32) error {
+	s.Log.Printf("# Entering AddTodoDependency(%d)", todoId)
+	dependency := &NewTodoDependency{}
+	if err := ctx.Bind(dependency); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("AddTodoDependency has invalid format [%v]", err))
+	}
+	if err := s.Store.AddDependency(ctx.Request().Context(), todoId, dependency.BlockerId); err != nil {
+		return s.storeError(ctx, "AddTodoDependency", todoId, err)
+	}
+	return s.sendBlockers(ctx, http.StatusCreated, todoId)
+}
+
+//RemoveTodoDependency will remove the blocker blockerId of the Todo with the given todoId
+//curl -v -XDELETE 'http://localhost:8080/todos/3/dependencies/2' ->  204 No Content if the todo was blocked by this todo
+func (s Service) RemoveTodoDependency(ctx echo.Context, todoId int32, blockerId int32) error {
+	s.Log.Printf("# Entering RemoveTodoDependency(%d, %d)", todoId, blockerId)
+	err := s.Store.RemoveDependency(ctx.Request().Context(), todoId, blockerId)
+	if errors.Is(err, ErrDependencyNotFound) {
 		return ctx.JSON(http.StatusNotFound, ErrorService{
-			Err:    errors.New("not found"),
+			Err:    err,
 			Status: http.StatusNotFound,
-			Msg:    fmt.Sprintf("todo id : %d does not exist", todoId),
+			Msg:    fmt.Sprintf("todo id : %d is not blocked by todo %d", todoId, blockerId),
 		})
-	} else {
-		err := s.Store.Delete(todoId)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("problem deleting todo :%v", err))
+	}
+	if err != nil {
+		return s.storeError(ctx, "RemoveTodoDependency", todoId, err)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// sendBlockers sends with the given status the live blockers of the todo with their computed fields
+func (s Service) sendBlockers(ctx echo.Context, status int, todoId int32) error {
+	list, err := s.Store.Blockers(ctx.Request().Context(), todoId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.Blockers :%v", err))
+	}
+	if list, err = s.addComputedFields(ctx, list); err != nil {
+		return err
+	}
+	return ctx.JSON(status, list)
+}
+
+//GetTodoHistory will retrieve the changes of the Todo with the given todoId in the order they occurred,
+//the history of a purged todo is still available
+//curl -H "Content-Type: application/json" 'http://localhost:8080/todos/1/history' |json_pp
+func (s Service) GetTodoHistory(ctx echo.Context, todoId int32) error {
+	s.Log.Printf("# Entering GetTodoHistory(%d)", todoId)
+	list, err := s.Store.History(ctx.Request().Context(), todoId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.History :%v", err))
+	}
+	if len(list) == 0 {
+		// the todos created before the history was recorded have no events
+		if _, err := s.Store.Get(ctx.Request().Context(), todoId); err != nil {
+			return s.storeError(ctx, "GetTodoHistory", todoId, err)
+		}
+	}
+	return ctx.JSON(http.StatusOK, list)
+}
+
+// seriesError converts an error of the series routes to the response sent to the client,
+// a todo that never recurred has no series so it gives 404 like a todo that does not exist
+func (s Service) seriesError(ctx echo.Context, operation string, todoId int32, err error) error {
+	if errors.Is(err, ErrNotRecurring) {
+		return ctx.JSON(http.StatusNotFound, ErrorService{
+			Err:    err,
+			Status: http.StatusNotFound,
+			Msg:    fmt.Sprintf("todo id : %d never recurred", todoId),
+		})
+	}
+	return s.storeError(ctx, operation, todoId, err)
+}
+
+//GetTodoSeries will retrieve the occurrences of the series of the recurring todo with the given todoId in the order of their id
+//curl -H "Content-Type: application/json" 'http://localhost:8080/todos/1/series' |json_pp
+func (s Service) GetTodoSeries(ctx echo.Context, todoId int32) error {
+	s.Log.Printf("# Entering GetTodoSeries(%d)", todoId)
+	t, err := s.Store.Get(ctx.Request().Context(), todoId)
+	if err == nil && t.SeriesId == nil {
+		err = ErrNotRecurring
+	}
+	if err != nil {
+		return s.seriesError(ctx, "GetTodoSeries", todoId, err)
+	}
+	list, err := s.Store.List(ctx.Request().Context(), ListParams{Limit: MaxListLimit, Filter: TodoFilter{SeriesId: t.SeriesId}})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.List :%v", err))
+	}
+	return ctx.JSON(http.StatusOK, list)
+}
+
+//UpdateTodoSeries will change the recurrence rule of the occurrences not yet completed of the series of the given todoId
+//curl -XPUT -H "Content-Type: application/json" -d '{"recurrence":"FREQ=WEEKLY;BYDAY=MO,TH"}'  'http://localhost:8080/todos/1/series'
+func (s Service) UpdateTodoSeries(ctx echo.Context, todoId int32) error {
+	s.Log.Printf("# Entering UpdateTodoSeries(%d)", todoId)
+	recurrence := &TodoRecurrence{}
+	if err := ctx.Bind(recurrence); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("UpdateTodoSeries has invalid format [%v]", err))
+	}
+	if recurrence.Recurrence == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "UpdateTodoSeries recurrence cannot be empty, DELETE stops the series")
+	}
+	list, err := s.Store.UpdateSeries(ctx.Request().Context(), todoId, recurrence.Recurrence)
+	if err != nil {
+		return s.seriesError(ctx, "UpdateTodoSeries", todoId, err)
+	}
+	return ctx.JSON(http.StatusOK, list)
+}
+
+//StopTodoSeries will remove the recurrence rule of the occurrences not yet completed of the series of the given todoId,
+//so no occurrence is created anymore when they are completed
+//curl -v -XDELETE 'http://localhost:8080/todos/1/series'
+func (s Service) StopTodoSeries(ctx echo.Context, todoId int32) error {
+	s.Log.Printf("# Entering StopTodoSeries(%d)", todoId)
+	if _, err := s.Store.UpdateSeries(ctx.Request().Context(), todoId, ""); err != nil {
+		return s.seriesError(ctx, "StopTodoSeries", todoId, err)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+//SnoozeTodo will postpone the reminder of the Todo with the given todoId for a duration or until a date-time,
+//its remind_at is changed so the reminder is sent again at this time
+//curl -XPOST -H "Content-Type: application/json" -d '{"duration":"2h"}'  'http://localhost:8080/todos/3/snooze'
+func (s Service) SnoozeTodo(ctx echo.Context, todoId int32) error {
+	s.Log.Printf("# Entering SnoozeTodo(%d)", todoId)
+	snooze := &TodoSnooze{}
+	if err := ctx.Bind(snooze); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("SnoozeTodo has invalid format [%v]", err))
+	}
+	until, err := getSnoozeUntil(*snooze, time.Now())
+	if err != nil {
+		return s.storeError(ctx, "SnoozeTodo", todoId, err)
+	}
+	current, err := s.Store.Get(ctx.Request().Context(), todoId)
+	if err == nil {
+		err = checkTodoState(current, liveTodo, 0)
+	}
+	if err == nil && current.Completed {
+		err = fmt.Errorf("%w : todo id %d is completed, it has no reminder", ErrConflict, todoId)
+	}
+	if err != nil {
+		return s.storeError(ctx, "SnoozeTodo", todoId, err)
+	}
+	// the todo is only snoozed if it was not completed in the meantime
+	snoozedTodo, err := s.Store.Patch(ctx.Request().Context(), todoId, TodoPatch{RemindAt: &until}, current.Version)
+	if errors.Is(err, ErrPreconditionFailed) {
+		err = fmt.Errorf("%w : todo id %d was modified while it was snoozed", ErrConflict, todoId)
+	}
+	if err != nil {
+		return s.storeError(ctx, "SnoozeTodo", todoId, err)
+	}
+	list, err := s.addComputedFields(ctx, []*Todo{snoozedTodo})
+	if err != nil {
+		return err
+	}
+	setETag(ctx, list[0])
+	return ctx.JSON(http.StatusOK, list[0])
+}
+
+//GetEvents will retrieve the changes of all the Todos that occurred at or after the since parameter, in the order they occurred
+//to get the next changes, call it again with the occurred_at of the last event and skip the events already received
+//curl -H "Content-Type: application/json" 'http://localhost:8080/events?since=2022-01-01T00:00:00Z&limit=10' |json_pp
+func (s Service) GetEvents(ctx echo.Context, params GetEventsParams) error {
+	s.Log.Printf("# Entering GetEvents() %v", params)
+	var since time.Time
+	if params.Since != nil {
+		since = *params.Since
+	}
+	limit := DefaultEventsLimit
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > MaxEventsLimit {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("GetEvents limit must be between 1 and %d", MaxEventsLimit))
+		}
+		limit = int(*params.Limit)
+	}
+	list, err := s.Store.Events(ctx.Request().Context(), since, limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.Events :%v", err))
+	}
+	return ctx.JSON(http.StatusOK, list)
+}
+
+//GetUsers will retrieve all the users, only the administrators can do it
+//curl -H "Content-Type: application/json" -H "X-User: admin" 'http://localhost:8080/users' |json_pp
+func (s Service) GetUsers(ctx echo.Context) error {
+	s.Log.Println("# Entering GetUsers()")
+	list, err := s.Store.ListUsers(ctx.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.ListUsers :%v", err))
+	}
+	return ctx.JSON(http.StatusOK, list)
+}
+
+//CreateUser will store the NewUser in the store, only the administrators can do it
+//curl -XPOST -H "Content-Type: application/json" -H "X-User: admin" -d '{"login":"bob","name":"Bob"}'  'http://localhost:8080/users'
+func (s Service) CreateUser(ctx echo.Context) error {
+	s.Log.Println("# Entering CreateUser()")
+	newUser := &NewUser{}
+	if err := ctx.Bind(newUser); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("CreateUser has invalid format [%v]", err))
+	}
+	userCreated, err := s.Store.CreateUser(ctx.Request().Context(), *newUser)
+	if err != nil {
+		return s.storeError(ctx, "CreateUser", 0, err)
+	}
+	return ctx.JSON(http.StatusCreated, userCreated)
+}
+
+//GetApiKeys will display all the API keys without their key, only the administrators can do it
+//curl -H "Content-Type: application/json" -H "X-User: admin" 'http://localhost:8080/admin/apikeys'
+func (s Service) GetApiKeys(ctx echo.Context) error {
+	s.Log.Println("# Entering GetApiKeys()")
+	list, err := s.Store.ListApiKeys(ctx.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.ListApiKeys :%v", err))
+	}
+	return ctx.JSON(http.StatusOK, list)
+}
+
+//CreateApiKey will store a new API key acting for a user and return it, the key is only returned in this response
+//curl -XPOST -H "Content-Type: application/json" -H "X-User: admin" -d '{"name":"backup","scopes":["todos:read"]}'  'http://localhost:8080/admin/apikeys'
+func (s Service) CreateApiKey(ctx echo.Context) error {
+	s.Log.Println("# Entering CreateApiKey()")
+	newApiKey := &NewApiKey{}
+	if err := ctx.Bind(newApiKey); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("CreateApiKey has invalid format [%v]", err))
+	}
+	if err := validateNewApiKey(*newApiKey); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("CreateApiKey %v", err))
+	}
+	login := UserFromContext(ctx.Request().Context()).Login
+	if newApiKey.Login != nil {
+		login = *newApiKey.Login
+	}
+	key, hash, err := generateApiKey()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("CreateApiKey could not generate the key :%v", err))
+	}
+	apiKey, err := s.Store.CreateApiKey(ctx.Request().Context(), ApiKey{
+		ExpiresAt: newApiKey.ExpiresAt,
+		Login:     login,
+		Name:      strings.TrimSpace(newApiKey.Name),
+		Prefix:    key[:apiKeyPrefixLength],
+		Scopes:    newApiKey.Scopes,
+	}, hash)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("CreateApiKey user %s does not exist", login))
+		}
+		return s.storeError(ctx, "CreateApiKey", 0, err)
+	}
+	return ctx.JSON(http.StatusCreated, ApiKeyCreated{ApiKey: *apiKey, Key: key})
+}
+
+//RevokeApiKey will remove the API key with the given id, the requests made with it are rejected immediately
+//curl -XDELETE -H "X-User: admin" 'http://localhost:8080/admin/apikeys/1'
+func (s Service) RevokeApiKey(ctx echo.Context, apiKeyId int32) error {
+	s.Log.Printf("# Entering RevokeApiKey(%d)", apiKeyId)
+	if err := s.Store.RevokeApiKey(ctx.Request().Context(), apiKeyId); err != nil {
+		if errors.Is(err, ErrApiKeyNotFound) {
+			return ctx.JSON(http.StatusNotFound, ErrorService{
+				Err:    err,
+				Status: http.StatusNotFound,
+				Msg:    fmt.Sprintf("api key id : %d does not exist", apiKeyId),
+			})
+		}
+		return s.storeError(ctx, "RevokeApiKey", 0, err)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// listError converts an error returned by the list methods of the Storage to the response sent to the client
+func (s Service) listError(ctx echo.Context, operation string, listId int32, err error) error {
+	if errors.Is(err, ErrListNotFound) {
+		return ctx.JSON(http.StatusNotFound, ErrorService{
+			Err:    err,
+			Status: http.StatusNotFound,
+			Msg:    fmt.Sprintf("list id : %d does not exist", listId),
+		})
+	}
+	return s.storeError(ctx, operation, 0, err)
+}
+
+//GetLists will retrieve the lists of todos in the order of their id, with the number of their todos
+//curl -H "Content-Type: application/json" 'http://localhost:8080/lists' |json_pp
+func (s Service) GetLists(ctx echo.Context) error {
+	s.Log.Println("# Entering GetLists()")
+	list, err := s.Store.ListTodoLists(ctx.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.ListTodoLists :%v", err))
+	}
+	return ctx.JSON(http.StatusOK, list)
+}
+
+//CreateList will store a new list of todos, the names of the lists of a user are unique
+//curl -XPOST -H "Content-Type: application/json" -d '{"name":"groceries"}'  'http://localhost:8080/lists'
+func (s Service) CreateList(ctx echo.Context) error {
+	s.Log.Println("# Entering CreateList()")
+	newList := &NewTodoList{}
+	if err := ctx.Bind(newList); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("CreateList has invalid format [%v]", err))
+	}
+	listCreated, err := s.Store.CreateTodoList(ctx.Request().Context(), *newList)
+	if err != nil {
+		return s.listError(ctx, "CreateList", 0, err)
+	}
+	return ctx.JSON(http.StatusCreated, listCreated)
+}
+
+//GetList will retrieve the list of todos with the given listId and the number of its todos
+//curl -H "Content-Type: application/json" 'http://localhost:8080/lists/1' |json_pp
+func (s Service) GetList(ctx echo.Context, listId int32) error {
+	s.Log.Printf("# Entering GetList(%d)", listId)
+	list, err := s.Store.GetTodoList(ctx.Request().Context(), listId)
+	if err != nil {
+		return s.listError(ctx, "GetList", listId, err)
+	}
+	return ctx.JSON(http.StatusOK, list)
+}
+
+//UpdateList will rename the list of todos with the given listId
+//curl -XPUT -H "Content-Type: application/json" -d '{"name":"shopping"}'  'http://localhost:8080/lists/1'
+func (s Service) UpdateList(ctx echo.Context, listId int32) error {
+	s.Log.Printf("# Entering UpdateList(%d)", listId)
+	newList := &NewTodoList{}
+	if err := ctx.Bind(newList); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("UpdateList has invalid format [%v]", err))
+	}
+	updatedList, err := s.Store.UpdateTodoList(ctx.Request().Context(), listId, *newList)
+	if err != nil {
+		return s.listError(ctx, "UpdateList", listId, err)
+	}
+	return ctx.JSON(http.StatusOK, updatedList)
+}
+
+//DeleteList will remove the list of todos with the given listId, its todos are removed permanently with it
+//curl -v -XDELETE -H "Content-Type: application/json" 'http://localhost:8080/lists/1' ->  204 No Content if present
+func (s Service) DeleteList(ctx echo.Context, listId int32) error {
+	s.Log.Printf("# Entering DeleteList(%d)", listId)
+	if err := s.Store.DeleteTodoList(ctx.Request().Context(), listId); err != nil {
+		return s.listError(ctx, "DeleteList", listId, err)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+//GetListTodos will retrieve one page of the Todos of the list with the given listId, like GetTodos
+//curl -i -H "Content-Type: application/json" 'http://localhost:8080/lists/1/todos?completed=false'
+func (s Service) GetListTodos(ctx echo.Context, listId int32, params GetListTodosParams) error {
+	s.Log.Printf("# Entering GetListTodos(%d) %v", listId, params)
+	listParams, err := getListParams(GetTodosParams{
+		Limit:     params.Limit,
+		Offset:    params.Offset,
+		After:     params.After,
+		Completed: params.Completed,
+		Sort:      params.Sort,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	listParams.Filter.ListId = &listId
+	if _, err := s.Store.GetTodoList(ctx.Request().Context(), listId); err != nil {
+		return s.listError(ctx, "GetListTodos", listId, err)
+	}
+	list, err := s.Store.List(ctx.Request().Context(), listParams)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.List :%v", err))
+	}
+	total, err := s.Store.Count(ctx.Request().Context(), listParams.Filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.Count :%v", err))
+	}
+	setPaginationHeaders(ctx, listParams, list, total)
+	if list, err = s.addComputedFields(ctx, list); err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, list)
+}
+
+//CreateListTodo will store the NewTodo task in the list with the given listId
+//curl -XPOST -H "Content-Type: application/json" -d '{"task":"buy some milk"}'  'http://localhost:8080/lists/1/todos'
+func (s Service) CreateListTodo(ctx echo.Context, listId int32) error {
+	s.Log.Printf("# Entering CreateListTodo(%d)", listId)
+	newTodo := &NewTodo{}
+	if err := ctx.Bind(newTodo); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("CreateListTodo has invalid format [%v]", err))
+	}
+	if _, err := s.Store.GetTodoList(ctx.Request().Context(), listId); err != nil {
+		return s.listError(ctx, "CreateListTodo", listId, err)
+	}
+	newTodo.ListId = &listId
+	todoCreated, err := s.Store.Create(ctx.Request().Context(), *newTodo)
+	if err != nil {
+		return s.storeError(ctx, "CreateListTodo", 0, err)
+	}
+	setETag(ctx, todoCreated)
+	return ctx.JSON(http.StatusCreated, todoCreated)
+}
+
+//GetTags will retrieve the tags of the live todos in alphabetical order, with the number of todos having them
+//curl -H "Content-Type: application/json" 'http://localhost:8080/tags' |json_pp
+func (s Service) GetTags(ctx echo.Context) error {
+	s.Log.Println("# Entering GetTags()")
+	list, err := s.Store.ListTags(ctx.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("there was a problem when calling store.ListTags :%v", err))
+	}
+	return ctx.JSON(http.StatusOK, list)
+}
+
+//RenameTag will replace the tag tagName by the NewTag name in all the todos having it, the two tags are merged
+//when some todos already have the new name
+//curl -XPUT -H "Content-Type: application/json" -d '{"name":"work"}'  'http://localhost:8080/tags/job'
+func (s Service) RenameTag(ctx echo.Context, tagName string) error {
+	s.Log.Printf("# Entering RenameTag(%s)", tagName)
+	newTag := &NewTag{}
+	if err := ctx.Bind(newTag); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("RenameTag has invalid format [%v]", err))
+	}
+	tag, err := s.Store.RenameTag(ctx.Request().Context(), tagName, newTag.Name)
+	if err != nil {
+		if errors.Is(err, ErrTagNotFound) {
+			return ctx.JSON(http.StatusNotFound, ErrorService{
+				Err:    err,
+				Status: http.StatusNotFound,
+				Msg:    fmt.Sprintf("tag : %s does not exist", tagName),
+			})
 		}
-		return ctx.NoContent(http.StatusNoContent)
+		return s.storeError(ctx, "RenameTag", 0, err)
 	}
+	return ctx.JSON(http.StatusOK, tag)
 }
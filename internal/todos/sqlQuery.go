@@ -0,0 +1,196 @@
+package todos
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timeValueFunc converts a time to the value that can be compared with the timestamp columns of a sql database
+type timeValueFunc func(t time.Time) interface{}
+
+// sqlQuery accumulates the conditions and the arguments of a parameterised sql query.
+// placeholders are numbered like $1, $2 ... so the query can be sent as is to the database driver.
+type sqlQuery struct {
+	conditions []string
+	args       []interface{}
+	timeValue  timeValueFunc
+}
+
+// arg registers a new argument and returns its placeholder
+func (q *sqlQuery) arg(value interface{}) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+// where adds a condition to the WHERE clause, every ? in condition is replaced by the placeholder of value
+func (q *sqlQuery) where(condition string, value interface{}) {
+	q.conditions = append(q.conditions, strings.ReplaceAll(condition, "?", q.arg(value)))
+}
+
+// whereIn adds a column IN (...) condition to the WHERE clause, with a placeholder for every value
+func (q *sqlQuery) whereIn(column string, values []int32) {
+	if len(values) == 0 {
+		q.conditions = append(q.conditions, "1 = 0")
+		return
+	}
+	placeholders := make([]string, len(values))
+	for i, value := range values {
+		placeholders[i] = q.arg(value)
+	}
+	q.conditions = append(q.conditions, fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", ")))
+}
+
+// whereTags adds the condition selecting the todos having all the tags when all is true, or at least one of them otherwise,
+// the tags must not contain duplicates
+func (q *sqlQuery) whereTags(tags []string, all bool) {
+	placeholders := make([]string, len(tags))
+	for i, tag := range tags {
+		placeholders[i] = q.arg(tag)
+	}
+	condition := fmt.Sprintf("id IN (SELECT tt.todo_id FROM todo_tags tt JOIN tags tg ON tg.id = tt.tag_id WHERE tg.name IN (%s)",
+		strings.Join(placeholders, ", "))
+	if all {
+		condition += fmt.Sprintf(" GROUP BY tt.todo_id HAVING COUNT(*) = %s", q.arg(len(tags)))
+	}
+	q.conditions = append(q.conditions, condition+")")
+}
+
+// whereClause returns the WHERE clause combining all the conditions with AND, or an empty string
+func (q *sqlQuery) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// newFilterQuery returns a sqlQuery with the conditions of the given filter on the todos table,
+// restricted to the todos of ownerId when it is not 0
+func newFilterQuery(filter TodoFilter, ownerId int32, timeValue timeValueFunc) *sqlQuery {
+	q := &sqlQuery{timeValue: timeValue}
+	if filter.Trashed {
+		q.conditions = append(q.conditions, "deleted_at IS NOT NULL")
+	} else {
+		q.conditions = append(q.conditions, "deleted_at IS NULL")
+	}
+	if ownerId != 0 {
+		q.where("owner_id = ?", ownerId)
+	}
+	if filter.Ids != nil {
+		q.whereIn("id", filter.Ids)
+	}
+	if filter.ListId != nil {
+		q.where("list_id = ?", *filter.ListId)
+	}
+	if filter.SeriesId != nil {
+		q.where("series_id = ?", *filter.SeriesId)
+	}
+	if filter.ParentId != nil {
+		q.where("parent_id = ?", *filter.ParentId)
+	}
+	if filter.Roots {
+		q.conditions = append(q.conditions, "parent_id IS NULL")
+	}
+	if filter.Ready {
+		q.conditions = append(q.conditions, `NOT EXISTS (SELECT 1 FROM todo_dependencies d JOIN todos b ON b.id = d.blocker_id
+    WHERE d.todo_id = todos.id AND b.deleted_at IS NULL AND NOT b.completed)`)
+	}
+	if len(filter.Tags) > 0 {
+		q.whereTags(filter.Tags, filter.AllTags)
+	}
+	if filter.Completed != nil {
+		q.where("completed = ?", *filter.Completed)
+	}
+	if filter.CreatedAfter != nil {
+		q.where("created_at >= ?", q.timeValue(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		q.where("created_at < ?", q.timeValue(*filter.CreatedBefore))
+	}
+	if filter.CompletedAfter != nil {
+		q.where("completed_at >= ?", q.timeValue(*filter.CompletedAfter))
+	}
+	if filter.CompletedBefore != nil {
+		q.where("completed_at < ?", q.timeValue(*filter.CompletedBefore))
+	}
+	if filter.DueAfter != nil {
+		q.where("due_at >= ?", q.timeValue(*filter.DueAfter))
+	}
+	if filter.DueBefore != nil {
+		q.where("due_at < ?", q.timeValue(*filter.DueBefore))
+	}
+	return q
+}
+
+// newTodoTagsQuery returns a sqlQuery reading the tags of the todos in alphabetical order,
+// its rows are read in todoTagRow and given to setTodosTags
+func newTodoTagsQuery(todos []*Todo) (string, []interface{}) {
+	q := &sqlQuery{}
+	ids := make([]int32, len(todos))
+	for i, t := range todos {
+		ids[i] = t.Id
+	}
+	q.whereIn("tt.todo_id", ids)
+	return "SELECT tt.todo_id, tg.name FROM todo_tags tt JOIN tags tg ON tg.id = tt.tag_id" + q.whereClause() + " ORDER BY tg.name", q.args
+}
+
+// newChildrenStatsQuery returns a sqlQuery counting the live children of the todos with given ids, restricted to the todos
+// of ownerId when it is not 0. its rows are read in childrenStatsRow and given to toChildrenStats
+func newChildrenStatsQuery(ids []int32, ownerId int32) (string, []interface{}) {
+	q := &sqlQuery{conditions: []string{"deleted_at IS NULL"}}
+	q.whereIn("parent_id", ids)
+	if ownerId != 0 {
+		q.where("owner_id = ?", ownerId)
+	}
+	return "SELECT parent_id, COUNT(*) AS count, COUNT(CASE WHEN completed THEN 1 END) AS completed FROM todos" +
+		q.whereClause() + " GROUP BY parent_id", q.args
+}
+
+// newOpenBlockersQuery returns a sqlQuery reading the live blockers of the todos with given ids in the order of their id,
+// restricted to the todos of ownerId when it is not 0. its rows are read in blockerRow and given to toOpenBlockers
+func newOpenBlockersQuery(ids []int32, ownerId int32) (string, []interface{}) {
+	q := &sqlQuery{conditions: []string{"b.deleted_at IS NULL"}}
+	q.whereIn("d.todo_id", ids)
+	if ownerId != 0 {
+		q.where("b.owner_id = ?", ownerId)
+	}
+	return "SELECT d.todo_id, d.blocker_id, NOT b.completed AS open FROM todo_dependencies d JOIN todos b ON b.id = d.blocker_id" +
+		q.whereClause() + " ORDER BY d.todo_id, d.blocker_id", q.args
+}
+
+// newDescendantsQuery returns a sqlQuery reading with selectTodos the live descendants of the todos with given ids
+// in the order of their id, restricted to the todos of ownerId when it is not 0. the tree is walked with a recursive query
+// which does not go below the todos in the trash
+func newDescendantsQuery(selectTodos string, ids []int32, ownerId int32) (string, []interface{}) {
+	q := &sqlQuery{conditions: []string{"deleted_at IS NULL"}}
+	q.whereIn("parent_id", ids)
+	roots := q.whereClause()
+	q.conditions = []string{"id IN (SELECT id FROM descendants)"}
+	if ownerId != 0 {
+		q.where("owner_id = ?", ownerId)
+	}
+	return `WITH RECURSIVE descendants(id) AS (
+    SELECT id FROM todos` + roots + `
+    UNION
+    SELECT t.id FROM todos t JOIN descendants d ON t.parent_id = d.id WHERE t.deleted_at IS NULL
+) ` + selectTodos + q.whereClause() + " ORDER BY id", q.args
+}
+
+// orderByClause returns the ORDER BY clause for a sort expression accepted by ParseSort
+func orderByClause(sort string) (string, error) {
+	field, descending, err := ParseSort(sort)
+	if err != nil {
+		return "", err
+	}
+	direction := "ASC"
+	if descending {
+		direction = "DESC"
+	}
+	switch field {
+	case "id":
+		return fmt.Sprintf(" ORDER BY id %s", direction), nil
+	case "due_at":
+		return fmt.Sprintf(" ORDER BY due_at %s NULLS LAST, priority ASC NULLS LAST, id %s", direction, direction), nil
+	}
+	return fmt.Sprintf(" ORDER BY %s %s NULLS LAST, id %s", field, direction, direction), nil
+}
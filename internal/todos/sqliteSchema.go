@@ -0,0 +1,172 @@
+package todos
+
+// sqliteTimeLayout is the format of the timestamp columns in sqlite, it sorts like the time it represents
+const sqliteTimeLayout = "2006-01-02T15:04:05.000Z"
+
+// sqliteNow is the sql expression returning the current time in the sqliteTimeLayout format
+const sqliteNow = "strftime('%Y-%m-%dT%H:%M:%fZ', 'now')"
+
+// sqliteMigrations contains the successive versions of the sqlite schema, they are applied in order when
+// the database is opened, and the index of the last one applied is kept in the user_version pragma.
+// like the files in db/migrations, a migration must never be modified once released, add a new one instead.
+var sqliteMigrations = []string{
+	// 1 : todos table with its full text search index
+	`CREATE TABLE todos
+(
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    task         TEXT      NOT NULL,
+    completed    BOOLEAN   NOT NULL DEFAULT FALSE,
+    created_at   TIMESTAMP NOT NULL DEFAULT (` + sqliteNow + `),
+    completed_at TIMESTAMP
+);
+
+CREATE VIRTUAL TABLE todos_fts USING fts5(task, content='todos', content_rowid='id');
+
+CREATE TRIGGER todos_fts_insert AFTER INSERT ON todos
+BEGIN
+    INSERT INTO todos_fts(rowid, task) VALUES (new.id, new.task);
+END;
+
+CREATE TRIGGER todos_fts_delete AFTER DELETE ON todos
+BEGIN
+    INSERT INTO todos_fts(todos_fts, rowid, task) VALUES ('delete', old.id, old.task);
+END;
+
+CREATE TRIGGER todos_fts_update AFTER UPDATE OF task ON todos
+BEGIN
+    INSERT INTO todos_fts(todos_fts, rowid, task) VALUES ('delete', old.id, old.task);
+    INSERT INTO todos_fts(rowid, task) VALUES (new.id, new.task);
+END;`,
+	// 2 : version of the todos used for optimistic concurrency
+	`ALTER TABLE todos ADD COLUMN version INTEGER NOT NULL DEFAULT 1;`,
+	// 3 : time of the last change of the todos, used for http caching
+	`ALTER TABLE todos ADD COLUMN updated_at TIMESTAMP;
+UPDATE todos SET updated_at = COALESCE(completed_at, created_at);`,
+	// 4 : time when the todos were moved to the trash
+	`ALTER TABLE todos ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX todos_deleted_at_idx ON todos (deleted_at) WHERE deleted_at IS NOT NULL;`,
+	// 5 : history of the changes of the todos, kept after the todos are purged
+	`CREATE TABLE todo_events
+(
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    todo_id     INTEGER   NOT NULL,
+    type        TEXT      NOT NULL,
+    actor       TEXT      NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    before      TEXT,
+    after       TEXT
+);
+
+CREATE INDEX todo_events_todo_id_idx ON todo_events (todo_id, id);
+
+CREATE INDEX todo_events_occurred_at_idx ON todo_events (occurred_at);`,
+	// 6 : users owning the todos, the existing todos and their events are given to the administrator
+	`CREATE TABLE users
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    login      TEXT      NOT NULL UNIQUE,
+    name       TEXT,
+    is_admin   BOOLEAN   NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT (` + sqliteNow + `)
+);
+
+INSERT INTO users (login, is_admin) VALUES ('admin', TRUE);
+
+ALTER TABLE todos ADD COLUMN owner_id INTEGER REFERENCES users (id);
+UPDATE todos SET owner_id = (SELECT id FROM users WHERE login = 'admin');
+
+CREATE INDEX todos_owner_id_idx ON todos (owner_id);
+
+ALTER TABLE todo_events ADD COLUMN owner_id INTEGER;
+UPDATE todo_events SET owner_id = (SELECT id FROM users WHERE login = 'admin');`,
+	// 7 : API keys of the scripts acting for a user, only the sha-256 hash of the keys is stored
+	`CREATE TABLE api_keys
+(
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    name         TEXT      NOT NULL,
+    prefix       TEXT      NOT NULL,
+    hash         TEXT      NOT NULL UNIQUE,
+    user_id      INTEGER   NOT NULL REFERENCES users (id),
+    scopes       TEXT      NOT NULL,
+    created_at   TIMESTAMP NOT NULL DEFAULT (` + sqliteNow + `),
+    expires_at   TIMESTAMP,
+    last_used_at TIMESTAMP
+);`,
+	// 8 : roles of the users, the administrators keep the admin role and the other users get the editor role
+	`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'editor' CHECK (role IN ('viewer', 'editor', 'admin'));
+UPDATE users SET role = 'admin' WHERE is_admin;
+
+ALTER TABLE users DROP COLUMN is_admin;`,
+	// 9 : named lists of todos, the todos of a list are removed with it
+	`CREATE TABLE lists
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       TEXT      NOT NULL,
+    owner_id   INTEGER   NOT NULL REFERENCES users (id),
+    created_at TIMESTAMP NOT NULL DEFAULT (` + sqliteNow + `),
+    updated_at TIMESTAMP NOT NULL DEFAULT (` + sqliteNow + `),
+    UNIQUE (owner_id, name)
+);
+
+ALTER TABLE todos ADD COLUMN list_id INTEGER REFERENCES lists (id) ON DELETE CASCADE;
+
+CREATE INDEX todos_list_id_idx ON todos (list_id);`,
+	// 10 : tags of the todos, shared by the todos of their owner
+	`CREATE TABLE tags
+(
+    id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_id INTEGER NOT NULL REFERENCES users (id),
+    name     TEXT    NOT NULL,
+    UNIQUE (owner_id, name)
+);
+
+CREATE TABLE todo_tags
+(
+    todo_id INTEGER NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+    tag_id  INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (todo_id, tag_id)
+);
+
+CREATE INDEX todo_tags_tag_id_idx ON todo_tags (tag_id);`,
+	// 11 : due dates and priorities, the overdue and upcoming todos are read with the partial index
+	`ALTER TABLE todos ADD COLUMN due_at TIMESTAMP;
+ALTER TABLE todos ADD COLUMN priority INTEGER CHECK (priority BETWEEN 1 AND 4);
+
+CREATE INDEX todos_due_at_priority_idx ON todos (due_at, priority) WHERE deleted_at IS NULL AND NOT completed;
+
+CREATE INDEX todos_priority_idx ON todos (priority);`,
+	// 12 : recurrence rules, the occurrences of a recurring todo share the id of the first one as series_id
+	`ALTER TABLE todos ADD COLUMN recurrence TEXT;
+ALTER TABLE todos ADD COLUMN series_id INTEGER;
+
+CREATE INDEX todos_series_id_idx ON todos (series_id);`,
+	// 13 : subtasks, the children of a todo have its id as parent_id
+	`ALTER TABLE todos ADD COLUMN parent_id INTEGER REFERENCES todos (id) ON DELETE SET NULL;
+ALTER TABLE todos ADD COLUMN auto_complete BOOLEAN;
+
+CREATE INDEX todos_parent_id_idx ON todos (parent_id);`,
+	// 14 : blocking dependencies, a todo cannot be completed while one of its blockers is open
+	`CREATE TABLE todo_dependencies
+(
+    todo_id    INTEGER NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+    blocker_id INTEGER NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+    PRIMARY KEY (todo_id, blocker_id),
+    CHECK (todo_id <> blocker_id)
+);
+
+CREATE INDEX todo_dependencies_blocker_id_idx ON todo_dependencies (blocker_id);`,
+	// 15 : reminders, reminded_at is the reminder time of the last reminder sent. the todos already overdue
+	// do not get a reminder for a due date that passed before the reminders existed
+	`ALTER TABLE todos ADD COLUMN remind_at TIMESTAMP;
+ALTER TABLE todos ADD COLUMN reminded_at TIMESTAMP;
+
+UPDATE todos SET reminded_at = due_at WHERE due_at <= strftime('%Y-%m-%dT%H:%M:%fZ', 'now');
+
+CREATE INDEX todos_reminder_idx ON todos (COALESCE(remind_at, due_at)) WHERE deleted_at IS NULL AND NOT completed;`,
+	// 16 : the creation time of the dependencies, the cached lists of todos are validated with the last one.
+	// the dependencies already there get the time of the migration
+	`ALTER TABLE todo_dependencies ADD COLUMN created_at TIMESTAMP;
+
+UPDATE todo_dependencies SET created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now');`,
+}
@@ -0,0 +1,1194 @@
+package todos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/georgysavva/scany/sqlscan"
+	"log"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"strings"
+	"time"
+)
+
+const (
+	getSqliteVersion  = "SELECT sqlite_version();"
+	sqliteTodoColumns = "id, task, completed, created_at, completed_at, updated_at, deleted_at, version, owner_id, list_id, due_at, priority, recurrence, series_id, parent_id, auto_complete, remind_at"
+	sqliteSelect      = "SELECT " + sqliteTodoColumns + " FROM todos"
+	sqliteGet         = sqliteSelect + " WHERE id=$1 AND ($2 = 0 OR owner_id = $2);"
+	sqliteExist       = "SELECT COUNT(*) FROM todos WHERE id=$1 AND deleted_at IS NULL AND ($2 = 0 OR owner_id = $2)"
+	sqliteCount       = "SELECT COUNT(*) FROM todos"
+	sqliteMaxId       = "SELECT COALESCE(MAX(id), 0) FROM todos WHERE ($1 = 0 OR owner_id = $1)"
+	sqliteCreate      = "INSERT INTO todos (task, owner_id, list_id, due_at, priority, recurrence, series_id, parent_id, auto_complete, remind_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, " + sqliteNow + ") RETURNING " + sqliteTodoColumns + ";"
+	sqlitePurge       = "DELETE FROM todos WHERE id = $1 AND ($2 = 0 OR version = $2)"
+	sqliteTrashed     = "SELECT id FROM todos WHERE deleted_at < $1 AND ($2 = 0 OR owner_id = $2) ORDER BY id"
+	// the aggregated times are returned as text, they are parsed with sqliteTimeLayout
+	sqliteFingerprint = `SELECT COALESCE(MAX(id), 0), COUNT(*),
+       MAX(COALESCE(updated_at, completed_at, created_at)),
+       COUNT(CASE WHEN NOT completed AND due_at < $2 THEN 1 END),
+       (SELECT COUNT(*) FROM todo_dependencies d JOIN todos t ON t.id = d.todo_id WHERE $1 = 0 OR t.owner_id = $1),
+       (SELECT MAX(d.created_at) FROM todo_dependencies d JOIN todos t ON t.id = d.todo_id WHERE $1 = 0 OR t.owner_id = $1)
+FROM todos WHERE ($1 = 0 OR owner_id = $1);`
+	// sqlitePatch changes only the fields whose parameter is not NULL and implements the completed_at business rule :
+	// it is set when the todo becomes completed, cleared when it is not completed anymore and left untouched otherwise.
+	// the row is only updated if $4 is 0 or the current version of the todo, the todo is removed from its list when $5 is 0,
+	// its due date is set to $7 only when $6 is true, its priority is removed when $8 is 0 and its recurrence is stopped
+	// when $9 is empty. a todo joins its own series the first time it gets a recurrence rule.
+	// it becomes a root todo when $10 is 0, and it is not completed with its children anymore when $11 is false.
+	// its reminder time is set to $13 only when $12 is true.
+	sqlitePatch = `UPDATE todos SET task = COALESCE($1, task), completed = COALESCE($2, completed),
+    completed_at = CASE WHEN COALESCE($2, completed) AND NOT completed THEN ` + sqliteNow + `
+        WHEN NOT COALESCE($2, completed) THEN NULL ELSE completed_at END,
+    list_id = CASE WHEN $5 IS NULL THEN list_id WHEN $5 = 0 THEN NULL ELSE $5 END,
+    due_at = CASE WHEN $6 THEN $7 ELSE due_at END,
+    priority = CASE WHEN $8 IS NULL THEN priority WHEN $8 = 0 THEN NULL ELSE $8 END,
+    recurrence = CASE WHEN $9 IS NULL THEN recurrence WHEN $9 = '' THEN NULL ELSE $9 END,
+    series_id = COALESCE(series_id, CASE WHEN NULLIF($9, '') IS NOT NULL THEN id END),
+    parent_id = CASE WHEN $10 IS NULL THEN parent_id WHEN $10 = 0 THEN NULL ELSE $10 END,
+    auto_complete = CASE WHEN $11 IS NULL THEN auto_complete WHEN $11 THEN TRUE END,
+    remind_at = CASE WHEN $12 THEN $13 ELSE remind_at END,
+    updated_at = ` + sqliteNow + `, version = version + 1
+WHERE id=$3 AND deleted_at IS NULL AND ($4 = 0 OR version = $4) RETURNING ` + sqliteTodoColumns + ";"
+	// sqliteDelete moves a live todo to the trash, sqliteRestore moves it back
+	sqliteDelete = `UPDATE todos SET deleted_at = ` + sqliteNow + `, updated_at = ` + sqliteNow + `, version = version + 1
+WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2) RETURNING ` + sqliteTodoColumns + ";"
+	sqliteRestore = `UPDATE todos SET deleted_at = NULL, updated_at = ` + sqliteNow + `, version = version + 1
+WHERE id = $1 AND deleted_at IS NOT NULL AND ($2 = 0 OR version = $2) RETURNING ` + sqliteTodoColumns + ";"
+	sqliteSearch = `SELECT t.id, t.task, t.completed, t.created_at, t.completed_at, t.updated_at, t.version, t.owner_id, t.list_id,
+       t.due_at, t.priority, t.recurrence, t.series_id, t.parent_id, t.auto_complete, t.remind_at,
+       -bm25(todos_fts) AS rank, highlight(todos_fts, 0, '` + headlineStartSel + `', '` + headlineStopSel + `') AS headline
+FROM todos_fts JOIN todos t ON t.id = todos_fts.rowid
+WHERE todos_fts MATCH $1 AND t.deleted_at IS NULL AND ($3 = 0 OR t.owner_id = $3)
+ORDER BY rank DESC, t.id
+LIMIT $2;`
+	sqliteEventColumns = "id, todo_id, owner_id, type, actor, occurred_at, before, after"
+	sqliteEventsInsert = `INSERT INTO todo_events (todo_id, owner_id, type, actor, occurred_at, before, after)
+VALUES($1, $2, $3, $4, $5, $6, $7);`
+	sqliteEventsHistory = "SELECT " + sqliteEventColumns + " FROM todo_events WHERE todo_id = $1 AND ($2 = 0 OR owner_id = $2) ORDER BY id;"
+	sqliteEventsSince   = "SELECT " + sqliteEventColumns + " FROM todo_events WHERE occurred_at >= $1 AND ($3 = 0 OR owner_id = $3) ORDER BY id LIMIT $2;"
+	sqliteUserColumns   = "id, login, name, role, role = 'admin' AS is_admin, created_at"
+	sqliteUsersCreate   = "INSERT INTO users (login, name, role) VALUES($1, $2, $3) RETURNING " + sqliteUserColumns + ";"
+	sqliteUsersGet      = "SELECT " + sqliteUserColumns + " FROM users WHERE login = $1;"
+	sqliteUsersList     = "SELECT " + sqliteUserColumns + " FROM users ORDER BY id;"
+	sqliteApiKeyColumns = "k.id, k.name, k.prefix, u.login, k.scopes, k.created_at, k.expires_at, k.last_used_at"
+	sqliteApiKeysSelect = "SELECT " + sqliteApiKeyColumns + " FROM api_keys k JOIN users u ON u.id = k.user_id"
+	sqliteApiKeysCreate = `INSERT INTO api_keys (name, prefix, hash, user_id, scopes, expires_at)
+SELECT $1, $2, $3, id, $5, $6 FROM users WHERE login = $4 RETURNING id;`
+	sqliteApiKeysGet    = sqliteApiKeysSelect + " WHERE k.id = $1;"
+	sqliteApiKeysByHash = sqliteApiKeysSelect + " WHERE k.hash = $1;"
+	sqliteApiKeysList   = sqliteApiKeysSelect + " ORDER BY k.id;"
+	sqliteApiKeysRevoke = "DELETE FROM api_keys WHERE id = $1;"
+	sqliteApiKeysTouch  = "UPDATE api_keys SET last_used_at = $2 WHERE id = $1;"
+	// the lists are read with the number of their todos and of their completed todos, the todos in the trash are not counted
+	sqliteListColumns = `l.id, l.name, l.owner_id, l.created_at, l.updated_at,
+       (SELECT COUNT(*) FROM todos t WHERE t.list_id = l.id AND t.deleted_at IS NULL) AS todos_count,
+       (SELECT COUNT(*) FROM todos t WHERE t.list_id = l.id AND t.deleted_at IS NULL AND t.completed) AS completed_count`
+	sqliteListsSelect = "SELECT " + sqliteListColumns + " FROM lists l"
+	sqliteListsGet    = sqliteListsSelect + " WHERE l.id = $1 AND ($2 = 0 OR l.owner_id = $2);"
+	sqliteListsList   = sqliteListsSelect + " WHERE ($1 = 0 OR l.owner_id = $1) ORDER BY l.id;"
+	sqliteListsOwned  = "SELECT COUNT(*) FROM lists WHERE id = $1 AND owner_id = $2"
+	sqliteListsCreate = "INSERT INTO lists (name, owner_id) VALUES($1, $2) RETURNING id;"
+	sqliteListsUpdate = "UPDATE lists SET name = $2, updated_at = " + sqliteNow + " WHERE id = $1 AND ($3 = 0 OR owner_id = $3) RETURNING id;"
+	sqliteListsExist  = "SELECT COUNT(*) FROM lists WHERE id = $1 AND ($2 = 0 OR owner_id = $2)"
+	sqliteListsTodos  = "SELECT id FROM todos WHERE list_id = $1 ORDER BY id"
+	sqliteListsDelete = "DELETE FROM lists WHERE id = $1;"
+	// the tags of a user are shared by its todos, only the tags of the live todos are listed and counted
+	sqliteTagsList = `SELECT tg.name, COUNT(*) AS todos_count FROM tags tg
+    JOIN todo_tags tt ON tt.tag_id = tg.id JOIN todos t ON t.id = tt.todo_id
+WHERE t.deleted_at IS NULL AND ($1 = 0 OR tg.owner_id = $1) GROUP BY tg.name ORDER BY tg.name;`
+	sqliteTagsCount = `SELECT COUNT(*) FROM tags tg JOIN todo_tags tt ON tt.tag_id = tg.id JOIN todos t ON t.id = tt.todo_id
+WHERE tg.name = $1 AND t.deleted_at IS NULL AND ($2 = 0 OR tg.owner_id = $2)`
+	sqliteTagsTodos     = "SELECT tt.todo_id FROM todo_tags tt JOIN tags tg ON tg.id = tt.tag_id WHERE tg.name = $1 AND ($2 = 0 OR tg.owner_id = $2) ORDER BY tt.todo_id"
+	sqliteTagsCreate    = "INSERT INTO tags (owner_id, name) VALUES($1, $2) ON CONFLICT (owner_id, name) DO NOTHING;"
+	sqliteTagsDelete    = "DELETE FROM tags WHERE name = $1 AND ($2 = 0 OR owner_id = $2);"
+	sqliteTodoTagsClear = "DELETE FROM todo_tags WHERE todo_id = $1;"
+	sqliteTodoTagsAdd   = "INSERT INTO todo_tags (todo_id, tag_id) SELECT $1, id FROM tags WHERE owner_id = $2 AND name = $3;"
+	// sqliteTouch records a change of the tags of a todo, even if it is in the trash
+	sqliteTouch = "UPDATE todos SET updated_at = " + sqliteNow + ", version = version + 1 WHERE id = $1 RETURNING " + sqliteTodoColumns + ";"
+	// the occurrences of a recurring todo share the id of the first one as series_id,
+	// sqliteStartSeries makes a new recurring todo the first occurrence of its series
+	sqliteStartSeries = "UPDATE todos SET series_id = id WHERE id = $1 RETURNING " + sqliteTodoColumns + ";"
+	sqliteOpenSeries  = "SELECT id FROM todos WHERE series_id = $1 AND deleted_at IS NULL AND NOT completed ORDER BY id"
+	// the children of a todo have its id as parent_id, sqliteParentCheck walks up the ancestors of the live todo $1 owned by $2,
+	// found is 0 when this todo cannot be a parent and cycles is greater than 0 when $3 is one of the ancestors
+	sqliteParentCheck = `WITH RECURSIVE ancestors(id, parent_id) AS (
+    SELECT id, parent_id FROM todos WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL
+    UNION
+    SELECT t.id, t.parent_id FROM todos t JOIN ancestors a ON t.id = a.parent_id
+)
+SELECT COUNT(*) AS found, COUNT(CASE WHEN id = $3 THEN 1 END) AS cycles FROM ancestors;`
+	// sqliteChildren returns the children of $1, even the ones in the trash when $2 is true
+	sqliteChildren = sqliteSelect + " WHERE parent_id = $1 AND ($2 OR deleted_at IS NULL) ORDER BY id"
+	// sqliteReparent moves a todo to another parent, even if it is in the trash
+	sqliteReparent = "UPDATE todos SET parent_id = $2, updated_at = " + sqliteNow + ", version = version + 1 WHERE id = $1 RETURNING " + sqliteTodoColumns + ";"
+)
+
+// a todo is blocked by the todos of its rows in todo_dependencies, they are removed with the todos
+const (
+	sqliteOpenBlockers = `SELECT b.id FROM todo_dependencies d JOIN todos b ON b.id = d.blocker_id
+WHERE d.todo_id = $1 AND b.deleted_at IS NULL AND NOT b.completed ORDER BY b.id`
+	sqliteBlockers = sqliteSelect + " WHERE id IN (SELECT blocker_id FROM todo_dependencies WHERE todo_id = $1) AND deleted_at IS NULL AND ($2 = 0 OR owner_id = $2) ORDER BY id"
+	// sqliteDependsOn walks the blockers of the todo $1 and of their own blockers, found is greater than 0 when $2 is one of them
+	sqliteDependsOn = `WITH RECURSIVE blockers(id) AS (
+    SELECT blocker_id FROM todo_dependencies WHERE todo_id = $1
+    UNION
+    SELECT d.blocker_id FROM todo_dependencies d JOIN blockers b ON d.todo_id = b.id
+)
+SELECT COUNT(*) AS found FROM blockers WHERE id = $2;`
+	sqliteDependencyAdd    = "INSERT INTO todo_dependencies (todo_id, blocker_id, created_at) VALUES ($1, $2, " + sqliteNow + ") ON CONFLICT DO NOTHING;"
+	sqliteDependencyRemove = "DELETE FROM todo_dependencies WHERE todo_id = $1 AND blocker_id = $2 AND todo_id IN (SELECT id FROM todos WHERE $3 = 0 OR owner_id = $3);"
+)
+
+// reminded_at is the reminder time, remind_at or else due_at, of the last reminder sent for a todo
+const (
+	// sqliteRemindersClaim records as sent the reminders reached at $1 and returns the todos in a single statement,
+	// the writes of sqlite are serialized so every reminder is claimed once
+	sqliteRemindersClaim = `UPDATE todos SET reminded_at = COALESCE(remind_at, due_at)
+WHERE id IN (SELECT id FROM todos WHERE deleted_at IS NULL AND NOT completed AND COALESCE(remind_at, due_at) <= $1
+        AND reminded_at IS NOT COALESCE(remind_at, due_at) AND ($3 = 0 OR owner_id = $3)
+    ORDER BY COALESCE(remind_at, due_at), id LIMIT $2)
+RETURNING ` + sqliteTodoColumns + ";"
+	sqliteReminderRelease = "UPDATE todos SET reminded_at = NULL WHERE id = $1 AND reminded_at = $2 AND ($3 = 0 OR owner_id = $3);"
+)
+
+// sqliteTimeValue converts a time for the sqlite timestamp columns, they are stored as text in UTC
+func sqliteTimeValue(t time.Time) interface{} {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+// sqliteDueAtValue converts an optional due date for the due_at column, nil when the todo has no due date
+func sqliteDueAtValue(dueAt *time.Time) interface{} {
+	if dueAt == nil {
+		return nil
+	}
+	return sqliteTimeValue(*dueAt)
+}
+
+// sqliteError converts the sqlite errors having a meaning for the callers to the errors of the Storage interface
+func sqliteError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return fmt.Errorf("%w : %s", ErrConflict, sqliteErr.Error())
+		}
+	}
+	return GetErrorF("error : todos could not be saved", err)
+}
+
+// SQLite is a Storage keeping the todos in a single local sqlite database file
+type SQLite struct {
+	Conn *sql.DB
+	log  *log.Logger
+}
+
+// NewSqliteDB opens (or creates) the sqlite database file at dbPath and applies the missing schema migrations
+func NewSqliteDB(dbPath string, log *log.Logger) (Storage, error) {
+	if dbPath == "" {
+		return nil, errors.New("sqlite database path cannot be empty")
+	}
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", dbPath)
+	conn, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening sqlite database %s. err : %s", dbPath, err)
+	}
+	// sqlite allows only one writer at a time, using a single connection avoids SQLITE_BUSY errors
+	conn.SetMaxOpenConns(1)
+	var version string
+	if errPing := conn.QueryRow(getSqliteVersion).Scan(&version); errPing != nil {
+		conn.Close()
+		log.Printf("connection to sqlite database %s is invalid ! ", dbPath)
+		return nil, errPing
+	}
+	if err := migrateSqlite(conn, log); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error applying schema to sqlite database %s. err : %s", dbPath, err)
+	}
+	var numberOfTodos int
+	if err := conn.QueryRow(sqliteCount).Scan(&numberOfTodos); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	log.Printf("SUCCESS opened sqlite DB %s ver: [%s]", dbPath, version)
+	log.Printf("SUCCESS database contains %d records in todos", numberOfTodos)
+	return &SQLite{
+		Conn: conn,
+		log:  log,
+	}, nil
+}
+
+// migrateSqlite applies in a transaction each migration of sqliteMigrations not yet applied to the database
+func migrateSqlite(conn *sql.DB, log *log.Logger) error {
+	var version int
+	if err := conn.QueryRow("PRAGMA user_version;").Scan(&version); err != nil {
+		return err
+	}
+	for ; version < len(sqliteMigrations); version++ {
+		tx, err := conn.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d failed : %v", version+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d;", version+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Printf("info : sqlite schema migrated to version %d", version+1)
+	}
+	return nil
+}
+
+// getQueryInt is a sqlite helper function for a query expecting an integer result
+func (db *SQLite) getQueryInt(ctx context.Context, query string, arguments ...interface{}) (result int, err error) {
+	err = db.Conn.QueryRowContext(ctx, query, arguments...).Scan(&result)
+	if err != nil {
+		db.log.Printf("error : getQueryInt(%s) queryRow unexpectedly failed. args : (%v), error : %v\n", query, arguments, err)
+		return 0, err
+	}
+	return result, err
+}
+
+// sqliteQuerier is implemented by the database and by a transaction, so the same statements can run in both
+type sqliteQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// sqliteWriter is the todoWriter running the write statements in a transaction, with the event of each change
+type sqliteWriter struct {
+	db *SQLite
+	q  sqliteQuerier
+}
+
+// lock reads the todo with given id, an error is returned if a write requiring the given state and matchVersion
+// cannot be applied to it. the todo cannot change until the end of the transaction because the database has a single connection
+func (w sqliteWriter) lock(ctx context.Context, operation string, id int32, state todoState, matchVersion int32) (*Todo, error) {
+	t := &Todo{}
+	if err := sqlscan.Get(ctx, w.q, t, sqliteGet, id, getOwnerId(ctx)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.db.log.Printf("info : %s(%d) id does not exist", operation, id)
+			return nil, ErrNotFound
+		}
+		return nil, GetErrorF("error : todos could not be read", err)
+	}
+	if err := w.db.loadTags(ctx, w.q, []*Todo{t}); err != nil {
+		return nil, err
+	}
+	if err := checkTodoState(t, state, matchVersion); err != nil {
+		w.db.log.Printf("info : %s(%d) cannot be applied : %v", operation, id, err)
+		return nil, err
+	}
+	return t, nil
+}
+
+// checkList returns the ValidationError of errUnknownList if the todos of ownerId cannot be in the list
+func (w sqliteWriter) checkList(ctx context.Context, listId *int32, ownerId int32) error {
+	if listId == nil {
+		return nil
+	}
+	var count int
+	if err := w.q.QueryRowContext(ctx, sqliteListsOwned, *listId, ownerId).Scan(&count); err != nil {
+		return GetErrorF("error : lists could not be read", err)
+	}
+	if count < 1 {
+		return errUnknownList(*listId)
+	}
+	return nil
+}
+
+// checkParent returns the ValidationError of errUnknownParent if the todo with given id, 0 for a new todo, of ownerId
+// cannot have this parent, or the one of errParentCycle if the parent is the todo itself or one of its descendants
+func (w sqliteWriter) checkParent(ctx context.Context, parentId *int32, ownerId int32, id int32) error {
+	if parentId == nil {
+		return nil
+	}
+	var found, cycles int
+	if err := w.q.QueryRowContext(ctx, sqliteParentCheck, *parentId, ownerId, id).Scan(&found, &cycles); err != nil {
+		return GetErrorF("error : todos could not be read", err)
+	}
+	if found < 1 {
+		return errUnknownParent(*parentId)
+	}
+	if cycles > 0 {
+		return errParentCycle(id, *parentId)
+	}
+	return nil
+}
+
+// setTags replaces the tags of the todo by the normalized tags, the missing tags of the owner are created
+func (w sqliteWriter) setTags(ctx context.Context, todoId, ownerId int32, tags []string) error {
+	if _, err := w.q.ExecContext(ctx, sqliteTodoTagsClear, todoId); err != nil {
+		return GetErrorF("error : tags could not be saved", err)
+	}
+	for _, tag := range tags {
+		if _, err := w.q.ExecContext(ctx, sqliteTagsCreate, ownerId, tag); err != nil {
+			return GetErrorF("error : tags could not be saved", err)
+		}
+		if _, err := w.q.ExecContext(ctx, sqliteTodoTagsAdd, todoId, ownerId, tag); err != nil {
+			return GetErrorF("error : tags could not be saved", err)
+		}
+	}
+	return nil
+}
+
+// record saves the event of the change of a todo from before to after
+func (w sqliteWriter) record(ctx context.Context, before, after *Todo) error {
+	event := newTodoEvent(ctx, before, after)
+	beforeJSON, err := todoJSON(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := todoJSON(after)
+	if err != nil {
+		return err
+	}
+	_, err = w.q.ExecContext(ctx, sqliteEventsInsert, event.TodoId, event.OwnerId, string(event.Type), event.Actor,
+		sqliteTimeValue(event.OccurredAt), beforeJSON, afterJSON)
+	if err != nil {
+		return GetErrorF("error : todo event could not be saved", err)
+	}
+	return nil
+}
+
+func (w sqliteWriter) create(ctx context.Context, todo NewTodo, previous *Todo) (*Todo, error) {
+	if err := validateTask(todo.Task); err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(todo.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePriority(todo.Priority); err != nil {
+		return nil, err
+	}
+	recurrence, err := normalizeRecurrence(todo.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	ownerId, err := getNewTodoOwnerId(ctx, previous)
+	if err != nil {
+		return nil, err
+	}
+	listId := getNewTodoListId(todo)
+	if err := w.checkList(ctx, listId, ownerId); err != nil {
+		return nil, err
+	}
+	parentId := getNewTodoParentId(todo)
+	if err := w.checkParent(ctx, parentId, ownerId, 0); err != nil {
+		return nil, err
+	}
+	res := &Todo{}
+	err = sqlscan.Get(ctx, w.q, res, sqliteCreate, todo.Task, ownerId, listId, sqliteDueAtValue(normalizeDueAt(todo.DueAt)), todo.Priority,
+		recurrence, getNewTodoSeriesId(previous), parentId, normalizeAutoComplete(todo.AutoComplete), sqliteDueAtValue(normalizeDueAt(todo.RemindAt)))
+	if err != nil {
+		w.db.log.Printf("error : Create(%v) unexpectedly failed. error : %v", todo.Task, err)
+		return nil, sqliteError(err)
+	}
+	if res.SeriesId == nil && recurrence != nil {
+		if err := sqlscan.Get(ctx, w.q, res, sqliteStartSeries, res.Id); err != nil {
+			return nil, sqliteError(err)
+		}
+	}
+	if err := w.setTags(ctx, res.Id, ownerId, tags); err != nil {
+		return nil, err
+	}
+	res.Tags = tagsValue(tags)
+	w.db.log.Printf("info : Create(%v) created with id : %v", todo.Task, res.Id)
+	return res, w.record(ctx, nil, res)
+}
+
+func (w sqliteWriter) patch(ctx context.Context, id int32, patch TodoPatch, matchVersion int32) (*Todo, error) {
+	// first check business rules for task field
+	if patch.Task != nil {
+		if err := validateTask(*patch.Task); err != nil {
+			return nil, err
+		}
+	}
+	tags, err := normalizeTags(patch.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePatchPriority(patch); err != nil {
+		return nil, err
+	}
+	if patch.Recurrence, err = normalizePatchRecurrence(patch); err != nil {
+		return nil, err
+	}
+	before, err := w.lock(ctx, "Patch", id, liveTodo, matchVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBlockers(ctx, w, before, patch); err != nil {
+		return nil, err
+	}
+	if err := w.checkList(ctx, getPatchListId(before.ListId, patch), before.OwnerId); err != nil {
+		return nil, err
+	}
+	if patch.ParentId != nil {
+		if err := w.checkParent(ctx, getPatchParentId(before.ParentId, patch), before.OwnerId, id); err != nil {
+			return nil, err
+		}
+	}
+	args := []interface{}{patch.Task, patch.Completed, id, matchVersion, patch.ListId,
+		patch.DueAt != nil, sqliteDueAtValue(normalizeDueAt(patch.DueAt)), patch.Priority, patch.Recurrence,
+		patch.ParentId, patch.AutoComplete, patch.RemindAt != nil, sqliteDueAtValue(normalizeDueAt(patch.RemindAt))}
+	tagsAfter := before.Tags
+	if patch.Tags != nil {
+		if err := w.setTags(ctx, id, before.OwnerId, tags); err != nil {
+			return nil, err
+		}
+		tagsAfter = tagsValue(tags)
+	}
+	res, err := w.update(ctx, "Patch", before, tagsAfter, sqlitePatch, args...)
+	if err != nil {
+		return nil, err
+	}
+	return res, afterPatch(ctx, w, before, res)
+}
+
+// openOccurrences returns the ids of the live todos of the series not yet completed
+func (w sqliteWriter) openOccurrences(ctx context.Context, seriesId int32) ([]int32, error) {
+	var res []int32
+	if err := sqlscan.Select(ctx, w.q, &res, sqliteOpenSeries, seriesId); err != nil {
+		return nil, GetErrorF("error : todos could not be read", err)
+	}
+	return res, nil
+}
+
+// children returns the children of the todo in the given state in the order of their id
+func (w sqliteWriter) children(ctx context.Context, parentId int32, state todoState) ([]*Todo, error) {
+	var res []*Todo
+	if err := sqlscan.Select(ctx, w.q, &res, sqliteChildren, parentId, state == anyTodo); err != nil {
+		return nil, GetErrorF("error : todos could not be read", err)
+	}
+	return res, w.db.loadTags(ctx, w.q, res)
+}
+
+// reparent moves the todo to the parent, nil for a root todo, even if it is in the trash
+func (w sqliteWriter) reparent(ctx context.Context, id int32, parentId *int32) (*Todo, error) {
+	before, err := w.lock(ctx, "Reparent", id, anyTodo, 0)
+	if err != nil {
+		return nil, err
+	}
+	return w.update(ctx, "Reparent", before, before.Tags, sqliteReparent, id, parentId)
+}
+
+// openBlockers returns the ids of the live blockers of the todo not yet completed, in ascending order
+func (w sqliteWriter) openBlockers(ctx context.Context, id int32) ([]int32, error) {
+	var res []int32
+	if err := sqlscan.Select(ctx, w.q, &res, sqliteOpenBlockers, id); err != nil {
+		return nil, GetErrorF("error : todos could not be read", err)
+	}
+	return res, nil
+}
+
+// dependsOn returns true when the todo with given id is blocked by blockerId, directly or through other blockers
+func (w sqliteWriter) dependsOn(ctx context.Context, id int32, blockerId int32) (bool, error) {
+	var found int
+	if err := w.q.QueryRowContext(ctx, sqliteDependsOn, id, blockerId).Scan(&found); err != nil {
+		return false, GetErrorF("error : todo dependencies could not be read", err)
+	}
+	return found > 0, nil
+}
+
+// addDependency blocks the todo with given id by blockerId
+func (w sqliteWriter) addDependency(ctx context.Context, id int32, blockerId int32) error {
+	if _, err := w.q.ExecContext(ctx, sqliteDependencyAdd, id, blockerId); err != nil {
+		w.db.log.Printf("error : AddDependency(%d, %d) unexpectedly failed. error : %v", id, blockerId, err)
+		return sqliteError(err)
+	}
+	return nil
+}
+
+// delete moves the todo to the trash
+func (w sqliteWriter) delete(ctx context.Context, id int32, matchVersion int32) error {
+	before, err := w.lock(ctx, "Delete", id, liveTodo, matchVersion)
+	if err != nil {
+		return err
+	}
+	_, err = w.update(ctx, "Delete", before, before.Tags, sqliteDelete, id, matchVersion)
+	return err
+}
+
+func (w sqliteWriter) restore(ctx context.Context, id int32, matchVersion int32) (*Todo, error) {
+	before, err := w.lock(ctx, "Restore", id, trashedTodo, matchVersion)
+	if err != nil {
+		return nil, err
+	}
+	return w.update(ctx, "Restore", before, before.Tags, sqliteRestore, id, matchVersion)
+}
+
+func (w sqliteWriter) purge(ctx context.Context, id int32, matchVersion int32) error {
+	before, err := w.lock(ctx, "Purge", id, anyTodo, matchVersion)
+	if err != nil {
+		return err
+	}
+	result, err := w.q.ExecContext(ctx, sqlitePurge, id, matchVersion)
+	if err != nil {
+		return GetErrorF("error : todos could not be purged", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return GetErrorF("error : todos could not be purged", err)
+	}
+	if rowsAffected < 1 {
+		return errModifiedDuring("Purge", id)
+	}
+	return w.record(ctx, before, nil)
+}
+
+// update runs a statement changing the todo before and returning its new state with the given tags, and records the change
+func (w sqliteWriter) update(ctx context.Context, operation string, before *Todo, tags *[]string, query string, args ...interface{}) (*Todo, error) {
+	res := &Todo{}
+	err := sqlscan.Get(ctx, w.q, res, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errModifiedDuring(operation, before.Id)
+		}
+		return nil, GetErrorF(fmt.Sprintf("error : %s of todos failed", operation), err)
+	}
+	res.Tags = tags
+	return res, w.record(ctx, before, res)
+}
+
+// retag replaces the tag from by to in the tags of the todo, even if it is in the trash
+func (w sqliteWriter) retag(ctx context.Context, id int32, from, to string) error {
+	before, err := w.lock(ctx, "RenameTag", id, anyTodo, 0)
+	if err != nil {
+		return err
+	}
+	tags := replaceTag(getTodoTags(before), from, to)
+	if err := w.setTags(ctx, id, before.OwnerId, tags); err != nil {
+		return err
+	}
+	_, err = w.update(ctx, "RenameTag", before, tagsValue(tags), sqliteTouch, id)
+	return err
+}
+
+// inTx runs f with a writer in a transaction, which is committed only if f succeeds,
+// so a change and its event are saved together, and all the operations of a batch or none
+func (db *SQLite) inTx(ctx context.Context, f func(w sqliteWriter) error) error {
+	tx, err := db.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return GetErrorF("error : transaction could not be started", err)
+	}
+	if err := f(sqliteWriter{db: db, q: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return sqliteError(err)
+	}
+	return nil
+}
+
+func (db *SQLite) Close() {
+	db.Conn.Close()
+	return
+}
+
+// loadTags reads with q the tags of the todos and sets their Tags
+func (db *SQLite) loadTags(ctx context.Context, q sqliteQuerier, todos []*Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	var rows []*todoTagRow
+	query, args := newTodoTagsQuery(todos)
+	if err := sqlscan.Select(ctx, q, &rows, query, args...); err != nil {
+		db.log.Printf("error : loadTags sqlscan.Select unexpectedly failed, error : %v", err)
+		return GetErrorF("error : tags could not be read", err)
+	}
+	setTodosTags(todos, rows)
+	return nil
+}
+
+// Create will store the new task in the store
+func (db *SQLite) Create(ctx context.Context, todo NewTodo) (*Todo, error) {
+	db.log.Printf("info : Entering Create(%#v)", todo)
+	var res *Todo
+	err := db.inTx(ctx, func(w sqliteWriter) (err error) {
+		res, err = w.create(ctx, todo, nil)
+		return err
+	})
+	return res, err
+}
+
+// List returns the todos matching params.Filter sorted on params.Sort,
+// skipping params.Offset todos or the ones up to params.AfterId
+func (db *SQLite) List(ctx context.Context, params ListParams) ([]*Todo, error) {
+	res := make([]*Todo, 0)
+	q := newFilterQuery(params.Filter, getOwnerId(ctx), sqliteTimeValue)
+	offset := params.Offset
+	if params.AfterId > 0 {
+		q.where("id > ?", params.AfterId)
+		offset = 0
+	}
+	orderBy, err := orderByClause(params.Sort)
+	if err != nil {
+		return nil, err
+	}
+	query := sqliteSelect + q.whereClause() + orderBy + fmt.Sprintf(" LIMIT %s OFFSET %s", q.arg(params.Limit), q.arg(offset))
+	err = sqlscan.Select(ctx, db.Conn, &res, query, q.args...)
+	if err != nil {
+		db.log.Printf("error : List sqlscan.Select unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return res, db.loadTags(ctx, db.Conn, res)
+}
+
+// Search returns at most limit todos whose task contains all the words of query, the most relevant first
+func (db *SQLite) Search(ctx context.Context, query string, limit int) ([]*TodoSearchResult, error) {
+	res := make([]*TodoSearchResult, 0)
+	// every word is quoted, so the fts5 query syntax cannot be injected and all the words are required
+	words := tokenize(query)
+	if len(words) == 0 {
+		return res, nil
+	}
+	match := `"` + strings.Join(words, `" "`) + `"`
+	var rows []*struct {
+		Todo
+		Rank     float32
+		Headline string
+	}
+	err := sqlscan.Select(ctx, db.Conn, &rows, sqliteSearch, match, limit, getOwnerId(ctx))
+	if err != nil {
+		db.log.Printf("error : Search(%s) sqlscan.Select unexpectedly failed, error : %v", query, err)
+		return nil, err
+	}
+	todos := make([]*Todo, 0, len(rows))
+	for _, row := range rows {
+		todos = append(todos, &row.Todo)
+	}
+	if err := db.loadTags(ctx, db.Conn, todos); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		res = append(res, &TodoSearchResult{
+			Headline: row.Headline,
+			Rank:     row.Rank,
+			Todo:     row.Todo,
+		})
+	}
+	return res, nil
+}
+
+func (db *SQLite) Get(ctx context.Context, id int32) (*Todo, error) {
+	db.log.Printf("info : Get(%d) entering...", id)
+	res := &Todo{}
+	err := sqlscan.Get(ctx, db.Conn, res, sqliteGet, id, getOwnerId(ctx))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			db.log.Printf("info : Get(%d) id does not exist", id)
+			return nil, ErrNotFound
+		}
+		db.log.Printf("error : Get(%d) sqlscan.Get unexpectedly failed, error : %v", id, err)
+		return nil, err
+	}
+	return res, db.loadTags(ctx, db.Conn, []*Todo{res})
+}
+
+// GetMaxId returns the maximum value of todos id existing in store.
+func (db *SQLite) GetMaxId(ctx context.Context) (int32, error) {
+	existingMaxId, err := db.getQueryInt(ctx, sqliteMaxId, getOwnerId(ctx))
+	if err != nil {
+		db.log.Printf("getMaxId() could not be retrieved from DB. failed db.Query err: %v", err)
+		return 0, err
+	}
+	return int32(existingMaxId), nil
+}
+
+// Exist returns true only if a todos with the specified id exists in store.
+func (db *SQLite) Exist(ctx context.Context, id int32) bool {
+	count, err := db.getQueryInt(ctx, sqliteExist, id, getOwnerId(ctx))
+	if err != nil {
+		db.log.Printf("exist(%d) could not be retrieved from DB. failed db.Query err: %v", id, err)
+		return false
+	}
+	return count > 0
+}
+
+// Count returns the number of todos stored in DB matching the filter
+func (db *SQLite) Count(ctx context.Context, filter TodoFilter) (int32, error) {
+	q := newFilterQuery(filter, getOwnerId(ctx), sqliteTimeValue)
+	count, err := db.getQueryInt(ctx, sqliteCount+q.whereClause(), q.args...)
+	if err != nil {
+		db.log.Printf("count(*) could not be retrieved from DB. failed db.Query err: %v", err)
+		return 0, err
+	}
+	return int32(count), nil
+}
+
+// Fingerprint returns the greatest id, the number of todos, the time of the most recent change, the number of overdue todos
+// and the number of dependencies with the time of the last one added
+func (db *SQLite) Fingerprint(ctx context.Context, now time.Time) (*Fingerprint, error) {
+	res := &Fingerprint{}
+	var lastModified, dependenciesModified sql.NullString
+	err := db.Conn.QueryRowContext(ctx, sqliteFingerprint, getOwnerId(ctx), sqliteTimeValue(now)).
+		Scan(&res.MaxId, &res.Count, &lastModified, &res.Overdue, &res.Dependencies, &dependenciesModified)
+	if err != nil {
+		db.log.Printf("error : Fingerprint() queryRow unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	if res.LastModified, err = parseSqliteTime(lastModified); err != nil {
+		return nil, GetErrorF("error : invalid last modification time in sqlite database", err)
+	}
+	if res.DependenciesModified, err = parseSqliteTime(dependenciesModified); err != nil {
+		return nil, GetErrorF("error : invalid dependency creation time in sqlite database", err)
+	}
+	return res, nil
+}
+
+// parseSqliteTime returns the time of a timestamp aggregated by sqlite, nil for NULL
+func parseSqliteTime(value sql.NullString) (*time.Time, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	t, err := time.Parse(sqliteTimeLayout, value.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Update the todos stored in DB with given id and other information in struct
+func (db *SQLite) Update(ctx context.Context, id int32, todo Todo, matchVersion int32) (*Todo, error) {
+	return db.Patch(ctx, id, TodoPatch{Task: &todo.Task, Completed: &todo.Completed, ListId: getUpdateListId(todo),
+		Tags: getUpdateTags(todo), DueAt: getUpdateDueAt(todo), Priority: getUpdatePriority(todo),
+		Recurrence: getUpdateRecurrence(todo), ParentId: getUpdateParentId(todo), AutoComplete: getUpdateAutoComplete(todo),
+		RemindAt: getUpdateRemindAt(todo)}, matchVersion)
+}
+
+// Patch changes only the fields given in patch of the todos stored in DB with given id
+func (db *SQLite) Patch(ctx context.Context, id int32, patch TodoPatch, matchVersion int32) (*Todo, error) {
+	var res *Todo
+	err := db.inTx(ctx, func(w sqliteWriter) (err error) {
+		res, err = w.patch(ctx, id, patch, matchVersion)
+		return err
+	})
+	return res, err
+}
+
+// Delete moves to the trash the todos stored in DB with given id, with its children or not
+func (db *SQLite) Delete(ctx context.Context, id int32, matchVersion int32, cascade bool) error {
+	return db.inTx(ctx, func(w sqliteWriter) error {
+		return deleteTodo(ctx, w, id, matchVersion, cascade)
+	})
+}
+
+// Restore moves back the todos stored in DB with given id from the trash
+func (db *SQLite) Restore(ctx context.Context, id int32, matchVersion int32) (*Todo, error) {
+	var res *Todo
+	err := db.inTx(ctx, func(w sqliteWriter) (err error) {
+		res, err = restoreTodo(ctx, w, id, matchVersion)
+		return err
+	})
+	return res, err
+}
+
+// Purge removes permanently the todos stored in DB with given id, with its children or not
+func (db *SQLite) Purge(ctx context.Context, id int32, matchVersion int32, cascade bool) error {
+	return db.inTx(ctx, func(w sqliteWriter) error {
+		return purgeTodo(ctx, w, id, matchVersion, cascade)
+	})
+}
+
+// PurgeTrash removes permanently the todos stored in DB moved to the trash before the given time
+func (db *SQLite) PurgeTrash(ctx context.Context, trashedBefore time.Time) (int, error) {
+	var ids []int32
+	err := db.inTx(ctx, func(w sqliteWriter) error {
+		if err := sqlscan.Select(ctx, w.q, &ids, sqliteTrashed, sqliteTimeValue(trashedBefore), getOwnerId(ctx)); err != nil {
+			return GetErrorF("error : trash could not be read", err)
+		}
+		// every todo is purged like with Purge, so it gets its event
+		for _, id := range ids {
+			if err := purgeTodo(ctx, w, id, 0, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// History returns the events of the todos with given id in the order they occurred
+func (db *SQLite) History(ctx context.Context, id int32) ([]*TodoEvent, error) {
+	var rows []*todoEventRow
+	if err := sqlscan.Select(ctx, db.Conn, &rows, sqliteEventsHistory, id, getOwnerId(ctx)); err != nil {
+		db.log.Printf("error : History(%d) sqlscan.Select unexpectedly failed, error : %v", id, err)
+		return nil, err
+	}
+	return toTodoEvents(rows)
+}
+
+// Events returns at most limit events that occurred at or after since, in the order they occurred
+func (db *SQLite) Events(ctx context.Context, since time.Time, limit int) ([]*TodoEvent, error) {
+	var rows []*todoEventRow
+	if err := sqlscan.Select(ctx, db.Conn, &rows, sqliteEventsSince, sqliteTimeValue(since), limit, getOwnerId(ctx)); err != nil {
+		db.log.Printf("error : Events(%v) sqlscan.Select unexpectedly failed, error : %v", since, err)
+		return nil, err
+	}
+	return toTodoEvents(rows)
+}
+
+// ListTodoLists returns the lists stored in DB in the order of their id, with the number of their todos
+func (db *SQLite) ListTodoLists(ctx context.Context) ([]*TodoList, error) {
+	res := make([]*TodoList, 0)
+	if err := sqlscan.Select(ctx, db.Conn, &res, sqliteListsList, getOwnerId(ctx)); err != nil {
+		db.log.Printf("error : ListTodoLists sqlscan.Select unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetTodoList returns the list stored in DB with given ID and the number of its todos
+func (db *SQLite) GetTodoList(ctx context.Context, id int32) (*TodoList, error) {
+	res := &TodoList{}
+	if err := sqlscan.Get(ctx, db.Conn, res, sqliteListsGet, id, getOwnerId(ctx)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrListNotFound
+		}
+		db.log.Printf("error : GetTodoList(%d) sqlscan.Get unexpectedly failed, error : %v", id, err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// CreateTodoList saves in DB a new list owned by the user of the context, the names of the lists of a user are unique
+func (db *SQLite) CreateTodoList(ctx context.Context, list NewTodoList) (*TodoList, error) {
+	if err := validateNewTodoList(list); err != nil {
+		return nil, err
+	}
+	owner := UserFromContext(ctx)
+	if owner == nil {
+		return nil, errNoOwner
+	}
+	var id int32
+	if err := db.Conn.QueryRowContext(ctx, sqliteListsCreate, list.Name, owner.Id).Scan(&id); err != nil {
+		db.log.Printf("error : CreateTodoList(%s) unexpectedly failed. error : %v", list.Name, err)
+		return nil, sqliteError(err)
+	}
+	return db.GetTodoList(ctx, id)
+}
+
+// UpdateTodoList renames the list stored in DB with given ID
+func (db *SQLite) UpdateTodoList(ctx context.Context, id int32, list NewTodoList) (*TodoList, error) {
+	if err := validateNewTodoList(list); err != nil {
+		return nil, err
+	}
+	var updatedId int32
+	if err := db.Conn.QueryRowContext(ctx, sqliteListsUpdate, id, list.Name, getOwnerId(ctx)).Scan(&updatedId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrListNotFound
+		}
+		db.log.Printf("error : UpdateTodoList(%d) unexpectedly failed. error : %v", id, err)
+		return nil, sqliteError(err)
+	}
+	return db.GetTodoList(ctx, id)
+}
+
+// DeleteTodoList purges the todos of the list stored in DB with given ID and removes it in a single transaction
+func (db *SQLite) DeleteTodoList(ctx context.Context, id int32) error {
+	return db.inTx(ctx, func(w sqliteWriter) error {
+		var count int
+		if err := w.q.QueryRowContext(ctx, sqliteListsExist, id, getOwnerId(ctx)).Scan(&count); err != nil {
+			return GetErrorF("error : lists could not be read", err)
+		}
+		if count < 1 {
+			return ErrListNotFound
+		}
+		var todoIds []int32
+		if err := sqlscan.Select(ctx, w.q, &todoIds, sqliteListsTodos, id); err != nil {
+			return GetErrorF("error : todos could not be read", err)
+		}
+		// every todo is purged like with Purge, so it gets its event
+		for _, todoId := range todoIds {
+			if err := purgeTodo(ctx, w, todoId, 0, false); err != nil {
+				return err
+			}
+		}
+		if _, err := w.q.ExecContext(ctx, sqliteListsDelete, id); err != nil {
+			return GetErrorF("error : list could not be deleted", err)
+		}
+		return nil
+	})
+}
+
+// ListTags returns the tags of the live todos stored in DB in alphabetical order, with the number of live todos having them
+func (db *SQLite) ListTags(ctx context.Context) ([]*Tag, error) {
+	res := make([]*Tag, 0)
+	if err := sqlscan.Select(ctx, db.Conn, &res, sqliteTagsList, getOwnerId(ctx)); err != nil {
+		db.log.Printf("error : ListTags sqlscan.Select unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// RenameTag replaces the tag name by newName in all the todos stored in DB having it in a single transaction
+func (db *SQLite) RenameTag(ctx context.Context, name, newName string) (*Tag, error) {
+	from := tagName(name)
+	to, err := normalizeTag(newName)
+	if err != nil {
+		return nil, err
+	}
+	res := &Tag{Name: to}
+	err = db.inTx(ctx, func(w sqliteWriter) error {
+		var todoIds []int32
+		if err := sqlscan.Select(ctx, w.q, &todoIds, sqliteTagsTodos, from, getOwnerId(ctx)); err != nil {
+			return GetErrorF("error : tags could not be read", err)
+		}
+		if len(todoIds) == 0 {
+			return ErrTagNotFound
+		}
+		if from != to {
+			// every todo is changed like with Patch, so it gets its event
+			for _, todoId := range todoIds {
+				if err := w.retag(ctx, todoId, from, to); err != nil {
+					return err
+				}
+			}
+			if _, err := w.q.ExecContext(ctx, sqliteTagsDelete, from, getOwnerId(ctx)); err != nil {
+				return GetErrorF("error : tag could not be deleted", err)
+			}
+		}
+		return w.q.QueryRowContext(ctx, sqliteTagsCount, to, getOwnerId(ctx)).Scan(&res.TodosCount)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// CreateUser saves a new user in DB, the logins are unique
+func (db *SQLite) CreateUser(ctx context.Context, user NewUser) (*User, error) {
+	if err := validateNewUser(user); err != nil {
+		return nil, err
+	}
+	res := &User{}
+	if err := sqlscan.Get(ctx, db.Conn, res, sqliteUsersCreate, user.Login, user.Name, getNewUserRole(user)); err != nil {
+		db.log.Printf("error : CreateUser(%s) unexpectedly failed. error : %v", user.Login, err)
+		return nil, sqliteError(err)
+	}
+	return res, nil
+}
+
+// GetUser returns the user stored in DB with given login
+func (db *SQLite) GetUser(ctx context.Context, login string) (*User, error) {
+	res := &User{}
+	if err := sqlscan.Get(ctx, db.Conn, res, sqliteUsersGet, login); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		db.log.Printf("error : GetUser(%s) sqlscan.Get unexpectedly failed, error : %v", login, err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListUsers returns all the users stored in DB in the order of their id
+func (db *SQLite) ListUsers(ctx context.Context) ([]*User, error) {
+	res := make([]*User, 0)
+	if err := sqlscan.Select(ctx, db.Conn, &res, sqliteUsersList); err != nil {
+		db.log.Printf("error : ListUsers sqlscan.Select unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// CreateApiKey saves a new API key in DB with the hash of its key
+func (db *SQLite) CreateApiKey(ctx context.Context, apiKey ApiKey, hash string) (*ApiKey, error) {
+	var expiresAt interface{}
+	if apiKey.ExpiresAt != nil {
+		expiresAt = sqliteTimeValue(*apiKey.ExpiresAt)
+	}
+	var id int32
+	err := db.Conn.QueryRowContext(ctx, sqliteApiKeysCreate, apiKey.Name, apiKey.Prefix, hash, apiKey.Login,
+		encodeScopes(apiKey.Scopes), expiresAt).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		db.log.Printf("error : CreateApiKey(%s) unexpectedly failed. error : %v", apiKey.Name, err)
+		return nil, sqliteError(err)
+	}
+	row := &apiKeyRow{}
+	if err := sqlscan.Get(ctx, db.Conn, row, sqliteApiKeysGet, id); err != nil {
+		db.log.Printf("error : CreateApiKey(%s) sqlscan.Get unexpectedly failed, error : %v", apiKey.Name, err)
+		return nil, err
+	}
+	return row.toApiKey(), nil
+}
+
+// GetApiKey returns the API key stored in DB whose key has the given hash
+func (db *SQLite) GetApiKey(ctx context.Context, hash string) (*ApiKey, error) {
+	row := &apiKeyRow{}
+	if err := sqlscan.Get(ctx, db.Conn, row, sqliteApiKeysByHash, hash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrApiKeyNotFound
+		}
+		db.log.Printf("error : GetApiKey sqlscan.Get unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return row.toApiKey(), nil
+}
+
+// ListApiKeys returns all the API keys stored in DB in the order of their id
+func (db *SQLite) ListApiKeys(ctx context.Context) ([]*ApiKey, error) {
+	var rows []*apiKeyRow
+	if err := sqlscan.Select(ctx, db.Conn, &rows, sqliteApiKeysList); err != nil {
+		db.log.Printf("error : ListApiKeys sqlscan.Select unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return toApiKeys(rows), nil
+}
+
+// RevokeApiKey removes the API key with given ID from DB
+func (db *SQLite) RevokeApiKey(ctx context.Context, id int32) error {
+	return db.execApiKey(ctx, "RevokeApiKey", sqliteApiKeysRevoke, id)
+}
+
+// TouchApiKey records in DB the time of the last use of the API key with given ID
+func (db *SQLite) TouchApiKey(ctx context.Context, id int32, usedAt time.Time) error {
+	return db.execApiKey(ctx, "TouchApiKey", sqliteApiKeysTouch, id, sqliteTimeValue(usedAt))
+}
+
+// execApiKey runs the statement changing the API key with given ID, ErrApiKeyNotFound is returned if no row was changed
+func (db *SQLite) execApiKey(ctx context.Context, operation, query string, id int32, arguments ...interface{}) error {
+	res, err := db.Conn.ExecContext(ctx, query, append([]interface{}{id}, arguments...)...)
+	if err != nil {
+		db.log.Printf("error : %s(%d) unexpectedly failed. error : %v", operation, id, err)
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count < 1 {
+		return ErrApiKeyNotFound
+	}
+	return nil
+}
+
+// UpdateSeries changes the recurrence rule of the open occurrences of the series of the todo stored in DB with given id
+func (db *SQLite) UpdateSeries(ctx context.Context, id int32, recurrence string) ([]*Todo, error) {
+	var res []*Todo
+	err := db.inTx(ctx, func(w sqliteWriter) error {
+		t, err := w.lock(ctx, "UpdateSeries", id, liveTodo, 0)
+		if err != nil {
+			return err
+		}
+		res, err = updateSeries(ctx, w, t, recurrence)
+		return err
+	})
+	return res, err
+}
+
+// CountChildren returns the numbers of live children of the todos stored in DB with given ids
+func (db *SQLite) CountChildren(ctx context.Context, ids []int32) (map[int32]ChildrenStats, error) {
+	var rows []*childrenStatsRow
+	query, args := newChildrenStatsQuery(ids, getOwnerId(ctx))
+	if err := sqlscan.Select(ctx, db.Conn, &rows, query, args...); err != nil {
+		db.log.Printf("error : CountChildren sqlscan.Select unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return toChildrenStats(rows), nil
+}
+
+// Descendants returns the live descendants of the todos stored in DB with given ids, found with a recursive query
+func (db *SQLite) Descendants(ctx context.Context, ids []int32) ([]*Todo, error) {
+	res := make([]*Todo, 0)
+	query, args := newDescendantsQuery(sqliteSelect, ids, getOwnerId(ctx))
+	if err := sqlscan.Select(ctx, db.Conn, &res, query, args...); err != nil {
+		db.log.Printf("error : Descendants sqlscan.Select unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return res, db.loadTags(ctx, db.Conn, res)
+}
+
+// OpenBlockers returns the ids of the open blockers of the todos stored in DB with given ids
+func (db *SQLite) OpenBlockers(ctx context.Context, ids []int32) (map[int32][]int32, error) {
+	var rows []*blockerRow
+	query, args := newOpenBlockersQuery(ids, getOwnerId(ctx))
+	if err := sqlscan.Select(ctx, db.Conn, &rows, query, args...); err != nil {
+		db.log.Printf("error : OpenBlockers sqlscan.Select unexpectedly failed, error : %v", err)
+		return nil, err
+	}
+	return toOpenBlockers(rows), nil
+}
+
+// Blockers returns the live blockers of the todo stored in DB with given id
+func (db *SQLite) Blockers(ctx context.Context, id int32) ([]*Todo, error) {
+	res := make([]*Todo, 0)
+	if err := sqlscan.Select(ctx, db.Conn, &res, sqliteBlockers, id, getOwnerId(ctx)); err != nil {
+		db.log.Printf("error : Blockers(%d) sqlscan.Select unexpectedly failed, error : %v", id, err)
+		return nil, err
+	}
+	return res, db.loadTags(ctx, db.Conn, res)
+}
+
+// AddDependency blocks the todo stored in DB with given id by the todo blockerId
+func (db *SQLite) AddDependency(ctx context.Context, id int32, blockerId int32) error {
+	return db.inTx(ctx, func(w sqliteWriter) error {
+		return addDependency(ctx, w, id, blockerId)
+	})
+}
+
+// RemoveDependency removes the blocker blockerId of the todo stored in DB with given id
+func (db *SQLite) RemoveDependency(ctx context.Context, id int32, blockerId int32) error {
+	res, err := db.Conn.ExecContext(ctx, sqliteDependencyRemove, id, blockerId, getOwnerId(ctx))
+	if err != nil {
+		db.log.Printf("error : RemoveDependency(%d, %d) unexpectedly failed. error : %v", id, blockerId, err)
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count < 1 {
+		return ErrDependencyNotFound
+	}
+	return nil
+}
+
+// ClaimReminders records as sent and returns the reminders of the todos stored in DB reached at now
+func (db *SQLite) ClaimReminders(ctx context.Context, now time.Time, limit int) ([]*Reminder, error) {
+	todos := make([]*Todo, 0)
+	if err := sqlscan.Select(ctx, db.Conn, &todos, sqliteRemindersClaim, sqliteTimeValue(now), limit, getOwnerId(ctx)); err != nil {
+		db.log.Printf("error : ClaimReminders sqlscan.Select unexpectedly failed, error : %v", err)
+		return nil, sqliteError(err)
+	}
+	if err := db.loadTags(ctx, db.Conn, todos); err != nil {
+		return nil, err
+	}
+	return toReminders(todos), nil
+}
+
+// ReleaseReminder records as not sent the reminder of the todo stored in DB with given id for the reminder time remindAt
+func (db *SQLite) ReleaseReminder(ctx context.Context, id int32, remindAt time.Time) error {
+	if _, err := db.Conn.ExecContext(ctx, sqliteReminderRelease, id, sqliteTimeValue(remindAt), getOwnerId(ctx)); err != nil {
+		db.log.Printf("error : ReleaseReminder(%d) unexpectedly failed. error : %v", id, err)
+		return sqliteError(err)
+	}
+	return nil
+}
+
+// Batch applies all the operations in a single transaction, it is rolled back when one of them fails
+func (db *SQLite) Batch(ctx context.Context, operations []BatchOperation) ([]*Todo, error) {
+	var res []*Todo
+	err := db.inTx(ctx, func(w sqliteWriter) (err error) {
+		res, err = runBatch(ctx, w, operations)
+		return err
+	})
+	return res, err
+}
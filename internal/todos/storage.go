@@ -1,43 +1,259 @@
 package todos
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"runtime"
+	"strings"
+	"time"
 )
 
 func IsDriverSupported(driver string) bool {
 	switch driver {
 	case "memory",
-		"postgres":
+		"postgres",
+		"sqlite":
 		return true
 	}
 	return false
 }
 
+const (
+	// DefaultListLimit is the number of todos returned by List when no limit is given
+	DefaultListLimit = 100
+	// MaxListLimit is the greatest number of todos that can be returned by a single List call
+	MaxListLimit = 1000
+	// DefaultSearchLimit is the number of results returned by Search when no limit is given
+	DefaultSearchLimit = 20
+	// MaxSearchLimit is the greatest number of results that can be returned by a single Search call
+	MaxSearchLimit = 100
+)
+
+// SortFields contains the todos fields that can be used to sort the results of Storage.List
+// the todos with the same due_at are sorted by priority, the most urgent first
+var SortFields = []string{"id", "task", "created_at", "completed_at", "due_at", "priority"}
+
+// TodoFilter contains the criteria used to select todos in Storage.List and Storage.Count, nil fields are ignored.
+// the After bounds are inclusive and the Before bounds are exclusive.
+type TodoFilter struct {
+	// Trashed selects the todos in the trash instead of the live ones
+	Trashed bool
+	// Ids selects only the todos with one of these ids
+	Ids []int32
+	// ListId selects only the todos of this list
+	ListId *int32
+	// SeriesId selects only the occurrences of this series of a recurring todo
+	SeriesId *int32
+	// ParentId selects only the children of this todo
+	ParentId *int32
+	// Roots selects only the todos without parent
+	Roots bool
+	// Ready selects only the todos whose live blockers are all completed
+	Ready bool
+	// Tags selects only the todos having all these tags when AllTags is true, or at least one of them otherwise
+	Tags            []string
+	AllTags         bool
+	Completed       *bool
+	CreatedAfter    *time.Time
+	CreatedBefore   *time.Time
+	CompletedAfter  *time.Time
+	CompletedBefore *time.Time
+	DueAfter        *time.Time
+	DueBefore       *time.Time
+}
+
+// ListParams contains the paging, filtering and sorting options for Storage.List
+type ListParams struct {
+	// Offset is the number of todos to skip (only used when AfterId is 0)
+	Offset int
+	// Limit is the maximum number of todos to return
+	Limit int
+	// AfterId is the keyset cursor : when greater than 0 only todos with an id greater than AfterId are returned
+	AfterId int32
+	// Filter selects the todos to return
+	Filter TodoFilter
+	// Sort is one of SortFields, prefixed with - for descending order, empty means id
+	Sort string
+}
+
+// ParseSort returns the field and the direction of a sort expression like -created_at
+func ParseSort(sort string) (field string, descending bool, err error) {
+	if sort == "" {
+		return "id", false, nil
+	}
+	field = strings.TrimPrefix(sort, "-")
+	descending = field != sort
+	for _, f := range SortFields {
+		if f == field {
+			return field, descending, nil
+		}
+	}
+	return "", false, fmt.Errorf("sort field %s is not one of %s", field, strings.Join(SortFields, ", "))
+}
+
+// Fingerprint summarizes the content of a store, it changes every time a todo is created, updated or deleted.
+// the computed fields of the todos are covered too, without changing the todos they are computed for :
+// the children counts and the blocked flags change with the children and the blockers themselves, which are counted
+// in Count and LastModified even in the trash, or with the dependencies, counted in Dependencies with the time of
+// the last one added in DependenciesModified. the overdue flags change with time only, Overdue counts the todos
+// they are true for, and this number grows as soon as a due date is passed.
+type Fingerprint struct {
+	// MaxId is the greatest id of the existing todos
+	MaxId int32
+	// Count is the number of existing todos
+	Count int32
+	// LastModified is the time of the most recent change of the existing todos, it is nil when the store is empty
+	LastModified *time.Time
+	// Overdue is the number of existing todos not completed whose due date is before the time of the fingerprint
+	Overdue int32
+	// Dependencies is the number of dependencies of the existing todos
+	Dependencies int32
+	// DependenciesModified is the time of the most recent dependency added, it is nil when there is none
+	DependenciesModified *time.Time
+}
+
+// todoLastModified returns the time of the last change of the todo,
+// todos imported without updated_at were last changed when they were completed or created
+func todoLastModified(t *Todo) *time.Time {
+	switch {
+	case t.UpdatedAt != nil:
+		return t.UpdatedAt
+	case t.CompletedAt != nil:
+		return t.CompletedAt
+	default:
+		return t.CreatedAt
+	}
+}
+
 // Storage is an interface to different implementation of persistence for Todos
+// every method receives the context of the caller (usually the http request context),
+// so implementations can abort their work when the client is gone or a deadline is reached.
+// every change of a todo is recorded atomically with the change in a TodoEvent, with the actor of the context (see WithActor).
+// when the context carries a user (see WithUser), every method only sees and changes the todos owned by this user,
+// the todos of the other users do not exist for it. the todos are created with the user of the context as owner.
+// the same applies to the lists, and a todo can only be in a list of its owner.
 type Storage interface {
-	// List returns the list of existing todos with the given offset and limit.
-	List(offset, limit int) ([]*Todo, error)
-	// Get returns the todos with the specified todos ID.
-	Get(id int32) (*Todo, error)
+	// List returns the list of existing todos matching params.Filter, sorted and paginated with the given params.
+	// todos having the same value for the sort field are always ordered by id.
+	List(ctx context.Context, params ListParams) ([]*Todo, error)
+	// Search returns at most limit todos whose task contains all the words of query, the most relevant first.
+	Search(ctx context.Context, query string, limit int) ([]*TodoSearchResult, error)
+	// Get returns the todos with the specified todos ID, even if it is in the trash (its DeletedAt is then set).
+	Get(ctx context.Context, id int32) (*Todo, error)
 	// GetMaxId returns the maximum value of todos id existing in store.
-	GetMaxId() (int32, error)
-	// Exist returns true only if a todos with the specified id exists in store.
-	Exist(id int32) bool
-	// Count returns the total number of todos.
-	Count() (int32, error)
+	GetMaxId(ctx context.Context) (int32, error)
+	// Exist returns true only if a todos with the specified id exists in store and is not in the trash.
+	Exist(ctx context.Context, id int32) bool
+	// Count returns the total number of todos matching the filter.
+	Count(ctx context.Context, filter TodoFilter) (int32, error)
+	// Fingerprint returns a cheap summary of the content of the store at now, used to validate cached lists of todos.
+	Fingerprint(ctx context.Context, now time.Time) (*Fingerprint, error)
 	// Create saves a new todos in the storage.
-	Create(todo NewTodo) (*Todo, error)
-	// Update updates the todos with given ID in the storage.
-	Update(id int32, todo Todo) (*Todo, error)
-	// Delete removes the todos with given ID from the storage.
-	Delete(id int32) error
+	Create(ctx context.Context, todo NewTodo) (*Todo, error)
+	// Update updates the todos with given ID in the storage and increments its version.
+	// when matchVersion is not 0, ErrPreconditionFailed is returned if the todo does not have this version anymore.
+	Update(ctx context.Context, id int32, todo Todo, matchVersion int32) (*Todo, error)
+	// Patch changes only the non nil fields of patch in the todos with given ID and increments its version,
+	// completed_at follows the same rules as in Update. when Update or Patch completes a recurring todo,
+	// the next occurrence of its series is created in the same transaction, and when they complete the last child
+	// not yet completed of a parent with auto_complete, the parent is completed too.
+	// the parent of a todo must be a live todo of the same owner, and cannot be one of its descendants.
+	// a *BlockedError is returned when the todo is completed while some of its live blockers are not completed.
+	// when matchVersion is not 0, ErrPreconditionFailed is returned if the todo does not have this version anymore.
+	Patch(ctx context.Context, id int32, patch TodoPatch, matchVersion int32) (*Todo, error)
+	// Delete moves the todos with given ID to the trash and increments its version, ErrNotFound is returned if it is already there.
+	// its live children are moved to the trash with it when cascade is true, and to its parent otherwise.
+	// when matchVersion is not 0, ErrPreconditionFailed is returned if the todo does not have this version anymore.
+	Delete(ctx context.Context, id int32, matchVersion int32, cascade bool) error
+	// Restore moves back the todo with given ID from the trash and increments its version, it becomes a root todo
+	// if its parent is not live. an error matching ErrConflict is returned if the todo is not in the trash.
+	// when matchVersion is not 0, ErrPreconditionFailed is returned if the todo does not have this version anymore.
+	Restore(ctx context.Context, id int32, matchVersion int32) (*Todo, error)
+	// Purge removes permanently the todo with given ID from the storage, whether it is in the trash or not.
+	// its children are purged with it when cascade is true, and moved to its parent otherwise.
+	// when matchVersion is not 0, ErrPreconditionFailed is returned if the todo does not have this version anymore.
+	Purge(ctx context.Context, id int32, matchVersion int32, cascade bool) error
+	// PurgeTrash removes permanently the todos moved to the trash before the given time and returns their number,
+	// their children are moved to their parent like with Purge.
+	PurgeTrash(ctx context.Context, trashedBefore time.Time) (int, error)
+	// History returns the events of the todo with given ID in the order they occurred, they are kept after a purge.
+	History(ctx context.Context, id int32) ([]*TodoEvent, error)
+	// Events returns at most limit events of all the todos that occurred at or after since, in the order they occurred.
+	Events(ctx context.Context, since time.Time, limit int) ([]*TodoEvent, error)
+	// CreateUser saves a new user in the storage, an error matching ErrConflict is returned if the login is already used.
+	CreateUser(ctx context.Context, user NewUser) (*User, error)
+	// GetUser returns the user with the given login, or ErrUserNotFound.
+	GetUser(ctx context.Context, login string) (*User, error)
+	// ListUsers returns all the users in the order of their id.
+	ListUsers(ctx context.Context) ([]*User, error)
+	// CreateApiKey saves a new API key acting for the user apiKey.Login, only the hash of the key is stored.
+	// ErrUserNotFound is returned if the user does not exist.
+	CreateApiKey(ctx context.Context, apiKey ApiKey, hash string) (*ApiKey, error)
+	// GetApiKey returns the API key whose key has the given hash, or ErrApiKeyNotFound.
+	GetApiKey(ctx context.Context, hash string) (*ApiKey, error)
+	// ListApiKeys returns all the API keys in the order of their id.
+	ListApiKeys(ctx context.Context) ([]*ApiKey, error)
+	// RevokeApiKey removes the API key with given ID, or returns ErrApiKeyNotFound.
+	RevokeApiKey(ctx context.Context, id int32) error
+	// TouchApiKey records the time of the last use of the API key with given ID.
+	TouchApiKey(ctx context.Context, id int32, usedAt time.Time) error
+	// ListTodoLists returns the lists in the order of their id, with the number of their todos.
+	ListTodoLists(ctx context.Context) ([]*TodoList, error)
+	// GetTodoList returns the list with given ID and the number of its todos, or ErrListNotFound.
+	GetTodoList(ctx context.Context, id int32) (*TodoList, error)
+	// CreateTodoList saves a new list owned by the user of the context, an error matching ErrConflict is returned
+	// if this user already has a list with the same name.
+	CreateTodoList(ctx context.Context, list NewTodoList) (*TodoList, error)
+	// UpdateTodoList renames the list with given ID, or returns ErrListNotFound.
+	UpdateTodoList(ctx context.Context, id int32, list NewTodoList) (*TodoList, error)
+	// DeleteTodoList removes the list with given ID and purges its todos, even the ones in the trash, or returns ErrListNotFound.
+	DeleteTodoList(ctx context.Context, id int32) error
+	// ListTags returns the tags of the live todos in alphabetical order, with the number of live todos having them.
+	ListTags(ctx context.Context) ([]*Tag, error)
+	// RenameTag replaces the tag name by newName in all the todos having it, even the ones in the trash,
+	// the tag is merged into newName when some todos already have it. ErrTagNotFound is returned if no todo has the tag.
+	RenameTag(ctx context.Context, name, newName string) (*Tag, error)
+	// UpdateSeries sets the recurrence rule of the occurrences not yet completed of the series of the todo with given ID,
+	// an empty rule stops the series. ErrNotRecurring is returned if the todo never recurred, and an error matching
+	// ErrConflict if the rule is not empty and all the occurrences are completed.
+	UpdateSeries(ctx context.Context, id int32, recurrence string) ([]*Todo, error)
+	// CountChildren returns the numbers of live children of the todos with given IDs having children.
+	CountChildren(ctx context.Context, ids []int32) (map[int32]ChildrenStats, error)
+	// Descendants returns the live descendants of the todos with given IDs in the order of their id,
+	// the descendants of a todo in the trash are not returned.
+	Descendants(ctx context.Context, ids []int32) ([]*Todo, error)
+	// OpenBlockers returns by ID the ids of the open blockers, live and not completed, of the todos with given IDs
+	// having live blockers. the todos whose blockers are all completed get an empty slice.
+	OpenBlockers(ctx context.Context, ids []int32) (map[int32][]int32, error)
+	// Blockers returns the live blockers of the todo with given ID in the order of their id.
+	Blockers(ctx context.Context, id int32) ([]*Todo, error)
+	// AddDependency blocks the todo with given ID by the todo blockerId until it is completed, adding an existing
+	// dependency does nothing. ErrNotFound is returned if the todo is not live, and a ValidationError if the blocker
+	// is not a live todo of the same owner, or if it is the todo itself or one of the todos it blocks, even indirectly.
+	AddDependency(ctx context.Context, id int32, blockerId int32) error
+	// RemoveDependency removes the blocker blockerId of the todo with given ID, or returns ErrDependencyNotFound.
+	// the dependencies of a todo are removed with it when it is purged, the ones of the todos in the trash are kept.
+	RemoveDependency(ctx context.Context, id int32, blockerId int32) error
+	// ClaimReminders returns at most limit reminders of the live todos not completed whose reminder time, their remind_at
+	// or else their due_at, is at or before now, in the order of their reminder time, and records them as sent.
+	// the reminder of a todo is returned once for each reminder time, even after a restart : it is returned again
+	// only when its remind_at or due_at changes. the todos do not get a new version.
+	ClaimReminders(ctx context.Context, now time.Time, limit int) ([]*Reminder, error)
+	// ReleaseReminder records as not sent the reminder of the todo with given ID for the reminder time remindAt,
+	// so it is returned again by ClaimReminders. it does nothing if the reminder of this time was not claimed.
+	ReleaseReminder(ctx context.Context, id int32, remindAt time.Time) error
+	// Batch applies atomically all the operations in order : when one of them fails a *BatchError is returned
+	// and none of them is applied. the result contains for each operation the todo created or modified, nil for a delete.
+	Batch(ctx context.Context, operations []BatchOperation) ([]*Todo, error)
 	// Close terminates properly the connection to the backend
 	Close()
 }
 
+// GetStorageInstance returns the Storage for the given driver,
+// dbConnectionString is the postgres DSN for postgres, the database file path for sqlite
+// and the directory of the write-ahead log for memory (empty for a store that is not durable)
 func GetStorageInstance(dbDriver, dbConnectionString string, log *log.Logger) (Storage, error) {
 	var db Storage
 	var err error
@@ -47,8 +263,13 @@ func GetStorageInstance(dbDriver, dbConnectionString string, log *log.Logger) (S
 		if err != nil {
 			return nil, fmt.Errorf("error opening postgresql database with pgx driver: %s", err)
 		}
+	case "sqlite":
+		db, err = NewSqliteDB(dbConnectionString, log)
+		if err != nil {
+			return nil, fmt.Errorf("error opening sqlite database: %s", err)
+		}
 	case "memory":
-		db, err = NewMemoryDB()
+		db, err = NewMemoryDB(dbConnectionString, log)
 		if err != nil {
 			return nil, fmt.Errorf("error opening memory store: %s", err)
 		}
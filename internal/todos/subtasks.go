@@ -0,0 +1,243 @@
+package todos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ChildrenStats contains the numbers of live children of a todo, returned by Storage.CountChildren
+type ChildrenStats struct {
+	Count     int32
+	Completed int32
+}
+
+// childrenStatsRow is the numbers of live children of a todo, as read by the query of newChildrenStatsQuery
+type childrenStatsRow struct {
+	ParentId  int32
+	Count     int32
+	Completed int32
+}
+
+// toChildrenStats returns the ChildrenStats of the rows read by the query of newChildrenStatsQuery by id of the parent
+func toChildrenStats(rows []*childrenStatsRow) map[int32]ChildrenStats {
+	res := make(map[int32]ChildrenStats, len(rows))
+	for _, row := range rows {
+		res[row.ParentId] = ChildrenStats{Count: row.Count, Completed: row.Completed}
+	}
+	return res
+}
+
+// errUnknownParent is the ValidationError returned when a todo is created under, or moved to, a parent that does not exist,
+// that is in the trash or that is not owned by the owner of the todo
+func errUnknownParent(parentId int32) error {
+	return &ValidationError{Field: "parent_id", Message: fmt.Sprintf("todo %d does not exist", parentId)}
+}
+
+// errParentCycle is the ValidationError returned when a todo is moved under itself or under one of its descendants
+func errParentCycle(id, parentId int32) error {
+	if id == parentId {
+		return &ValidationError{Field: "parent_id", Message: fmt.Sprintf("todo %d cannot be its own parent", id)}
+	}
+	return &ValidationError{Field: "parent_id", Message: fmt.Sprintf("todo %d is a descendant of todo %d, it cannot be its parent", parentId, id)}
+}
+
+// getPatchParentId returns the parent_id column value of a todo after the patch, nil for a root todo.
+// patch.ParentId is nil when the parent does not change and 0 when the todo becomes a root todo
+func getPatchParentId(current *int32, patch TodoPatch) *int32 {
+	switch {
+	case patch.ParentId == nil:
+		return current
+	case *patch.ParentId == 0:
+		return nil
+	default:
+		return patch.ParentId
+	}
+}
+
+// getUpdateParentId returns the TodoPatch.ParentId replacing the parent of a todo by the one of todo, 0 for a root todo
+func getUpdateParentId(todo Todo) *int32 {
+	parentId := int32(0)
+	if todo.ParentId != nil {
+		parentId = *todo.ParentId
+	}
+	return &parentId
+}
+
+// getNewTodoParentId returns the parent_id of a new todo, nil for a root todo
+func getNewTodoParentId(todo NewTodo) *int32 {
+	if todo.ParentId == nil || *todo.ParentId == 0 {
+		return nil
+	}
+	return todo.ParentId
+}
+
+// normalizeAutoComplete returns the auto_complete column value : true, or nil when the todo is not completed with its children
+func normalizeAutoComplete(autoComplete *bool) *bool {
+	if autoComplete == nil || !*autoComplete {
+		return nil
+	}
+	return autoComplete
+}
+
+// getPatchAutoComplete returns the auto_complete column value of a todo after the patch
+func getPatchAutoComplete(current *bool, patch TodoPatch) *bool {
+	if patch.AutoComplete == nil {
+		return current
+	}
+	return normalizeAutoComplete(patch.AutoComplete)
+}
+
+// getUpdateAutoComplete returns the TodoPatch.AutoComplete replacing the auto_complete of a todo by the one of todo
+func getUpdateAutoComplete(todo Todo) *bool {
+	autoComplete := todo.AutoComplete != nil && *todo.AutoComplete
+	return &autoComplete
+}
+
+// deleteTodo moves with w the todo with given id to the trash. its live children are moved to the trash with it,
+// and so are their own children, when cascade is true. otherwise they are moved to the parent of the todo.
+func deleteTodo(ctx context.Context, w todoWriter, id int32, matchVersion int32, cascade bool) error {
+	t, err := w.lock(ctx, "Delete", id, liveTodo, matchVersion)
+	if err != nil {
+		return err
+	}
+	children, err := w.children(ctx, id, liveTodo)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if cascade {
+			err = deleteTodo(ctx, w, child.Id, 0, true)
+		} else {
+			_, err = w.reparent(ctx, child.Id, t.ParentId)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return w.delete(ctx, id, matchVersion)
+}
+
+// purgeTodo removes permanently with w the todo with given id, whether it is in the trash or not. its children,
+// even the ones in the trash, are purged with it when cascade is true and moved to the parent of the todo otherwise.
+func purgeTodo(ctx context.Context, w todoWriter, id int32, matchVersion int32, cascade bool) error {
+	t, err := w.lock(ctx, "Purge", id, anyTodo, matchVersion)
+	if err != nil {
+		return err
+	}
+	children, err := w.children(ctx, id, anyTodo)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if cascade {
+			err = purgeTodo(ctx, w, child.Id, 0, true)
+		} else {
+			_, err = w.reparent(ctx, child.Id, t.ParentId)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return w.purge(ctx, id, matchVersion)
+}
+
+// restoreTodo moves back with w the todo with given id from the trash,
+// it becomes a root todo when its parent is not restored with it
+func restoreTodo(ctx context.Context, w todoWriter, id int32, matchVersion int32) (*Todo, error) {
+	t, err := w.restore(ctx, id, matchVersion)
+	if err != nil || t.ParentId == nil {
+		return t, err
+	}
+	if _, err := w.lock(ctx, "Restore", *t.ParentId, liveTodo, 0); !errors.Is(err, ErrNotFound) {
+		return t, err
+	}
+	return w.reparent(ctx, id, nil)
+}
+
+// completeParent completes with w the parent of the todo changed from before to after, when the change completes
+// the last child not yet completed of a parent with auto_complete. a parent with open blockers is not completed.
+func completeParent(ctx context.Context, w todoWriter, before, after *Todo) error {
+	if before.Completed || !after.Completed || after.ParentId == nil {
+		return nil
+	}
+	parent, err := w.lock(ctx, "Patch", *after.ParentId, liveTodo, 0)
+	if errors.Is(err, ErrNotFound) {
+		// the parent is in the trash
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if parent.Completed || parent.AutoComplete == nil || !*parent.AutoComplete {
+		return nil
+	}
+	if blockers, err := w.openBlockers(ctx, parent.Id); err != nil || len(blockers) > 0 {
+		return err
+	}
+	children, err := w.children(ctx, parent.Id, liveTodo)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if !child.Completed {
+			return nil
+		}
+	}
+	completed := true
+	_, err = w.patch(ctx, parent.Id, TodoPatch{Completed: &completed}, 0)
+	return err
+}
+
+// afterPatch applies with w the changes following the patch of a todo from before to after :
+// the next occurrence of a recurring todo is created and a parent with auto_complete is completed with its last child
+func afterPatch(ctx context.Context, w todoWriter, before, after *Todo) error {
+	if err := recur(ctx, w, before, after); err != nil {
+		return err
+	}
+	return completeParent(ctx, w, before, after)
+}
+
+// withChildrenStats returns copies of the todos with the numbers of their live children, the todos are not modified
+// because the stores may share them
+func withChildrenStats(todos []*Todo, stats map[int32]ChildrenStats) []*Todo {
+	res := make([]*Todo, len(todos))
+	for i, t := range todos {
+		todo := *t
+		if s, found := stats[t.Id]; found && s.Count > 0 {
+			count, completed := s.Count, s.Completed
+			todo.ChildrenCount = &count
+			todo.ChildrenCompleted = &completed
+		}
+		res[i] = &todo
+	}
+	return res
+}
+
+// buildTree returns copies of the roots with their descendants nested in their Children, in the order of the descendants
+func buildTree(roots, descendants []*Todo) []*Todo {
+	children := make(map[int32][]*Todo)
+	for _, t := range descendants {
+		if t.ParentId != nil {
+			children[*t.ParentId] = append(children[*t.ParentId], t)
+		}
+	}
+	var nest func(t *Todo) Todo
+	nest = func(t *Todo) Todo {
+		todo := *t
+		if c := children[t.Id]; len(c) > 0 {
+			nested := make([]Todo, len(c))
+			for i, child := range c {
+				nested[i] = nest(child)
+			}
+			todo.Children = &nested
+		}
+		return todo
+	}
+	res := make([]*Todo, len(roots))
+	for i, root := range roots {
+		todo := nest(root)
+		res[i] = &todo
+	}
+	return res
+}
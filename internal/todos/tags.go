@@ -0,0 +1,136 @@
+package todos
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// MaxTagLength is the greatest number of characters of a tag
+	MaxTagLength = 50
+	// MaxTagsPerTodo is the greatest number of tags of a todo
+	MaxTagsPerTodo = 20
+)
+
+// ErrTagNotFound is returned when no todo of the user has the tag
+var ErrTagNotFound = errors.New("no todo has this tag")
+
+// tagName returns the tag in lowercase and without the leading # of the tags written in the tasks
+func tagName(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// normalizeTag returns the tag as stored, or a ValidationError when it is not a valid tag
+func normalizeTag(tag string) (string, error) {
+	name := tagName(tag)
+	if len(name) < 1 {
+		return "", &ValidationError{Field: "tags", Message: "cannot contain an empty tag"}
+	}
+	if len([]rune(name)) > MaxTagLength {
+		return "", &ValidationError{Field: "tags", Message: fmt.Sprintf("tag %s is longer than %d characters", name, MaxTagLength)}
+	}
+	if strings.IndexFunc(name, func(r rune) bool { return unicode.IsSpace(r) || r == ',' || r == '#' }) >= 0 {
+		return "", &ValidationError{Field: "tags", Message: fmt.Sprintf("tag %s cannot contain spaces, commas or #", name)}
+	}
+	return name, nil
+}
+
+// normalizeTags returns the normalized tags in alphabetical order without duplicates, nil when there is no tag
+func normalizeTags(tags *[]string) ([]string, error) {
+	if tags == nil || len(*tags) == 0 {
+		return nil, nil
+	}
+	unique := make(map[string]bool)
+	for _, tag := range *tags {
+		name, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		unique[name] = true
+	}
+	if len(unique) > MaxTagsPerTodo {
+		return nil, &ValidationError{Field: "tags", Message: fmt.Sprintf("cannot contain more than %d tags", MaxTagsPerTodo)}
+	}
+	res := make([]string, 0, len(unique))
+	for name := range unique {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// tagsValue returns the Todo.Tags value of normalized tags, nil when there is no tag so the field is omitted
+func tagsValue(tags []string) *[]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	return &tags
+}
+
+// getTodoTags returns the tags of the todo, nil when it has none
+func getTodoTags(t *Todo) []string {
+	if t.Tags == nil {
+		return nil
+	}
+	return *t.Tags
+}
+
+// getUpdateTags returns the TodoPatch.Tags replacing the tags of a todo by the ones of todo, empty when it has none
+func getUpdateTags(todo Todo) *[]string {
+	tags := make([]string, 0)
+	if todo.Tags != nil {
+		tags = *todo.Tags
+	}
+	return &tags
+}
+
+// replaceTag returns the normalized tags with from replaced by to, to appears only once when the tags already contain it
+func replaceTag(tags []string, from, to string) []string {
+	res := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag == from {
+			tag = to
+		}
+		res = append(res, tag)
+	}
+	// the tags are already valid, so only the duplicates are removed here
+	normalized, _ := normalizeTags(&res)
+	return normalized
+}
+
+// todoTagRow is a tag of a todo, as read by the query of newTodoTagsQuery
+type todoTagRow struct {
+	TodoId int32
+	Name   string
+}
+
+// setTodosTags sets the Tags of the todos from the rows read by the query of newTodoTagsQuery
+func setTodosTags(todos []*Todo, rows []*todoTagRow) {
+	tags := make(map[int32][]string)
+	for _, row := range rows {
+		tags[row.TodoId] = append(tags[row.TodoId], row.Name)
+	}
+	for _, t := range todos {
+		t.Tags = tagsValue(tags[t.Id])
+	}
+}
+
+// hasTags returns true only if the todo has all the tags when all is true, or at least one of them otherwise
+func hasTags(t *Todo, tags []string, all bool) bool {
+	for _, tag := range tags {
+		found := false
+		for _, todoTag := range getTodoTags(t) {
+			if todoTag == tag {
+				found = true
+				break
+			}
+		}
+		if found != all {
+			return found
+		}
+	}
+	return all
+}
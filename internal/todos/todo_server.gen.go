@@ -13,34 +13,945 @@ import (
 
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// Returns all API keys
+	// (GET /admin/apikeys)
+	GetApiKeys(ctx echo.Context) error
+
+	// (POST /admin/apikeys)
+	CreateApiKey(ctx echo.Context) error
+
+	// (DELETE /admin/apikeys/{apiKeyId})
+	RevokeApiKey(ctx echo.Context, apiKeyId int32) error
+	// Returns the changes of all the Todos
+	// (GET /events)
+	GetEvents(ctx echo.Context, params GetEventsParams) error
+
+	// (GET /lists)
+	GetLists(ctx echo.Context) error
+
+	// (POST /lists)
+	CreateList(ctx echo.Context) error
+
+	// (DELETE /lists/{listId})
+	DeleteList(ctx echo.Context, listId int32) error
+
+	// (GET /lists/{listId})
+	GetList(ctx echo.Context, listId int32) error
+
+	// (PUT /lists/{listId})
+	UpdateList(ctx echo.Context, listId int32) error
+
+	// (GET /lists/{listId}/todos)
+	GetListTodos(ctx echo.Context, listId int32, params GetListTodosParams) error
+
+	// (POST /lists/{listId}/todos)
+	CreateListTodo(ctx echo.Context, listId int32) error
+
+	// (GET /tags)
+	GetTags(ctx echo.Context) error
+
+	// (PUT /tags/{tagName})
+	RenameTag(ctx echo.Context, tagName string) error
 	// Returns all Todos
 	// (GET /todos)
 	GetTodos(ctx echo.Context, params GetTodosParams) error
 
-	// (POST /todos)
-	CreateTodo(ctx echo.Context) error
+	// (POST /todos)
+	CreateTodo(ctx echo.Context) error
+	// Returns the overdue Todos
+	// (GET /todos/overdue)
+	GetOverdueTodos(ctx echo.Context, params GetOverdueTodosParams) error
+	// Returns the ready Todos
+	// (GET /todos/ready)
+	GetReadyTodos(ctx echo.Context, params GetReadyTodosParams) error
+	// Search Todos
+	// (GET /todos/search)
+	SearchTodos(ctx echo.Context, params SearchTodosParams) error
+	// Returns the Todos in the trash
+	// (GET /todos/trash)
+	GetTrash(ctx echo.Context, params GetTrashParams) error
+	// Returns the upcoming Todos
+	// (GET /todos/upcoming)
+	GetUpcomingTodos(ctx echo.Context, params GetUpcomingTodosParams) error
+
+	// (DELETE /todos/{todoId})
+	DeleteTodo(ctx echo.Context, todoId int32, params DeleteTodoParams) error
+
+	// (GET /todos/{todoId})
+	GetTodo(ctx echo.Context, todoId int32, params GetTodoParams) error
+
+	// (PATCH /todos/{todoId})
+	PatchTodo(ctx echo.Context, todoId int32, params PatchTodoParams) error
+
+	// (PUT /todos/{todoId})
+	UpdateTodo(ctx echo.Context, todoId int32, params UpdateTodoParams) error
+	// Returns the children of a Todo
+	// (GET /todos/{todoId}/children)
+	GetTodoChildren(ctx echo.Context, todoId int32, params GetTodoChildrenParams) error
+	// Returns the blockers of a Todo
+	// (GET /todos/{todoId}/dependencies)
+	GetTodoDependencies(ctx echo.Context, todoId int32) error
+
+	// (POST /todos/{todoId}/dependencies)
+	AddTodoDependency(ctx echo.Context, todoId int32) error
+
+	// (DELETE /todos/{todoId}/dependencies/{blockerId})
+	RemoveTodoDependency(ctx echo.Context, todoId int32, blockerId int32) error
+	// Returns the history of a Todo
+	// (GET /todos/{todoId}/history)
+	GetTodoHistory(ctx echo.Context, todoId int32) error
+
+	// (POST /todos/{todoId}/restore)
+	RestoreTodo(ctx echo.Context, todoId int32, params RestoreTodoParams) error
+
+	// (DELETE /todos/{todoId}/series)
+	StopTodoSeries(ctx echo.Context, todoId int32) error
+	// Returns the series of a recurring Todo
+	// (GET /todos/{todoId}/series)
+	GetTodoSeries(ctx echo.Context, todoId int32) error
+
+	// (PUT /todos/{todoId}/series)
+	UpdateTodoSeries(ctx echo.Context, todoId int32) error
+	// Snoozes the reminder of a Todo
+	// (POST /todos/{todoId}/snooze)
+	SnoozeTodo(ctx echo.Context, todoId int32) error
+	// Apply a batch of operations
+	// (POST /todos:batch)
+	BatchTodos(ctx echo.Context) error
+	// Returns all Users
+	// (GET /users)
+	GetUsers(ctx echo.Context) error
+
+	// (POST /users)
+	CreateUser(ctx echo.Context) error
+}
+
+// ServerInterfaceWrapper converts echo contexts to parameters.
+type ServerInterfaceWrapper struct {
+	Handler ServerInterface
+}
+
+// GetApiKeys converts echo context to params.
+func (w *ServerInterfaceWrapper) GetApiKeys(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetApiKeys(ctx)
+	return err
+}
+
+// CreateApiKey converts echo context to params.
+func (w *ServerInterfaceWrapper) CreateApiKey(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.CreateApiKey(ctx)
+	return err
+}
+
+// RevokeApiKey converts echo context to params.
+func (w *ServerInterfaceWrapper) RevokeApiKey(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "apiKeyId" -------------
+	var apiKeyId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "apiKeyId", runtime.ParamLocationPath, ctx.Param("apiKeyId"), &apiKeyId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter apiKeyId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RevokeApiKey(ctx, apiKeyId)
+	return err
+}
+
+// GetEvents converts echo context to params.
+func (w *ServerInterfaceWrapper) GetEvents(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetEventsParams
+	// ------------- Optional query parameter "since" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "since", ctx.QueryParams(), &params.Since)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter since: %s", err))
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetEvents(ctx, params)
+	return err
+}
+
+// GetLists converts echo context to params.
+func (w *ServerInterfaceWrapper) GetLists(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetLists(ctx)
+	return err
+}
+
+// CreateList converts echo context to params.
+func (w *ServerInterfaceWrapper) CreateList(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.CreateList(ctx)
+	return err
+}
+
+// DeleteList converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteList(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "listId" -------------
+	var listId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "listId", runtime.ParamLocationPath, ctx.Param("listId"), &listId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter listId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.DeleteList(ctx, listId)
+	return err
+}
+
+// GetList converts echo context to params.
+func (w *ServerInterfaceWrapper) GetList(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "listId" -------------
+	var listId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "listId", runtime.ParamLocationPath, ctx.Param("listId"), &listId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter listId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetList(ctx, listId)
+	return err
+}
+
+// UpdateList converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdateList(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "listId" -------------
+	var listId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "listId", runtime.ParamLocationPath, ctx.Param("listId"), &listId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter listId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.UpdateList(ctx, listId)
+	return err
+}
+
+// GetListTodos converts echo context to params.
+func (w *ServerInterfaceWrapper) GetListTodos(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "listId" -------------
+	var listId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "listId", runtime.ParamLocationPath, ctx.Param("listId"), &listId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter listId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetListTodosParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "offset" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "offset", ctx.QueryParams(), &params.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter offset: %s", err))
+	}
+
+	// ------------- Optional query parameter "after" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "after", ctx.QueryParams(), &params.After)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter after: %s", err))
+	}
+
+	// ------------- Optional query parameter "completed" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "completed", ctx.QueryParams(), &params.Completed)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter completed: %s", err))
+	}
+
+	// ------------- Optional query parameter "sort" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "sort", ctx.QueryParams(), &params.Sort)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter sort: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetListTodos(ctx, listId, params)
+	return err
+}
+
+// CreateListTodo converts echo context to params.
+func (w *ServerInterfaceWrapper) CreateListTodo(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "listId" -------------
+	var listId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "listId", runtime.ParamLocationPath, ctx.Param("listId"), &listId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter listId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.CreateListTodo(ctx, listId)
+	return err
+}
+
+// GetTags converts echo context to params.
+func (w *ServerInterfaceWrapper) GetTags(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetTags(ctx)
+	return err
+}
+
+// RenameTag converts echo context to params.
+func (w *ServerInterfaceWrapper) RenameTag(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "tagName" -------------
+	var tagName string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "tagName", runtime.ParamLocationPath, ctx.Param("tagName"), &tagName)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter tagName: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RenameTag(ctx, tagName)
+	return err
+}
+
+// GetTodos converts echo context to params.
+func (w *ServerInterfaceWrapper) GetTodos(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetTodosParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "offset" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "offset", ctx.QueryParams(), &params.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter offset: %s", err))
+	}
+
+	// ------------- Optional query parameter "after" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "after", ctx.QueryParams(), &params.After)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter after: %s", err))
+	}
+
+	// ------------- Optional query parameter "completed" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "completed", ctx.QueryParams(), &params.Completed)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter completed: %s", err))
+	}
+
+	// ------------- Optional query parameter "created_after" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "created_after", ctx.QueryParams(), &params.CreatedAfter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter created_after: %s", err))
+	}
+
+	// ------------- Optional query parameter "created_before" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "created_before", ctx.QueryParams(), &params.CreatedBefore)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter created_before: %s", err))
+	}
+
+	// ------------- Optional query parameter "completed_after" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "completed_after", ctx.QueryParams(), &params.CompletedAfter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter completed_after: %s", err))
+	}
+
+	// ------------- Optional query parameter "completed_before" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "completed_before", ctx.QueryParams(), &params.CompletedBefore)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter completed_before: %s", err))
+	}
+
+	// ------------- Optional query parameter "sort" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "sort", ctx.QueryParams(), &params.Sort)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter sort: %s", err))
+	}
+
+	// ------------- Optional query parameter "ids" -------------
+
+	err = runtime.BindQueryParameter("form", false, false, "ids", ctx.QueryParams(), &params.Ids)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter ids: %s", err))
+	}
+
+	// ------------- Optional query parameter "tag" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "tag", ctx.QueryParams(), &params.Tag)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter tag: %s", err))
+	}
+
+	// ------------- Optional query parameter "tag_match" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "tag_match", ctx.QueryParams(), &params.TagMatch)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter tag_match: %s", err))
+	}
+
+	// ------------- Optional query parameter "all_owners" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "all_owners", ctx.QueryParams(), &params.AllOwners)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter all_owners: %s", err))
+	}
+
+	// ------------- Optional query parameter "tree" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "tree", ctx.QueryParams(), &params.Tree)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter tree: %s", err))
+	}
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "If-None-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-None-Match")]; found {
+		var IfNoneMatch string
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-None-Match, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-None-Match", runtime.ParamLocationHeader, valueList[0], &IfNoneMatch)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-None-Match: %s", err))
+		}
+
+		params.IfNoneMatch = &IfNoneMatch
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetTodos(ctx, params)
+	return err
+}
+
+// CreateTodo converts echo context to params.
+func (w *ServerInterfaceWrapper) CreateTodo(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.CreateTodo(ctx)
+	return err
+}
+
+// GetOverdueTodos converts echo context to params.
+func (w *ServerInterfaceWrapper) GetOverdueTodos(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetOverdueTodosParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "offset" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "offset", ctx.QueryParams(), &params.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter offset: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetOverdueTodos(ctx, params)
+	return err
+}
+
+// GetReadyTodos converts echo context to params.
+func (w *ServerInterfaceWrapper) GetReadyTodos(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetReadyTodosParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "offset" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "offset", ctx.QueryParams(), &params.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter offset: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetReadyTodos(ctx, params)
+	return err
+}
+
+// SearchTodos converts echo context to params.
+func (w *ServerInterfaceWrapper) SearchTodos(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params SearchTodosParams
+	// ------------- Required query parameter "q" -------------
+
+	err = runtime.BindQueryParameter("form", true, true, "q", ctx.QueryParams(), &params.Q)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter q: %s", err))
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.SearchTodos(ctx, params)
+	return err
+}
+
+// GetTrash converts echo context to params.
+func (w *ServerInterfaceWrapper) GetTrash(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetTrashParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "offset" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "offset", ctx.QueryParams(), &params.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter offset: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetTrash(ctx, params)
+	return err
+}
+
+// GetUpcomingTodos converts echo context to params.
+func (w *ServerInterfaceWrapper) GetUpcomingTodos(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetUpcomingTodosParams
+	// ------------- Optional query parameter "within" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "within", ctx.QueryParams(), &params.Within)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter within: %s", err))
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "offset" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "offset", ctx.QueryParams(), &params.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter offset: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetUpcomingTodos(ctx, params)
+	return err
+}
+
+// DeleteTodo converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteTodo(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "todoId" -------------
+	var todoId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "todoId", runtime.ParamLocationPath, ctx.Param("todoId"), &todoId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter todoId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
 
-	// (DELETE /todos/{todoId})
-	DeleteTodo(ctx echo.Context, todoId int32) error
+	ctx.Set(UserLoginScopes, []string{""})
 
-	// (GET /todos/{todoId})
-	GetTodo(ctx echo.Context, todoId int32) error
+	// Parameter object where we will unmarshal all parameters from the context
+	var params DeleteTodoParams
+	// ------------- Optional query parameter "purge" -------------
 
-	// (PUT /todos/{todoId})
-	UpdateTodo(ctx echo.Context, todoId int32) error
+	err = runtime.BindQueryParameter("form", true, false, "purge", ctx.QueryParams(), &params.Purge)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter purge: %s", err))
+	}
+
+	// ------------- Optional query parameter "children" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "children", ctx.QueryParams(), &params.Children)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter children: %s", err))
+	}
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "If-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Match")]; found {
+		var IfMatch string
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-Match, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-Match", runtime.ParamLocationHeader, valueList[0], &IfMatch)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-Match: %s", err))
+		}
+
+		params.IfMatch = &IfMatch
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.DeleteTodo(ctx, todoId, params)
+	return err
 }
 
-// ServerInterfaceWrapper converts echo contexts to parameters.
-type ServerInterfaceWrapper struct {
-	Handler ServerInterface
+// GetTodo converts echo context to params.
+func (w *ServerInterfaceWrapper) GetTodo(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "todoId" -------------
+	var todoId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "todoId", runtime.ParamLocationPath, ctx.Param("todoId"), &todoId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter todoId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetTodoParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "If-None-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-None-Match")]; found {
+		var IfNoneMatch string
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-None-Match, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-None-Match", runtime.ParamLocationHeader, valueList[0], &IfNoneMatch)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-None-Match: %s", err))
+		}
+
+		params.IfNoneMatch = &IfNoneMatch
+	}
+	// ------------- Optional header parameter "If-Modified-Since" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Modified-Since")]; found {
+		var IfModifiedSince string
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-Modified-Since, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-Modified-Since", runtime.ParamLocationHeader, valueList[0], &IfModifiedSince)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-Modified-Since: %s", err))
+		}
+
+		params.IfModifiedSince = &IfModifiedSince
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetTodo(ctx, todoId, params)
+	return err
 }
 
-// GetTodos converts echo context to params.
-func (w *ServerInterfaceWrapper) GetTodos(ctx echo.Context) error {
+// PatchTodo converts echo context to params.
+func (w *ServerInterfaceWrapper) PatchTodo(ctx echo.Context) error {
 	var err error
+	// ------------- Path parameter "todoId" -------------
+	var todoId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "todoId", runtime.ParamLocationPath, ctx.Param("todoId"), &todoId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter todoId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
 
 	// Parameter object where we will unmarshal all parameters from the context
-	var params GetTodosParams
+	var params PatchTodoParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "If-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Match")]; found {
+		var IfMatch string
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-Match, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-Match", runtime.ParamLocationHeader, valueList[0], &IfMatch)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-Match: %s", err))
+		}
+
+		params.IfMatch = &IfMatch
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.PatchTodo(ctx, todoId, params)
+	return err
+}
+
+// UpdateTodo converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdateTodo(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "todoId" -------------
+	var todoId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "todoId", runtime.ParamLocationPath, ctx.Param("todoId"), &todoId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter todoId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params UpdateTodoParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "If-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Match")]; found {
+		var IfMatch string
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-Match, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-Match", runtime.ParamLocationHeader, valueList[0], &IfMatch)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-Match: %s", err))
+		}
+
+		params.IfMatch = &IfMatch
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.UpdateTodo(ctx, todoId, params)
+	return err
+}
+
+// GetTodoChildren converts echo context to params.
+func (w *ServerInterfaceWrapper) GetTodoChildren(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "todoId" -------------
+	var todoId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "todoId", runtime.ParamLocationPath, ctx.Param("todoId"), &todoId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter todoId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetTodoChildrenParams
 	// ------------- Optional query parameter "limit" -------------
 
 	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
@@ -48,22 +959,64 @@ func (w *ServerInterfaceWrapper) GetTodos(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
 	}
 
+	// ------------- Optional query parameter "offset" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "offset", ctx.QueryParams(), &params.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter offset: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.GetTodos(ctx, params)
+	err = w.Handler.GetTodoChildren(ctx, todoId, params)
 	return err
 }
 
-// CreateTodo converts echo context to params.
-func (w *ServerInterfaceWrapper) CreateTodo(ctx echo.Context) error {
+// GetTodoDependencies converts echo context to params.
+func (w *ServerInterfaceWrapper) GetTodoDependencies(ctx echo.Context) error {
 	var err error
+	// ------------- Path parameter "todoId" -------------
+	var todoId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "todoId", runtime.ParamLocationPath, ctx.Param("todoId"), &todoId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter todoId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
 
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.CreateTodo(ctx)
+	err = w.Handler.GetTodoDependencies(ctx, todoId)
 	return err
 }
 
-// DeleteTodo converts echo context to params.
-func (w *ServerInterfaceWrapper) DeleteTodo(ctx echo.Context) error {
+// AddTodoDependency converts echo context to params.
+func (w *ServerInterfaceWrapper) AddTodoDependency(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "todoId" -------------
+	var todoId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "todoId", runtime.ParamLocationPath, ctx.Param("todoId"), &todoId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter todoId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.AddTodoDependency(ctx, todoId)
+	return err
+}
+
+// RemoveTodoDependency converts echo context to params.
+func (w *ServerInterfaceWrapper) RemoveTodoDependency(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "todoId" -------------
 	var todoId int32
@@ -73,13 +1026,27 @@ func (w *ServerInterfaceWrapper) DeleteTodo(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter todoId: %s", err))
 	}
 
+	// ------------- Path parameter "blockerId" -------------
+	var blockerId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "blockerId", runtime.ParamLocationPath, ctx.Param("blockerId"), &blockerId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter blockerId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.DeleteTodo(ctx, todoId)
+	err = w.Handler.RemoveTodoDependency(ctx, todoId, blockerId)
 	return err
 }
 
-// GetTodo converts echo context to params.
-func (w *ServerInterfaceWrapper) GetTodo(ctx echo.Context) error {
+// GetTodoHistory converts echo context to params.
+func (w *ServerInterfaceWrapper) GetTodoHistory(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "todoId" -------------
 	var todoId int32
@@ -89,13 +1056,127 @@ func (w *ServerInterfaceWrapper) GetTodo(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter todoId: %s", err))
 	}
 
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.GetTodo(ctx, todoId)
+	err = w.Handler.GetTodoHistory(ctx, todoId)
 	return err
 }
 
-// UpdateTodo converts echo context to params.
-func (w *ServerInterfaceWrapper) UpdateTodo(ctx echo.Context) error {
+// RestoreTodo converts echo context to params.
+func (w *ServerInterfaceWrapper) RestoreTodo(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "todoId" -------------
+	var todoId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "todoId", runtime.ParamLocationPath, ctx.Param("todoId"), &todoId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter todoId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params RestoreTodoParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "If-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Match")]; found {
+		var IfMatch string
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-Match, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-Match", runtime.ParamLocationHeader, valueList[0], &IfMatch)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-Match: %s", err))
+		}
+
+		params.IfMatch = &IfMatch
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RestoreTodo(ctx, todoId, params)
+	return err
+}
+
+// StopTodoSeries converts echo context to params.
+func (w *ServerInterfaceWrapper) StopTodoSeries(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "todoId" -------------
+	var todoId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "todoId", runtime.ParamLocationPath, ctx.Param("todoId"), &todoId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter todoId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.StopTodoSeries(ctx, todoId)
+	return err
+}
+
+// GetTodoSeries converts echo context to params.
+func (w *ServerInterfaceWrapper) GetTodoSeries(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "todoId" -------------
+	var todoId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "todoId", runtime.ParamLocationPath, ctx.Param("todoId"), &todoId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter todoId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetTodoSeries(ctx, todoId)
+	return err
+}
+
+// UpdateTodoSeries converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdateTodoSeries(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "todoId" -------------
+	var todoId int32
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "todoId", runtime.ParamLocationPath, ctx.Param("todoId"), &todoId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter todoId: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.UpdateTodoSeries(ctx, todoId)
+	return err
+}
+
+// SnoozeTodo converts echo context to params.
+func (w *ServerInterfaceWrapper) SnoozeTodo(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "todoId" -------------
 	var todoId int32
@@ -105,8 +1186,59 @@ func (w *ServerInterfaceWrapper) UpdateTodo(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter todoId: %s", err))
 	}
 
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.SnoozeTodo(ctx, todoId)
+	return err
+}
+
+// BatchTodos converts echo context to params.
+func (w *ServerInterfaceWrapper) BatchTodos(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.BatchTodos(ctx)
+	return err
+}
+
+// GetUsers converts echo context to params.
+func (w *ServerInterfaceWrapper) GetUsers(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetUsers(ctx)
+	return err
+}
+
+// CreateUser converts echo context to params.
+func (w *ServerInterfaceWrapper) CreateUser(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{""})
+
+	ctx.Set(ApiKeyAuthScopes, []string{""})
+
+	ctx.Set(UserLoginScopes, []string{""})
+
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.UpdateTodo(ctx, todoId)
+	err = w.Handler.CreateUser(ctx)
 	return err
 }
 
@@ -138,10 +1270,42 @@ func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL
 		Handler: si,
 	}
 
+	router.GET(baseURL+"/admin/apikeys", wrapper.GetApiKeys)
+	router.POST(baseURL+"/admin/apikeys", wrapper.CreateApiKey)
+	router.DELETE(baseURL+"/admin/apikeys/:apiKeyId", wrapper.RevokeApiKey)
+	router.GET(baseURL+"/events", wrapper.GetEvents)
+	router.GET(baseURL+"/lists", wrapper.GetLists)
+	router.POST(baseURL+"/lists", wrapper.CreateList)
+	router.DELETE(baseURL+"/lists/:listId", wrapper.DeleteList)
+	router.GET(baseURL+"/lists/:listId", wrapper.GetList)
+	router.PUT(baseURL+"/lists/:listId", wrapper.UpdateList)
+	router.GET(baseURL+"/lists/:listId/todos", wrapper.GetListTodos)
+	router.POST(baseURL+"/lists/:listId/todos", wrapper.CreateListTodo)
+	router.GET(baseURL+"/tags", wrapper.GetTags)
+	router.PUT(baseURL+"/tags/:tagName", wrapper.RenameTag)
 	router.GET(baseURL+"/todos", wrapper.GetTodos)
 	router.POST(baseURL+"/todos", wrapper.CreateTodo)
+	router.GET(baseURL+"/todos/overdue", wrapper.GetOverdueTodos)
+	router.GET(baseURL+"/todos/ready", wrapper.GetReadyTodos)
+	router.GET(baseURL+"/todos/search", wrapper.SearchTodos)
+	router.GET(baseURL+"/todos/trash", wrapper.GetTrash)
+	router.GET(baseURL+"/todos/upcoming", wrapper.GetUpcomingTodos)
 	router.DELETE(baseURL+"/todos/:todoId", wrapper.DeleteTodo)
 	router.GET(baseURL+"/todos/:todoId", wrapper.GetTodo)
+	router.PATCH(baseURL+"/todos/:todoId", wrapper.PatchTodo)
 	router.PUT(baseURL+"/todos/:todoId", wrapper.UpdateTodo)
+	router.GET(baseURL+"/todos/:todoId/children", wrapper.GetTodoChildren)
+	router.GET(baseURL+"/todos/:todoId/dependencies", wrapper.GetTodoDependencies)
+	router.POST(baseURL+"/todos/:todoId/dependencies", wrapper.AddTodoDependency)
+	router.DELETE(baseURL+"/todos/:todoId/dependencies/:blockerId", wrapper.RemoveTodoDependency)
+	router.GET(baseURL+"/todos/:todoId/history", wrapper.GetTodoHistory)
+	router.POST(baseURL+"/todos/:todoId/restore", wrapper.RestoreTodo)
+	router.DELETE(baseURL+"/todos/:todoId/series", wrapper.StopTodoSeries)
+	router.GET(baseURL+"/todos/:todoId/series", wrapper.GetTodoSeries)
+	router.PUT(baseURL+"/todos/:todoId/series", wrapper.UpdateTodoSeries)
+	router.POST(baseURL+"/todos/:todoId/snooze", wrapper.SnoozeTodo)
+	router.POST(baseURL+"/todos\\:batch", wrapper.BatchTodos)
+	router.GET(baseURL+"/users", wrapper.GetUsers)
+	router.POST(baseURL+"/users", wrapper.CreateUser)
 
 }
@@ -7,34 +7,690 @@ import (
 	"time"
 )
 
+const (
+	ApiKeyAuthScopes = "apiKeyAuth.Scopes"
+	BearerAuthScopes = "bearerAuth.Scopes"
+	UserLoginScopes  = "userLogin.Scopes"
+)
+
+// Defines values for ApiKeyScope.
+const (
+	ApiKeyScopeAdmin ApiKeyScope = "admin"
+
+	ApiKeyScopeTodosRead ApiKeyScope = "todos:read"
+
+	ApiKeyScopeTodosWrite ApiKeyScope = "todos:write"
+)
+
+// Defines values for BatchOperationOp.
+const (
+	BatchOperationOpComplete BatchOperationOp = "complete"
+
+	BatchOperationOpCreate BatchOperationOp = "create"
+
+	BatchOperationOpDelete BatchOperationOp = "delete"
+
+	BatchOperationOpUpdate BatchOperationOp = "update"
+)
+
+// Defines values for DeleteTodoParamsChildren.
+const (
+	DeleteTodoParamsChildrenCascade DeleteTodoParamsChildren = "cascade"
+
+	DeleteTodoParamsChildrenReparent DeleteTodoParamsChildren = "reparent"
+)
+
+// Defines values for GetTodosParamsTagMatch.
+const (
+	GetTodosParamsTagMatchAll GetTodosParamsTagMatch = "all"
+
+	GetTodosParamsTagMatchAny GetTodosParamsTagMatch = "any"
+)
+
+// Defines values for JSONPatchOperationOp.
+const (
+	JSONPatchOperationOpAdd JSONPatchOperationOp = "add"
+
+	JSONPatchOperationOpCopy JSONPatchOperationOp = "copy"
+
+	JSONPatchOperationOpMove JSONPatchOperationOp = "move"
+
+	JSONPatchOperationOpRemove JSONPatchOperationOp = "remove"
+
+	JSONPatchOperationOpReplace JSONPatchOperationOp = "replace"
+
+	JSONPatchOperationOpTest JSONPatchOperationOp = "test"
+)
+
+// Defines values for TodoEventType.
+const (
+	TodoEventTypeCompleted TodoEventType = "completed"
+
+	TodoEventTypeCreated TodoEventType = "created"
+
+	TodoEventTypeDeleted TodoEventType = "deleted"
+
+	TodoEventTypePurged TodoEventType = "purged"
+
+	TodoEventTypeRestored TodoEventType = "restored"
+
+	TodoEventTypeUncompleted TodoEventType = "uncompleted"
+
+	TodoEventTypeUpdated TodoEventType = "updated"
+)
+
+// Defines values for UserRole.
+const (
+	UserRoleAdmin UserRole = "admin"
+
+	UserRoleEditor UserRole = "editor"
+
+	UserRoleViewer UserRole = "viewer"
+)
+
+// returned with 403 Forbidden when the role of the user or the scopes of the API key do not allow the operation
+type AccessDenied struct {
+	Code    int32  `json:"code"`
+	Message string `json:"message"`
+
+	// operationId of the denied operation
+	Operation string `json:"operation"`
+
+	// permission required by the operation
+	Permission string `json:"permission"`
+
+	// viewer can only read the todos, editor can also create, change and delete them, admin can also manage the users and the API keys and list the todos of all the users. editor is the default
+	Role *UserRole `json:"role,omitempty"`
+}
+
+// ApiKey defines model for ApiKey.
+type ApiKey struct {
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+
+	// the key is rejected after this date-time, it never expires when it is not given
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Id        int32      `json:"id"`
+
+	// date-time of the last request made with the key, updated at most once per minute
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+
+	// login of the user the key acts for
+	Login string `json:"login"`
+	Name  string `json:"name"`
+
+	// first characters of the key, to recognize it since the key itself is never returned again
+	Prefix string        `json:"prefix"`
+	Scopes []ApiKeyScope `json:"scopes"`
+}
+
+// ApiKeyCreated defines model for ApiKeyCreated.
+type ApiKeyCreated struct {
+	// Embedded struct due to allOf(#/components/schemas/ApiKey)
+	ApiKey `yaml:",inline"`
+	// Embedded fields due to inline allOf schema
+	// the key to send in the X-API-Key header, it is only returned once and only its hash is stored
+	Key string `json:"key"`
+}
+
+// todos:read allows the GET routes of the todos, todos:write the other routes of the todos and admin all the routes
+type ApiKeyScope string
+
+// BatchOperation defines model for BatchOperation.
+type BatchOperation struct {
+	// new completed status of the todo to update
+	Completed *bool `json:"completed,omitempty"`
+
+	// Id of the todo to update, complete or delete
+	Id *int32 `json:"id,omitempty"`
+
+	// Id of the list of the todo to create, or of the list to move the todo to update to, 0 to remove it from its list
+	ListId *int32           `json:"list_id,omitempty"`
+	Op     BatchOperationOp `json:"op"`
+
+	// task of the todo to create or new task of the todo to update
+	Task *string `json:"task,omitempty"`
+
+	// the operation fails if the todo does not have this version anymore, like with the If-Match header
+	Version *int32 `json:"version,omitempty"`
+}
+
+// BatchOperationOp defines model for BatchOperation.Op.
+type BatchOperationOp string
+
+// BatchResult defines model for BatchResult.
+type BatchResult struct {
+	// reason why the operation failed
+	Error *string `json:"error,omitempty"`
+
+	// http status code of the operation, 424 for the operations not applied because another one failed
+	Status int32 `json:"status"`
+	Todo   *Todo `json:"todo,omitempty"`
+}
+
 // Error defines model for Error.
 type Error struct {
 	Code    int32  `json:"code"`
 	Message string `json:"message"`
 }
 
+// JSONPatchOperation defines model for JSONPatchOperation.
+type JSONPatchOperation struct {
+	// JSON Pointer of the source field of the move and copy operations
+	From *string              `json:"from,omitempty"`
+	Op   JSONPatchOperationOp `json:"op"`
+
+	// JSON Pointer of the field of the todo, for example /task
+	Path string `json:"path"`
+
+	// value of the add, replace and test operations
+	Value *interface{} `json:"value,omitempty"`
+}
+
+// JSONPatchOperationOp defines model for JSONPatchOperation.Op.
+type JSONPatchOperationOp string
+
+// NewApiKey defines model for NewApiKey.
+type NewApiKey struct {
+	// the key is rejected after this date-time, it never expires when it is not given
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// login of the user the key acts for, the administrator creating the key when it is not given
+	Login *string `json:"login,omitempty"`
+
+	// what the key is used for, like the name of the script
+	Name   string        `json:"name"`
+	Scopes []ApiKeyScope `json:"scopes"`
+}
+
+// NewTag defines model for NewTag.
+type NewTag struct {
+	// new name of the tag, the tag is merged into the existing tag when the user already has a tag with this name
+	Name string `json:"name"`
+}
+
 // NewTodo defines model for NewTodo.
 type NewTodo struct {
-	Task string `json:"task"`
+	// when true the new todo is completed as soon as all its children are completed
+	AutoComplete *bool `json:"auto_complete,omitempty"`
+
+	// date-time when the todo must be completed, with its time zone offset
+	DueAt *time.Time `json:"due_at,omitempty"`
+
+	// Id of the list of the new todo, it is not in a list when it is not given
+	ListId *int32 `json:"list_id,omitempty"`
+
+	// Id of the parent of the new todo, a live todo of the same owner, it is a root todo when it is not given
+	ParentId *int32 `json:"parent_id,omitempty"`
+
+	// priority of the new todo, from 1 for the most urgent to 4, it has no priority when it is not given
+	Priority *int32 `json:"priority,omitempty"`
+
+	// recurrence rule of the new todo, when it is completed its next occurrence is created in the same series, a subset of the iCalendar RRULE like FREQ=WEEKLY;BYDAY=MO,TH : FREQ is DAILY, WEEKLY with an optional BYDAY or MONTHLY with an optional BYMONTHDAY, INTERVAL repeats every N periods and FREQ=DAILY;INTERVAL=N;X-FROM=COMPLETION repeats N days after the completion
+	Recurrence *string `json:"recurrence,omitempty"`
+
+	// date-time when the owner of the todo is reminded of it, the due date is used when it is not given
+	RemindAt *time.Time `json:"remind_at,omitempty"`
+
+	// tags of the new todo, they are stored in lowercase and without their leading hash sign
+	Tags *[]string `json:"tags,omitempty"`
+	Task string    `json:"task"`
+}
+
+// NewTodoDependency defines model for NewTodoDependency.
+type NewTodoDependency struct {
+	// Id of the todo which must be completed before the todo, a live todo of the same owner
+	BlockerId int32 `json:"blocker_id"`
+}
+
+// NewTodoList defines model for NewTodoList.
+type NewTodoList struct {
+	// name of the list, unique among the lists of the user
+	Name string `json:"name"`
+}
+
+// NewUser defines model for NewUser.
+type NewUser struct {
+	// deprecated, use role admin instead. true is the same as role admin
+	IsAdmin *bool   `json:"is_admin,omitempty"`
+	Login   string  `json:"login"`
+	Name    *string `json:"name,omitempty"`
+
+	// viewer can only read the todos, editor can also create, change and delete them, admin can also manage the users and the API keys and list the todos of all the users. editor is the default
+	Role *UserRole `json:"role,omitempty"`
+}
+
+// body of the POST request sent to the REMINDER_WEBHOOK_URL when the reminder date-time of a todo not completed is reached, it is sent once for each reminder date-time
+type Reminder struct {
+	// reminder date-time of the todo, its remind_at or else its due_at
+	RemindAt time.Time `json:"remind_at"`
+	Todo     Todo      `json:"todo"`
+}
+
+// a tag of the todos of the user
+type Tag struct {
+	Name string `json:"name"`
+
+	// number of todos with this tag, the todos in the trash are not counted
+	TodosCount int32 `json:"todos_count"`
 }
 
 // Todo defines model for Todo.
 type Todo struct {
-	Completed   bool       `json:"completed"`
-	CompletedAt *time.Time `json:"completed_at,omitempty"`
-	CreatedAt   *time.Time `json:"created_at,omitempty"`
-	Id          int32      `json:"id"`
-	Task        string     `json:"task"`
+	// true when the todo is completed as soon as all its children are completed, absent otherwise
+	AutoComplete *bool `json:"auto_complete,omitempty"`
+
+	// true when one of the blockers of the todo is not completed, it cannot be completed until then. absent when the todo has no blocker
+	Blocked *bool `json:"blocked,omitempty"`
+
+	// live children of the todo in the order of their id, only present in the responses of GET /todos with tree=true
+	Children *[]Todo `json:"children,omitempty"`
+
+	// number of the completed live children of the todo, absent when the todo has no children
+	ChildrenCompleted *int32 `json:"children_completed,omitempty"`
+
+	// number of the live children of the todo, absent when the todo has no children
+	ChildrenCount *int32     `json:"children_count,omitempty"`
+	Completed     bool       `json:"completed"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+
+	// date-time when the todo was moved to the trash, only present for the todos in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// date-time when the todo must be completed, absent when the todo has no due date
+	DueAt *time.Time `json:"due_at,omitempty"`
+	Id    int32      `json:"id"`
+
+	// Id of the list containing the todo, absent when the todo is not in a list
+	ListId *int32 `json:"list_id,omitempty"`
+
+	// true when the todo is not completed and its due date is past, absent when the todo has no due date
+	Overdue *bool `json:"overdue,omitempty"`
+
+	// Id of the user owning the todo, only this user can see and change it
+	OwnerId int32 `json:"owner_id"`
+
+	// Id of the parent of the todo, absent for a root todo
+	ParentId *int32 `json:"parent_id,omitempty"`
+
+	// priority of the todo, from 1 for the most urgent to 4, absent when the todo has no priority
+	Priority *int32 `json:"priority,omitempty"`
+
+	// recurrence rule of the todo, absent when the todo does not recur, a subset of the iCalendar RRULE like FREQ=WEEKLY;BYDAY=MO,TH : FREQ is DAILY, WEEKLY with an optional BYDAY or MONTHLY with an optional BYMONTHDAY, INTERVAL repeats every N periods and FREQ=DAILY;INTERVAL=N;X-FROM=COMPLETION repeats N days after the completion
+	Recurrence *string `json:"recurrence,omitempty"`
+
+	// date-time when the owner of the todo is reminded of it if it is not completed, absent when the reminder is sent at the due date or when the todo has no due date
+	RemindAt *time.Time `json:"remind_at,omitempty"`
+
+	// Id of the first todo of the series of occurrences of a recurring todo, absent when the todo never recurred
+	SeriesId *int32 `json:"series_id,omitempty"`
+
+	// tags of the todo in alphabetical order, absent when the todo has no tag
+	Tags *[]string `json:"tags,omitempty"`
+	Task string    `json:"task"`
+
+	// date-time of the last change of the todo, it is also sent in the Last-Modified header
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+
+	// incremented at every change of the todo, it is also sent in the ETag header
+	Version int32 `json:"version"`
+}
+
+// returned with 409 Conflict when a todo is completed while some of its blockers are not completed
+type TodoBlocked struct {
+	// Ids of the blockers of the todo not yet completed, in ascending order
+	Blockers []int32 `json:"blockers"`
+	Code     int32   `json:"code"`
+	Message  string  `json:"message"`
+	TodoId   int32   `json:"todo_id"`
 }
 
+// TodoEvent defines model for TodoEvent.
+type TodoEvent struct {
+	// who made the change
+	Actor  string `json:"actor"`
+	After  *Todo  `json:"after,omitempty"`
+	Before *Todo  `json:"before,omitempty"`
+
+	// Id of the event, the events are numbered in the order they occurred
+	Id         int64     `json:"id"`
+	OccurredAt time.Time `json:"occurred_at"`
+
+	// Id of the user owning the todo
+	OwnerId int32         `json:"owner_id"`
+	TodoId  int32         `json:"todo_id"`
+	Type    TodoEventType `json:"type"`
+}
+
+// TodoEventType defines model for TodoEvent.Type.
+type TodoEventType string
+
+// a named list of todos, like backlog or groceries
+type TodoList struct {
+	// number of completed todos in the list, the todos in the trash are not counted
+	CompletedCount int32      `json:"completed_count"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	Id             int32      `json:"id"`
+	Name           string     `json:"name"`
+
+	// Id of the user owning the list, only this user can see it and add todos to it
+	OwnerId int32 `json:"owner_id"`
+
+	// number of todos in the list, the todos in the trash are not counted
+	TodosCount int32      `json:"todos_count"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+// TodoPatch defines model for TodoPatch.
+type TodoPatch struct {
+	// true to complete the todo as soon as all its children are completed, false or null to stop it
+	AutoComplete *bool `json:"auto_complete,omitempty"`
+	Completed    *bool `json:"completed,omitempty"`
+
+	// new due date-time of the todo, null to remove its due date
+	DueAt *time.Time `json:"due_at,omitempty"`
+
+	// Id of the list to move the todo to, null to remove it from its list
+	ListId *int32 `json:"list_id,omitempty"`
+
+	// Id of the new parent of the todo, a live todo of the same owner which is not one of its descendants, null to make it a root todo
+	ParentId *int32 `json:"parent_id,omitempty"`
+
+	// new priority of the todo, null to remove its priority
+	Priority *int32 `json:"priority,omitempty"`
+
+	// new recurrence rule of the todo, null to stop its recurrence
+	Recurrence *string `json:"recurrence,omitempty"`
+
+	// new reminder date-time of the todo, null to be reminded at its due date
+	RemindAt *time.Time `json:"remind_at,omitempty"`
+
+	// new tags of the todo replacing all its tags, null to remove all its tags
+	Tags *[]string `json:"tags,omitempty"`
+	Task *string   `json:"task,omitempty"`
+}
+
+// TodoRecurrence defines model for TodoRecurrence.
+type TodoRecurrence struct {
+	// new recurrence rule of the series, a subset of the iCalendar RRULE like FREQ=WEEKLY;BYDAY=MO,TH : FREQ is DAILY, WEEKLY with an optional BYDAY or MONTHLY with an optional BYMONTHDAY, INTERVAL repeats every N periods and FREQ=DAILY;INTERVAL=N;X-FROM=COMPLETION repeats N days after the completion
+	Recurrence string `json:"recurrence"`
+}
+
+// TodoSearchResult defines model for TodoSearchResult.
+type TodoSearchResult struct {
+	// the task with the matching words surrounded by <b> and </b>
+	Headline string `json:"headline"`
+
+	// relevance of the todo for the search query, higher is better
+	Rank float32 `json:"rank"`
+	Todo Todo    `json:"todo"`
+}
+
+// postpones the reminder of a todo, either for a duration or until a date-time
+type TodoSnooze struct {
+	// the reminder is sent again after this duration from now, like 90m, 2h or 1d
+	Duration *string `json:"duration,omitempty"`
+
+	// the reminder is sent again at this date-time, in the future
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// User defines model for User.
+type User struct {
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	Id        int32      `json:"id"`
+
+	// deprecated, true when the role is admin
+	IsAdmin bool    `json:"is_admin"`
+	Login   string  `json:"login"`
+	Name    *string `json:"name,omitempty"`
+
+	// viewer can only read the todos, editor can also create, change and delete them, admin can also manage the users and the API keys and list the todos of all the users. editor is the default
+	Role UserRole `json:"role"`
+}
+
+// viewer can only read the todos, editor can also create, change and delete them, admin can also manage the users and the API keys and list the todos of all the users. editor is the default
+type UserRole string
+
+// CreateApiKeyJSONBody defines parameters for CreateApiKey.
+type CreateApiKeyJSONBody NewApiKey
+
+// GetEventsParams defines parameters for GetEvents.
+type GetEventsParams struct {
+	// return only the changes that occurred at or after this date-time
+	Since *time.Time `json:"since,omitempty"`
+
+	// maximum number of results to return
+	Limit *int32 `json:"limit,omitempty"`
+}
+
+// CreateListJSONBody defines parameters for CreateList.
+type CreateListJSONBody NewTodoList
+
+// UpdateListJSONBody defines parameters for UpdateList.
+type UpdateListJSONBody NewTodoList
+
+// GetListTodosParams defines parameters for GetListTodos.
+type GetListTodosParams struct {
+	// maximum number of results to return
+	Limit *int32 `json:"limit,omitempty"`
+
+	// number of results to skip before starting to return results
+	Offset *int32 `json:"offset,omitempty"`
+
+	// opaque cursor returned in the Link header, only results after this cursor are returned (cannot be combined with offset)
+	After *string `json:"after,omitempty"`
+
+	// only return the todos with this completed status
+	Completed *bool `json:"completed,omitempty"`
+
+	// field used to sort the results, prefix it with - for descending order (cursor paging requires sort=id)
+	Sort *string `json:"sort,omitempty"`
+}
+
+// CreateListTodoJSONBody defines parameters for CreateListTodo.
+type CreateListTodoJSONBody NewTodo
+
+// RenameTagJSONBody defines parameters for RenameTag.
+type RenameTagJSONBody NewTag
+
 // GetTodosParams defines parameters for GetTodos.
 type GetTodosParams struct {
 	// maximum number of results to return
 	Limit *int32 `json:"limit,omitempty"`
+
+	// number of results to skip before starting to return results
+	Offset *int32 `json:"offset,omitempty"`
+
+	// opaque cursor returned in the Link header, only results after this cursor are returned (cannot be combined with offset)
+	After *string `json:"after,omitempty"`
+
+	// only return the todos with this completed status
+	Completed *bool `json:"completed,omitempty"`
+
+	// only return the todos created at or after this date-time
+	CreatedAfter *time.Time `json:"created_after,omitempty"`
+
+	// only return the todos created before this date-time
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+
+	// only return the todos completed at or after this date-time
+	CompletedAfter *time.Time `json:"completed_after,omitempty"`
+
+	// only return the todos completed before this date-time
+	CompletedBefore *time.Time `json:"completed_before,omitempty"`
+
+	// field used to sort the results, prefix it with - for descending order (cursor paging requires sort=id)
+	Sort *string `json:"sort,omitempty"`
+
+	// only return the todos with one of these ids, for example ids=1,2,3
+	Ids *[]int32 `json:"ids,omitempty"`
+
+	// only return the todos with these tags, for example tag=home&tag=urgent
+	Tag *[]string `json:"tag,omitempty"`
+
+	// with all the todos must have all the given tags, with any they must have at least one of them
+	TagMatch *GetTodosParamsTagMatch `json:"tag_match,omitempty"`
+
+	// return the todos of all the users instead of the ones of the current user, only allowed to the administrators
+	AllOwners *bool `json:"all_owners,omitempty"`
+
+	// only return the root todos matching the other parameters, each one with all its live descendants nested in children, the paging applies to the root todos
+	Tree *bool `json:"tree,omitempty"`
+
+	// ETag of a previous response, 304 Not Modified is returned if the todos did not change and no todo became overdue since
+	IfNoneMatch *string `json:"If-None-Match,omitempty"`
 }
 
+// GetTodosParamsTagMatch defines parameters for GetTodos.
+type GetTodosParamsTagMatch string
+
 // CreateTodoJSONBody defines parameters for CreateTodo.
 type CreateTodoJSONBody NewTodo
 
+// GetOverdueTodosParams defines parameters for GetOverdueTodos.
+type GetOverdueTodosParams struct {
+	// maximum number of results to return
+	Limit *int32 `json:"limit,omitempty"`
+
+	// number of results to skip before starting to return results
+	Offset *int32 `json:"offset,omitempty"`
+}
+
+// GetReadyTodosParams defines parameters for GetReadyTodos.
+type GetReadyTodosParams struct {
+	// maximum number of results to return
+	Limit *int32 `json:"limit,omitempty"`
+
+	// number of results to skip before starting to return results
+	Offset *int32 `json:"offset,omitempty"`
+}
+
+// SearchTodosParams defines parameters for SearchTodos.
+type SearchTodosParams struct {
+	// words to search in the todo's task
+	Q string `json:"q"`
+
+	// maximum number of results to return
+	Limit *int32 `json:"limit,omitempty"`
+}
+
+// GetTrashParams defines parameters for GetTrash.
+type GetTrashParams struct {
+	// maximum number of results to return
+	Limit *int32 `json:"limit,omitempty"`
+
+	// number of results to skip before starting to return results
+	Offset *int32 `json:"offset,omitempty"`
+}
+
+// GetUpcomingTodosParams defines parameters for GetUpcomingTodos.
+type GetUpcomingTodosParams struct {
+	// duration from now in which the todos are due, like 90m, 72h or 7d
+	Within *string `json:"within,omitempty"`
+
+	// maximum number of results to return
+	Limit *int32 `json:"limit,omitempty"`
+
+	// number of results to skip before starting to return results
+	Offset *int32 `json:"offset,omitempty"`
+}
+
+// DeleteTodoParams defines parameters for DeleteTodo.
+type DeleteTodoParams struct {
+	// remove permanently the todo, even if it is in the trash, instead of moving it to the trash
+	Purge *bool `json:"purge,omitempty"`
+
+	// with reparent the children of the todo are moved to its parent, with cascade they are deleted with the todo
+	Children *DeleteTodoParamsChildren `json:"children,omitempty"`
+
+	// delete the todo only if the todo still has one of these ETag values, otherwise 412 Precondition Failed is returned
+	IfMatch *string `json:"If-Match,omitempty"`
+}
+
+// DeleteTodoParamsChildren defines parameters for DeleteTodo.
+type DeleteTodoParamsChildren string
+
+// GetTodoParams defines parameters for GetTodo.
+type GetTodoParams struct {
+	// ETag of a previous response, 304 Not Modified is returned if the todo did not change and did not become overdue since
+	IfNoneMatch *string `json:"If-None-Match,omitempty"`
+
+	// HTTP-date of a previous Last-Modified header, 304 Not Modified is returned if the todo did not change and did not become overdue since (ignored when If-None-Match is given)
+	IfModifiedSince *string `json:"If-Modified-Since,omitempty"`
+}
+
+// PatchTodoParams defines parameters for PatchTodo.
+type PatchTodoParams struct {
+	// patch the todo only if the todo still has one of these ETag values, otherwise 412 Precondition Failed is returned
+	IfMatch *string `json:"If-Match,omitempty"`
+}
+
+// UpdateTodoParams defines parameters for UpdateTodo.
+type UpdateTodoParams struct {
+	// update the todo only if the todo still has one of these ETag values, otherwise 412 Precondition Failed is returned
+	IfMatch *string `json:"If-Match,omitempty"`
+}
+
+// GetTodoChildrenParams defines parameters for GetTodoChildren.
+type GetTodoChildrenParams struct {
+	// maximum number of results to return
+	Limit *int32 `json:"limit,omitempty"`
+
+	// number of results to skip before starting to return results
+	Offset *int32 `json:"offset,omitempty"`
+}
+
+// AddTodoDependencyJSONBody defines parameters for AddTodoDependency.
+type AddTodoDependencyJSONBody NewTodoDependency
+
+// RestoreTodoParams defines parameters for RestoreTodo.
+type RestoreTodoParams struct {
+	// restore the todo only if the todo still has one of these ETag values, otherwise 412 Precondition Failed is returned
+	IfMatch *string `json:"If-Match,omitempty"`
+}
+
+// UpdateTodoSeriesJSONBody defines parameters for UpdateTodoSeries.
+type UpdateTodoSeriesJSONBody TodoRecurrence
+
+// SnoozeTodoJSONBody defines parameters for SnoozeTodo.
+type SnoozeTodoJSONBody TodoSnooze
+
+// BatchTodosJSONBody defines parameters for BatchTodos.
+type BatchTodosJSONBody []BatchOperation
+
+// CreateUserJSONBody defines parameters for CreateUser.
+type CreateUserJSONBody NewUser
+
+// CreateApiKeyJSONRequestBody defines body for CreateApiKey for application/json ContentType.
+type CreateApiKeyJSONRequestBody CreateApiKeyJSONBody
+
+// CreateListJSONRequestBody defines body for CreateList for application/json ContentType.
+type CreateListJSONRequestBody CreateListJSONBody
+
+// UpdateListJSONRequestBody defines body for UpdateList for application/json ContentType.
+type UpdateListJSONRequestBody UpdateListJSONBody
+
+// CreateListTodoJSONRequestBody defines body for CreateListTodo for application/json ContentType.
+type CreateListTodoJSONRequestBody CreateListTodoJSONBody
+
+// RenameTagJSONRequestBody defines body for RenameTag for application/json ContentType.
+type RenameTagJSONRequestBody RenameTagJSONBody
+
 // CreateTodoJSONRequestBody defines body for CreateTodo for application/json ContentType.
 type CreateTodoJSONRequestBody CreateTodoJSONBody
+
+// AddTodoDependencyJSONRequestBody defines body for AddTodoDependency for application/json ContentType.
+type AddTodoDependencyJSONRequestBody AddTodoDependencyJSONBody
+
+// UpdateTodoSeriesJSONRequestBody defines body for UpdateTodoSeries for application/json ContentType.
+type UpdateTodoSeriesJSONRequestBody UpdateTodoSeriesJSONBody
+
+// SnoozeTodoJSONRequestBody defines body for SnoozeTodo for application/json ContentType.
+type SnoozeTodoJSONRequestBody SnoozeTodoJSONBody
+
+// BatchTodosJSONRequestBody defines body for BatchTodos for application/json ContentType.
+type BatchTodosJSONRequestBody BatchTodosJSONBody
+
+// CreateUserJSONRequestBody defines body for CreateUser for application/json ContentType.
+type CreateUserJSONRequestBody CreateUserJSONBody
@@ -0,0 +1,65 @@
+package todos
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// todoState is the state a todo must be in for a write to be applied
+type todoState int
+
+const (
+	// liveTodo is a todo not in the trash, the only ones that can be updated or moved to the trash
+	liveTodo todoState = iota
+	// trashedTodo is a todo in the trash, the only ones that can be restored
+	trashedTodo
+	// anyTodo is a todo in the trash or not, all of them can be purged
+	anyTodo
+)
+
+// checkTodoState returns the reason why a write requiring the given state and matchVersion cannot be applied to t,
+// or nil when it can. a todo in the trash does not exist for the writes on live todos.
+func checkTodoState(t *Todo, state todoState, matchVersion int32) error {
+	switch {
+	case state == liveTodo && t.DeletedAt != nil:
+		return ErrNotFound
+	case state == trashedTodo && t.DeletedAt == nil:
+		return fmt.Errorf("%w : todo id %d is not in the trash", ErrConflict, t.Id)
+	case matchVersion != 0 && t.Version != matchVersion:
+		return ErrPreconditionFailed
+	}
+	return nil
+}
+
+// errModifiedDuring is the error of a write finding the todo changed by someone else after its state was checked
+func errModifiedDuring(operation string, id int32) error {
+	return fmt.Errorf("%w : todo id %d was modified during %s", ErrConflict, id, operation)
+}
+
+// RunTrashJanitor purges every interval the todos moved to the trash more than retention ago, until ctx is done.
+// it does nothing when retention is not positive.
+func RunTrashJanitor(ctx context.Context, store Storage, retention time.Duration, interval time.Duration, log *log.Logger) {
+	if retention <= 0 {
+		log.Printf("info : trash janitor is disabled, the todos stay in the trash until they are purged")
+		return
+	}
+	// the purges are recorded in the history of the todos with the janitor as actor
+	ctx = WithActor(ctx, TrashJanitorActor)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purged, err := store.PurgeTrash(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("error : trash janitor could not purge the trash, error : %v", err)
+		} else if purged > 0 {
+			log.Printf("info : trash janitor purged %d todos trashed more than %v ago", purged, retention)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package todos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// HeaderUser is the request header containing the login of the user making the request
+	HeaderUser = "X-User"
+	// AdminLogin is the login of the administrator created with the store, it owns the todos created before the users existed
+	AdminLogin = "admin"
+	// MaxLoginLength is the greatest number of characters of a user login
+	MaxLoginLength = 50
+)
+
+// ErrUserNotFound is returned when the user with the given login does not exist in the store
+var ErrUserNotFound = errors.New("user with this login does not exist")
+
+// errNoOwner is returned when a todo is created with a context without user, so it would have no owner
+var errNoOwner = errors.New("a todo cannot be created without a user in the context")
+
+// userKey is the key of the user in a context
+type userKey struct{}
+
+// allOwnersKey is the key of the access to the todos of all the users in a context
+type allOwnersKey struct{}
+
+// WithUser returns a copy of ctx carrying the user, the stores only give access to the todos owned by this user
+// with ctx, and record the login of the user as the actor of the changes
+func WithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the user carried by ctx, or nil for the internal tasks like the trash janitor
+func UserFromContext(ctx context.Context) *User {
+	user, _ := ctx.Value(userKey{}).(*User)
+	return user
+}
+
+// withAllOwners returns a copy of ctx giving access to the todos of all the users, it is only used for the administrators
+func withAllOwners(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allOwnersKey{}, true)
+}
+
+// getOwnerId returns the id of the user whose todos are accessible with ctx,
+// 0 when the todos of all the users are accessible (ctx without user or given by withAllOwners)
+func getOwnerId(ctx context.Context) int32 {
+	user := UserFromContext(ctx)
+	if user == nil {
+		return 0
+	}
+	if allOwners, _ := ctx.Value(allOwnersKey{}).(bool); allOwners {
+		return 0
+	}
+	return user.Id
+}
+
+// isOwnedBy returns true only if the todo is accessible to the owner returned by getOwnerId
+func isOwnedBy(t *Todo, ownerId int32) bool {
+	return ownerId == 0 || t.OwnerId == ownerId
+}
+
+// validateNewUser checks the business rules of a new user, shared by all the stores
+func validateNewUser(user NewUser) error {
+	login := strings.TrimSpace(user.Login)
+	if len(login) < 1 {
+		return &ValidationError{Field: "login", Message: "cannot be empty"}
+	}
+	if login != user.Login {
+		return &ValidationError{Field: "login", Message: "cannot start or end with spaces"}
+	}
+	if len(login) > MaxLoginLength {
+		return &ValidationError{Field: "login", Message: "maxLength is 50"}
+	}
+	if user.Role != nil {
+		switch *user.Role {
+		case UserRoleViewer, UserRoleEditor, UserRoleAdmin:
+		default:
+			return &ValidationError{Field: "role", Message: fmt.Sprintf("%s is not one of viewer, editor, admin", *user.Role)}
+		}
+		if user.IsAdmin != nil && *user.IsAdmin && *user.Role != UserRoleAdmin {
+			return &ValidationError{Field: "is_admin", Message: "cannot be true with the " + string(*user.Role) + " role"}
+		}
+	}
+	return nil
+}
+
+// getNewUserRole returns the role of the new user, editor when neither role nor the deprecated is_admin are given
+func getNewUserRole(user NewUser) UserRole {
+	switch {
+	case user.Role != nil:
+		return *user.Role
+	case user.IsAdmin != nil && *user.IsAdmin:
+		return UserRoleAdmin
+	default:
+		return UserRoleEditor
+	}
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"errors"
+	"os"
+)
+
+// minAdminApiKeyLength is the smallest number of characters of the API key of the administrator
+const minAdminApiKeyLength = 32
+
+//GetAdminApiKeyFromEnv returns the API key of the administrator created at startup based on the value of environment variables :
+//  ADMIN_API_KEY : string of at least 32 characters sent in the X-API-Key header of the requests of the administrator,
+//  when it is empty no key is created and the keys already in the store are used
+// in case the ENV variable ADMIN_API_KEY is too short the functions returns an empty string and an error
+func GetAdminApiKeyFromEnv() (string, error) {
+	val, exist := os.LookupEnv("ADMIN_API_KEY")
+	if !exist || val == "" {
+		return "", nil
+	}
+	if len(val) < minAdminApiKeyLength {
+		return "", &ErrorConfig{
+			err: errors.New("key is too short"),
+			msg: "ERROR: CONFIG ENV ADMIN_API_KEY should contain at least 32 characters.",
+		}
+	}
+	return val, nil
+}
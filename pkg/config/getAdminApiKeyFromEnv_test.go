@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGetAdminApiKeyFromEnv(t *testing.T) {
+	tests := []struct {
+		name           string
+		envAdminApiKey string
+		want           string
+		wantErr        bool
+	}{
+		{
+			name:           "should return an empty key when env variable is not set",
+			envAdminApiKey: "",
+			want:           "",
+			wantErr:        false,
+		},
+		{
+			name:           "should return the env variable value when it is long enough",
+			envAdminApiKey: "todos_Choose_your_own_admin_api_key",
+			want:           "todos_Choose_your_own_admin_api_key",
+			wantErr:        false,
+		},
+		{
+			name:           "should return an error when env variable is too short",
+			envAdminApiKey: "todos_admin",
+			want:           "",
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.envAdminApiKey) > 0 {
+				err := os.Setenv("ADMIN_API_KEY", tt.envAdminApiKey)
+				if err != nil {
+					t.Errorf("Unable to set env variable ADMIN_API_KEY")
+					return
+				}
+				defer os.Unsetenv("ADMIN_API_KEY")
+			}
+			got, err := GetAdminApiKeyFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetAdminApiKeyFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetAdminApiKeyFromEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
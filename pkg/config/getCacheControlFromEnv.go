@@ -0,0 +1,17 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+//GetCacheControlFromEnv returns the Cache-Control policy of the todos responses based on the value of environment variables :
+//  CACHE_CONTROL : string containing the Cache-Control header value, like no-cache or private, max-age=60
+func GetCacheControlFromEnv(defaultCacheControl string) string {
+	cacheControl := defaultCacheControl
+	val, exist := os.LookupEnv("CACHE_CONTROL")
+	if exist {
+		cacheControl = val
+	}
+	return fmt.Sprintf("%s", cacheControl)
+}
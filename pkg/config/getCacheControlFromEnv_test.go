@@ -0,0 +1,51 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGetCacheControlFromEnv(t *testing.T) {
+	type args struct {
+		defaultCacheControl string
+	}
+
+	tests := []struct {
+		name            string
+		args            args
+		envCacheControl string
+		want            string
+	}{
+		{
+			name: "should return the default value when env variable is not set",
+			args: args{
+				defaultCacheControl: "no-cache",
+			},
+			envCacheControl: "",
+			want:            "no-cache",
+		},
+		{
+			name: "should return the env variable value when it is set",
+			args: args{
+				defaultCacheControl: "no-cache",
+			},
+			envCacheControl: "private, max-age=60",
+			want:            "private, max-age=60",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.envCacheControl) > 0 {
+				err := os.Setenv("CACHE_CONTROL", tt.envCacheControl)
+				if err != nil {
+					t.Errorf("Unable to set env variable CACHE_CONTROL")
+					return
+				}
+				defer os.Unsetenv("CACHE_CONTROL")
+			}
+			if got := GetCacheControlFromEnv(tt.args.defaultCacheControl); got != tt.want {
+				t.Errorf("GetCacheControlFromEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
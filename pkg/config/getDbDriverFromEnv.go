@@ -6,7 +6,7 @@ import (
 )
 
 //GetDbDriverFromEnv returns the DB driver based on the value of environment variables :
-//  DB_DRIVER : string containing the type of storage to use one of (memory|postgres)
+//  DB_DRIVER : string containing the type of storage to use one of (memory|postgres|sqlite)
 func GetDbDriverFromEnv(defaultDbDriver string) string {
 	dbDriver := defaultDbDriver
 	val, exist := os.LookupEnv("DB_DRIVER")
@@ -0,0 +1,18 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+//GetDbMemoryDirFromEnv returns the directory used to persist the memory driver based on the value of environment variables :
+//  DB_MEMORY_DIR : string containing the directory of the write-ahead log and snapshots of the memory driver,
+//  when it is empty the memory driver is not durable and all todos are lost when the server stops
+func GetDbMemoryDirFromEnv(defaultDbMemoryDir string) string {
+	dbMemoryDir := defaultDbMemoryDir
+	val, exist := os.LookupEnv("DB_MEMORY_DIR")
+	if exist {
+		dbMemoryDir = val
+	}
+	return fmt.Sprintf("%s", dbMemoryDir)
+}
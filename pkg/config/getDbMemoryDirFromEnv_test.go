@@ -0,0 +1,51 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGetDbMemoryDirFromEnv(t *testing.T) {
+	type args struct {
+		defaultDbMemoryDir string
+	}
+
+	tests := []struct {
+		name           string
+		args           args
+		envDbMemoryDir string
+		want           string
+	}{
+		{
+			name: "should return an empty directory by default when env variable is not set",
+			args: args{
+				defaultDbMemoryDir: "",
+			},
+			envDbMemoryDir: "",
+			want:           "",
+		},
+		{
+			name: "should return the env variable value when it is set",
+			args: args{
+				defaultDbMemoryDir: "",
+			},
+			envDbMemoryDir: "/var/lib/todos",
+			want:           "/var/lib/todos",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.envDbMemoryDir) > 0 {
+				err := os.Setenv("DB_MEMORY_DIR", tt.envDbMemoryDir)
+				if err != nil {
+					t.Errorf("Unable to set env variable DB_MEMORY_DIR")
+					return
+				}
+				defer os.Unsetenv("DB_MEMORY_DIR")
+			}
+			if got := GetDbMemoryDirFromEnv(tt.args.defaultDbMemoryDir); got != tt.want {
+				t.Errorf("GetDbMemoryDirFromEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
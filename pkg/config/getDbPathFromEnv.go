@@ -0,0 +1,17 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+//GetDbPathFromEnv returns the path of the local database file based on the value of environment variables :
+//  DB_PATH : string containing the path of the database file used by the sqlite driver
+func GetDbPathFromEnv(defaultDbPath string) string {
+	dbPath := defaultDbPath
+	val, exist := os.LookupEnv("DB_PATH")
+	if exist {
+		dbPath = val
+	}
+	return fmt.Sprintf("%s", dbPath)
+}
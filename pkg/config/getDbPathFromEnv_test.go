@@ -0,0 +1,51 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGetDbPathFromEnv(t *testing.T) {
+	type args struct {
+		defaultDbPath string
+	}
+
+	tests := []struct {
+		name      string
+		args      args
+		envDbPath string
+		want      string
+	}{
+		{
+			name: "should return the default value when env variable is not set",
+			args: args{
+				defaultDbPath: "todos.db",
+			},
+			envDbPath: "",
+			want:      "todos.db",
+		},
+		{
+			name: "should return the env variable value when it is set",
+			args: args{
+				defaultDbPath: "todos.db",
+			},
+			envDbPath: "/var/lib/todos/todos.db",
+			want:      "/var/lib/todos/todos.db",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.envDbPath) > 0 {
+				err := os.Setenv("DB_PATH", tt.envDbPath)
+				if err != nil {
+					t.Errorf("Unable to set env variable DB_PATH")
+					return
+				}
+				defer os.Unsetenv("DB_PATH")
+			}
+			if got := GetDbPathFromEnv(tt.args.defaultDbPath); got != tt.want {
+				t.Errorf("GetDbPathFromEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// JwtAuth contains the settings of the validation of the JSON Web Tokens sent in the Authorization: Bearer header
+type JwtAuth struct {
+	// Secret is the shared secret of the tokens signed with HS256
+	Secret string
+	// PublicKeyFiles are the PEM files of the public keys of the tokens signed with RS256 or ES256
+	PublicKeyFiles []string
+	// JwksFile is the local JSON Web Key Set file with the public keys of the tokens signed with RS256 or ES256
+	JwksFile string
+	// Issuer is the required value of the iss claim
+	Issuer string
+	// Audience is a required value of the aud claim
+	Audience string
+}
+
+//GetJwtAuthFromEnv returns the settings of the validation of the JSON Web Tokens based on the value of environment variables :
+//	JWT_SECRET : the shared secret of the tokens signed with HS256
+//	JWT_PUBLIC_KEY_FILES : comma separated list of the PEM files of the public keys of the tokens signed with RS256 or ES256
+//	JWT_JWKS_FILE : a local JSON Web Key Set file with the public keys of the tokens signed with RS256 or ES256
+//	JWT_ISSUER : the required value of the iss claim (not checked if env is not defined)
+//	JWT_AUDIENCE : a required value of the aud claim (not checked if env is not defined)
+// the function returns nil when none of JWT_SECRET, JWT_PUBLIC_KEY_FILES and JWT_JWKS_FILE is defined, the tokens
+// are then not used. in case JWT_ISSUER or JWT_AUDIENCE is defined without any key the functions returns an error
+func GetJwtAuthFromEnv() (*JwtAuth, error) {
+	jwtAuth := JwtAuth{
+		Secret:   os.Getenv("JWT_SECRET"),
+		JwksFile: os.Getenv("JWT_JWKS_FILE"),
+		Issuer:   os.Getenv("JWT_ISSUER"),
+		Audience: os.Getenv("JWT_AUDIENCE"),
+	}
+	for _, file := range strings.Split(os.Getenv("JWT_PUBLIC_KEY_FILES"), ",") {
+		if file = strings.TrimSpace(file); file != "" {
+			jwtAuth.PublicKeyFiles = append(jwtAuth.PublicKeyFiles, file)
+		}
+	}
+	if jwtAuth.Secret == "" && jwtAuth.JwksFile == "" && len(jwtAuth.PublicKeyFiles) == 0 {
+		if jwtAuth.Issuer != "" || jwtAuth.Audience != "" {
+			return nil, &ErrorConfig{
+				err: errors.New("missing key"),
+				msg: "ERROR: CONFIG ENV JWT_SECRET, JWT_PUBLIC_KEY_FILES or JWT_JWKS_FILE is required with JWT_ISSUER and JWT_AUDIENCE",
+			}
+		}
+		return nil, nil
+	}
+	return &jwtAuth, nil
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestGetJwtAuthFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    *JwtAuth
+		wantErr bool
+	}{
+		{
+			name:    "should return nil when no env variable is set",
+			env:     map[string]string{},
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name: "should return the shared secret and the claims to check",
+			env: map[string]string{
+				"JWT_SECRET":   "a-shared-secret-of-at-least-32-bytes",
+				"JWT_ISSUER":   "https://auth.example.com",
+				"JWT_AUDIENCE": "todos",
+			},
+			want: &JwtAuth{
+				Secret:   "a-shared-secret-of-at-least-32-bytes",
+				Issuer:   "https://auth.example.com",
+				Audience: "todos",
+			},
+			wantErr: false,
+		},
+		{
+			name: "should return the list of PEM files and the JWKS file",
+			env: map[string]string{
+				"JWT_PUBLIC_KEY_FILES": "keys/rsa.pem, keys/ec.pem,",
+				"JWT_JWKS_FILE":        "keys/jwks.json",
+			},
+			want: &JwtAuth{
+				PublicKeyFiles: []string{"keys/rsa.pem", "keys/ec.pem"},
+				JwksFile:       "keys/jwks.json",
+			},
+			wantErr: false,
+		},
+		{
+			name: "should return an error when the claims to check are set without any key",
+			env: map[string]string{
+				"JWT_ISSUER": "https://auth.example.com",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				err := os.Setenv(key, value)
+				if err != nil {
+					t.Errorf("Unable to set env variable %s", key)
+					return
+				}
+				defer os.Unsetenv(key)
+			}
+			got, err := GetJwtAuthFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetJwtAuthFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetJwtAuthFromEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
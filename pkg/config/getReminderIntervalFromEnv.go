@@ -0,0 +1,33 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+//GetReminderIntervalFromEnv returns the delay between two scans of the reminders of the todos to send,
+//based on the value of the environment variable :
+//	REMINDER_INTERVAL : a duration like 1m or 30s (defaultInterval will be used if env is not defined)
+// a duration of 0 disables the reminders. in case the ENV variable REMINDER_INTERVAL
+// contains an invalid or negative duration the functions returns 0 and an error
+func GetReminderIntervalFromEnv(defaultInterval time.Duration) (time.Duration, error) {
+	val, exist := os.LookupEnv("REMINDER_INTERVAL")
+	if !exist {
+		return defaultInterval, nil
+	}
+	interval, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, &ErrorConfig{
+			err: err,
+			msg: "ERROR: CONFIG ENV REMINDER_INTERVAL should contain a valid duration.",
+		}
+	}
+	if interval < 0 {
+		return 0, &ErrorConfig{
+			err: errors.New("negative duration"),
+			msg: "ERROR: CONFIG ENV REMINDER_INTERVAL should contain a positive duration or 0",
+		}
+	}
+	return interval, nil
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGetReminderIntervalFromEnv(t *testing.T) {
+	type args struct {
+		defaultInterval time.Duration
+	}
+
+	tests := []struct {
+		name                string
+		args                args
+		envReminderInterval string
+		want                time.Duration
+		wantErr             bool
+	}{
+		{
+			name: "should return the default value when env variable is not set",
+			args: args{
+				defaultInterval: time.Minute,
+			},
+			envReminderInterval: "",
+			want:                time.Minute,
+			wantErr:             false,
+		},
+		{
+			name: "should return the env variable value when it is set",
+			args: args{
+				defaultInterval: time.Minute,
+			},
+			envReminderInterval: "30s",
+			want:                30 * time.Second,
+			wantErr:             false,
+		},
+		{
+			name: "should return 0 when env variable disables the reminders",
+			args: args{
+				defaultInterval: time.Minute,
+			},
+			envReminderInterval: "0",
+			want:                0,
+			wantErr:             false,
+		},
+		{
+			name: "should return an error when env variable is not a duration",
+			args: args{
+				defaultInterval: time.Minute,
+			},
+			envReminderInterval: "every minute",
+			want:                0,
+			wantErr:             true,
+		},
+		{
+			name: "should return an error when env variable is negative",
+			args: args{
+				defaultInterval: time.Minute,
+			},
+			envReminderInterval: "-1m",
+			want:                0,
+			wantErr:             true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.envReminderInterval) > 0 {
+				err := os.Setenv("REMINDER_INTERVAL", tt.envReminderInterval)
+				if err != nil {
+					t.Errorf("Unable to set env variable REMINDER_INTERVAL")
+					return
+				}
+				defer os.Unsetenv("REMINDER_INTERVAL")
+			}
+			got, err := GetReminderIntervalFromEnv(tt.args.defaultInterval)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetReminderIntervalFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetReminderIntervalFromEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
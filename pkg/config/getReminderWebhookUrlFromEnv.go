@@ -0,0 +1,29 @@
+package config
+
+import (
+	"errors"
+	"net/url"
+	"os"
+)
+
+//GetReminderWebhookUrlFromEnv returns the URL receiving the reminders of the todos based on the value of environment variables :
+//  REMINDER_WEBHOOK_URL : string containing an absolute http or https URL receiving the reminders in POST requests,
+//  when it is empty the reminders are only written to the log
+// in case the ENV variable REMINDER_WEBHOOK_URL is not an absolute http or https URL the functions returns an empty string and an error
+func GetReminderWebhookUrlFromEnv() (string, error) {
+	val, exist := os.LookupEnv("REMINDER_WEBHOOK_URL")
+	if !exist || val == "" {
+		return "", nil
+	}
+	webhookUrl, err := url.Parse(val)
+	if err == nil && ((webhookUrl.Scheme != "http" && webhookUrl.Scheme != "https") || webhookUrl.Host == "") {
+		err = errors.New("not an absolute http or https URL")
+	}
+	if err != nil {
+		return "", &ErrorConfig{
+			err: err,
+			msg: "ERROR: CONFIG ENV REMINDER_WEBHOOK_URL should contain a valid http or https URL.",
+		}
+	}
+	return val, nil
+}
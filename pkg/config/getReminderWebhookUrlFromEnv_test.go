@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGetReminderWebhookUrlFromEnv(t *testing.T) {
+	tests := []struct {
+		name                  string
+		envReminderWebhookUrl string
+		want                  string
+		wantErr               bool
+	}{
+		{
+			name:                  "should return an empty URL when env variable is not set",
+			envReminderWebhookUrl: "",
+			want:                  "",
+			wantErr:               false,
+		},
+		{
+			name:                  "should return the env variable value when it is set",
+			envReminderWebhookUrl: "https://hooks.example.com/todos/reminders",
+			want:                  "https://hooks.example.com/todos/reminders",
+			wantErr:               false,
+		},
+		{
+			name:                  "should return an error when env variable is not an absolute URL",
+			envReminderWebhookUrl: "/todos/reminders",
+			want:                  "",
+			wantErr:               true,
+		},
+		{
+			name:                  "should return an error when env variable is not an http URL",
+			envReminderWebhookUrl: "ftp://hooks.example.com/todos",
+			want:                  "",
+			wantErr:               true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.envReminderWebhookUrl) > 0 {
+				err := os.Setenv("REMINDER_WEBHOOK_URL", tt.envReminderWebhookUrl)
+				if err != nil {
+					t.Errorf("Unable to set env variable REMINDER_WEBHOOK_URL")
+					return
+				}
+				defer os.Unsetenv("REMINDER_WEBHOOK_URL")
+			}
+			got, err := GetReminderWebhookUrlFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetReminderWebhookUrlFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetReminderWebhookUrlFromEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
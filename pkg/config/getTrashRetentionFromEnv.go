@@ -0,0 +1,33 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+//GetTrashRetentionFromEnv returns the duration the deleted todos stay in the trash before being purged automatically,
+//based on the value of the environment variable :
+//	TRASH_RETENTION : a duration like 720h or 90m (defaultRetention will be used if env is not defined)
+// a duration of 0 keeps the todos in the trash until they are purged. in case the ENV variable TRASH_RETENTION
+// contains an invalid or negative duration the functions returns 0 and an error
+func GetTrashRetentionFromEnv(defaultRetention time.Duration) (time.Duration, error) {
+	val, exist := os.LookupEnv("TRASH_RETENTION")
+	if !exist {
+		return defaultRetention, nil
+	}
+	retention, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, &ErrorConfig{
+			err: err,
+			msg: "ERROR: CONFIG ENV TRASH_RETENTION should contain a valid duration.",
+		}
+	}
+	if retention < 0 {
+		return 0, &ErrorConfig{
+			err: errors.New("negative duration"),
+			msg: "ERROR: CONFIG ENV TRASH_RETENTION should contain a positive duration or 0",
+		}
+	}
+	return retention, nil
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGetTrashRetentionFromEnv(t *testing.T) {
+	type args struct {
+		defaultRetention time.Duration
+	}
+
+	tests := []struct {
+		name              string
+		args              args
+		envTrashRetention string
+		want              time.Duration
+		wantErr           bool
+	}{
+		{
+			name: "should return the default value when env variable is not set",
+			args: args{
+				defaultRetention: 30 * 24 * time.Hour,
+			},
+			envTrashRetention: "",
+			want:              30 * 24 * time.Hour,
+			wantErr:           false,
+		},
+		{
+			name: "should return the env variable value when it is set",
+			args: args{
+				defaultRetention: 30 * 24 * time.Hour,
+			},
+			envTrashRetention: "90m",
+			want:              90 * time.Minute,
+			wantErr:           false,
+		},
+		{
+			name: "should return 0 when env variable disables the purge",
+			args: args{
+				defaultRetention: 30 * 24 * time.Hour,
+			},
+			envTrashRetention: "0",
+			want:              0,
+			wantErr:           false,
+		},
+		{
+			name: "should return an error when env variable is not a duration",
+			args: args{
+				defaultRetention: 30 * 24 * time.Hour,
+			},
+			envTrashRetention: "a month",
+			want:              0,
+			wantErr:           true,
+		},
+		{
+			name: "should return an error when env variable is negative",
+			args: args{
+				defaultRetention: 30 * 24 * time.Hour,
+			},
+			envTrashRetention: "-1h",
+			want:              0,
+			wantErr:           true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.envTrashRetention) > 0 {
+				err := os.Setenv("TRASH_RETENTION", tt.envTrashRetention)
+				if err != nil {
+					t.Errorf("Unable to set env variable TRASH_RETENTION")
+					return
+				}
+				defer os.Unsetenv("TRASH_RETENTION")
+			}
+			got, err := GetTrashRetentionFromEnv(tt.args.defaultRetention)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetTrashRetentionFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetTrashRetentionFromEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+//GetTrustUserHeaderFromEnv returns true when the users are trusted from the X-User header without any token,
+//based on the value of the environment variable :
+//	AUTH_TRUST_USER_HEADER : true to trust the X-User header, only do this in dev or behind a proxy setting it
+// it returns false when env is not defined. in case the ENV variable AUTH_TRUST_USER_HEADER
+// does not contain a boolean the functions returns false and an error
+func GetTrustUserHeaderFromEnv() (bool, error) {
+	val, exist := os.LookupEnv("AUTH_TRUST_USER_HEADER")
+	if !exist || val == "" {
+		return false, nil
+	}
+	trust, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, &ErrorConfig{
+			err: err,
+			msg: "ERROR: CONFIG ENV AUTH_TRUST_USER_HEADER should contain true or false.",
+		}
+	}
+	return trust, nil
+}
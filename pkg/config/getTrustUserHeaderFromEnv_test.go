@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGetTrustUserHeaderFromEnv(t *testing.T) {
+	tests := []struct {
+		name               string
+		envTrustUserHeader string
+		want               bool
+		wantErr            bool
+	}{
+		{
+			name:               "should not trust the header when env variable is not set",
+			envTrustUserHeader: "",
+			want:               false,
+			wantErr:            false,
+		},
+		{
+			name:               "should trust the header when env variable is true",
+			envTrustUserHeader: "true",
+			want:               true,
+			wantErr:            false,
+		},
+		{
+			name:               "should not trust the header when env variable is false",
+			envTrustUserHeader: "false",
+			want:               false,
+			wantErr:            false,
+		},
+		{
+			name:               "should return an error when env variable is not a boolean",
+			envTrustUserHeader: "yes please",
+			want:               false,
+			wantErr:            true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.envTrustUserHeader) > 0 {
+				err := os.Setenv("AUTH_TRUST_USER_HEADER", tt.envTrustUserHeader)
+				if err != nil {
+					t.Errorf("Unable to set env variable AUTH_TRUST_USER_HEADER")
+					return
+				}
+				defer os.Unsetenv("AUTH_TRUST_USER_HEADER")
+			}
+			got, err := GetTrustUserHeaderFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetTrustUserHeaderFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetTrustUserHeaderFromEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
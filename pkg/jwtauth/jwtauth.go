@@ -0,0 +1,300 @@
+// Package jwtauth provides an echo middleware validating the JSON Web Tokens sent in the Authorization: Bearer header.
+// the tokens can be signed with HS256 and a shared secret, or with RS256 and ES256 and the public keys
+// loaded from PEM files or from a local JWKS file.
+package jwtauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// ContextKey is the key of the jwt.MapClaims of the validated token in the echo context
+	ContextKey = "jwtClaims"
+	// MinSecretLength is the smallest number of bytes of the HS256 shared secret
+	MinSecretLength = 32
+)
+
+var (
+	ErrMissingToken      = errors.New("the Authorization header with a Bearer token is required")
+	ErrNoKey             = errors.New("a shared secret or at least one public key is required to validate the tokens")
+	ErrSecretTooShort    = fmt.Errorf("the shared secret must contain at least %d bytes", MinSecretLength)
+	ErrUnexpectedMethod  = errors.New("the token is signed with an unexpected algorithm")
+	ErrUnknownKey        = errors.New("the token is signed with an unknown key")
+	ErrTokenExpired      = errors.New("the token is expired")
+	ErrTokenNotValidYet  = errors.New("the token is not valid yet")
+	ErrInvalidIssuer     = errors.New("the token has an invalid issuer")
+	ErrInvalidAudience   = errors.New("the token has an invalid audience")
+	ErrUnsupportedJwkKey = errors.New("only the RSA and the EC P-256 keys are supported")
+)
+
+// Config defines how the tokens are validated, at least Secret or one of the Keys must be given
+type Config struct {
+	// Secret is the shared secret of the tokens signed with HS256
+	Secret []byte
+	// Keys are the public keys of the tokens signed with RS256 or ES256, indexed by their key id (kid header of the token)
+	Keys map[string]crypto.PublicKey
+	// Issuer is the required value of the iss claim, it is not checked when empty
+	Issuer string
+	// Audience is a required value of the aud claim, it is not checked when empty
+	Audience string
+	// Leeway is the clock skew tolerated when checking the exp and nbf claims
+	Leeway time.Duration
+	// Skipper selects the requests made anonymously
+	Skipper middleware.Skipper
+}
+
+// Validate checks the config can validate tokens
+func (c Config) Validate() error {
+	if len(c.Secret) == 0 && len(c.Keys) == 0 {
+		return ErrNoKey
+	}
+	if len(c.Secret) > 0 && len(c.Secret) < MinSecretLength {
+		return ErrSecretTooShort
+	}
+	return nil
+}
+
+// keyFunc returns the key validating the signature of the token, the signing algorithm must match the type of the key,
+// so a token cannot be signed with HS256 and a public key as secret
+func (c Config) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		if len(c.Secret) == 0 {
+			return nil, ErrUnexpectedMethod
+		}
+		return c.Secret, nil
+	case jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg():
+		key, err := c.publicKey(token)
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			if token.Method.Alg() == jwt.SigningMethodRS256.Alg() {
+				return k, nil
+			}
+		case *ecdsa.PublicKey:
+			if token.Method.Alg() == jwt.SigningMethodES256.Alg() && k.Curve == elliptic.P256() {
+				return k, nil
+			}
+		}
+		return nil, ErrUnexpectedMethod
+	default:
+		return nil, ErrUnexpectedMethod
+	}
+}
+
+// publicKey returns the key with the kid of the token, or the only key when the token has no kid
+func (c Config) publicKey(token *jwt.Token) (crypto.PublicKey, error) {
+	kid, hasKid := token.Header["kid"].(string)
+	if !hasKid {
+		if len(c.Keys) == 1 {
+			for _, key := range c.Keys {
+				return key, nil
+			}
+		}
+		return nil, ErrUnknownKey
+	}
+	key, exist := c.Keys[kid]
+	if !exist {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// ParseToken checks the signature of the token and its exp, nbf, iss and aud claims, then returns its claims
+func (c Config) ParseToken(tokenString string) (jwt.MapClaims, error) {
+	parser := jwt.Parser{
+		ValidMethods: []string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()},
+		// the time claims are checked below with the leeway
+		SkipClaimsValidation: true,
+	}
+	claims := jwt.MapClaims{}
+	if _, err := parser.ParseWithClaims(tokenString, claims, c.keyFunc); err != nil {
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) {
+			if validationErr.Inner != nil {
+				return nil, validationErr.Inner
+			}
+			if validationErr.Errors&jwt.ValidationErrorSignatureInvalid != 0 {
+				// the signing method is not one of the ValidMethods
+				return nil, ErrUnexpectedMethod
+			}
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if !claims.VerifyExpiresAt(now.Add(-c.Leeway).Unix(), true) {
+		return nil, ErrTokenExpired
+	}
+	if !claims.VerifyNotBefore(now.Add(c.Leeway).Unix(), false) {
+		return nil, ErrTokenNotValidYet
+	}
+	if c.Issuer != "" && !claims.VerifyIssuer(c.Issuer, true) {
+		return nil, ErrInvalidIssuer
+	}
+	if c.Audience != "" && !claims.VerifyAudience(c.Audience, true) {
+		return nil, ErrInvalidAudience
+	}
+	return claims, nil
+}
+
+// Middleware returns the echo middleware rejecting with 401 the requests without a valid token,
+// the claims of the token are put in the echo context, see ClaimsFromContext
+func Middleware(c Config) echo.MiddlewareFunc {
+	skipper := c.Skipper
+	if skipper == nil {
+		skipper = middleware.DefaultSkipper
+	}
+	return middleware.JWTWithConfig(middleware.JWTConfig{
+		Skipper:    skipper,
+		ContextKey: ContextKey,
+		ParseTokenFunc: func(auth string, ctx echo.Context) (interface{}, error) {
+			return c.ParseToken(auth)
+		},
+		ErrorHandlerWithContext: func(err error, ctx echo.Context) error {
+			if errors.Is(err, middleware.ErrJWTMissing) {
+				// not kept as internal error, echo would then send its 400 Bad Request
+				return echo.NewHTTPError(http.StatusUnauthorized, ErrMissingToken.Error())
+			}
+			return &echo.HTTPError{
+				Code:     http.StatusUnauthorized,
+				Message:  fmt.Sprintf("invalid token: %v", err),
+				Internal: err,
+			}
+		},
+	})
+}
+
+// ClaimsFromContext returns the claims of the token validated by Middleware, or nil when the middleware was skipped
+func ClaimsFromContext(ctx echo.Context) jwt.MapClaims {
+	claims, _ := ctx.Get(ContextKey).(jwt.MapClaims)
+	return claims
+}
+
+// LoadPEMKeys reads the RSA or EC public keys of the PEM files, they are indexed by the name of their file
+// without the extension, so a token with the kid header "issuer-2022" is validated with the key in issuer-2022.pem
+func LoadPEMKeys(files []string) (map[string]crypto.PublicKey, error) {
+	keys := make(map[string]crypto.PublicKey, len(files))
+	for _, file := range files {
+		pem, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var key crypto.PublicKey
+		key, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			key, err = jwt.ParseECPublicKeyFromPEM(pem)
+			if err != nil {
+				return nil, fmt.Errorf("%s does not contain an RSA or an EC public key", file)
+			}
+		}
+		kid := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		keys[kid] = key
+	}
+	return keys, nil
+}
+
+// jwk is a JSON Web Key as defined by RFC 7517, only the fields of the RSA and EC public keys are read
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// LoadJWKS reads the public keys of a JSON Web Key Set file, they are indexed by their kid.
+// the keys used for encryption (use=enc) are ignored
+func LoadJWKS(file string) (map[string]crypto.PublicKey, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &jwks); err != nil {
+		return nil, fmt.Errorf("%s is not a valid JWKS file: %w", file, err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("%s key %q: %w", file, k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+// publicKey decodes the RSA or EC public key of the JWK
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, ErrUnsupportedJwkKey
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("the point is not on the P-256 curve")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, ErrUnsupportedJwkKey
+	}
+}
+
+// decodeBigInt decodes the base64url encoded big-endian integers of the JWK
+func decodeBigInt(value string) (*big.Int, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes) == 0 {
+		return nil, errors.New("missing key parameter")
+	}
+	return new(big.Int).SetBytes(bytes), nil
+}
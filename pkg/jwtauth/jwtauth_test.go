@@ -0,0 +1,278 @@
+package jwtauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testSecret = "a-shared-secret-of-at-least-32-bytes"
+
+func signToken(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("unable to sign the token: %v", err)
+	}
+	return signed
+}
+
+func writePublicKey(t *testing.T, dir, name string, key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("unable to marshal the public key: %v", err)
+	}
+	file := filepath.Join(dir, name)
+	err = os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatalf("unable to write the public key: %v", err)
+	}
+	return file
+}
+
+func encodeBigInt(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
+
+func TestConfig_ParseToken(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	config := Config{
+		Secret:   []byte(testSecret),
+		Keys:     map[string]crypto.PublicKey{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey},
+		Issuer:   "https://auth.example.com",
+		Audience: "todos",
+		Leeway:   time.Minute,
+	}
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub": "admin",
+			"iss": "https://auth.example.com",
+			"aud": []string{"other", "todos"},
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+	}
+	withClaim := func(name string, value interface{}) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{
+			name:  "should accept a valid token signed with HS256",
+			token: signToken(t, jwt.SigningMethodHS256, []byte(testSecret), "", validClaims()),
+		},
+		{
+			name:  "should accept a valid token signed with RS256",
+			token: signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa", validClaims()),
+		},
+		{
+			name:  "should accept a valid token signed with ES256",
+			token: signToken(t, jwt.SigningMethodES256, ecKey, "ec", validClaims()),
+		},
+		{
+			name:  "should accept a token expired since less than the leeway",
+			token: signToken(t, jwt.SigningMethodHS256, []byte(testSecret), "", withClaim("exp", time.Now().Add(-30*time.Second).Unix())),
+		},
+		{
+			name:    "should reject a token signed with another secret",
+			token:   signToken(t, jwt.SigningMethodHS256, []byte("another-secret-of-at-least-32-bytes"), "", validClaims()),
+			wantErr: jwt.ErrSignatureInvalid,
+		},
+		{
+			name:    "should reject a token signed with RS256 and the key of another kid",
+			token:   signToken(t, jwt.SigningMethodRS256, rsaKey, "ec", validClaims()),
+			wantErr: ErrUnexpectedMethod,
+		},
+		{
+			name:    "should reject a token signed with RS256 and an unknown kid",
+			token:   signToken(t, jwt.SigningMethodRS256, rsaKey, "nope", validClaims()),
+			wantErr: ErrUnknownKey,
+		},
+		{
+			name:    "should reject a token without kid when there are several keys",
+			token:   signToken(t, jwt.SigningMethodRS256, rsaKey, "", validClaims()),
+			wantErr: ErrUnknownKey,
+		},
+		{
+			name:    "should reject an expired token",
+			token:   signToken(t, jwt.SigningMethodHS256, []byte(testSecret), "", withClaim("exp", time.Now().Add(-time.Hour).Unix())),
+			wantErr: ErrTokenExpired,
+		},
+		{
+			name:    "should reject a token without exp",
+			token:   signToken(t, jwt.SigningMethodHS256, []byte(testSecret), "", withClaim("exp", nil)),
+			wantErr: ErrTokenExpired,
+		},
+		{
+			name:    "should reject a token not valid yet",
+			token:   signToken(t, jwt.SigningMethodHS256, []byte(testSecret), "", withClaim("nbf", time.Now().Add(time.Hour).Unix())),
+			wantErr: ErrTokenNotValidYet,
+		},
+		{
+			name:    "should reject a token of another issuer",
+			token:   signToken(t, jwt.SigningMethodHS256, []byte(testSecret), "", withClaim("iss", "https://evil.example.com")),
+			wantErr: ErrInvalidIssuer,
+		},
+		{
+			name:    "should reject a token for another audience",
+			token:   signToken(t, jwt.SigningMethodHS256, []byte(testSecret), "", withClaim("aud", "other")),
+			wantErr: ErrInvalidAudience,
+		},
+		{
+			name:    "should reject a token without signature",
+			token:   signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", validClaims()),
+			wantErr: ErrUnexpectedMethod,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := config.ParseToken(tt.token)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("ParseToken() error = %v, want no error", err)
+					return
+				}
+				if claims["sub"] != "admin" {
+					t.Errorf("ParseToken() sub = %v, want admin", claims["sub"])
+				}
+				return
+			}
+			if err != tt.wantErr {
+				t.Errorf("ParseToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr error
+	}{
+		{
+			name:    "should accept a long enough secret",
+			config:  Config{Secret: []byte(testSecret)},
+			wantErr: nil,
+		},
+		{
+			name:    "should accept a public key without secret",
+			config:  Config{Keys: map[string]crypto.PublicKey{"rsa": &rsa.PublicKey{}}},
+			wantErr: nil,
+		},
+		{
+			name:    "should reject a config without secret and keys",
+			config:  Config{},
+			wantErr: ErrNoKey,
+		},
+		{
+			name:    "should reject a short secret",
+			config:  Config{Secret: []byte("secret")},
+			wantErr: ErrSecretTooShort,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); err != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadPEMKeys(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaFile := writePublicKey(t, dir, "issuer-rsa.pem", &rsaKey.PublicKey)
+	ecFile := writePublicKey(t, dir, "issuer-ec.pem", &ecKey.PublicKey)
+	invalidFile := filepath.Join(dir, "invalid.pem")
+	_ = os.WriteFile(invalidFile, []byte("not a key"), 0600)
+
+	keys, err := LoadPEMKeys([]string{rsaFile, ecFile})
+	if err != nil {
+		t.Fatalf("LoadPEMKeys() error = %v", err)
+	}
+	if rsaPublicKey, ok := keys["issuer-rsa"].(*rsa.PublicKey); !ok || !rsaPublicKey.Equal(&rsaKey.PublicKey) {
+		t.Errorf("LoadPEMKeys() issuer-rsa = %v, want the RSA public key", keys["issuer-rsa"])
+	}
+	if ecPublicKey, ok := keys["issuer-ec"].(*ecdsa.PublicKey); !ok || !ecPublicKey.Equal(&ecKey.PublicKey) {
+		t.Errorf("LoadPEMKeys() issuer-ec = %v, want the EC public key", keys["issuer-ec"])
+	}
+	if _, err := LoadPEMKeys([]string{invalidFile}); err == nil {
+		t.Errorf("LoadPEMKeys() with an invalid file, error = nil, want an error")
+	}
+	if _, err := LoadPEMKeys([]string{filepath.Join(dir, "missing.pem")}); err == nil {
+		t.Errorf("LoadPEMKeys() with a missing file, error = nil, want an error")
+	}
+}
+
+func TestLoadJWKS(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	writeJWKS := func(name, content string) string {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatalf("unable to write the JWKS: %v", err)
+		}
+		return file
+	}
+	validFile := writeJWKS("jwks.json", fmt.Sprintf(`{"keys":[
+		{"kty":"RSA","kid":"rsa","use":"sig","n":"%s","e":"%s"},
+		{"kty":"EC","kid":"ec","crv":"P-256","x":"%s","y":"%s"},
+		{"kty":"RSA","kid":"enc","use":"enc","n":"","e":""}]}`,
+		encodeBigInt(rsaKey.N), encodeBigInt(big.NewInt(int64(rsaKey.E))), encodeBigInt(ecKey.X), encodeBigInt(ecKey.Y)))
+
+	keys, err := LoadJWKS(validFile)
+	if err != nil {
+		t.Fatalf("LoadJWKS() error = %v", err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("LoadJWKS() returned %d keys, want 2 (the encryption key is ignored)", len(keys))
+	}
+	if rsaPublicKey, ok := keys["rsa"].(*rsa.PublicKey); !ok || !rsaPublicKey.Equal(&rsaKey.PublicKey) {
+		t.Errorf("LoadJWKS() rsa = %v, want the RSA public key", keys["rsa"])
+	}
+	if ecPublicKey, ok := keys["ec"].(*ecdsa.PublicKey); !ok || !ecPublicKey.Equal(&ecKey.PublicKey) {
+		t.Errorf("LoadJWKS() ec = %v, want the EC public key", keys["ec"])
+	}
+
+	invalidFiles := map[string]string{
+		"not json":           "keys",
+		"unsupported curve":  `{"keys":[{"kty":"EC","kid":"ec","crv":"P-384","x":"AQ","y":"AQ"}]}`,
+		"point not on curve": `{"keys":[{"kty":"EC","kid":"ec","crv":"P-256","x":"AQ","y":"AQ"}]}`,
+		"unsupported type":   `{"keys":[{"kty":"OKP","kid":"ed","crv":"Ed25519","x":"AQ"}]}`,
+		"missing modulus":    `{"keys":[{"kty":"RSA","kid":"rsa","e":"AQAB"}]}`,
+	}
+	for name, content := range invalidFiles {
+		if _, err := LoadJWKS(writeJWKS("invalid.json", content)); err == nil {
+			t.Errorf("LoadJWKS() with %s, error = nil, want an error", name)
+		}
+	}
+}
@@ -1,14 +1,16 @@
 #!/bin/bash
-curl -H "Content-Type: application/json" 'http://localhost:8080/todos' |json_pp
-curl -XPOST -H "Content-Type: application/json" -d '{"task":"learn Linux"}'  'http://localhost:8080/todos'
-curl -H "Content-Type: application/json" 'http://localhost:8080/todos' |json_pp
-curl -H "Content-Type: application/json" 'http://localhost:8080/todos/1' |json_pp
-curl -H "Content-Type: application/json" 'http://localhost:8080/todos/3' |json_pp
-curl -v -XPUT -H "Content-Type: application/json" -d '{"id": 3, "task":"learn Linux", "completed": true}' 'http://localhost:8080/todos/3'
-curl -H "Content-Type: application/json" 'http://localhost:8080/todos/3' |json_pp
-curl -v -XPUT -H "Content-Type: application/json" -d '{"id": 3, "task":"learn Linux", "completed": false}' 'http://localhost:8080/todos/3'
-curl -H "Content-Type: application/json" 'http://localhost:8080/todos/3' |json_pp
-curl -H "Content-Type: application/json" 'http://localhost:8080/todos/maxid'
-curl -v -XDELETE -H "Content-Type: application/json" 'http://localhost:8080/todos/3'
-curl -H "Content-Type: application/json" 'http://localhost:8080/todos/maxid'
-curl -H "Content-Type: application/json" 'http://localhost:8080/todos' |json_pp
+# the requests are authenticated with the API key of ADMIN_API_KEY, the one given to the server in your .env file
+ADMIN_API_KEY=${ADMIN_API_KEY:?"please set ADMIN_API_KEY to the key given to the server"}
+curl -H "X-API-Key: $ADMIN_API_KEY" -H "Content-Type: application/json" 'http://localhost:8080/todos' |json_pp
+curl -XPOST -H "X-API-Key: $ADMIN_API_KEY" -H "Content-Type: application/json" -d '{"task":"learn Linux"}'  'http://localhost:8080/todos'
+curl -H "X-API-Key: $ADMIN_API_KEY" -H "Content-Type: application/json" 'http://localhost:8080/todos' |json_pp
+curl -H "X-API-Key: $ADMIN_API_KEY" -H "Content-Type: application/json" 'http://localhost:8080/todos/1' |json_pp
+curl -H "X-API-Key: $ADMIN_API_KEY" -H "Content-Type: application/json" 'http://localhost:8080/todos/3' |json_pp
+curl -v -XPUT -H "X-API-Key: $ADMIN_API_KEY" -H "Content-Type: application/json" -d '{"id": 3, "task":"learn Linux", "completed": true}' 'http://localhost:8080/todos/3'
+curl -H "X-API-Key: $ADMIN_API_KEY" -H "Content-Type: application/json" 'http://localhost:8080/todos/3' |json_pp
+curl -v -XPUT -H "X-API-Key: $ADMIN_API_KEY" -H "Content-Type: application/json" -d '{"id": 3, "task":"learn Linux", "completed": false}' 'http://localhost:8080/todos/3'
+curl -H "X-API-Key: $ADMIN_API_KEY" -H "Content-Type: application/json" 'http://localhost:8080/todos/3' |json_pp
+curl -H "X-API-Key: $ADMIN_API_KEY" -H "Content-Type: application/json" 'http://localhost:8080/todos/maxid'
+curl -v -XDELETE -H "X-API-Key: $ADMIN_API_KEY" -H "Content-Type: application/json" 'http://localhost:8080/todos/3'
+curl -H "X-API-Key: $ADMIN_API_KEY" -H "Content-Type: application/json" 'http://localhost:8080/todos/maxid'
+curl -H "X-API-Key: $ADMIN_API_KEY" -H "Content-Type: application/json" 'http://localhost:8080/todos' |json_pp
@@ -15,3 +15,336 @@ create unique index todos_id_uindex on public.todos (id);
 alter table public.todos
     add constraint todos_pk primary key (id);
 
+
+alter table public.todos
+    add column task_tsv tsvector generated always as (to_tsvector('simple', coalesce(task, ''))) stored;
+
+create index todos_task_tsv_idx on public.todos using gin (task_tsv);
+
+alter table public.todos
+    add column version integer not null default 1;
+
+comment
+on column public.todos.version is 'incremented at every change of the todo, used for optimistic concurrency';
+
+alter table public.todos
+    add column updated_at timestamp;
+
+update public.todos
+set updated_at = coalesce(completed_at, created_at);
+
+comment
+on column public.todos.updated_at is 'time of the last change of the todo, used for http caching';
+
+alter table public.todos
+    add column deleted_at timestamp;
+
+create index todos_deleted_at_idx on public.todos (deleted_at) where deleted_at is not null;
+
+comment
+on column public.todos.deleted_at is 'time when the todo was moved to the trash, null for the live todos';
+
+create table public.todo_events
+(
+    id          bigserial,
+    todo_id     int       not null,
+    type        text      not null,
+    actor       text      not null,
+    occurred_at timestamp not null default now(),
+    before      jsonb,
+    after       jsonb
+);
+
+comment
+on table public.todo_events is 'history of the changes of the todos, the events are kept after the todos are purged';
+
+alter table public.todo_events
+    add constraint todo_events_pk primary key (id);
+
+create index todo_events_todo_id_idx on public.todo_events (todo_id, id);
+
+create index todo_events_occurred_at_idx on public.todo_events (occurred_at);
+
+create table public.users
+(
+    id         serial,
+    login      text      not null,
+    name       text,
+    is_admin   boolean   not null default false,
+    created_at timestamp not null default now()
+);
+
+comment
+on table public.users is 'users owning the todos, only the owner of a todo can see and change it';
+
+alter table public.users
+    add constraint users_pk primary key (id);
+
+alter table public.users
+    add constraint users_login_uk unique (login);
+
+insert into public.users (login, is_admin)
+values ('admin', true);
+
+alter table public.todos
+    add column owner_id int;
+
+update public.todos
+set owner_id = (select id from public.users where login = 'admin');
+
+alter table public.todos
+    alter column owner_id set not null;
+
+alter table public.todos
+    add constraint todos_owner_id_fk foreign key (owner_id) references public.users (id);
+
+create index todos_owner_id_idx on public.todos (owner_id);
+
+alter table public.todo_events
+    add column owner_id int;
+
+update public.todo_events
+set owner_id = (select id from public.users where login = 'admin');
+
+alter table public.todo_events
+    alter column owner_id set not null;
+
+create table public.api_keys
+(
+    id           serial,
+    name         text      not null,
+    prefix       text      not null,
+    hash         text      not null,
+    user_id      int       not null,
+    scopes       text      not null,
+    created_at   timestamp not null default now(),
+    expires_at   timestamp,
+    last_used_at timestamp
+);
+
+comment
+on table public.api_keys is 'API keys of the scripts acting for a user, only the sha-256 hash of the keys is stored';
+
+alter table public.api_keys
+    add constraint api_keys_pk primary key (id);
+
+alter table public.api_keys
+    add constraint api_keys_hash_uk unique (hash);
+
+alter table public.api_keys
+    add constraint api_keys_user_id_fk foreign key (user_id) references public.users (id);
+
+alter table public.users
+    add column role text not null default 'editor';
+
+alter table public.users
+    add constraint users_role_ck check (role in ('viewer', 'editor', 'admin'));
+
+update public.users
+set role = 'admin'
+where is_admin;
+
+alter table public.users
+    drop column is_admin;
+
+comment
+on column public.users.role is 'viewer can only read the todos, editor can also change them, admin can also manage the users and the API keys';
+
+create table public.lists
+(
+    id         serial,
+    name       text      not null,
+    owner_id   int       not null,
+    created_at timestamp not null default now(),
+    updated_at timestamp not null default now()
+);
+
+comment
+on table public.lists is 'named lists of todos, the names of the lists of a user are unique';
+
+alter table public.lists
+    add constraint lists_pk primary key (id);
+
+alter table public.lists
+    add constraint lists_owner_id_name_uk unique (owner_id, name);
+
+alter table public.lists
+    add constraint lists_owner_id_fk foreign key (owner_id) references public.users (id);
+
+alter table public.todos
+    add column list_id int;
+
+alter table public.todos
+    add constraint todos_list_id_fk foreign key (list_id) references public.lists (id) on delete cascade;
+
+create index todos_list_id_idx on public.todos (list_id);
+
+comment
+on column public.todos.list_id is 'list containing the todo, the todos of a list are removed with it';
+
+create table public.tags
+(
+    id       serial,
+    owner_id int  not null,
+    name     text not null
+);
+
+comment
+on table public.tags is 'tags of the todos of a user, stored in lowercase and without their leading hash sign';
+
+alter table public.tags
+    add constraint tags_pk primary key (id);
+
+alter table public.tags
+    add constraint tags_owner_id_name_uk unique (owner_id, name);
+
+alter table public.tags
+    add constraint tags_owner_id_fk foreign key (owner_id) references public.users (id);
+
+create table public.todo_tags
+(
+    todo_id int not null,
+    tag_id  int not null
+);
+
+comment
+on table public.todo_tags is 'tags of every todo, they are removed with the todo or the tag';
+
+alter table public.todo_tags
+    add constraint todo_tags_pk primary key (todo_id, tag_id);
+
+alter table public.todo_tags
+    add constraint todo_tags_todo_id_fk foreign key (todo_id) references public.todos (id) on delete cascade;
+
+alter table public.todo_tags
+    add constraint todo_tags_tag_id_fk foreign key (tag_id) references public.tags (id) on delete cascade;
+
+create index todo_tags_tag_id_idx on public.todo_tags (tag_id);
+
+alter table public.todos
+    add column due_at timestamptz;
+
+alter table public.todos
+    add column priority int;
+
+alter table public.todos
+    add constraint todos_priority_ck check (priority between 1 and 4);
+
+comment
+on column public.todos.due_at is 'date-time when the todo must be completed';
+
+comment
+on column public.todos.priority is 'priority of the todo, from 1 for the most urgent to 4';
+
+create index todos_due_at_priority_idx on public.todos (due_at, priority) where deleted_at is null and not completed;
+
+comment
+on index public.todos_due_at_priority_idx is 'the overdue and upcoming todos are the live todos not completed sorted by due date then priority';
+
+create index todos_priority_idx on public.todos (priority);
+
+alter table public.todos
+    add column recurrence text;
+
+alter table public.todos
+    add column series_id int;
+
+comment
+on column public.todos.recurrence is 'recurrence rule of the todo, a subset of the iCalendar RRULE in its canonical form';
+
+comment
+on column public.todos.series_id is 'id of the first todo of the series of occurrences of a recurring todo';
+
+create index todos_series_id_idx on public.todos (series_id);
+
+alter table public.todos
+    add column parent_id int references public.todos (id) on delete set null,
+    add constraint todos_parent_id_check check (parent_id <> id);
+
+alter table public.todos
+    add column auto_complete boolean;
+
+comment
+on column public.todos.parent_id is 'id of the parent of the todo, null for a root todo';
+
+comment
+on column public.todos.auto_complete is 'true when the todo is completed as soon as all its children are completed, null otherwise';
+
+create index todos_parent_id_idx on public.todos (parent_id);
+
+create table public.todo_dependencies
+(
+    todo_id    int not null,
+    blocker_id int not null
+);
+
+comment
+on table public.todo_dependencies is 'blocking dependencies, a todo cannot be completed while one of its blockers is open. they are removed with the todos';
+
+alter table public.todo_dependencies
+    add constraint todo_dependencies_pk primary key (todo_id, blocker_id);
+
+alter table public.todo_dependencies
+    add constraint todo_dependencies_check check (todo_id <> blocker_id);
+
+alter table public.todo_dependencies
+    add constraint todo_dependencies_todo_id_fk foreign key (todo_id) references public.todos (id) on delete cascade;
+
+alter table public.todo_dependencies
+    add constraint todo_dependencies_blocker_id_fk foreign key (blocker_id) references public.todos (id) on delete cascade;
+
+create index todo_dependencies_blocker_id_idx on public.todo_dependencies (blocker_id);
+
+alter table public.todos
+    add column remind_at timestamptz;
+
+alter table public.todos
+    add column reminded_at timestamptz;
+
+comment
+on column public.todos.remind_at is 'date-time when the owner of the todo is reminded of it, the due date is used when it is null';
+
+comment
+on column public.todos.reminded_at is 'reminder date-time of the last reminder sent, the reminder is sent again when remind_at or due_at changes';
+
+-- the todos already overdue do not get a reminder for a due date that passed before the reminders existed
+update public.todos
+set reminded_at = due_at
+where due_at <= now();
+
+create index todos_reminder_idx on public.todos (coalesce(remind_at, due_at)) where deleted_at is null and not completed;
+
+comment
+on index public.todos_reminder_idx is 'the reminders are sent for the live todos not completed in the order of their reminder date-time';
+
+-- the dependencies already there get the time of the migration
+alter table public.todo_dependencies
+    add column created_at timestamptz not null default now();
+
+comment
+on column public.todo_dependencies.created_at is 'date-time when the dependency was added, the cached lists of todos are validated with the last one';
+
+-- the dates of the todos were stored without time zone in UTC, they get a time zone like their due date
+-- so they are compared with it and with now() whatever the time zone of the session
+alter table public.todos
+    alter column created_at type timestamptz using created_at at time zone 'UTC',
+    alter column completed_at type timestamptz using completed_at at time zone 'UTC',
+    alter column updated_at type timestamptz using updated_at at time zone 'UTC',
+    alter column deleted_at type timestamptz using deleted_at at time zone 'UTC';
+
+-- like the todos, all the dates are stored with time zone, so remind_at and due_at are compared with them
+-- and with now() whatever the time zone of the session
+alter table public.todo_events
+    alter column occurred_at type timestamptz using occurred_at at time zone 'UTC';
+
+alter table public.users
+    alter column created_at type timestamptz using created_at at time zone 'UTC';
+
+alter table public.api_keys
+    alter column created_at type timestamptz using created_at at time zone 'UTC',
+    alter column expires_at type timestamptz using expires_at at time zone 'UTC',
+    alter column last_used_at type timestamptz using last_used_at at time zone 'UTC';
+
+alter table public.lists
+    alter column created_at type timestamptz using created_at at time zone 'UTC',
+    alter column updated_at type timestamptz using updated_at at time zone 'UTC';
@@ -1,3 +1,3 @@
-insert INTO todos (task,completed,created_at,completed_at)
-VALUES ('Learn GO', true, '2020-02-21T08:00:23.877Z', '2021-10-07T15:02:23.877Z' ),
-       ('Learn OpenAPI', false, '2020-02-21T08:00:23.877Z', null );
+insert INTO todos (task,completed,created_at,completed_at,owner_id)
+VALUES ('Learn GO', true, '2020-02-21T08:00:23.877Z', '2021-10-07T15:02:23.877Z', (select id from users where login = 'admin') ),
+       ('Learn OpenAPI', false, '2020-02-21T08:00:23.877Z', null, (select id from users where login = 'admin') );